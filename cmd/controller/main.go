@@ -4,6 +4,16 @@ import (
 	"flag"
 	"github.com/tommenx/hdfs-operator/pkg/controller"
 	"github.com/tommenx/hdfs-operator/pkg/controller/hdfscluster"
+	"github.com/tommenx/hdfs-operator/pkg/controller/hdfsdirectory"
+	"github.com/tommenx/hdfs-operator/pkg/controller/hdfsoperation"
+	"github.com/tommenx/hdfs-operator/pkg/controller/hdfsreplication"
+	"github.com/tommenx/hdfs-operator/pkg/controller/hdfssnapshot"
+	"github.com/tommenx/hdfs-operator/pkg/controller/hdfssnapshotschedule"
+	"time"
+)
+
+var (
+	statusRefreshInterval = flag.Duration("status-refresh-interval", 30*time.Second, "interval to refresh the hdfs status from the name node")
 )
 
 func init() {
@@ -17,9 +27,19 @@ func main() {
 	cli, informerFactory := controller.NewSLCliAndInformerFactory(path)
 	stopCh := make(chan struct{})
 	defer close(stopCh)
-	control := hdfscluster.NewController(kubeCli, cli, informerFactory, kubeInformerFactory)
+	control := hdfscluster.NewController(kubeCli, cli, informerFactory, kubeInformerFactory, *statusRefreshInterval)
+	snapshotControl := hdfssnapshot.NewController(kubeCli, cli, informerFactory, kubeInformerFactory)
+	scheduleControl := hdfssnapshotschedule.NewController(cli, informerFactory)
+	directoryControl := hdfsdirectory.NewController(kubeCli, cli, informerFactory, kubeInformerFactory, *statusRefreshInterval)
+	operationControl := hdfsoperation.NewController(kubeCli, cli, informerFactory, kubeInformerFactory)
+	replicationControl := hdfsreplication.NewController(kubeCli, cli, informerFactory, kubeInformerFactory)
 	go informerFactory.Start(stopCh)
 	go kubeInformerFactory.Start(stopCh)
+	go snapshotControl.Run(1, stopCh)
+	go scheduleControl.Run(1, stopCh)
+	go directoryControl.Run(1, stopCh)
+	go operationControl.Run(1, stopCh)
+	go replicationControl.Run(1, stopCh)
 	control.Run(1, stopCh)
 
 }
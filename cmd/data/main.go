@@ -21,18 +21,23 @@ func main() {
 	podInformer := informerFactory.Core().V1().Pods()
 	svcInformer := informerFactory.Core().V1().Services()
 	setInformer := informerFactory.Apps().V1().StatefulSets()
+	cmInformer := informerFactory.Core().V1().ConfigMaps()
+	jobInformer := informerFactory.Batch().V1().Jobs()
 	go informerFactory.Start(stopCh)
 	svcControl := controller.NewRealServiceControl(kubeCli, svcInformer.Lister())
 	//pvcControl := controller.NewRealPVCControl(kubeCli)
 	setControl := controller.NewRealStatefulSetControl(kubeCli, setInformer.Lister())
 	//deployControl := controller.NewRealDeploymentControl(kubeCli)
-	//podControl := controller.NewRealPodControl(kubeCli, podInformer.Lister())
-	if !cache.WaitForCacheSync(stopCh, podInformer.Informer().HasSynced) {
+	podControl := controller.NewRealPodControl(kubeCli, podInformer.Lister())
+	cmControl := controller.NewRealConfigMapControl(kubeCli, cmInformer.Lister())
+	jobControl := controller.NewRealJobControl(kubeCli, jobInformer.Lister())
+	if !cache.WaitForCacheSync(stopCh, podInformer.Informer().HasSynced, cmInformer.Informer().HasSynced, jobInformer.Informer().HasSynced) {
 		return
 	}
 	hdfsControl := hdfscluster.NewHdfsController(cli)
-	//namenode := manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl)
-	datanode := manager.NewDataNodeManager(setControl, svcControl, manager.NewDataNodeScaler())
+	eventControl := controller.NewRealEventControl(kubeCli)
+	//namenode := manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl, restorer)
+	datanode := manager.NewDataNodeManager(setControl, svcControl, manager.NewDataNodeScaler(cmControl, jobControl, podControl, eventControl, controller.NewHdfsClient), eventControl)
 	hc, err := hdfsControl.Get()
 	if err != nil {
 		glog.Errorf("get hdfs cluster error,err=%+v", err)
@@ -6,6 +6,7 @@ import (
 	"github.com/tommenx/hdfs-operator/pkg/controller"
 	"github.com/tommenx/hdfs-operator/pkg/controller/hdfscluster"
 	"github.com/tommenx/hdfs-operator/pkg/manager"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
 	"k8s.io/client-go/tools/cache"
 )
 
@@ -31,7 +32,8 @@ func main() {
 		return
 	}
 	hdfsControl := hdfscluster.NewHdfsController(cli)
-	namenode := manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl)
+	restorer := manager.NewNameNodeRestorer(controller.NewRealSecretControl(kubeCli), s3.NewClient)
+	namenode := manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl, restorer)
 	hc, err := hdfsControl.Get()
 	if err != nil {
 		glog.Errorf("get hdfs cluster error,err=%+v", err)
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/webhook"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+)
+
+var (
+	certFile = flag.String("tls-cert-file", "/etc/webhook/certs/cert.pem", "x509 certificate for https")
+	keyFile  = flag.String("tls-private-key-file", "/etc/webhook/certs/key.pem", "x509 private key matching tls-cert-file")
+	port     = flag.Int("port", 8443, "port the webhook server listens on")
+)
+
+func init() {
+	flag.Set("logtostderr", "true")
+}
+
+func main() {
+	flag.Parse()
+	path := "/root/.kube/config"
+	cli, _ := controller.NewSLCliAndInformerFactory(path)
+	injector := webhook.NewPodInjector(func(namespace string, name string) (*v1alpha1.HdfsCluster, error) {
+		return cli.StorageV1alpha1().HdfsClusters(namespace).Get(name, metav1.GetOptions{})
+	})
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/mutate-pod", webhook.Serve(injector.Admit))
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: mux,
+	}
+	glog.Infof("pod webhook server listens on %s", server.Addr)
+	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil {
+		glog.Fatalf("pod webhook server error, err=%+v", err)
+	}
+}
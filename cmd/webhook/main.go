@@ -0,0 +1,38 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/webhook"
+	"net/http"
+)
+
+var (
+	certFile = flag.String("tls-cert-file", "/etc/webhook/certs/cert.pem", "x509 certificate for https")
+	keyFile  = flag.String("tls-private-key-file", "/etc/webhook/certs/key.pem", "x509 private key matching tls-cert-file")
+	port     = flag.Int("port", 8443, "port the webhook server listens on")
+)
+
+func init() {
+	flag.Set("logtostderr", "true")
+}
+
+func main() {
+	flag.Parse()
+	path := "/root/.kube/config"
+	cli, _ := controller.NewSLCliAndInformerFactory(path)
+	validator := webhook.NewHdfsClusterValidator(cli)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/validate-hdfscluster", webhook.Serve(validator.Admit))
+	server := &http.Server{
+		Addr:    fmt.Sprintf(":%d", *port),
+		Handler: mux,
+	}
+	glog.Infof("webhook server listens on %s", server.Addr)
+	if err := server.ListenAndServeTLS(*certFile, *keyFile); err != nil {
+		glog.Fatalf("webhook server error, err=%+v", err)
+	}
+}
@@ -0,0 +1,85 @@
+#!/bin/bash -e
+
+# starts the kdc of deploy/kerberos and the kerberized demo cluster in the
+# default namespace of the current kubectl context, then checks that hdfs
+# refuses a client without a ticket and does a write and read round trip
+# with one. The operator and its crd have to be running already
+#
+#   hack/e2e-kerberos.sh          leaves everything running
+#   CLEANUP=1 hack/e2e-kerberos.sh deletes the cluster, the kdc and the secrets
+
+cd "$(dirname "$0")/.."
+
+timeout=${TIMEOUT:-600s}
+nn=demonn.default.svc.cluster.local
+tmp=$(mktemp -d)
+
+cleanup() {
+  rm -rf $tmp
+  if [ -n "$CLEANUP" ]; then
+    kubectl delete --ignore-not-found -f deploy/kerberos/demo.yaml -f deploy/kerberos/kdc.yaml
+    kubectl delete --ignore-not-found secret demo-ca demo-nn-keytab demo-dn-keytab demo-admin-keytab
+  fi
+}
+trap cleanup EXIT
+
+echo "starting the kdc"
+kubectl apply -f deploy/kerberos/kdc.yaml
+kubectl rollout status deployment/kdc --timeout=$timeout
+kdc=$(kubectl get pod -l app=kdc -o jsonpath='{.items[0].metadata.name}')
+# setup.sh writes the keytabs before it starts the kdc
+for i in $(seq 60); do
+  if kubectl exec $kdc -- test -s /keytabs/dn.keytab 2>/dev/null; then
+    break
+  fi
+  sleep 5
+done
+
+echo "copying the keytabs into secrets"
+for k in nn dn admin; do
+  kubectl exec $kdc -- cat /keytabs/$k.keytab > $tmp/$k.keytab
+  kubectl create secret generic demo-$k-keytab --from-file=keytab=$tmp/$k.keytab \
+    --dry-run -o yaml | kubectl apply -f -
+done
+if ! kubectl get secret demo-ca > /dev/null 2>&1; then
+  openssl req -x509 -newkey rsa:2048 -nodes -days 30 -subj /CN=hdfs-ca \
+    -keyout $tmp/ca.key -out $tmp/ca.crt 2> /dev/null
+  kubectl create secret tls demo-ca --cert=$tmp/ca.crt --key=$tmp/ca.key
+fi
+
+echo "starting the kerberized cluster"
+kubectl apply -f deploy/kerberos/demo.yaml
+for i in $(seq 60); do
+  if kubectl get deployment/demo-namenode statefulset/demo-datanode > /dev/null 2>&1; then
+    break
+  fi
+  sleep 5
+done
+kubectl rollout status deployment/demo-namenode --timeout=$timeout
+kubectl rollout status statefulset/demo-datanode --timeout=$timeout
+
+pod=$(kubectl get pod -l app=namenode -o jsonpath='{.items[0].metadata.name}')
+nn_exec() {
+  kubectl exec -i $pod -- bash -c "$1"
+}
+nn_exec 'command -v kinit > /dev/null || (apt-get update && apt-get install -y krb5-user) > /dev/null'
+
+echo "checking that hdfs refuses a client without a ticket"
+if nn_exec 'kdestroy > /dev/null 2>&1; hdfs dfs -ls /' > /dev/null 2>&1; then
+  echo "hdfs answers a client without a kerberos ticket"
+  exit 1
+fi
+
+echo "writing and reading a file with a ticket"
+content="kerberos round trip $(date +%s)"
+got=$(nn_exec "set -e
+kinit -kt /etc/security/keytabs/hdfs.keytab nn/$nn@EXAMPLE.COM
+echo '$content' | hdfs dfs -put -f - /e2e-kerberos
+hdfs dfs -cat /e2e-kerberos
+hdfs dfs -rm -skipTrash /e2e-kerberos > /dev/null
+kdestroy")
+if [ "$got" != "$content" ]; then
+  echo "read back \"$got\", want \"$content\""
+  exit 1
+fi
+echo "ok"
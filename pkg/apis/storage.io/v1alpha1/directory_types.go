@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsDirectory struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HdfsDirectorySpec   `json:"spec"`
+	Status HdfsDirectoryStatus `json:"status"`
+}
+
+// HdfsDirectorySpec is the desired state of a directory, an unset field is
+// left as it is on the name node. Deleting the HdfsDirectory keeps the directory
+type HdfsDirectorySpec struct {
+	// Cluster is the hdfs cluster in the same namespace
+	Cluster string `json:"cluster"`
+	// Path is created with its missing parents
+	Path  string `json:"path"`
+	Owner string `json:"owner,omitempty"`
+	Group string `json:"group,omitempty"`
+	// Permission is octal, e.g. 750
+	Permission string `json:"permission,omitempty"`
+	// NamespaceQuota limits the number of files and directories, -1 clears it
+	NamespaceQuota *int64 `json:"namespace_quota,omitempty"`
+	// SpaceQuota limits the raw space consumed including replicas, a negative value clears it
+	SpaceQuota *resource.Quantity `json:"space_quota,omitempty"`
+	// StoragePolicy is one of HOT, WARM, COLD, ALL_SSD, ONE_SSD and LAZY_PERSIST
+	StoragePolicy string `json:"storage_policy,omitempty"`
+	// EncryptionKey makes the path an encryption zone of the key, which is
+	// created in the kms of the cluster when missing. The zone can only be
+	// created while the directory is empty and never changes afterwards
+	EncryptionKey string `json:"encryption_key,omitempty"`
+}
+
+type HdfsDirectoryPhase string
+
+const (
+	HdfsDirectoryPending HdfsDirectoryPhase = "Pending"
+	HdfsDirectoryReady   HdfsDirectoryPhase = "Ready"
+	HdfsDirectoryFailed  HdfsDirectoryPhase = "Failed"
+)
+
+type HdfsDirectoryStatus struct {
+	Phase HdfsDirectoryPhase `json:"phase,omitempty"`
+	// ObservedGeneration is the generation of the spec last converged
+	ObservedGeneration int64 `json:"observed_generation,omitempty"`
+	// Usage is refreshed periodically
+	Usage *DirectoryUsage `json:"usage,omitempty"`
+	// EncryptionZone is the key of the encryption zone created on the path
+	EncryptionZone string `json:"encryption_zone,omitempty"`
+	Message        string `json:"message,omitempty"`
+}
+
+// DirectoryUsage is the content summary of the directory, quotas are -1 when unset
+type DirectoryUsage struct {
+	FileCount      int64 `json:"file_count"`
+	DirectoryCount int64 `json:"directory_count"`
+	// Length is the logical size of the files in bytes
+	Length int64 `json:"length"`
+	// SpaceConsumed is the raw size of the files including replicas, counted against SpaceQuota
+	SpaceConsumed  int64 `json:"space_consumed"`
+	NamespaceQuota int64 `json:"namespace_quota"`
+	SpaceQuota     int64 `json:"space_quota"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsDirectoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HdfsDirectory `json:"items"`
+}
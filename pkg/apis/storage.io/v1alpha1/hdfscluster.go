@@ -0,0 +1,79 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition with the given type, or nil if it is not set
+func (hc *HdfsCluster) GetCondition(condType HdfsClusterConditionType) *HdfsClusterCondition {
+	for i := range hc.Status.Conditions {
+		if hc.Status.Conditions[i].Type == condType {
+			return &hc.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds or replaces the condition of the same type,
+// the transition time only moves when the status changes
+func (hc *HdfsCluster) SetCondition(condType HdfsClusterConditionType, status corev1.ConditionStatus, reason, message string) {
+	cond := hc.GetCondition(condType)
+	if cond == nil {
+		hc.Status.Conditions = append(hc.Status.Conditions, HdfsClusterCondition{
+			Type:               condType,
+			Status:             status,
+			LastTransitionTime: metav1.Now(),
+			Reason:             reason,
+			Message:            message,
+		})
+		return
+	}
+	if cond.Status != status {
+		cond.Status = status
+		cond.LastTransitionTime = metav1.Now()
+	}
+	cond.Reason = reason
+	cond.Message = message
+}
+
+// IsConditionTrue reports whether the condition with the given type is set to true
+func (hc *HdfsCluster) IsConditionTrue(condType HdfsClusterConditionType) bool {
+	cond := hc.GetCondition(condType)
+	return cond != nil && cond.Status == corev1.ConditionTrue
+}
+
+// NameNodePVCRetentionPolicy returns the name node policy with Retain as default
+func (hc *HdfsCluster) NameNodePVCRetentionPolicy() ComponentPVCRetentionPolicy {
+	var p *ComponentPVCRetentionPolicy
+	if hc.Spec.PVCRetentionPolicy != nil {
+		p = hc.Spec.PVCRetentionPolicy.NameNode
+	}
+	return withRetainDefault(p)
+}
+
+// DataNodePVCRetentionPolicy returns the data node policy with Retain as default
+func (hc *HdfsCluster) DataNodePVCRetentionPolicy() ComponentPVCRetentionPolicy {
+	var p *ComponentPVCRetentionPolicy
+	if hc.Spec.PVCRetentionPolicy != nil {
+		p = hc.Spec.PVCRetentionPolicy.DataNode
+	}
+	return withRetainDefault(p)
+}
+
+func withRetainDefault(p *ComponentPVCRetentionPolicy) ComponentPVCRetentionPolicy {
+	out := ComponentPVCRetentionPolicy{
+		WhenScaled:  RetainPVCRetentionPolicy,
+		WhenDeleted: RetainPVCRetentionPolicy,
+	}
+	if p == nil {
+		return out
+	}
+	if p.WhenScaled == DeletePVCRetentionPolicy {
+		out.WhenScaled = DeletePVCRetentionPolicy
+	}
+	if p.WhenDeleted == DeletePVCRetentionPolicy {
+		out.WhenDeleted = DeletePVCRetentionPolicy
+	}
+	return out
+}
@@ -0,0 +1,95 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsOperation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HdfsOperationSpec   `json:"spec"`
+	Status HdfsOperationStatus `json:"status"`
+}
+
+type HdfsOperationType string
+
+const (
+	// OperationFsck checks the health of the file system under a path
+	OperationFsck HdfsOperationType = "Fsck"
+	// OperationBalancer moves blocks until every data node is within the threshold
+	OperationBalancer HdfsOperationType = "Balancer"
+	// OperationRefreshNodes rereads the include and exclude hosts
+	OperationRefreshNodes HdfsOperationType = "RefreshNodes"
+	// OperationSaveNamespace checkpoints the namespace into a new fsimage
+	OperationSaveNamespace HdfsOperationType = "SaveNamespace"
+	// OperationSafeMode enters or leaves safe mode
+	OperationSafeMode HdfsOperationType = "SafeMode"
+	// OperationReport reports the capacity and the data nodes
+	OperationReport HdfsOperationType = "Report"
+)
+
+// HdfsOperationSpec is run once as a job, changing it after the job is
+// created has no effect
+type HdfsOperationSpec struct {
+	// Cluster is the hdfs cluster in the same namespace
+	Cluster  string            `json:"cluster"`
+	Type     HdfsOperationType `json:"type"`
+	Fsck     *FsckOptions      `json:"fsck,omitempty"`
+	Balancer *BalancerOptions  `json:"balancer,omitempty"`
+	SafeMode *SafeModeOptions  `json:"safe_mode,omitempty"`
+}
+
+type FsckOptions struct {
+	// Path defaults to /
+	Path string `json:"path,omitempty"`
+}
+
+type BalancerOptions struct {
+	// Threshold is the allowed deviation in percent of each data node from
+	// the average utilization, defaults to 10
+	Threshold int32 `json:"threshold,omitempty"`
+}
+
+type SafeModeAction string
+
+const (
+	SafeModeEnter SafeModeAction = "enter"
+	SafeModeLeave SafeModeAction = "leave"
+)
+
+type SafeModeOptions struct {
+	Action SafeModeAction `json:"action"`
+}
+
+type HdfsOperationPhase string
+
+const (
+	// HdfsOperationPending waits for the cluster or for another exclusive operation
+	HdfsOperationPending   HdfsOperationPhase = "Pending"
+	HdfsOperationRunning   HdfsOperationPhase = "Running"
+	HdfsOperationSucceeded HdfsOperationPhase = "Succeeded"
+	HdfsOperationFailed    HdfsOperationPhase = "Failed"
+)
+
+type HdfsOperationStatus struct {
+	Phase   HdfsOperationPhase `json:"phase,omitempty"`
+	JobName string             `json:"job_name,omitempty"`
+	// Exclusive operations hold the cluster lock while running
+	Exclusive      bool         `json:"exclusive,omitempty"`
+	StartTime      *metav1.Time `json:"start_time,omitempty"`
+	CompletionTime *metav1.Time `json:"completion_time,omitempty"`
+	// Summary is the tail of the command output, the head for a report
+	Summary string `json:"summary,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsOperationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HdfsOperation `json:"items"`
+}
@@ -38,6 +38,16 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&HdfsCluster{},
 		&HdfsClusterList{},
+		&HdfsSnapshot{},
+		&HdfsSnapshotList{},
+		&HdfsSnapshotSchedule{},
+		&HdfsSnapshotScheduleList{},
+		&HdfsDirectory{},
+		&HdfsDirectoryList{},
+		&HdfsOperation{},
+		&HdfsOperationList{},
+		&HdfsReplication{},
+		&HdfsReplicationList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -0,0 +1,87 @@
+package v1alpha1
+
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsReplication struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HdfsReplicationSpec   `json:"spec"`
+	Status HdfsReplicationStatus `json:"status"`
+}
+
+type ReplicationMode string
+
+const (
+	// ReplicationFull compares every file of the source with the target
+	ReplicationFull ReplicationMode = "Full"
+	// ReplicationSnapshotDiff snapshots the source on every run and only
+	// copies the changes since the snapshot of the last run, the target must
+	// not be changed between the runs
+	ReplicationSnapshotDiff ReplicationMode = "SnapshotDiff"
+)
+
+// HdfsReplicationSpec copies source to target with distcp on every
+// schedule, a run is skipped while the previous one is still copying
+type HdfsReplicationSpec struct {
+	Source ReplicationEndpoint `json:"source"`
+	// Target is in the namespace of the HdfsReplication, the distcp jobs run
+	// there with the config and the kerberos admin of the target cluster
+	Target ReplicationEndpoint `json:"target"`
+	// Schedule is a cron expression, in UTC
+	Schedule string `json:"schedule"`
+	// Mode defaults to Full
+	Mode ReplicationMode `json:"mode,omitempty"`
+	// Bandwidth is the bytes per second each map may copy, distcp rounds
+	// it up to whole MB, defaults to the distcp default of 100MB
+	Bandwidth *resource.Quantity `json:"bandwidth,omitempty"`
+	// Maps is the number of parallel copies, defaults to 20
+	Maps int32 `json:"maps,omitempty"`
+	// DeleteMissing deletes the files of the target missing in the source
+	// in Full mode, the snapshot diff replicates deletes anyway
+	DeleteMissing bool `json:"delete_missing,omitempty"`
+	// Suspend stops scheduling new runs, a running job is finished
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+type ReplicationEndpoint struct {
+	// Cluster is the name of the hdfs cluster
+	Cluster string `json:"cluster"`
+	// Namespace of the source cluster, defaults to the namespace of the
+	// HdfsReplication. A source in another namespace must list the namespace
+	// of the HdfsReplication in its replication_namespaces. Both clusters
+	// need the same kerberos realm
+	Namespace string `json:"namespace,omitempty"`
+	Path      string `json:"path"`
+}
+
+type HdfsReplicationStatus struct {
+	LastScheduleTime *metav1.Time `json:"last_schedule_time,omitempty"`
+	// JobName is the running distcp job
+	JobName   string       `json:"job_name,omitempty"`
+	StartTime *metav1.Time `json:"start_time,omitempty"`
+	// LastSuccessfulSyncTime is the start of the last successful run, the
+	// target has the source as of then
+	LastSuccessfulSyncTime *metav1.Time `json:"last_successful_sync_time,omitempty"`
+	// BytesCopied is what the last successful run copied
+	BytesCopied int64 `json:"bytes_copied"`
+	// LastSnapshot is the snapshot of the last successful SnapshotDiff run,
+	// it exists on the source and the target
+	LastSnapshot string `json:"last_snapshot,omitempty"`
+	// Summary is the tail of the output of the last run
+	Summary string `json:"summary,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsReplicationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HdfsReplication `json:"items"`
+}
@@ -0,0 +1,86 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HdfsSnapshotSpec   `json:"spec"`
+	Status HdfsSnapshotStatus `json:"status"`
+}
+
+type HdfsSnapshotSpec struct {
+	// Cluster is the hdfs cluster in the same namespace
+	Cluster string `json:"cluster"`
+	// Path is the directory to snapshot, it is made snapshottable if needed
+	Path string `json:"path"`
+	// SnapshotName defaults to the name of the HdfsSnapshot
+	SnapshotName string `json:"snapshot_name,omitempty"`
+}
+
+type HdfsSnapshotPhase string
+
+const (
+	HdfsSnapshotPending HdfsSnapshotPhase = "Pending"
+	HdfsSnapshotCreated HdfsSnapshotPhase = "Created"
+	HdfsSnapshotFailed  HdfsSnapshotPhase = "Failed"
+)
+
+type HdfsSnapshotStatus struct {
+	Phase HdfsSnapshotPhase `json:"phase,omitempty"`
+	// SnapshotPath is the read only path of the snapshot, e.g. /data/.snapshot/s1
+	SnapshotPath string       `json:"snapshot_path,omitempty"`
+	CreationTime *metav1.Time `json:"creation_time,omitempty"`
+	Message      string       `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HdfsSnapshot `json:"items"`
+}
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsSnapshotSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata"`
+
+	Spec   HdfsSnapshotScheduleSpec   `json:"spec"`
+	Status HdfsSnapshotScheduleStatus `json:"status"`
+}
+
+// HdfsSnapshotScheduleSpec creates an HdfsSnapshot of path on every schedule
+// and deletes the oldest ones beyond retention
+type HdfsSnapshotScheduleSpec struct {
+	Cluster string `json:"cluster"`
+	Path    string `json:"path"`
+	// Schedule is a cron expression, in UTC
+	Schedule string `json:"schedule"`
+	// Retention is the number of snapshots to keep, defaults to 7
+	Retention int32 `json:"retention,omitempty"`
+	// Suspend stops creating new snapshots, existing ones are kept
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+type HdfsSnapshotScheduleStatus struct {
+	LastScheduleTime *metav1.Time `json:"last_schedule_time,omitempty"`
+	// Snapshots are the HdfsSnapshots of this schedule, newest first
+	Snapshots []string `json:"snapshots,omitempty"`
+	Message   string   `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+type HdfsSnapshotScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata"`
+
+	Items []HdfsSnapshotSchedule `json:"items"`
+}
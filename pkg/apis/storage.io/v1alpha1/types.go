@@ -1,11 +1,12 @@
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 type HdfsCluster struct {
 	metav1.TypeMeta   `json:",inline"`
@@ -13,11 +14,320 @@ type HdfsCluster struct {
 
 	// Spec defines the behavior of a tidb cluster
 	Spec HdfsClusterSpec `json:"spec"`
+
+	// Most recently observed status of the hdfs cluster
+	Status HdfsClusterStatus `json:"status"`
 }
 
 type HdfsClusterSpec struct {
 	NameNode NameNodeSpec `json:"name_node"`
 	DataNode DataNodeSpec `json:"data_node"`
+	// PVCRetentionPolicy decides what happens to the pvcs when data nodes are
+	// scaled in or the cluster is deleted, pvcs are retained by default
+	PVCRetentionPolicy *PVCRetentionPolicy `json:"pvc_retention_policy,omitempty"`
+	// DeletionProtection makes the webhook reject deleting the cluster
+	DeletionProtection bool `json:"deletion_protection,omitempty"`
+	// Teardown controls what runs before the cluster is deleted
+	Teardown *TeardownSpec `json:"teardown,omitempty"`
+	// Backup copies the latest name node checkpoint on a schedule
+	Backup *BackupSpec `json:"backup,omitempty"`
+	// RestoreFrom loads the name node of a new cluster from a backup
+	// instead of formatting it
+	RestoreFrom *RestoreSource `json:"restore_from,omitempty"`
+	// ReplicationNamespaces are the other namespaces whose HdfsReplications
+	// may copy from this cluster, replications in the namespace of the
+	// cluster always may
+	ReplicationNamespaces []string `json:"replication_namespaces,omitempty"`
+	// Balancer spreads the blocks over the data nodes on a schedule and after scale out
+	Balancer *BalancerSpec `json:"balancer,omitempty"`
+	// RackAwareness places the replicas of a block across the racks derived
+	// from the node labels of the data node pods
+	RackAwareness *RackAwarenessSpec `json:"rack_awareness,omitempty"`
+	Security      *SecuritySpec      `json:"security,omitempty"`
+	// KMS runs a hadoop kms holding the keys of the encryption zones.
+	// Turning it off keeps the kms running, but the clients no longer find
+	// it so the encrypted files can not be read
+	KMS *KMSSpec `json:"kms,omitempty"`
+	// ClientConfig tunes the <cluster>-client-config configmap the workloads
+	// mount as their hadoop config, the configmap is published without it too
+	ClientConfig *ClientConfigSpec `json:"client_config,omitempty"`
+	// NameServices federates the cluster, every name service is served by
+	// a name node with a namespace and block pool of its own and the data
+	// nodes store the blocks of all of them. name_node serves the name
+	// service named after the cluster
+	NameServices []NameServiceSpec `json:"name_services,omitempty"`
+	// Mounts is the mount table joining the namespaces of the name services,
+	// the client config uses it as a viewfs mount table
+	Mounts []MountSpec `json:"mounts,omitempty"`
+	// Router serves all name services behind the <cluster>-router service
+	// with mounts as its mount table, the client config then points at the
+	// router instead of the viewfs mount table
+	Router *RouterSpec `json:"router,omitempty"`
+	// HttpFS serves the webhdfs api behind the <cluster>-httpfs service for
+	// clients that can not speak hdfs rpc
+	HttpFS *HttpFSSpec `json:"httpfs,omitempty"`
+	// NFSGateway exports the file system over nfs v3 behind the <cluster>-nfs service
+	NFSGateway *NFSGatewaySpec `json:"nfs_gateway,omitempty"`
+	// Yarn runs a resource manager behind the <cluster>-resourcemanager
+	// service and node managers next to the data nodes
+	Yarn *YarnSpec `json:"yarn,omitempty"`
+	// Images of the name nodes and the data nodes, changing them upgrades
+	// the cluster with an hdfs rolling upgrade instead of replacing the pods
+	Images  *ImagesSpec  `json:"images,omitempty"`
+	Upgrade *UpgradeSpec `json:"upgrade,omitempty"`
+}
+
+// ImagesSpec are images built like the uhopper hadoop images, the entrypoint
+// writes the config from the env and $HADOOP_PREFIX is the hadoop install
+type ImagesSpec struct {
+	// NameNode runs the name nodes of all name services, defaults to
+	// uhopper/hadoop-namenode:2.7.2
+	NameNode string `json:"name_node,omitempty"`
+	// DataNode defaults to uhopper/hadoop-datanode:2.7.2
+	DataNode string `json:"data_node,omitempty"`
+}
+
+type UpgradeSpec struct {
+	// Rollback rolls the running upgrade back to the images it started from
+	// and to the namespace and the blocks of its start, everything written
+	// since is lost. It has no effect once the upgrade is being finalized,
+	// while it is set no new upgrade starts
+	Rollback bool `json:"rollback,omitempty"`
+}
+
+// YarnSpec runs yarn on the config of the cluster, the jobs read and write
+// the cluster as its clients. It can not be combined with kerberos yet
+type YarnSpec struct {
+	ResourceManager ResourceManagerSpec `json:"resource_manager,omitempty"`
+	NodeManager     NodeManagerSpec     `json:"node_manager,omitempty"`
+}
+
+type ResourceManagerSpec struct {
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// NodeManagerSpec places every node manager on a node running a data node
+// of the namespace, spread over as many of those nodes as possible
+type NodeManagerSpec struct {
+	// Replicas defaults to the replicas of the data nodes
+	Replicas int32 `json:"replicas,omitempty"`
+	// Resources of the node manager, the containers of the jobs run inside
+	// its pod. The memory limit less 1Gi for the node manager itself and the
+	// cpu limit are offered to the jobs, the yarn defaults of 8Gi and 8
+	// cores are offered without limits
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// HttpFSSpec runs httpfs as the admin principal impersonating the users, with
+// kerberos it authenticates the users by SPNEGO. It serves http only, tls
+// just secures its connections to the cluster
+type HttpFSSpec struct {
+	Replicas  int32                       `json:"replicas"`
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// ProxyHosts are the ips or CIDRs the gateway pods reach the name node
+	// from, usually the pod CIDR of the kubernetes cluster, only they may
+	// impersonate the users
+	ProxyHosts []string `json:"proxy_hosts"`
+}
+
+// NFSGatewaySpec runs the nfs gateway as the admin principal impersonating
+// the users, the uid of an nfs client is looked up by name in the gateway pod
+type NFSGatewaySpec struct {
+	// Replicas share the service with client ip affinity, an nfs client has
+	// to keep writing a file through the same gateway
+	Replicas  int32                       `json:"replicas"`
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+	// AllowedHosts is nfs.exports.allowed.hosts, defaults to "* rw"
+	AllowedHosts string `json:"allowed_hosts,omitempty"`
+	// ProxyHosts are the ips or CIDRs the gateway pods reach the name node
+	// from, see HttpFSSpec
+	ProxyHosts []string `json:"proxy_hosts"`
+}
+
+type RouterSpec struct {
+	Replicas int32 `json:"replicas"`
+	// Image is a hadoop 3 image, hadoop 2.7 has no router
+	Image string `json:"image,omitempty"`
+	// ZooKeeper is the connection string of the state store of the routers,
+	// the state store is kept in the name service of the cluster without it
+	ZooKeeper string `json:"zookeeper,omitempty"`
+}
+
+type NameServiceSpec struct {
+	Name     string       `json:"name"`
+	NameNode NameNodeSpec `json:"name_node"`
+}
+
+type MountSpec struct {
+	// Path is where the mount is seen by the clients
+	Path string `json:"path"`
+	// NameService is the cluster name or one of name_services
+	NameService string `json:"name_service"`
+	// TargetPath is the directory in the name service, Path by default
+	TargetPath string `json:"target_path,omitempty"`
+}
+
+type ClientConfigSpec struct {
+	// CoreSite and HdfsSite are added to the generated core-site.xml and
+	// hdfs-site.xml and replace the generated properties of the same name
+	CoreSite map[string]string `json:"core_site,omitempty"`
+	HdfsSite map[string]string `json:"hdfs_site,omitempty"`
+	// MirrorNamespaceSelector copies the configmap into the namespaces it
+	// selects, the copies are removed once a namespace is no longer selected
+	MirrorNamespaceSelector *metav1.LabelSelector `json:"mirror_namespace_selector,omitempty"`
+}
+
+type KMSSpec struct {
+	// Storage is the size of the pvc keeping the keystore, the pvc follows
+	// the retention policy of the name node
+	Storage      string `json:"storage"`
+	StorageClass string `json:"storage_class"`
+	// KeystorePasswordSecret holds the keystore password under the key password
+	KeystorePasswordSecret string `json:"keystore_password_secret"`
+	// KeyUsers may decrypt the keys of the encryption zones, in the hadoop acl
+	// format "user1,user2 group1", defaults to everyone but the name node user.
+	// Only the admin manages the keys and the key material is never handed out
+	KeyUsers string `json:"key_users,omitempty"`
+}
+
+type SecuritySpec struct {
+	Kerberos *KerberosSpec `json:"kerberos,omitempty"`
+	TLS      *TLSSpec      `json:"tls,omitempty"`
+}
+
+// TLSSpec serves the web endpoints over https only and encrypts the block
+// data transfer, exactly one of Secret and CASecret is set
+type TLSSpec struct {
+	// Secret is a cert-manager style secret with tls.crt, tls.key and ca.crt
+	// shared by every pod, the certificate must cover the name node service
+	// and the data node pods, e.g. *.<cluster>dn.<namespace>.svc.cluster.local.
+	// Renewing it is up to its issuer, the pods restart when it changes
+	Secret string `json:"secret,omitempty"`
+	// CASecret holds the tls.crt and tls.key of a CA the operator issues a
+	// certificate for every pod from
+	CASecret string `json:"ca_secret,omitempty"`
+	// Duration is the validity of the issued certificates, defaults to 2160h
+	Duration *metav1.Duration `json:"duration,omitempty"`
+	// RenewBefore is how long before they expire the issued certificates are
+	// renewed, defaults to 720h
+	RenewBefore *metav1.Duration `json:"renew_before,omitempty"`
+}
+
+// KerberosSpec turns on kerberos authentication and SASL data transfer, it
+// requires TLS. WebHDFS then needs SPNEGO, which the operator does not speak,
+// so HdfsDirectory and HdfsSnapshot fail on the cluster. Backups run in a job
+// logged in as the admin and keep working
+type KerberosSpec struct {
+	Realm string `json:"realm"`
+	// KDC is the host[:port] of the kdc, it is also the admin server
+	KDC      string            `json:"kdc"`
+	NameNode KerberosPrincipal `json:"name_node"`
+	// DataNode is shared by every data node, with _HOST in the principal
+	// the keytab must hold the principal of every data node pod
+	DataNode KerberosPrincipal `json:"data_node"`
+	// Admin is the identity of the admin jobs, it must map to the super user,
+	// i.e. the short name of the name node principal
+	Admin KerberosPrincipal `json:"admin"`
+	// WebPrincipal is the SPNEGO principal of the web endpoints, it must be
+	// in the keytab of every component, defaults to HTTP/_HOST@<realm>
+	WebPrincipal string `json:"web_principal,omitempty"`
+	// DataTransferProtection is authentication, integrity or privacy, defaults to authentication
+	DataTransferProtection string `json:"data_transfer_protection,omitempty"`
+}
+
+type KerberosPrincipal struct {
+	// Principal defaults to nn/<name node service fqdn>@<realm> for the name
+	// node and dn/_HOST@<realm> for the data nodes, the admin principal is required
+	Principal string `json:"principal,omitempty"`
+	// KeytabSecret is the secret in the same namespace holding the keytab
+	KeytabSecret string `json:"keytab_secret"`
+	// KeytabKey is the key of the keytab in the secret, defaults to keytab
+	KeytabKey string `json:"keytab_key,omitempty"`
+}
+
+type RackAwarenessSpec struct {
+	// NodeLabels are the node labels whose values make up the rack, e.g.
+	// /us-east-1a for topology.kubernetes.io/zone, which is the default
+	NodeLabels []string `json:"node_labels,omitempty"`
+}
+
+type BalancerSpec struct {
+	Enabled bool `json:"enabled"`
+	// Threshold is the allowed deviation in percent of each data node from
+	// the average utilization, defaults to 10
+	Threshold int32 `json:"threshold,omitempty"`
+	// Bandwidth is the bytes per second each data node may use for balancing,
+	// the data node default is kept when unset
+	Bandwidth *resource.Quantity `json:"bandwidth,omitempty"`
+	// Schedule is a cron expression in UTC, empty means no scheduled runs
+	Schedule string `json:"schedule,omitempty"`
+	// RunAfterScaleOut runs the balancer once the added data nodes are live
+	RunAfterScaleOut bool `json:"run_after_scale_out,omitempty"`
+}
+
+type TeardownSpec struct {
+	// SkipSaveNamespace skips the saveNamespace which otherwise
+	// writes a final fsimage before the cluster is deleted
+	SkipSaveNamespace bool `json:"skip_save_namespace,omitempty"`
+	// Backup fetches the final fsimage into a pvc that outlives the cluster
+	Backup bool `json:"backup,omitempty"`
+}
+
+type BackupSpec struct {
+	// Schedule is a cron expression like "0 2 * * *", in UTC
+	Schedule string `json:"schedule"`
+	// Retention is the number of backups to keep, defaults to 7
+	Retention int32        `json:"retention,omitempty"`
+	Target    BackupTarget `json:"target"`
+}
+
+// BackupTarget is where the backups are stored, exactly one of S3 and PVC is set
+type BackupTarget struct {
+	S3  *S3BackupTarget  `json:"s3,omitempty"`
+	PVC *PVCBackupTarget `json:"pvc,omitempty"`
+}
+
+type S3BackupTarget struct {
+	// Endpoint is the url of an S3 compatible store, e.g. http://minio:9000
+	Endpoint string `json:"endpoint"`
+	Region   string `json:"region,omitempty"`
+	Bucket   string `json:"bucket"`
+	// Prefix is prepended to the object keys, defaults to <namespace>/<cluster>
+	Prefix string `json:"prefix,omitempty"`
+	// SecretName is a secret in the cluster namespace with the keys
+	// access_key and secret_key
+	SecretName string `json:"secret_name"`
+}
+
+type PVCBackupTarget struct {
+	// ClaimName is an existing pvc the backup job mounts
+	ClaimName string `json:"claim_name"`
+}
+
+// RestoreSource references a backup listed in status.backup of the lost cluster
+type RestoreSource struct {
+	// Backup is the name of the backup, e.g. 20191010-020000
+	Backup string `json:"backup"`
+	// Target is where the backup is stored, usually the backup target of the lost
+	// cluster, the s3 prefix has to be set when the cluster name changed
+	Target BackupTarget `json:"target"`
+}
+
+type PVCRetentionPolicyType string
+
+const (
+	RetainPVCRetentionPolicy PVCRetentionPolicyType = "Retain"
+	DeletePVCRetentionPolicy PVCRetentionPolicyType = "Delete"
+)
+
+type PVCRetentionPolicy struct {
+	NameNode *ComponentPVCRetentionPolicy `json:"name_node,omitempty"`
+	DataNode *ComponentPVCRetentionPolicy `json:"data_node,omitempty"`
+}
+
+type ComponentPVCRetentionPolicy struct {
+	WhenScaled  PVCRetentionPolicyType `json:"when_scaled,omitempty"`
+	WhenDeleted PVCRetentionPolicyType `json:"when_deleted,omitempty"`
 }
 
 type NameNodeSpec struct {
@@ -29,6 +339,235 @@ type DataNodeSpec struct {
 	Storage      string `json:"storage"`
 	StorageClass string `json:"storage_class"`
 	Replicas     int32  `json:"replicas"`
+	// Autoscaling adjusts the replicas by the used capacity, replicas is
+	// only the initial size when it is set
+	Autoscaling *DataNodeAutoscalingSpec `json:"autoscaling,omitempty"`
+}
+
+type DataNodeAutoscalingSpec struct {
+	MinReplicas int32 `json:"min_replicas"`
+	MaxReplicas int32 `json:"max_replicas"`
+	// TargetUsedPercent is the used capacity percent to keep the cluster at, defaults to 70
+	TargetUsedPercent int32 `json:"target_used_percent,omitempty"`
+	// CooldownSeconds is the minimal interval between two scaling decisions, defaults to 300
+	CooldownSeconds int32 `json:"cooldown_seconds,omitempty"`
+	// ScaleInEnabled lets the autoscaler remove data nodes, each one is
+	// decommissioned before it is removed
+	ScaleInEnabled bool `json:"scale_in_enabled,omitempty"`
+}
+
+type HdfsClusterConditionType string
+
+const (
+	// NameNodeAvailable is true when the name node pod is ready and
+	// its rpc and web endpoints answer
+	NameNodeAvailable HdfsClusterConditionType = "NameNodeAvailable"
+	// Degraded is true when blocks are missing or data nodes are dead
+	Degraded HdfsClusterConditionType = "Degraded"
+	// DeletionBlocked is true when a deleted cluster is held by deletion protection
+	DeletionBlocked HdfsClusterConditionType = "DeletionBlocked"
+	// Restored is true once the name node has started from the backup in spec.restore_from
+	Restored HdfsClusterConditionType = "Restored"
+)
+
+type HdfsClusterCondition struct {
+	Type               HdfsClusterConditionType `json:"type"`
+	Status             corev1.ConditionStatus   `json:"status"`
+	LastTransitionTime metav1.Time              `json:"last_transition_time,omitempty"`
+	Reason             string                   `json:"reason,omitempty"`
+	Message            string                   `json:"message,omitempty"`
+}
+
+// HdfsStatus is the file system state reported by the name node
+type HdfsStatus struct {
+	CapacityTotal     int64 `json:"capacity_total"`
+	CapacityUsed      int64 `json:"capacity_used"`
+	CapacityRemaining int64 `json:"capacity_remaining"`
+	LiveDataNodes     int32 `json:"live_data_nodes"`
+	// DeadDataNodes leaves out the decommissioned data nodes that were scaled in
+	DeadDataNodes            int32        `json:"dead_data_nodes"`
+	StaleDataNodes           int32        `json:"stale_data_nodes"`
+	DecommissioningDataNodes int32        `json:"decommissioning_data_nodes"`
+	MissingBlocks            int64        `json:"missing_blocks"`
+	CorruptBlocks            int64        `json:"corrupt_blocks"`
+	UnderReplicatedBlocks    int64        `json:"under_replicated_blocks"`
+	SafeMode                 bool         `json:"safe_mode"`
+	LastCheckpointTime       *metav1.Time `json:"last_checkpoint_time,omitempty"`
+	LastUpdateTime           metav1.Time  `json:"last_update_time"`
+}
+
+// AutoscalingStatus records the last data node autoscaling decision
+type AutoscalingStatus struct {
+	DesiredReplicas int32        `json:"desired_replicas"`
+	UsedPercent     int32        `json:"used_percent"`
+	LastScaleTime   *metav1.Time `json:"last_scale_time,omitempty"`
+	LastDecision    string       `json:"last_decision,omitempty"`
+}
+
+type VolumePhase string
+
+const (
+	// VolumeResized means the pvc capacity matches the requested storage
+	VolumeResized VolumePhase = "Resized"
+	// VolumeResizing means the volume is being expanded by the storage provider
+	VolumeResizing VolumePhase = "Resizing"
+	// VolumeFileSystemResizePending means the file system is expanded when the pod restarts
+	VolumeFileSystemResizePending VolumePhase = "FileSystemResizePending"
+	// VolumeResizeRejected means the requested storage can not be applied to the pvc
+	VolumeResizeRejected VolumePhase = "ResizeRejected"
+)
+
+// VolumeStatus is the resize progress of a name node or data node pvc
+type VolumeStatus struct {
+	Name          string      `json:"name"`
+	Component     string      `json:"component"`
+	RequestedSize string      `json:"requested_size"`
+	CurrentSize   string      `json:"current_size"`
+	Phase         VolumePhase `json:"phase"`
+	Message       string      `json:"message,omitempty"`
+}
+
+// BackupStatus lists the completed backups, newest first
+type BackupStatus struct {
+	LastScheduleTime *metav1.Time `json:"last_schedule_time,omitempty"`
+	// LastError is the error of the last scheduled backup, empty when it succeeded
+	LastError string         `json:"last_error,omitempty"`
+	Backups   []BackupRecord `json:"backups,omitempty"`
+}
+
+type BackupRecord struct {
+	Name string `json:"name"`
+	// Location is the s3 url or the pvc path of the fsimage
+	Location       string      `json:"location"`
+	TxID           int64       `json:"txid,omitempty"`
+	Size           int64       `json:"size,omitempty"`
+	CompletionTime metav1.Time `json:"completion_time"`
+	// ClusterID and BlockPoolID are restored with the fsimage so the
+	// data nodes accept the restored name node
+	ClusterID   string `json:"cluster_id,omitempty"`
+	BlockPoolID string `json:"block_pool_id,omitempty"`
+}
+
+type BalancerStatus struct {
+	// JobName is the running balancer job, empty when none is running
+	JobName string `json:"job_name,omitempty"`
+	// Trigger is why the running job was started
+	Trigger          string       `json:"trigger,omitempty"`
+	LastScheduleTime *metav1.Time `json:"last_schedule_time,omitempty"`
+	// ObservedDataNodes is the number of live data nodes when the balancer
+	// last started, more live data nodes means a scale out happened
+	ObservedDataNodes int32        `json:"observed_data_nodes,omitempty"`
+	LastStartTime     *metav1.Time `json:"last_start_time,omitempty"`
+	LastResult        *BalancerRun `json:"last_result,omitempty"`
+	// Message explains why a due run is waiting
+	Message string `json:"message,omitempty"`
+}
+
+type BalancerRun struct {
+	// Trigger is Schedule or ScaleOut
+	Trigger        string      `json:"trigger"`
+	Succeeded      bool        `json:"succeeded"`
+	CompletionTime metav1.Time `json:"completion_time"`
+	// Summary is the tail of the balancer output
+	Summary string `json:"summary,omitempty"`
+}
+
+type HdfsClusterStatus struct {
+	Conditions  []HdfsClusterCondition `json:"conditions,omitempty"`
+	Hdfs        *HdfsStatus            `json:"hdfs,omitempty"`
+	Autoscaling *AutoscalingStatus     `json:"autoscaling,omitempty"`
+	Volumes     []VolumeStatus         `json:"volumes,omitempty"`
+	Backup      *BackupStatus          `json:"backup,omitempty"`
+	Balancer    *BalancerStatus        `json:"balancer,omitempty"`
+	TLS         *TLSStatus             `json:"tls,omitempty"`
+	// ClientConfig is where the client configuration of the cluster is published
+	ClientConfig *ClientConfigStatus `json:"client_config,omitempty"`
+	Federation   *FederationStatus   `json:"federation,omitempty"`
+	Router       *RouterStatus       `json:"router,omitempty"`
+	Upgrade      *UpgradeStatus      `json:"upgrade,omitempty"`
+}
+
+type UpgradePhase string
+
+const (
+	// UpgradePending waits for a healthy cluster, setting the images back
+	// to the ones running cancels the upgrade
+	UpgradePending UpgradePhase = "Pending"
+	// UpgradePreparing has every name node save the rollback fsimage
+	UpgradePreparing UpgradePhase = "Preparing"
+	// UpgradeNameNodes restarts the name nodes on the new image
+	UpgradeNameNodes UpgradePhase = "UpgradingNameNodes"
+	// UpgradeDataNodes restarts the data nodes on the new image one at a
+	// time, from the highest ordinal down
+	UpgradeDataNodes UpgradePhase = "UpgradingDataNodes"
+	// UpgradeFinalizing drops the rollback fsimages once the cluster is healthy
+	UpgradeFinalizing UpgradePhase = "Finalizing"
+	// UpgradeRollingBackNameNodes restarts the name nodes on the old image
+	// from the rollback fsimage
+	UpgradeRollingBackNameNodes UpgradePhase = "RollingBackNameNodes"
+	// UpgradeRollingBackDataNodes restarts the data nodes on the old image
+	// restoring the blocks deleted since the upgrade started
+	UpgradeRollingBackDataNodes UpgradePhase = "RollingBackDataNodes"
+	// UpgradeRolledBack waits for the images to be set back to the ones the
+	// upgrade started from
+	UpgradeRolledBack UpgradePhase = "RolledBack"
+)
+
+// UpgradeStatus is the rolling upgrade in progress, it is removed once the
+// upgrade is finalized or the images are set back after a rollback
+type UpgradeStatus struct {
+	Phase UpgradePhase `json:"phase"`
+	// From are the images running before the upgrade, a rollback goes back to them
+	From ImagesSpec `json:"from"`
+	To   ImagesSpec `json:"to"`
+	// DataNodePartition is the partition of the data node statefulset, the
+	// data nodes from this ordinal on run the new image
+	DataNodePartition int32 `json:"data_node_partition"`
+	// JobName is the prepare or finalize job
+	JobName   string       `json:"job_name,omitempty"`
+	StartTime *metav1.Time `json:"start_time,omitempty"`
+	// Message is what the upgrade is waiting for
+	Message string `json:"message,omitempty"`
+}
+
+type RouterStatus struct {
+	// Mounts is the mount table last applied to the state store
+	Mounts []MountSpec `json:"mounts,omitempty"`
+	// JobName is the job applying the mount table
+	JobName string `json:"job_name,omitempty"`
+	// Message is why the mount table could not be applied
+	Message string `json:"message,omitempty"`
+}
+
+type FederationStatus struct {
+	// ClusterID is shared by the name nodes of all name services, the
+	// name nodes of new name services are formatted with it
+	ClusterID    string              `json:"cluster_id"`
+	NameServices []NameServiceStatus `json:"name_services,omitempty"`
+}
+
+type NameServiceStatus struct {
+	Name        string `json:"name"`
+	Ready       bool   `json:"ready"`
+	BlockPoolID string `json:"block_pool_id,omitempty"`
+}
+
+type ClientConfigStatus struct {
+	// ConfigMap is the name of the configmap in the namespace of the cluster
+	ConfigMap string `json:"config_map"`
+	// MirroredNamespaces hold a copy of the configmap under the same name
+	MirroredNamespaces []string `json:"mirrored_namespaces,omitempty"`
+}
+
+type TLSStatus struct {
+	// Revision changes with the certificates, the pods restart to load them
+	Revision string `json:"revision"`
+	// NotAfter is when the first of the certificates expires
+	NotAfter *metav1.Time `json:"not_after,omitempty"`
+	// CACertificate is the pem the operator verifies the web endpoints with
+	CACertificate string `json:"ca_certificate,omitempty"`
+	// RenewTime is when the issued certificates were last renewed
+	RenewTime *metav1.Time `json:"renew_time,omitempty"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
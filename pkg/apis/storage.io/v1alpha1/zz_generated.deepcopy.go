@@ -21,12 +21,296 @@ limitations under the License.
 package v1alpha1
 
 import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingStatus) DeepCopyInto(out *AutoscalingStatus) {
+	*out = *in
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingStatus.
+func (in *AutoscalingStatus) DeepCopy() *AutoscalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRecord) DeepCopyInto(out *BackupRecord) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRecord.
+func (in *BackupRecord) DeepCopy() *BackupRecord {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRecord)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupSpec) DeepCopyInto(out *BackupSpec) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupSpec.
+func (in *BackupSpec) DeepCopy() *BackupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupStatus) DeepCopyInto(out *BackupStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Backups != nil {
+		in, out := &in.Backups, &out.Backups
+		*out = make([]BackupRecord, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupStatus.
+func (in *BackupStatus) DeepCopy() *BackupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupTarget) DeepCopyInto(out *BackupTarget) {
+	*out = *in
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(S3BackupTarget)
+		**out = **in
+	}
+	if in.PVC != nil {
+		in, out := &in.PVC, &out.PVC
+		*out = new(PVCBackupTarget)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupTarget.
+func (in *BackupTarget) DeepCopy() *BackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BalancerOptions) DeepCopyInto(out *BalancerOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalancerOptions.
+func (in *BalancerOptions) DeepCopy() *BalancerOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(BalancerOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BalancerRun) DeepCopyInto(out *BalancerRun) {
+	*out = *in
+	in.CompletionTime.DeepCopyInto(&out.CompletionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalancerRun.
+func (in *BalancerRun) DeepCopy() *BalancerRun {
+	if in == nil {
+		return nil
+	}
+	out := new(BalancerRun)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BalancerSpec) DeepCopyInto(out *BalancerSpec) {
+	*out = *in
+	if in.Bandwidth != nil {
+		in, out := &in.Bandwidth, &out.Bandwidth
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalancerSpec.
+func (in *BalancerSpec) DeepCopy() *BalancerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BalancerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BalancerStatus) DeepCopyInto(out *BalancerStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastStartTime != nil {
+		in, out := &in.LastStartTime, &out.LastStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastResult != nil {
+		in, out := &in.LastResult, &out.LastResult
+		*out = new(BalancerRun)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalancerStatus.
+func (in *BalancerStatus) DeepCopy() *BalancerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BalancerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientConfigSpec) DeepCopyInto(out *ClientConfigSpec) {
+	*out = *in
+	if in.CoreSite != nil {
+		in, out := &in.CoreSite, &out.CoreSite
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.HdfsSite != nil {
+		in, out := &in.HdfsSite, &out.HdfsSite
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MirrorNamespaceSelector != nil {
+		in, out := &in.MirrorNamespaceSelector, &out.MirrorNamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientConfigSpec.
+func (in *ClientConfigSpec) DeepCopy() *ClientConfigSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientConfigSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClientConfigStatus) DeepCopyInto(out *ClientConfigStatus) {
+	*out = *in
+	if in.MirroredNamespaces != nil {
+		in, out := &in.MirroredNamespaces, &out.MirroredNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClientConfigStatus.
+func (in *ClientConfigStatus) DeepCopy() *ClientConfigStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClientConfigStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ComponentPVCRetentionPolicy) DeepCopyInto(out *ComponentPVCRetentionPolicy) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ComponentPVCRetentionPolicy.
+func (in *ComponentPVCRetentionPolicy) DeepCopy() *ComponentPVCRetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ComponentPVCRetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataNodeAutoscalingSpec) DeepCopyInto(out *DataNodeAutoscalingSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataNodeAutoscalingSpec.
+func (in *DataNodeAutoscalingSpec) DeepCopy() *DataNodeAutoscalingSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataNodeAutoscalingSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DataNodeSpec) DeepCopyInto(out *DataNodeSpec) {
 	*out = *in
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(DataNodeAutoscalingSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -40,12 +324,66 @@ func (in *DataNodeSpec) DeepCopy() *DataNodeSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DirectoryUsage) DeepCopyInto(out *DirectoryUsage) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DirectoryUsage.
+func (in *DirectoryUsage) DeepCopy() *DirectoryUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(DirectoryUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FederationStatus) DeepCopyInto(out *FederationStatus) {
+	*out = *in
+	if in.NameServices != nil {
+		in, out := &in.NameServices, &out.NameServices
+		*out = make([]NameServiceStatus, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FederationStatus.
+func (in *FederationStatus) DeepCopy() *FederationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(FederationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FsckOptions) DeepCopyInto(out *FsckOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FsckOptions.
+func (in *FsckOptions) DeepCopy() *FsckOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(FsckOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HdfsCluster) DeepCopyInto(out *HdfsCluster) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -67,6 +405,23 @@ func (in *HdfsCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsClusterCondition) DeepCopyInto(out *HdfsClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsClusterCondition.
+func (in *HdfsClusterCondition) DeepCopy() *HdfsClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *HdfsClusterList) DeepCopyInto(out *HdfsClusterList) {
 	*out = *in
@@ -104,7 +459,97 @@ func (in *HdfsClusterList) DeepCopyObject() runtime.Object {
 func (in *HdfsClusterSpec) DeepCopyInto(out *HdfsClusterSpec) {
 	*out = *in
 	out.NameNode = in.NameNode
-	out.DataNode = in.DataNode
+	in.DataNode.DeepCopyInto(&out.DataNode)
+	if in.PVCRetentionPolicy != nil {
+		in, out := &in.PVCRetentionPolicy, &out.PVCRetentionPolicy
+		*out = new(PVCRetentionPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Teardown != nil {
+		in, out := &in.Teardown, &out.Teardown
+		*out = new(TeardownSpec)
+		**out = **in
+	}
+	if in.ReplicationNamespaces != nil {
+		in, out := &in.ReplicationNamespaces, &out.ReplicationNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RestoreFrom != nil {
+		in, out := &in.RestoreFrom, &out.RestoreFrom
+		*out = new(RestoreSource)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Balancer != nil {
+		in, out := &in.Balancer, &out.Balancer
+		*out = new(BalancerSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RackAwareness != nil {
+		in, out := &in.RackAwareness, &out.RackAwareness
+		*out = new(RackAwarenessSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Security != nil {
+		in, out := &in.Security, &out.Security
+		*out = new(SecuritySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.KMS != nil {
+		in, out := &in.KMS, &out.KMS
+		*out = new(KMSSpec)
+		**out = **in
+	}
+	if in.ClientConfig != nil {
+		in, out := &in.ClientConfig, &out.ClientConfig
+		*out = new(ClientConfigSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NameServices != nil {
+		in, out := &in.NameServices, &out.NameServices
+		*out = make([]NameServiceSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Mounts != nil {
+		in, out := &in.Mounts, &out.Mounts
+		*out = make([]MountSpec, len(*in))
+		copy(*out, *in)
+	}
+	if in.Router != nil {
+		in, out := &in.Router, &out.Router
+		*out = new(RouterSpec)
+		**out = **in
+	}
+	if in.HttpFS != nil {
+		in, out := &in.HttpFS, &out.HttpFS
+		*out = new(HttpFSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.NFSGateway != nil {
+		in, out := &in.NFSGateway, &out.NFSGateway
+		*out = new(NFSGatewaySpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Yarn != nil {
+		in, out := &in.Yarn, &out.Yarn
+		*out = new(YarnSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = new(ImagesSpec)
+		**out = **in
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeSpec)
+		**out = **in
+	}
 	return
 }
 
@@ -119,17 +564,1169 @@ func (in *HdfsClusterSpec) DeepCopy() *HdfsClusterSpec {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NameNodeSpec) DeepCopyInto(out *NameNodeSpec) {
+func (in *HdfsClusterStatus) DeepCopyInto(out *HdfsClusterStatus) {
 	*out = *in
-	return
-}
-
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NameNodeSpec.
-func (in *NameNodeSpec) DeepCopy() *NameNodeSpec {
-	if in == nil {
-		return nil
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]HdfsClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
 	}
-	out := new(NameNodeSpec)
+	if in.Hdfs != nil {
+		in, out := &in.Hdfs, &out.Hdfs
+		*out = new(HdfsStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]VolumeStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Backup != nil {
+		in, out := &in.Backup, &out.Backup
+		*out = new(BackupStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Balancer != nil {
+		in, out := &in.Balancer, &out.Balancer
+		*out = new(BalancerStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ClientConfig != nil {
+		in, out := &in.ClientConfig, &out.ClientConfig
+		*out = new(ClientConfigStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Federation != nil {
+		in, out := &in.Federation, &out.Federation
+		*out = new(FederationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Router != nil {
+		in, out := &in.Router, &out.Router
+		*out = new(RouterStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Upgrade != nil {
+		in, out := &in.Upgrade, &out.Upgrade
+		*out = new(UpgradeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsClusterStatus.
+func (in *HdfsClusterStatus) DeepCopy() *HdfsClusterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsClusterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsDirectory) DeepCopyInto(out *HdfsDirectory) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsDirectory.
+func (in *HdfsDirectory) DeepCopy() *HdfsDirectory {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsDirectory)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsDirectory) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsDirectoryList) DeepCopyInto(out *HdfsDirectoryList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HdfsDirectory, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsDirectoryList.
+func (in *HdfsDirectoryList) DeepCopy() *HdfsDirectoryList {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsDirectoryList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsDirectoryList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsDirectorySpec) DeepCopyInto(out *HdfsDirectorySpec) {
+	*out = *in
+	if in.NamespaceQuota != nil {
+		in, out := &in.NamespaceQuota, &out.NamespaceQuota
+		*out = new(int64)
+		**out = **in
+	}
+	if in.SpaceQuota != nil {
+		in, out := &in.SpaceQuota, &out.SpaceQuota
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsDirectorySpec.
+func (in *HdfsDirectorySpec) DeepCopy() *HdfsDirectorySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsDirectorySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsDirectoryStatus) DeepCopyInto(out *HdfsDirectoryStatus) {
+	*out = *in
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = new(DirectoryUsage)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsDirectoryStatus.
+func (in *HdfsDirectoryStatus) DeepCopy() *HdfsDirectoryStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsDirectoryStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsOperation) DeepCopyInto(out *HdfsOperation) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsOperation.
+func (in *HdfsOperation) DeepCopy() *HdfsOperation {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsOperation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsOperation) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsOperationList) DeepCopyInto(out *HdfsOperationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HdfsOperation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsOperationList.
+func (in *HdfsOperationList) DeepCopy() *HdfsOperationList {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsOperationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsOperationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsOperationSpec) DeepCopyInto(out *HdfsOperationSpec) {
+	*out = *in
+	if in.Fsck != nil {
+		in, out := &in.Fsck, &out.Fsck
+		*out = new(FsckOptions)
+		**out = **in
+	}
+	if in.Balancer != nil {
+		in, out := &in.Balancer, &out.Balancer
+		*out = new(BalancerOptions)
+		**out = **in
+	}
+	if in.SafeMode != nil {
+		in, out := &in.SafeMode, &out.SafeMode
+		*out = new(SafeModeOptions)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsOperationSpec.
+func (in *HdfsOperationSpec) DeepCopy() *HdfsOperationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsOperationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsOperationStatus) DeepCopyInto(out *HdfsOperationStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsOperationStatus.
+func (in *HdfsOperationStatus) DeepCopy() *HdfsOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsReplication) DeepCopyInto(out *HdfsReplication) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsReplication.
+func (in *HdfsReplication) DeepCopy() *HdfsReplication {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsReplication)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsReplication) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsReplicationList) DeepCopyInto(out *HdfsReplicationList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HdfsReplication, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsReplicationList.
+func (in *HdfsReplicationList) DeepCopy() *HdfsReplicationList {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsReplicationList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsReplicationList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsReplicationSpec) DeepCopyInto(out *HdfsReplicationSpec) {
+	*out = *in
+	out.Source = in.Source
+	out.Target = in.Target
+	if in.Bandwidth != nil {
+		in, out := &in.Bandwidth, &out.Bandwidth
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsReplicationSpec.
+func (in *HdfsReplicationSpec) DeepCopy() *HdfsReplicationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsReplicationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsReplicationStatus) DeepCopyInto(out *HdfsReplicationStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulSyncTime != nil {
+		in, out := &in.LastSuccessfulSyncTime, &out.LastSuccessfulSyncTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsReplicationStatus.
+func (in *HdfsReplicationStatus) DeepCopy() *HdfsReplicationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsReplicationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshot) DeepCopyInto(out *HdfsSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshot.
+func (in *HdfsSnapshot) DeepCopy() *HdfsSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotList) DeepCopyInto(out *HdfsSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HdfsSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotList.
+func (in *HdfsSnapshotList) DeepCopy() *HdfsSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotSchedule) DeepCopyInto(out *HdfsSnapshotSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotSchedule.
+func (in *HdfsSnapshotSchedule) DeepCopy() *HdfsSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsSnapshotSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotScheduleList) DeepCopyInto(out *HdfsSnapshotScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	out.ListMeta = in.ListMeta
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HdfsSnapshotSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotScheduleList.
+func (in *HdfsSnapshotScheduleList) DeepCopy() *HdfsSnapshotScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HdfsSnapshotScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotScheduleSpec) DeepCopyInto(out *HdfsSnapshotScheduleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotScheduleSpec.
+func (in *HdfsSnapshotScheduleSpec) DeepCopy() *HdfsSnapshotScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotScheduleStatus) DeepCopyInto(out *HdfsSnapshotScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Snapshots != nil {
+		in, out := &in.Snapshots, &out.Snapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotScheduleStatus.
+func (in *HdfsSnapshotScheduleStatus) DeepCopy() *HdfsSnapshotScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotSpec) DeepCopyInto(out *HdfsSnapshotSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotSpec.
+func (in *HdfsSnapshotSpec) DeepCopy() *HdfsSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsSnapshotStatus) DeepCopyInto(out *HdfsSnapshotStatus) {
+	*out = *in
+	if in.CreationTime != nil {
+		in, out := &in.CreationTime, &out.CreationTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsSnapshotStatus.
+func (in *HdfsSnapshotStatus) DeepCopy() *HdfsSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HdfsStatus) DeepCopyInto(out *HdfsStatus) {
+	*out = *in
+	if in.LastCheckpointTime != nil {
+		in, out := &in.LastCheckpointTime, &out.LastCheckpointTime
+		*out = (*in).DeepCopy()
+	}
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HdfsStatus.
+func (in *HdfsStatus) DeepCopy() *HdfsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HdfsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HttpFSSpec) DeepCopyInto(out *HttpFSSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.ProxyHosts != nil {
+		in, out := &in.ProxyHosts, &out.ProxyHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HttpFSSpec.
+func (in *HttpFSSpec) DeepCopy() *HttpFSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HttpFSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImagesSpec) DeepCopyInto(out *ImagesSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImagesSpec.
+func (in *ImagesSpec) DeepCopy() *ImagesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ImagesSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KMSSpec) DeepCopyInto(out *KMSSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KMSSpec.
+func (in *KMSSpec) DeepCopy() *KMSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KMSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KerberosPrincipal) DeepCopyInto(out *KerberosPrincipal) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KerberosPrincipal.
+func (in *KerberosPrincipal) DeepCopy() *KerberosPrincipal {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosPrincipal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *KerberosSpec) DeepCopyInto(out *KerberosSpec) {
+	*out = *in
+	out.NameNode = in.NameNode
+	out.DataNode = in.DataNode
+	out.Admin = in.Admin
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new KerberosSpec.
+func (in *KerberosSpec) DeepCopy() *KerberosSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(KerberosSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MountSpec) DeepCopyInto(out *MountSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MountSpec.
+func (in *MountSpec) DeepCopy() *MountSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MountSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NFSGatewaySpec) DeepCopyInto(out *NFSGatewaySpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	if in.ProxyHosts != nil {
+		in, out := &in.ProxyHosts, &out.ProxyHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NFSGatewaySpec.
+func (in *NFSGatewaySpec) DeepCopy() *NFSGatewaySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NFSGatewaySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NameNodeSpec) DeepCopyInto(out *NameNodeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NameNodeSpec.
+func (in *NameNodeSpec) DeepCopy() *NameNodeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NameNodeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NameServiceSpec) DeepCopyInto(out *NameServiceSpec) {
+	*out = *in
+	out.NameNode = in.NameNode
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NameServiceSpec.
+func (in *NameServiceSpec) DeepCopy() *NameServiceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NameServiceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NameServiceStatus) DeepCopyInto(out *NameServiceStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NameServiceStatus.
+func (in *NameServiceStatus) DeepCopy() *NameServiceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NameServiceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeManagerSpec) DeepCopyInto(out *NodeManagerSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeManagerSpec.
+func (in *NodeManagerSpec) DeepCopy() *NodeManagerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeManagerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCBackupTarget) DeepCopyInto(out *PVCBackupTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCBackupTarget.
+func (in *PVCBackupTarget) DeepCopy() *PVCBackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCBackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PVCRetentionPolicy) DeepCopyInto(out *PVCRetentionPolicy) {
+	*out = *in
+	if in.NameNode != nil {
+		in, out := &in.NameNode, &out.NameNode
+		*out = new(ComponentPVCRetentionPolicy)
+		**out = **in
+	}
+	if in.DataNode != nil {
+		in, out := &in.DataNode, &out.DataNode
+		*out = new(ComponentPVCRetentionPolicy)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PVCRetentionPolicy.
+func (in *PVCRetentionPolicy) DeepCopy() *PVCRetentionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(PVCRetentionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RackAwarenessSpec) DeepCopyInto(out *RackAwarenessSpec) {
+	*out = *in
+	if in.NodeLabels != nil {
+		in, out := &in.NodeLabels, &out.NodeLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RackAwarenessSpec.
+func (in *RackAwarenessSpec) DeepCopy() *RackAwarenessSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RackAwarenessSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationEndpoint) DeepCopyInto(out *ReplicationEndpoint) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationEndpoint.
+func (in *ReplicationEndpoint) DeepCopy() *ReplicationEndpoint {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationEndpoint)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceManagerSpec) DeepCopyInto(out *ResourceManagerSpec) {
+	*out = *in
+	in.Resources.DeepCopyInto(&out.Resources)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceManagerSpec.
+func (in *ResourceManagerSpec) DeepCopy() *ResourceManagerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceManagerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RestoreSource) DeepCopyInto(out *RestoreSource) {
+	*out = *in
+	in.Target.DeepCopyInto(&out.Target)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RestoreSource.
+func (in *RestoreSource) DeepCopy() *RestoreSource {
+	if in == nil {
+		return nil
+	}
+	out := new(RestoreSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterSpec) DeepCopyInto(out *RouterSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterSpec.
+func (in *RouterSpec) DeepCopy() *RouterSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RouterStatus) DeepCopyInto(out *RouterStatus) {
+	*out = *in
+	if in.Mounts != nil {
+		in, out := &in.Mounts, &out.Mounts
+		*out = make([]MountSpec, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RouterStatus.
+func (in *RouterStatus) DeepCopy() *RouterStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RouterStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3BackupTarget) DeepCopyInto(out *S3BackupTarget) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3BackupTarget.
+func (in *S3BackupTarget) DeepCopy() *S3BackupTarget {
+	if in == nil {
+		return nil
+	}
+	out := new(S3BackupTarget)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SafeModeOptions) DeepCopyInto(out *SafeModeOptions) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SafeModeOptions.
+func (in *SafeModeOptions) DeepCopy() *SafeModeOptions {
+	if in == nil {
+		return nil
+	}
+	out := new(SafeModeOptions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SecuritySpec) DeepCopyInto(out *SecuritySpec) {
+	*out = *in
+	if in.Kerberos != nil {
+		in, out := &in.Kerberos, &out.Kerberos
+		*out = new(KerberosSpec)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(TLSSpec)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecuritySpec.
+func (in *SecuritySpec) DeepCopy() *SecuritySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(SecuritySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSSpec) DeepCopyInto(out *TLSSpec) {
+	*out = *in
+	if in.Duration != nil {
+		in, out := &in.Duration, &out.Duration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.RenewBefore != nil {
+		in, out := &in.RenewBefore, &out.RenewBefore
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSSpec.
+func (in *TLSSpec) DeepCopy() *TLSSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TLSStatus) DeepCopyInto(out *TLSStatus) {
+	*out = *in
+	if in.NotAfter != nil {
+		in, out := &in.NotAfter, &out.NotAfter
+		*out = (*in).DeepCopy()
+	}
+	if in.RenewTime != nil {
+		in, out := &in.RenewTime, &out.RenewTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TLSStatus.
+func (in *TLSStatus) DeepCopy() *TLSStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(TLSStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TeardownSpec) DeepCopyInto(out *TeardownSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TeardownSpec.
+func (in *TeardownSpec) DeepCopy() *TeardownSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TeardownSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeSpec) DeepCopyInto(out *UpgradeSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeSpec.
+func (in *UpgradeSpec) DeepCopy() *UpgradeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeStatus) DeepCopyInto(out *UpgradeStatus) {
+	*out = *in
+	out.From = in.From
+	out.To = in.To
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeStatus.
+func (in *UpgradeStatus) DeepCopy() *UpgradeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeStatus) DeepCopyInto(out *VolumeStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeStatus.
+func (in *VolumeStatus) DeepCopy() *VolumeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *YarnSpec) DeepCopyInto(out *YarnSpec) {
+	*out = *in
+	in.ResourceManager.DeepCopyInto(&out.ResourceManager)
+	in.NodeManager.DeepCopyInto(&out.NodeManager)
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new YarnSpec.
+func (in *YarnSpec) DeepCopy() *YarnSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(YarnSpec)
 	in.DeepCopyInto(out)
 	return out
 }
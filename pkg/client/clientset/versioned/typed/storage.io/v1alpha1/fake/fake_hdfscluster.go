@@ -100,6 +100,18 @@ func (c *FakeHdfsClusters) Update(hdfsCluster *v1alpha1.HdfsCluster) (result *v1
 	return obj.(*v1alpha1.HdfsCluster), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsClusters) UpdateStatus(hdfsCluster *v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfsclustersResource, "status", c.ns, hdfsCluster), &v1alpha1.HdfsCluster{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsCluster), err
+}
+
 // Delete takes name of the hdfsCluster and deletes it. Returns an error if one occurs.
 func (c *FakeHdfsClusters) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHdfsDirectories implements HdfsDirectoryInterface
+type FakeHdfsDirectories struct {
+	Fake *FakeStorageV1alpha1
+	ns   string
+}
+
+var hdfsdirectoriesResource = schema.GroupVersionResource{Group: "storage.io", Version: "v1alpha1", Resource: "hdfsdirectories"}
+
+var hdfsdirectoriesKind = schema.GroupVersionKind{Group: "storage.io", Version: "v1alpha1", Kind: "HdfsDirectory"}
+
+// Get takes name of the hdfsDirectory, and returns the corresponding hdfsDirectory object, and an error if there is any.
+func (c *FakeHdfsDirectories) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsDirectory, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(hdfsdirectoriesResource, c.ns, name), &v1alpha1.HdfsDirectory{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsDirectory), err
+}
+
+// List takes label and field selectors, and returns the list of HdfsDirectories that match those selectors.
+func (c *FakeHdfsDirectories) List(opts v1.ListOptions) (result *v1alpha1.HdfsDirectoryList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(hdfsdirectoriesResource, hdfsdirectoriesKind, c.ns, opts), &v1alpha1.HdfsDirectoryList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.HdfsDirectoryList{ListMeta: obj.(*v1alpha1.HdfsDirectoryList).ListMeta}
+	for _, item := range obj.(*v1alpha1.HdfsDirectoryList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsDirectories.
+func (c *FakeHdfsDirectories) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(hdfsdirectoriesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a hdfsDirectory and creates it.  Returns the server's representation of the hdfsDirectory, and an error, if there is any.
+func (c *FakeHdfsDirectories) Create(hdfsDirectory *v1alpha1.HdfsDirectory) (result *v1alpha1.HdfsDirectory, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(hdfsdirectoriesResource, c.ns, hdfsDirectory), &v1alpha1.HdfsDirectory{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsDirectory), err
+}
+
+// Update takes the representation of a hdfsDirectory and updates it. Returns the server's representation of the hdfsDirectory, and an error, if there is any.
+func (c *FakeHdfsDirectories) Update(hdfsDirectory *v1alpha1.HdfsDirectory) (result *v1alpha1.HdfsDirectory, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(hdfsdirectoriesResource, c.ns, hdfsDirectory), &v1alpha1.HdfsDirectory{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsDirectory), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsDirectories) UpdateStatus(hdfsDirectory *v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfsdirectoriesResource, "status", c.ns, hdfsDirectory), &v1alpha1.HdfsDirectory{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsDirectory), err
+}
+
+// Delete takes name of the hdfsDirectory and deletes it. Returns an error if one occurs.
+func (c *FakeHdfsDirectories) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(hdfsdirectoriesResource, c.ns, name), &v1alpha1.HdfsDirectory{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHdfsDirectories) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(hdfsdirectoriesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.HdfsDirectoryList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hdfsDirectory.
+func (c *FakeHdfsDirectories) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsDirectory, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(hdfsdirectoriesResource, c.ns, name, pt, data, subresources...), &v1alpha1.HdfsDirectory{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsDirectory), err
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHdfsOperations implements HdfsOperationInterface
+type FakeHdfsOperations struct {
+	Fake *FakeStorageV1alpha1
+	ns   string
+}
+
+var hdfsoperationsResource = schema.GroupVersionResource{Group: "storage.io", Version: "v1alpha1", Resource: "hdfsoperations"}
+
+var hdfsoperationsKind = schema.GroupVersionKind{Group: "storage.io", Version: "v1alpha1", Kind: "HdfsOperation"}
+
+// Get takes name of the hdfsOperation, and returns the corresponding hdfsOperation object, and an error if there is any.
+func (c *FakeHdfsOperations) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsOperation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(hdfsoperationsResource, c.ns, name), &v1alpha1.HdfsOperation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsOperation), err
+}
+
+// List takes label and field selectors, and returns the list of HdfsOperations that match those selectors.
+func (c *FakeHdfsOperations) List(opts v1.ListOptions) (result *v1alpha1.HdfsOperationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(hdfsoperationsResource, hdfsoperationsKind, c.ns, opts), &v1alpha1.HdfsOperationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.HdfsOperationList{ListMeta: obj.(*v1alpha1.HdfsOperationList).ListMeta}
+	for _, item := range obj.(*v1alpha1.HdfsOperationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsOperations.
+func (c *FakeHdfsOperations) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(hdfsoperationsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a hdfsOperation and creates it.  Returns the server's representation of the hdfsOperation, and an error, if there is any.
+func (c *FakeHdfsOperations) Create(hdfsOperation *v1alpha1.HdfsOperation) (result *v1alpha1.HdfsOperation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(hdfsoperationsResource, c.ns, hdfsOperation), &v1alpha1.HdfsOperation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsOperation), err
+}
+
+// Update takes the representation of a hdfsOperation and updates it. Returns the server's representation of the hdfsOperation, and an error, if there is any.
+func (c *FakeHdfsOperations) Update(hdfsOperation *v1alpha1.HdfsOperation) (result *v1alpha1.HdfsOperation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(hdfsoperationsResource, c.ns, hdfsOperation), &v1alpha1.HdfsOperation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsOperation), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsOperations) UpdateStatus(hdfsOperation *v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfsoperationsResource, "status", c.ns, hdfsOperation), &v1alpha1.HdfsOperation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsOperation), err
+}
+
+// Delete takes name of the hdfsOperation and deletes it. Returns an error if one occurs.
+func (c *FakeHdfsOperations) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(hdfsoperationsResource, c.ns, name), &v1alpha1.HdfsOperation{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHdfsOperations) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(hdfsoperationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.HdfsOperationList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hdfsOperation.
+func (c *FakeHdfsOperations) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsOperation, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(hdfsoperationsResource, c.ns, name, pt, data, subresources...), &v1alpha1.HdfsOperation{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsOperation), err
+}
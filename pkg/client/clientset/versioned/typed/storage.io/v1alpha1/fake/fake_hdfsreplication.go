@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHdfsReplications implements HdfsReplicationInterface
+type FakeHdfsReplications struct {
+	Fake *FakeStorageV1alpha1
+	ns   string
+}
+
+var hdfsreplicationsResource = schema.GroupVersionResource{Group: "storage.io", Version: "v1alpha1", Resource: "hdfsreplications"}
+
+var hdfsreplicationsKind = schema.GroupVersionKind{Group: "storage.io", Version: "v1alpha1", Kind: "HdfsReplication"}
+
+// Get takes name of the hdfsReplication, and returns the corresponding hdfsReplication object, and an error if there is any.
+func (c *FakeHdfsReplications) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsReplication, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(hdfsreplicationsResource, c.ns, name), &v1alpha1.HdfsReplication{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsReplication), err
+}
+
+// List takes label and field selectors, and returns the list of HdfsReplications that match those selectors.
+func (c *FakeHdfsReplications) List(opts v1.ListOptions) (result *v1alpha1.HdfsReplicationList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(hdfsreplicationsResource, hdfsreplicationsKind, c.ns, opts), &v1alpha1.HdfsReplicationList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.HdfsReplicationList{ListMeta: obj.(*v1alpha1.HdfsReplicationList).ListMeta}
+	for _, item := range obj.(*v1alpha1.HdfsReplicationList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsReplications.
+func (c *FakeHdfsReplications) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(hdfsreplicationsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a hdfsReplication and creates it.  Returns the server's representation of the hdfsReplication, and an error, if there is any.
+func (c *FakeHdfsReplications) Create(hdfsReplication *v1alpha1.HdfsReplication) (result *v1alpha1.HdfsReplication, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(hdfsreplicationsResource, c.ns, hdfsReplication), &v1alpha1.HdfsReplication{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsReplication), err
+}
+
+// Update takes the representation of a hdfsReplication and updates it. Returns the server's representation of the hdfsReplication, and an error, if there is any.
+func (c *FakeHdfsReplications) Update(hdfsReplication *v1alpha1.HdfsReplication) (result *v1alpha1.HdfsReplication, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(hdfsreplicationsResource, c.ns, hdfsReplication), &v1alpha1.HdfsReplication{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsReplication), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsReplications) UpdateStatus(hdfsReplication *v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfsreplicationsResource, "status", c.ns, hdfsReplication), &v1alpha1.HdfsReplication{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsReplication), err
+}
+
+// Delete takes name of the hdfsReplication and deletes it. Returns an error if one occurs.
+func (c *FakeHdfsReplications) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(hdfsreplicationsResource, c.ns, name), &v1alpha1.HdfsReplication{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHdfsReplications) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(hdfsreplicationsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.HdfsReplicationList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hdfsReplication.
+func (c *FakeHdfsReplications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsReplication, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(hdfsreplicationsResource, c.ns, name, pt, data, subresources...), &v1alpha1.HdfsReplication{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsReplication), err
+}
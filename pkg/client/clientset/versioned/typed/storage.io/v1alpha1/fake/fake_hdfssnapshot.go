@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHdfsSnapshots implements HdfsSnapshotInterface
+type FakeHdfsSnapshots struct {
+	Fake *FakeStorageV1alpha1
+	ns   string
+}
+
+var hdfssnapshotsResource = schema.GroupVersionResource{Group: "storage.io", Version: "v1alpha1", Resource: "hdfssnapshots"}
+
+var hdfssnapshotsKind = schema.GroupVersionKind{Group: "storage.io", Version: "v1alpha1", Kind: "HdfsSnapshot"}
+
+// Get takes name of the hdfsSnapshot, and returns the corresponding hdfsSnapshot object, and an error if there is any.
+func (c *FakeHdfsSnapshots) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsSnapshot, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(hdfssnapshotsResource, c.ns, name), &v1alpha1.HdfsSnapshot{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), err
+}
+
+// List takes label and field selectors, and returns the list of HdfsSnapshots that match those selectors.
+func (c *FakeHdfsSnapshots) List(opts v1.ListOptions) (result *v1alpha1.HdfsSnapshotList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(hdfssnapshotsResource, hdfssnapshotsKind, c.ns, opts), &v1alpha1.HdfsSnapshotList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.HdfsSnapshotList{ListMeta: obj.(*v1alpha1.HdfsSnapshotList).ListMeta}
+	for _, item := range obj.(*v1alpha1.HdfsSnapshotList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsSnapshots.
+func (c *FakeHdfsSnapshots) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(hdfssnapshotsResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a hdfsSnapshot and creates it.  Returns the server's representation of the hdfsSnapshot, and an error, if there is any.
+func (c *FakeHdfsSnapshots) Create(hdfsSnapshot *v1alpha1.HdfsSnapshot) (result *v1alpha1.HdfsSnapshot, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(hdfssnapshotsResource, c.ns, hdfsSnapshot), &v1alpha1.HdfsSnapshot{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), err
+}
+
+// Update takes the representation of a hdfsSnapshot and updates it. Returns the server's representation of the hdfsSnapshot, and an error, if there is any.
+func (c *FakeHdfsSnapshots) Update(hdfsSnapshot *v1alpha1.HdfsSnapshot) (result *v1alpha1.HdfsSnapshot, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(hdfssnapshotsResource, c.ns, hdfsSnapshot), &v1alpha1.HdfsSnapshot{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsSnapshots) UpdateStatus(hdfsSnapshot *v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfssnapshotsResource, "status", c.ns, hdfsSnapshot), &v1alpha1.HdfsSnapshot{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), err
+}
+
+// Delete takes name of the hdfsSnapshot and deletes it. Returns an error if one occurs.
+func (c *FakeHdfsSnapshots) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(hdfssnapshotsResource, c.ns, name), &v1alpha1.HdfsSnapshot{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHdfsSnapshots) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(hdfssnapshotsResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.HdfsSnapshotList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hdfsSnapshot.
+func (c *FakeHdfsSnapshots) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshot, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(hdfssnapshotsResource, c.ns, name, pt, data, subresources...), &v1alpha1.HdfsSnapshot{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), err
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package fake
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	labels "k8s.io/apimachinery/pkg/labels"
+	schema "k8s.io/apimachinery/pkg/runtime/schema"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	testing "k8s.io/client-go/testing"
+)
+
+// FakeHdfsSnapshotSchedules implements HdfsSnapshotScheduleInterface
+type FakeHdfsSnapshotSchedules struct {
+	Fake *FakeStorageV1alpha1
+	ns   string
+}
+
+var hdfssnapshotschedulesResource = schema.GroupVersionResource{Group: "storage.io", Version: "v1alpha1", Resource: "hdfssnapshotschedules"}
+
+var hdfssnapshotschedulesKind = schema.GroupVersionKind{Group: "storage.io", Version: "v1alpha1", Kind: "HdfsSnapshotSchedule"}
+
+// Get takes name of the hdfsSnapshotSchedule, and returns the corresponding hdfsSnapshotSchedule object, and an error if there is any.
+func (c *FakeHdfsSnapshotSchedules) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewGetAction(hdfssnapshotschedulesResource, c.ns, name), &v1alpha1.HdfsSnapshotSchedule{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), err
+}
+
+// List takes label and field selectors, and returns the list of HdfsSnapshotSchedules that match those selectors.
+func (c *FakeHdfsSnapshotSchedules) List(opts v1.ListOptions) (result *v1alpha1.HdfsSnapshotScheduleList, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewListAction(hdfssnapshotschedulesResource, hdfssnapshotschedulesKind, c.ns, opts), &v1alpha1.HdfsSnapshotScheduleList{})
+
+	if obj == nil {
+		return nil, err
+	}
+
+	label, _, _ := testing.ExtractFromListOptions(opts)
+	if label == nil {
+		label = labels.Everything()
+	}
+	list := &v1alpha1.HdfsSnapshotScheduleList{ListMeta: obj.(*v1alpha1.HdfsSnapshotScheduleList).ListMeta}
+	for _, item := range obj.(*v1alpha1.HdfsSnapshotScheduleList).Items {
+		if label.Matches(labels.Set(item.Labels)) {
+			list.Items = append(list.Items, item)
+		}
+	}
+	return list, err
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsSnapshotSchedules.
+func (c *FakeHdfsSnapshotSchedules) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	return c.Fake.
+		InvokesWatch(testing.NewWatchAction(hdfssnapshotschedulesResource, c.ns, opts))
+
+}
+
+// Create takes the representation of a hdfsSnapshotSchedule and creates it.  Returns the server's representation of the hdfsSnapshotSchedule, and an error, if there is any.
+func (c *FakeHdfsSnapshotSchedules) Create(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewCreateAction(hdfssnapshotschedulesResource, c.ns, hdfsSnapshotSchedule), &v1alpha1.HdfsSnapshotSchedule{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), err
+}
+
+// Update takes the representation of a hdfsSnapshotSchedule and updates it. Returns the server's representation of the hdfsSnapshotSchedule, and an error, if there is any.
+func (c *FakeHdfsSnapshotSchedules) Update(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateAction(hdfssnapshotschedulesResource, c.ns, hdfsSnapshotSchedule), &v1alpha1.HdfsSnapshotSchedule{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), err
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeHdfsSnapshotSchedules) UpdateStatus(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(hdfssnapshotschedulesResource, "status", c.ns, hdfsSnapshotSchedule), &v1alpha1.HdfsSnapshotSchedule{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), err
+}
+
+// Delete takes name of the hdfsSnapshotSchedule and deletes it. Returns an error if one occurs.
+func (c *FakeHdfsSnapshotSchedules) Delete(name string, options *v1.DeleteOptions) error {
+	_, err := c.Fake.
+		Invokes(testing.NewDeleteAction(hdfssnapshotschedulesResource, c.ns, name), &v1alpha1.HdfsSnapshotSchedule{})
+
+	return err
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *FakeHdfsSnapshotSchedules) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	action := testing.NewDeleteCollectionAction(hdfssnapshotschedulesResource, c.ns, listOptions)
+
+	_, err := c.Fake.Invokes(action, &v1alpha1.HdfsSnapshotScheduleList{})
+	return err
+}
+
+// Patch applies the patch and returns the patched hdfsSnapshotSchedule.
+func (c *FakeHdfsSnapshotSchedules) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(hdfssnapshotschedulesResource, c.ns, name, pt, data, subresources...), &v1alpha1.HdfsSnapshotSchedule{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), err
+}
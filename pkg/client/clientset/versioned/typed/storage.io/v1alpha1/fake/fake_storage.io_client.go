@@ -32,6 +32,26 @@ func (c *FakeStorageV1alpha1) HdfsClusters(namespace string) v1alpha1.HdfsCluste
 	return &FakeHdfsClusters{c, namespace}
 }
 
+func (c *FakeStorageV1alpha1) HdfsSnapshots(namespace string) v1alpha1.HdfsSnapshotInterface {
+	return &FakeHdfsSnapshots{c, namespace}
+}
+
+func (c *FakeStorageV1alpha1) HdfsSnapshotSchedules(namespace string) v1alpha1.HdfsSnapshotScheduleInterface {
+	return &FakeHdfsSnapshotSchedules{c, namespace}
+}
+
+func (c *FakeStorageV1alpha1) HdfsDirectories(namespace string) v1alpha1.HdfsDirectoryInterface {
+	return &FakeHdfsDirectories{c, namespace}
+}
+
+func (c *FakeStorageV1alpha1) HdfsOperations(namespace string) v1alpha1.HdfsOperationInterface {
+	return &FakeHdfsOperations{c, namespace}
+}
+
+func (c *FakeStorageV1alpha1) HdfsReplications(namespace string) v1alpha1.HdfsReplicationInterface {
+	return &FakeHdfsReplications{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeStorageV1alpha1) RESTClient() rest.Interface {
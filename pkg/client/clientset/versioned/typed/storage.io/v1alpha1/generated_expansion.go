@@ -19,3 +19,13 @@ limitations under the License.
 package v1alpha1
 
 type HdfsClusterExpansion interface{}
+
+type HdfsSnapshotExpansion interface{}
+
+type HdfsSnapshotScheduleExpansion interface{}
+
+type HdfsDirectoryExpansion interface{}
+
+type HdfsOperationExpansion interface{}
+
+type HdfsReplicationExpansion interface{}
@@ -39,6 +39,7 @@ type HdfsClustersGetter interface {
 type HdfsClusterInterface interface {
 	Create(*v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error)
 	Update(*v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error)
+	UpdateStatus(*v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error)
 	Delete(name string, options *v1.DeleteOptions) error
 	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
 	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsCluster, error)
@@ -132,6 +133,22 @@ func (c *hdfsClusters) Update(hdfsCluster *v1alpha1.HdfsCluster) (result *v1alph
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsClusters) UpdateStatus(hdfsCluster *v1alpha1.HdfsCluster) (result *v1alpha1.HdfsCluster, err error) {
+	result = &v1alpha1.HdfsCluster{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsclusters").
+		Name(hdfsCluster.Name).
+		SubResource("status").
+		Body(hdfsCluster).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the hdfsCluster and deletes it. Returns an error if one occurs.
 func (c *hdfsClusters) Delete(name string, options *v1.DeleteOptions) error {
 	return c.client.Delete().
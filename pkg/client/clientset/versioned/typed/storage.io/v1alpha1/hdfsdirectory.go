@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	scheme "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HdfsDirectoriesGetter has a method to return a HdfsDirectoryInterface.
+// A group's client should implement this interface.
+type HdfsDirectoriesGetter interface {
+	HdfsDirectories(namespace string) HdfsDirectoryInterface
+}
+
+// HdfsDirectoryInterface has methods to work with HdfsDirectory resources.
+type HdfsDirectoryInterface interface {
+	Create(*v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error)
+	Update(*v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error)
+	UpdateStatus(*v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsDirectory, error)
+	List(opts v1.ListOptions) (*v1alpha1.HdfsDirectoryList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsDirectory, err error)
+	HdfsDirectoryExpansion
+}
+
+// hdfsDirectories implements HdfsDirectoryInterface
+type hdfsDirectories struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHdfsDirectories returns a HdfsDirectories
+func newHdfsDirectories(c *StorageV1alpha1Client, namespace string) *hdfsDirectories {
+	return &hdfsDirectories{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the hdfsDirectory, and returns the corresponding hdfsDirectory object, and an error if there is any.
+func (c *hdfsDirectories) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsDirectory, err error) {
+	result = &v1alpha1.HdfsDirectory{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HdfsDirectories that match those selectors.
+func (c *hdfsDirectories) List(opts v1.ListOptions) (result *v1alpha1.HdfsDirectoryList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.HdfsDirectoryList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsDirectories.
+func (c *hdfsDirectories) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a hdfsDirectory and creates it.  Returns the server's representation of the hdfsDirectory, and an error, if there is any.
+func (c *hdfsDirectories) Create(hdfsDirectory *v1alpha1.HdfsDirectory) (result *v1alpha1.HdfsDirectory, err error) {
+	result = &v1alpha1.HdfsDirectory{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		Body(hdfsDirectory).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hdfsDirectory and updates it. Returns the server's representation of the hdfsDirectory, and an error, if there is any.
+func (c *hdfsDirectories) Update(hdfsDirectory *v1alpha1.HdfsDirectory) (result *v1alpha1.HdfsDirectory, err error) {
+	result = &v1alpha1.HdfsDirectory{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		Name(hdfsDirectory.Name).
+		Body(hdfsDirectory).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsDirectories) UpdateStatus(hdfsDirectory *v1alpha1.HdfsDirectory) (result *v1alpha1.HdfsDirectory, err error) {
+	result = &v1alpha1.HdfsDirectory{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		Name(hdfsDirectory.Name).
+		SubResource("status").
+		Body(hdfsDirectory).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the hdfsDirectory and deletes it. Returns an error if one occurs.
+func (c *hdfsDirectories) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hdfsDirectories) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched hdfsDirectory.
+func (c *hdfsDirectories) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsDirectory, err error) {
+	result = &v1alpha1.HdfsDirectory{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("hdfsdirectories").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	scheme "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HdfsOperationsGetter has a method to return a HdfsOperationInterface.
+// A group's client should implement this interface.
+type HdfsOperationsGetter interface {
+	HdfsOperations(namespace string) HdfsOperationInterface
+}
+
+// HdfsOperationInterface has methods to work with HdfsOperation resources.
+type HdfsOperationInterface interface {
+	Create(*v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error)
+	Update(*v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error)
+	UpdateStatus(*v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsOperation, error)
+	List(opts v1.ListOptions) (*v1alpha1.HdfsOperationList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsOperation, err error)
+	HdfsOperationExpansion
+}
+
+// hdfsOperations implements HdfsOperationInterface
+type hdfsOperations struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHdfsOperations returns a HdfsOperations
+func newHdfsOperations(c *StorageV1alpha1Client, namespace string) *hdfsOperations {
+	return &hdfsOperations{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the hdfsOperation, and returns the corresponding hdfsOperation object, and an error if there is any.
+func (c *hdfsOperations) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsOperation, err error) {
+	result = &v1alpha1.HdfsOperation{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HdfsOperations that match those selectors.
+func (c *hdfsOperations) List(opts v1.ListOptions) (result *v1alpha1.HdfsOperationList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.HdfsOperationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsOperations.
+func (c *hdfsOperations) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a hdfsOperation and creates it.  Returns the server's representation of the hdfsOperation, and an error, if there is any.
+func (c *hdfsOperations) Create(hdfsOperation *v1alpha1.HdfsOperation) (result *v1alpha1.HdfsOperation, err error) {
+	result = &v1alpha1.HdfsOperation{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		Body(hdfsOperation).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hdfsOperation and updates it. Returns the server's representation of the hdfsOperation, and an error, if there is any.
+func (c *hdfsOperations) Update(hdfsOperation *v1alpha1.HdfsOperation) (result *v1alpha1.HdfsOperation, err error) {
+	result = &v1alpha1.HdfsOperation{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		Name(hdfsOperation.Name).
+		Body(hdfsOperation).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsOperations) UpdateStatus(hdfsOperation *v1alpha1.HdfsOperation) (result *v1alpha1.HdfsOperation, err error) {
+	result = &v1alpha1.HdfsOperation{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		Name(hdfsOperation.Name).
+		SubResource("status").
+		Body(hdfsOperation).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the hdfsOperation and deletes it. Returns an error if one occurs.
+func (c *hdfsOperations) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hdfsOperations) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched hdfsOperation.
+func (c *hdfsOperations) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsOperation, err error) {
+	result = &v1alpha1.HdfsOperation{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("hdfsoperations").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
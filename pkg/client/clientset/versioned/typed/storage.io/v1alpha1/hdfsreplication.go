@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	scheme "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HdfsReplicationsGetter has a method to return a HdfsReplicationInterface.
+// A group's client should implement this interface.
+type HdfsReplicationsGetter interface {
+	HdfsReplications(namespace string) HdfsReplicationInterface
+}
+
+// HdfsReplicationInterface has methods to work with HdfsReplication resources.
+type HdfsReplicationInterface interface {
+	Create(*v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error)
+	Update(*v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error)
+	UpdateStatus(*v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsReplication, error)
+	List(opts v1.ListOptions) (*v1alpha1.HdfsReplicationList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsReplication, err error)
+	HdfsReplicationExpansion
+}
+
+// hdfsReplications implements HdfsReplicationInterface
+type hdfsReplications struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHdfsReplications returns a HdfsReplications
+func newHdfsReplications(c *StorageV1alpha1Client, namespace string) *hdfsReplications {
+	return &hdfsReplications{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the hdfsReplication, and returns the corresponding hdfsReplication object, and an error if there is any.
+func (c *hdfsReplications) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsReplication, err error) {
+	result = &v1alpha1.HdfsReplication{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HdfsReplications that match those selectors.
+func (c *hdfsReplications) List(opts v1.ListOptions) (result *v1alpha1.HdfsReplicationList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.HdfsReplicationList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsReplications.
+func (c *hdfsReplications) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a hdfsReplication and creates it.  Returns the server's representation of the hdfsReplication, and an error, if there is any.
+func (c *hdfsReplications) Create(hdfsReplication *v1alpha1.HdfsReplication) (result *v1alpha1.HdfsReplication, err error) {
+	result = &v1alpha1.HdfsReplication{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		Body(hdfsReplication).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hdfsReplication and updates it. Returns the server's representation of the hdfsReplication, and an error, if there is any.
+func (c *hdfsReplications) Update(hdfsReplication *v1alpha1.HdfsReplication) (result *v1alpha1.HdfsReplication, err error) {
+	result = &v1alpha1.HdfsReplication{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		Name(hdfsReplication.Name).
+		Body(hdfsReplication).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsReplications) UpdateStatus(hdfsReplication *v1alpha1.HdfsReplication) (result *v1alpha1.HdfsReplication, err error) {
+	result = &v1alpha1.HdfsReplication{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		Name(hdfsReplication.Name).
+		SubResource("status").
+		Body(hdfsReplication).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the hdfsReplication and deletes it. Returns an error if one occurs.
+func (c *hdfsReplications) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hdfsReplications) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched hdfsReplication.
+func (c *hdfsReplications) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsReplication, err error) {
+	result = &v1alpha1.HdfsReplication{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("hdfsreplications").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
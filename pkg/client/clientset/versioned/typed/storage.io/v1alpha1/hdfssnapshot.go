@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	scheme "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HdfsSnapshotsGetter has a method to return a HdfsSnapshotInterface.
+// A group's client should implement this interface.
+type HdfsSnapshotsGetter interface {
+	HdfsSnapshots(namespace string) HdfsSnapshotInterface
+}
+
+// HdfsSnapshotInterface has methods to work with HdfsSnapshot resources.
+type HdfsSnapshotInterface interface {
+	Create(*v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error)
+	Update(*v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error)
+	UpdateStatus(*v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsSnapshot, error)
+	List(opts v1.ListOptions) (*v1alpha1.HdfsSnapshotList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshot, err error)
+	HdfsSnapshotExpansion
+}
+
+// hdfsSnapshots implements HdfsSnapshotInterface
+type hdfsSnapshots struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHdfsSnapshots returns a HdfsSnapshots
+func newHdfsSnapshots(c *StorageV1alpha1Client, namespace string) *hdfsSnapshots {
+	return &hdfsSnapshots{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the hdfsSnapshot, and returns the corresponding hdfsSnapshot object, and an error if there is any.
+func (c *hdfsSnapshots) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsSnapshot, err error) {
+	result = &v1alpha1.HdfsSnapshot{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HdfsSnapshots that match those selectors.
+func (c *hdfsSnapshots) List(opts v1.ListOptions) (result *v1alpha1.HdfsSnapshotList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.HdfsSnapshotList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsSnapshots.
+func (c *hdfsSnapshots) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a hdfsSnapshot and creates it.  Returns the server's representation of the hdfsSnapshot, and an error, if there is any.
+func (c *hdfsSnapshots) Create(hdfsSnapshot *v1alpha1.HdfsSnapshot) (result *v1alpha1.HdfsSnapshot, err error) {
+	result = &v1alpha1.HdfsSnapshot{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		Body(hdfsSnapshot).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hdfsSnapshot and updates it. Returns the server's representation of the hdfsSnapshot, and an error, if there is any.
+func (c *hdfsSnapshots) Update(hdfsSnapshot *v1alpha1.HdfsSnapshot) (result *v1alpha1.HdfsSnapshot, err error) {
+	result = &v1alpha1.HdfsSnapshot{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		Name(hdfsSnapshot.Name).
+		Body(hdfsSnapshot).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsSnapshots) UpdateStatus(hdfsSnapshot *v1alpha1.HdfsSnapshot) (result *v1alpha1.HdfsSnapshot, err error) {
+	result = &v1alpha1.HdfsSnapshot{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		Name(hdfsSnapshot.Name).
+		SubResource("status").
+		Body(hdfsSnapshot).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the hdfsSnapshot and deletes it. Returns an error if one occurs.
+func (c *hdfsSnapshots) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hdfsSnapshots) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched hdfsSnapshot.
+func (c *hdfsSnapshots) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshot, err error) {
+	result = &v1alpha1.HdfsSnapshot{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("hdfssnapshots").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
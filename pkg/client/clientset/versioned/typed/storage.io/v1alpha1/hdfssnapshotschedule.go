@@ -0,0 +1,191 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by client-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"time"
+
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	scheme "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned/scheme"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	types "k8s.io/apimachinery/pkg/types"
+	watch "k8s.io/apimachinery/pkg/watch"
+	rest "k8s.io/client-go/rest"
+)
+
+// HdfsSnapshotSchedulesGetter has a method to return a HdfsSnapshotScheduleInterface.
+// A group's client should implement this interface.
+type HdfsSnapshotSchedulesGetter interface {
+	HdfsSnapshotSchedules(namespace string) HdfsSnapshotScheduleInterface
+}
+
+// HdfsSnapshotScheduleInterface has methods to work with HdfsSnapshotSchedule resources.
+type HdfsSnapshotScheduleInterface interface {
+	Create(*v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error)
+	Update(*v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error)
+	UpdateStatus(*v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error)
+	Delete(name string, options *v1.DeleteOptions) error
+	DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error
+	Get(name string, options v1.GetOptions) (*v1alpha1.HdfsSnapshotSchedule, error)
+	List(opts v1.ListOptions) (*v1alpha1.HdfsSnapshotScheduleList, error)
+	Watch(opts v1.ListOptions) (watch.Interface, error)
+	Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshotSchedule, err error)
+	HdfsSnapshotScheduleExpansion
+}
+
+// hdfsSnapshotSchedules implements HdfsSnapshotScheduleInterface
+type hdfsSnapshotSchedules struct {
+	client rest.Interface
+	ns     string
+}
+
+// newHdfsSnapshotSchedules returns a HdfsSnapshotSchedules
+func newHdfsSnapshotSchedules(c *StorageV1alpha1Client, namespace string) *hdfsSnapshotSchedules {
+	return &hdfsSnapshotSchedules{
+		client: c.RESTClient(),
+		ns:     namespace,
+	}
+}
+
+// Get takes name of the hdfsSnapshotSchedule, and returns the corresponding hdfsSnapshotSchedule object, and an error if there is any.
+func (c *hdfsSnapshotSchedules) Get(name string, options v1.GetOptions) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	result = &v1alpha1.HdfsSnapshotSchedule{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		Name(name).
+		VersionedParams(&options, scheme.ParameterCodec).
+		Do().
+		Into(result)
+	return
+}
+
+// List takes label and field selectors, and returns the list of HdfsSnapshotSchedules that match those selectors.
+func (c *hdfsSnapshotSchedules) List(opts v1.ListOptions) (result *v1alpha1.HdfsSnapshotScheduleList, err error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result = &v1alpha1.HdfsSnapshotScheduleList{}
+	err = c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Do().
+		Into(result)
+	return
+}
+
+// Watch returns a watch.Interface that watches the requested hdfsSnapshotSchedules.
+func (c *hdfsSnapshotSchedules) Watch(opts v1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		VersionedParams(&opts, scheme.ParameterCodec).
+		Timeout(timeout).
+		Watch()
+}
+
+// Create takes the representation of a hdfsSnapshotSchedule and creates it.  Returns the server's representation of the hdfsSnapshotSchedule, and an error, if there is any.
+func (c *hdfsSnapshotSchedules) Create(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	result = &v1alpha1.HdfsSnapshotSchedule{}
+	err = c.client.Post().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		Body(hdfsSnapshotSchedule).
+		Do().
+		Into(result)
+	return
+}
+
+// Update takes the representation of a hdfsSnapshotSchedule and updates it. Returns the server's representation of the hdfsSnapshotSchedule, and an error, if there is any.
+func (c *hdfsSnapshotSchedules) Update(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	result = &v1alpha1.HdfsSnapshotSchedule{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		Name(hdfsSnapshotSchedule.Name).
+		Body(hdfsSnapshotSchedule).
+		Do().
+		Into(result)
+	return
+}
+
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *hdfsSnapshotSchedules) UpdateStatus(hdfsSnapshotSchedule *v1alpha1.HdfsSnapshotSchedule) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	result = &v1alpha1.HdfsSnapshotSchedule{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		Name(hdfsSnapshotSchedule.Name).
+		SubResource("status").
+		Body(hdfsSnapshotSchedule).
+		Do().
+		Into(result)
+	return
+}
+
+// Delete takes name of the hdfsSnapshotSchedule and deletes it. Returns an error if one occurs.
+func (c *hdfsSnapshotSchedules) Delete(name string, options *v1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		Name(name).
+		Body(options).
+		Do().
+		Error()
+}
+
+// DeleteCollection deletes a collection of objects.
+func (c *hdfsSnapshotSchedules) DeleteCollection(options *v1.DeleteOptions, listOptions v1.ListOptions) error {
+	var timeout time.Duration
+	if listOptions.TimeoutSeconds != nil {
+		timeout = time.Duration(*listOptions.TimeoutSeconds) * time.Second
+	}
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		VersionedParams(&listOptions, scheme.ParameterCodec).
+		Timeout(timeout).
+		Body(options).
+		Do().
+		Error()
+}
+
+// Patch applies the patch and returns the patched hdfsSnapshotSchedule.
+func (c *hdfsSnapshotSchedules) Patch(name string, pt types.PatchType, data []byte, subresources ...string) (result *v1alpha1.HdfsSnapshotSchedule, err error) {
+	result = &v1alpha1.HdfsSnapshotSchedule{}
+	err = c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("hdfssnapshotschedules").
+		SubResource(subresources...).
+		Name(name).
+		Body(data).
+		Do().
+		Into(result)
+	return
+}
@@ -28,6 +28,11 @@ import (
 type StorageV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	HdfsClustersGetter
+	HdfsSnapshotsGetter
+	HdfsSnapshotSchedulesGetter
+	HdfsDirectoriesGetter
+	HdfsOperationsGetter
+	HdfsReplicationsGetter
 }
 
 // StorageV1alpha1Client is used to interact with features provided by the storage.io group.
@@ -39,6 +44,26 @@ func (c *StorageV1alpha1Client) HdfsClusters(namespace string) HdfsClusterInterf
 	return newHdfsClusters(c, namespace)
 }
 
+func (c *StorageV1alpha1Client) HdfsSnapshots(namespace string) HdfsSnapshotInterface {
+	return newHdfsSnapshots(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) HdfsSnapshotSchedules(namespace string) HdfsSnapshotScheduleInterface {
+	return newHdfsSnapshotSchedules(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) HdfsDirectories(namespace string) HdfsDirectoryInterface {
+	return newHdfsDirectories(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) HdfsOperations(namespace string) HdfsOperationInterface {
+	return newHdfsOperations(c, namespace)
+}
+
+func (c *StorageV1alpha1Client) HdfsReplications(namespace string) HdfsReplicationInterface {
+	return newHdfsReplications(c, namespace)
+}
+
 // NewForConfig creates a new StorageV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*StorageV1alpha1Client, error) {
 	config := *c
@@ -55,6 +55,16 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 	// Group=storage.io, Version=v1alpha1
 	case v1alpha1.SchemeGroupVersion.WithResource("hdfsclusters"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsClusters().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hdfssnapshots"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsSnapshots().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hdfssnapshotschedules"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsSnapshotSchedules().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hdfsdirectories"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsDirectories().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hdfsoperations"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsOperations().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hdfsreplications"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Storage().V1alpha1().HdfsReplications().Informer()}, nil
 
 	}
 
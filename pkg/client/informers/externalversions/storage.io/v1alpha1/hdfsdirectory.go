@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	time "time"
+
+	storageiov1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	versioned "github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	internalinterfaces "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// HdfsDirectoryInformer provides access to a shared informer and lister for
+// HdfsDirectories.
+type HdfsDirectoryInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.HdfsDirectoryLister
+}
+
+type hdfsDirectoryInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewHdfsDirectoryInformer constructs a new informer for HdfsDirectory type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewHdfsDirectoryInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredHdfsDirectoryInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredHdfsDirectoryInformer constructs a new informer for HdfsDirectory type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredHdfsDirectoryInformer(client versioned.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.StorageV1alpha1().HdfsDirectories(namespace).List(options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.StorageV1alpha1().HdfsDirectories(namespace).Watch(options)
+			},
+		},
+		&storageiov1alpha1.HdfsDirectory{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *hdfsDirectoryInformer) defaultInformer(client versioned.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredHdfsDirectoryInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *hdfsDirectoryInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&storageiov1alpha1.HdfsDirectory{}, f.defaultInformer)
+}
+
+func (f *hdfsDirectoryInformer) Lister() v1alpha1.HdfsDirectoryLister {
+	return v1alpha1.NewHdfsDirectoryLister(f.Informer().GetIndexer())
+}
@@ -26,6 +26,16 @@ import (
 type Interface interface {
 	// HdfsClusters returns a HdfsClusterInformer.
 	HdfsClusters() HdfsClusterInformer
+	// HdfsSnapshots returns a HdfsSnapshotInformer.
+	HdfsSnapshots() HdfsSnapshotInformer
+	// HdfsSnapshotSchedules returns a HdfsSnapshotScheduleInformer.
+	HdfsSnapshotSchedules() HdfsSnapshotScheduleInformer
+	// HdfsDirectories returns a HdfsDirectoryInformer.
+	HdfsDirectories() HdfsDirectoryInformer
+	// HdfsOperations returns a HdfsOperationInformer.
+	HdfsOperations() HdfsOperationInformer
+	// HdfsReplications returns a HdfsReplicationInformer.
+	HdfsReplications() HdfsReplicationInformer
 }
 
 type version struct {
@@ -43,3 +53,28 @@ func New(f internalinterfaces.SharedInformerFactory, namespace string, tweakList
 func (v *version) HdfsClusters() HdfsClusterInformer {
 	return &hdfsClusterInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// HdfsSnapshots returns a HdfsSnapshotInformer.
+func (v *version) HdfsSnapshots() HdfsSnapshotInformer {
+	return &hdfsSnapshotInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// HdfsSnapshotSchedules returns a HdfsSnapshotScheduleInformer.
+func (v *version) HdfsSnapshotSchedules() HdfsSnapshotScheduleInformer {
+	return &hdfsSnapshotScheduleInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// HdfsDirectories returns a HdfsDirectoryInformer.
+func (v *version) HdfsDirectories() HdfsDirectoryInformer {
+	return &hdfsDirectoryInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// HdfsOperations returns a HdfsOperationInformer.
+func (v *version) HdfsOperations() HdfsOperationInformer {
+	return &hdfsOperationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
+
+// HdfsReplications returns a HdfsReplicationInformer.
+func (v *version) HdfsReplications() HdfsReplicationInformer {
+	return &hdfsReplicationInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
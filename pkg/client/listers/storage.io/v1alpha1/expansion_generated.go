@@ -25,3 +25,43 @@ type HdfsClusterListerExpansion interface{}
 // HdfsClusterNamespaceListerExpansion allows custom methods to be added to
 // HdfsClusterNamespaceLister.
 type HdfsClusterNamespaceListerExpansion interface{}
+
+// HdfsSnapshotListerExpansion allows custom methods to be added to
+// HdfsSnapshotLister.
+type HdfsSnapshotListerExpansion interface{}
+
+// HdfsSnapshotNamespaceListerExpansion allows custom methods to be added to
+// HdfsSnapshotNamespaceLister.
+type HdfsSnapshotNamespaceListerExpansion interface{}
+
+// HdfsSnapshotScheduleListerExpansion allows custom methods to be added to
+// HdfsSnapshotScheduleLister.
+type HdfsSnapshotScheduleListerExpansion interface{}
+
+// HdfsSnapshotScheduleNamespaceListerExpansion allows custom methods to be added to
+// HdfsSnapshotScheduleNamespaceLister.
+type HdfsSnapshotScheduleNamespaceListerExpansion interface{}
+
+// HdfsDirectoryListerExpansion allows custom methods to be added to
+// HdfsDirectoryLister.
+type HdfsDirectoryListerExpansion interface{}
+
+// HdfsDirectoryNamespaceListerExpansion allows custom methods to be added to
+// HdfsDirectoryNamespaceLister.
+type HdfsDirectoryNamespaceListerExpansion interface{}
+
+// HdfsOperationListerExpansion allows custom methods to be added to
+// HdfsOperationLister.
+type HdfsOperationListerExpansion interface{}
+
+// HdfsOperationNamespaceListerExpansion allows custom methods to be added to
+// HdfsOperationNamespaceLister.
+type HdfsOperationNamespaceListerExpansion interface{}
+
+// HdfsReplicationListerExpansion allows custom methods to be added to
+// HdfsReplicationLister.
+type HdfsReplicationListerExpansion interface{}
+
+// HdfsReplicationNamespaceListerExpansion allows custom methods to be added to
+// HdfsReplicationNamespaceLister.
+type HdfsReplicationNamespaceListerExpansion interface{}
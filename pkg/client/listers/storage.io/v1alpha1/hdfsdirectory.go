@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// HdfsDirectoryLister helps list HdfsDirectories.
+type HdfsDirectoryLister interface {
+	// List lists all HdfsDirectories in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsDirectory, err error)
+	// HdfsDirectories returns an object that can list and get HdfsDirectories.
+	HdfsDirectories(namespace string) HdfsDirectoryNamespaceLister
+	HdfsDirectoryListerExpansion
+}
+
+// hdfsDirectoryLister implements the HdfsDirectoryLister interface.
+type hdfsDirectoryLister struct {
+	indexer cache.Indexer
+}
+
+// NewHdfsDirectoryLister returns a new HdfsDirectoryLister.
+func NewHdfsDirectoryLister(indexer cache.Indexer) HdfsDirectoryLister {
+	return &hdfsDirectoryLister{indexer: indexer}
+}
+
+// List lists all HdfsDirectories in the indexer.
+func (s *hdfsDirectoryLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsDirectory, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsDirectory))
+	})
+	return ret, err
+}
+
+// HdfsDirectories returns an object that can list and get HdfsDirectories.
+func (s *hdfsDirectoryLister) HdfsDirectories(namespace string) HdfsDirectoryNamespaceLister {
+	return hdfsDirectoryNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// HdfsDirectoryNamespaceLister helps list and get HdfsDirectories.
+type HdfsDirectoryNamespaceLister interface {
+	// List lists all HdfsDirectories in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsDirectory, err error)
+	// Get retrieves the HdfsDirectory from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.HdfsDirectory, error)
+	HdfsDirectoryNamespaceListerExpansion
+}
+
+// hdfsDirectoryNamespaceLister implements the HdfsDirectoryNamespaceLister
+// interface.
+type hdfsDirectoryNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all HdfsDirectories in the indexer for a given namespace.
+func (s hdfsDirectoryNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsDirectory, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsDirectory))
+	})
+	return ret, err
+}
+
+// Get retrieves the HdfsDirectory from the indexer for a given namespace and name.
+func (s hdfsDirectoryNamespaceLister) Get(name string) (*v1alpha1.HdfsDirectory, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("hdfscluster"), name)
+	}
+	return obj.(*v1alpha1.HdfsDirectory), nil
+}
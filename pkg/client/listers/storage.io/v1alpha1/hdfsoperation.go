@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// HdfsOperationLister helps list HdfsOperations.
+type HdfsOperationLister interface {
+	// List lists all HdfsOperations in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsOperation, err error)
+	// HdfsOperations returns an object that can list and get HdfsOperations.
+	HdfsOperations(namespace string) HdfsOperationNamespaceLister
+	HdfsOperationListerExpansion
+}
+
+// hdfsOperationLister implements the HdfsOperationLister interface.
+type hdfsOperationLister struct {
+	indexer cache.Indexer
+}
+
+// NewHdfsOperationLister returns a new HdfsOperationLister.
+func NewHdfsOperationLister(indexer cache.Indexer) HdfsOperationLister {
+	return &hdfsOperationLister{indexer: indexer}
+}
+
+// List lists all HdfsOperations in the indexer.
+func (s *hdfsOperationLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsOperation, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsOperation))
+	})
+	return ret, err
+}
+
+// HdfsOperations returns an object that can list and get HdfsOperations.
+func (s *hdfsOperationLister) HdfsOperations(namespace string) HdfsOperationNamespaceLister {
+	return hdfsOperationNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// HdfsOperationNamespaceLister helps list and get HdfsOperations.
+type HdfsOperationNamespaceLister interface {
+	// List lists all HdfsOperations in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsOperation, err error)
+	// Get retrieves the HdfsOperation from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.HdfsOperation, error)
+	HdfsOperationNamespaceListerExpansion
+}
+
+// hdfsOperationNamespaceLister implements the HdfsOperationNamespaceLister
+// interface.
+type hdfsOperationNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all HdfsOperations in the indexer for a given namespace.
+func (s hdfsOperationNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsOperation, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsOperation))
+	})
+	return ret, err
+}
+
+// Get retrieves the HdfsOperation from the indexer for a given namespace and name.
+func (s hdfsOperationNamespaceLister) Get(name string) (*v1alpha1.HdfsOperation, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("hdfscluster"), name)
+	}
+	return obj.(*v1alpha1.HdfsOperation), nil
+}
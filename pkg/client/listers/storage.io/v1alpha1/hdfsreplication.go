@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// HdfsReplicationLister helps list HdfsReplications.
+type HdfsReplicationLister interface {
+	// List lists all HdfsReplications in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsReplication, err error)
+	// HdfsReplications returns an object that can list and get HdfsReplications.
+	HdfsReplications(namespace string) HdfsReplicationNamespaceLister
+	HdfsReplicationListerExpansion
+}
+
+// hdfsReplicationLister implements the HdfsReplicationLister interface.
+type hdfsReplicationLister struct {
+	indexer cache.Indexer
+}
+
+// NewHdfsReplicationLister returns a new HdfsReplicationLister.
+func NewHdfsReplicationLister(indexer cache.Indexer) HdfsReplicationLister {
+	return &hdfsReplicationLister{indexer: indexer}
+}
+
+// List lists all HdfsReplications in the indexer.
+func (s *hdfsReplicationLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsReplication, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsReplication))
+	})
+	return ret, err
+}
+
+// HdfsReplications returns an object that can list and get HdfsReplications.
+func (s *hdfsReplicationLister) HdfsReplications(namespace string) HdfsReplicationNamespaceLister {
+	return hdfsReplicationNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// HdfsReplicationNamespaceLister helps list and get HdfsReplications.
+type HdfsReplicationNamespaceLister interface {
+	// List lists all HdfsReplications in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsReplication, err error)
+	// Get retrieves the HdfsReplication from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.HdfsReplication, error)
+	HdfsReplicationNamespaceListerExpansion
+}
+
+// hdfsReplicationNamespaceLister implements the HdfsReplicationNamespaceLister
+// interface.
+type hdfsReplicationNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all HdfsReplications in the indexer for a given namespace.
+func (s hdfsReplicationNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsReplication, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsReplication))
+	})
+	return ret, err
+}
+
+// Get retrieves the HdfsReplication from the indexer for a given namespace and name.
+func (s hdfsReplicationNamespaceLister) Get(name string) (*v1alpha1.HdfsReplication, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("hdfscluster"), name)
+	}
+	return obj.(*v1alpha1.HdfsReplication), nil
+}
@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// HdfsSnapshotLister helps list HdfsSnapshots.
+type HdfsSnapshotLister interface {
+	// List lists all HdfsSnapshots in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshot, err error)
+	// HdfsSnapshots returns an object that can list and get HdfsSnapshots.
+	HdfsSnapshots(namespace string) HdfsSnapshotNamespaceLister
+	HdfsSnapshotListerExpansion
+}
+
+// hdfsSnapshotLister implements the HdfsSnapshotLister interface.
+type hdfsSnapshotLister struct {
+	indexer cache.Indexer
+}
+
+// NewHdfsSnapshotLister returns a new HdfsSnapshotLister.
+func NewHdfsSnapshotLister(indexer cache.Indexer) HdfsSnapshotLister {
+	return &hdfsSnapshotLister{indexer: indexer}
+}
+
+// List lists all HdfsSnapshots in the indexer.
+func (s *hdfsSnapshotLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshot, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsSnapshot))
+	})
+	return ret, err
+}
+
+// HdfsSnapshots returns an object that can list and get HdfsSnapshots.
+func (s *hdfsSnapshotLister) HdfsSnapshots(namespace string) HdfsSnapshotNamespaceLister {
+	return hdfsSnapshotNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// HdfsSnapshotNamespaceLister helps list and get HdfsSnapshots.
+type HdfsSnapshotNamespaceLister interface {
+	// List lists all HdfsSnapshots in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshot, err error)
+	// Get retrieves the HdfsSnapshot from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.HdfsSnapshot, error)
+	HdfsSnapshotNamespaceListerExpansion
+}
+
+// hdfsSnapshotNamespaceLister implements the HdfsSnapshotNamespaceLister
+// interface.
+type hdfsSnapshotNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all HdfsSnapshots in the indexer for a given namespace.
+func (s hdfsSnapshotNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshot, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsSnapshot))
+	})
+	return ret, err
+}
+
+// Get retrieves the HdfsSnapshot from the indexer for a given namespace and name.
+func (s hdfsSnapshotNamespaceLister) Get(name string) (*v1alpha1.HdfsSnapshot, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("hdfscluster"), name)
+	}
+	return obj.(*v1alpha1.HdfsSnapshot), nil
+}
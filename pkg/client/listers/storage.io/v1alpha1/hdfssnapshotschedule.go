@@ -0,0 +1,94 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Code generated by lister-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	v1alpha1 "github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// HdfsSnapshotScheduleLister helps list HdfsSnapshotSchedules.
+type HdfsSnapshotScheduleLister interface {
+	// List lists all HdfsSnapshotSchedules in the indexer.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshotSchedule, err error)
+	// HdfsSnapshotSchedules returns an object that can list and get HdfsSnapshotSchedules.
+	HdfsSnapshotSchedules(namespace string) HdfsSnapshotScheduleNamespaceLister
+	HdfsSnapshotScheduleListerExpansion
+}
+
+// hdfsSnapshotScheduleLister implements the HdfsSnapshotScheduleLister interface.
+type hdfsSnapshotScheduleLister struct {
+	indexer cache.Indexer
+}
+
+// NewHdfsSnapshotScheduleLister returns a new HdfsSnapshotScheduleLister.
+func NewHdfsSnapshotScheduleLister(indexer cache.Indexer) HdfsSnapshotScheduleLister {
+	return &hdfsSnapshotScheduleLister{indexer: indexer}
+}
+
+// List lists all HdfsSnapshotSchedules in the indexer.
+func (s *hdfsSnapshotScheduleLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshotSchedule, err error) {
+	err = cache.ListAll(s.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsSnapshotSchedule))
+	})
+	return ret, err
+}
+
+// HdfsSnapshotSchedules returns an object that can list and get HdfsSnapshotSchedules.
+func (s *hdfsSnapshotScheduleLister) HdfsSnapshotSchedules(namespace string) HdfsSnapshotScheduleNamespaceLister {
+	return hdfsSnapshotScheduleNamespaceLister{indexer: s.indexer, namespace: namespace}
+}
+
+// HdfsSnapshotScheduleNamespaceLister helps list and get HdfsSnapshotSchedules.
+type HdfsSnapshotScheduleNamespaceLister interface {
+	// List lists all HdfsSnapshotSchedules in the indexer for a given namespace.
+	List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshotSchedule, err error)
+	// Get retrieves the HdfsSnapshotSchedule from the indexer for a given namespace and name.
+	Get(name string) (*v1alpha1.HdfsSnapshotSchedule, error)
+	HdfsSnapshotScheduleNamespaceListerExpansion
+}
+
+// hdfsSnapshotScheduleNamespaceLister implements the HdfsSnapshotScheduleNamespaceLister
+// interface.
+type hdfsSnapshotScheduleNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+// List lists all HdfsSnapshotSchedules in the indexer for a given namespace.
+func (s hdfsSnapshotScheduleNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.HdfsSnapshotSchedule, err error) {
+	err = cache.ListAllByNamespace(s.indexer, s.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.HdfsSnapshotSchedule))
+	})
+	return ret, err
+}
+
+// Get retrieves the HdfsSnapshotSchedule from the indexer for a given namespace and name.
+func (s hdfsSnapshotScheduleNamespaceLister) Get(name string) (*v1alpha1.HdfsSnapshotSchedule, error) {
+	obj, exists, err := s.indexer.GetByKey(s.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("hdfscluster"), name)
+	}
+	return obj.(*v1alpha1.HdfsSnapshotSchedule), nil
+}
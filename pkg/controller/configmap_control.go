@@ -0,0 +1,68 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+type ConfigMapControlInterface interface {
+	CreateConfigMap(*v1alpha1.HdfsCluster, *corev1.ConfigMap) error
+	GetConfigMap(hc *v1alpha1.HdfsCluster, name string) (*corev1.ConfigMap, error)
+	UpdateConfigMap(*v1alpha1.HdfsCluster, *corev1.ConfigMap) error
+	DeleteConfigMap(*v1alpha1.HdfsCluster, *corev1.ConfigMap) error
+	// ListConfigMaps lists the configmaps of all namespaces matching selector
+	ListConfigMaps(selector labels.Selector) ([]*corev1.ConfigMap, error)
+}
+
+type realConfigMapControl struct {
+	kubeCli  kubernetes.Interface
+	cmLister corelisters.ConfigMapLister
+}
+
+// NewRealConfigMapControl creates a new ConfigMapControlInterface
+func NewRealConfigMapControl(kubeCli kubernetes.Interface, cmLister corelisters.ConfigMapLister) ConfigMapControlInterface {
+	return &realConfigMapControl{
+		kubeCli,
+		cmLister,
+	}
+}
+
+func (c *realConfigMapControl) CreateConfigMap(hc *v1alpha1.HdfsCluster, cm *corev1.ConfigMap) error {
+	_, err := c.kubeCli.CoreV1().ConfigMaps(cm.Namespace).Create(cm)
+	if err != nil {
+		glog.Errorf("create configmap %s/%s error, err=%+v", cm.Namespace, cm.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *realConfigMapControl) GetConfigMap(hc *v1alpha1.HdfsCluster, name string) (*corev1.ConfigMap, error) {
+	return c.cmLister.ConfigMaps(hc.Namespace).Get(name)
+}
+
+func (c *realConfigMapControl) UpdateConfigMap(hc *v1alpha1.HdfsCluster, cm *corev1.ConfigMap) error {
+	_, err := c.kubeCli.CoreV1().ConfigMaps(cm.Namespace).Update(cm)
+	if err != nil {
+		glog.Errorf("update configmap %s/%s error, err=%+v", cm.Namespace, cm.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *realConfigMapControl) DeleteConfigMap(hc *v1alpha1.HdfsCluster, cm *corev1.ConfigMap) error {
+	err := c.kubeCli.CoreV1().ConfigMaps(cm.Namespace).Delete(cm.Name, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf("delete configmap %s/%s error, err=%+v", cm.Namespace, cm.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *realConfigMapControl) ListConfigMaps(selector labels.Selector) ([]*corev1.ConfigMap, error) {
+	return c.cmLister.List(selector)
+}
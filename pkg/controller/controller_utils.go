@@ -4,12 +4,150 @@ import (
 	"fmt"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
 )
 
 var (
-	controllerKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsCluster")
+	controllerKind  = v1alpha1.SchemeGroupVersion.WithKind("HdfsCluster")
+	operationKind   = v1alpha1.SchemeGroupVersion.WithKind("HdfsOperation")
+	directoryKind   = v1alpha1.SchemeGroupVersion.WithKind("HdfsDirectory")
+	replicationKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsReplication")
+	snapshotKind    = v1alpha1.SchemeGroupVersion.WithKind("HdfsSnapshot")
 )
 
+// HadoopImage runs the hdfs command line for admin jobs
+const HadoopImage = "uhopper/hadoop:2.7.2"
+
+// NameNodeImage and DataNodeImage are the images of a cluster without images
+const (
+	NameNodeImage = "uhopper/hadoop-namenode:2.7.2"
+	DataNodeImage = "uhopper/hadoop-datanode:2.7.2"
+)
+
+const (
+	NameNodeRPCPort  = 8020
+	NameNodeHTTPPort = 50070
+	// NameNodeWebPort is the service port in front of NameNodeHTTPPort
+	NameNodeWebPort   = 80
+	NameNodeHTTPSPort = 50470
+	// NameNodeWebTLSPort replaces NameNodeWebPort once tls is on
+	NameNodeWebTLSPort = 443
+	KMSPort            = 16000
+	RouterRPCPort      = 8888
+	RouterAdminPort    = 8111
+	RouterHTTPPort     = 50071
+	HttpFSPort         = 14000
+	NFSPort            = 2049
+	NFSMountdPort      = 4242
+	PortmapPort        = 111
+	// ResourceManager*Port are the yarn defaults
+	ResourceManagerPort          = 8032
+	ResourceManagerSchedulerPort = 8030
+	ResourceManagerTrackerPort   = 8031
+	ResourceManagerAdminPort     = 8033
+	ResourceManagerWebPort       = 8088
+	NodeManagerWebPort           = 8042
+)
+
+// RouterImage runs the dfs router, hadoop 2.7 has no router
+const RouterImage = "apache/hadoop:3.3.6"
+
+const (
+	ResourceManagerImage = "uhopper/hadoop-resourcemanager:2.7.2"
+	NodeManagerImage     = "uhopper/hadoop-nodemanager:2.7.2"
+)
+
+// TLSRevisionAnnotation on a pod template restarts its pods when the
+// certificates they loaded are renewed
+const TLSRevisionAnnotation = "storage.io/tls-revision"
+
+// NodesRefreshedAnnotation on the data node exclude configmap is "true" once
+// the name nodes have read its current content
+const NodesRefreshedAnnotation = "storage.io/nodes-refreshed"
+
+// PVCRetentionFinalizer holds the hdfs cluster until its pvcs are
+// deleted or orphaned by the pvc retention policy
+const PVCRetentionFinalizer = "storage.io/pvc-retention"
+
+// TeardownFinalizer holds the hdfs cluster until the namespace is saved
+// and the optional metadata backup is taken
+const TeardownFinalizer = "storage.io/safe-teardown"
+
+// ClientConfigFinalizer holds the hdfs cluster until the copies of its
+// client configmap in other namespaces are deleted
+const ClientConfigFinalizer = "storage.io/client-config"
+
+// ClientConfigClusterLabelKey and ClientConfigNamespaceLabelKey mark the
+// copies of a client configmap with the cluster they were copied from,
+// owner references can not cross namespaces
+const (
+	ClientConfigClusterLabelKey   = "storage.io/client-config-cluster"
+	ClientConfigNamespaceLabelKey = "storage.io/client-config-namespace"
+)
+
+// SnapshotFinalizer holds an HdfsSnapshot until its hdfs snapshot is deleted
+const SnapshotFinalizer = "storage.io/hdfs-snapshot"
+
+// SnapshotScheduleLabelKey marks the HdfsSnapshots created by a schedule
+const SnapshotScheduleLabelKey = "storage.io/snapshot-schedule"
+
+// HasFinalizer returns whether obj has the finalizer
+func HasFinalizer(obj metav1.Object, finalizer string) bool {
+	for _, f := range obj.GetFinalizers() {
+		if f == finalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// AddFinalizer adds the finalizer to obj if it is missing
+func AddFinalizer(obj metav1.Object, finalizer string) {
+	if !HasFinalizer(obj, finalizer) {
+		obj.SetFinalizers(append(obj.GetFinalizers(), finalizer))
+	}
+}
+
+// RemoveFinalizer removes the finalizer from obj
+func RemoveFinalizer(obj metav1.Object, finalizer string) {
+	finalizers := []string{}
+	for _, f := range obj.GetFinalizers() {
+		if f != finalizer {
+			finalizers = append(finalizers, f)
+		}
+	}
+	obj.SetFinalizers(finalizers)
+}
+
+// RequeueError is used to requeue the item after a delay, it is not treated as a sync failure
+type RequeueError struct {
+	s     string
+	after time.Duration
+}
+
+func (re *RequeueError) Error() string {
+	return re.s
+}
+
+// RequeueErrorf returns a RequeueError
+func RequeueErrorf(after time.Duration, format string, a ...interface{}) error {
+	return &RequeueError{fmt.Sprintf(format, a...), after}
+}
+
+// IsRequeueError returns whether err is a RequeueError
+func IsRequeueError(err error) bool {
+	_, ok := err.(*RequeueError)
+	return ok
+}
+
+// RequeueAfter returns the delay carried by a RequeueError
+func RequeueAfter(err error) time.Duration {
+	if re, ok := err.(*RequeueError); ok {
+		return re.after
+	}
+	return 0
+}
+
 func GetOwnerRef(tc *v1alpha1.HdfsCluster) metav1.OwnerReference {
 	controller := true
 	blockOwnerDeletion := true
@@ -23,6 +161,62 @@ func GetOwnerRef(tc *v1alpha1.HdfsCluster) metav1.OwnerReference {
 	}
 }
 
+// GetDirectoryOwnerRef is the controller reference of the jobs of an HdfsDirectory
+func GetDirectoryOwnerRef(dir *v1alpha1.HdfsDirectory) metav1.OwnerReference {
+	controller := true
+	blockOwnerDeletion := true
+	return metav1.OwnerReference{
+		APIVersion:         directoryKind.GroupVersion().String(),
+		Kind:               directoryKind.Kind,
+		Name:               dir.GetName(),
+		UID:                dir.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+}
+
+// GetSnapshotOwnerRef is the controller reference of the jobs of an HdfsSnapshot
+func GetSnapshotOwnerRef(snap *v1alpha1.HdfsSnapshot) metav1.OwnerReference {
+	controller := true
+	blockOwnerDeletion := true
+	return metav1.OwnerReference{
+		APIVersion:         snapshotKind.GroupVersion().String(),
+		Kind:               snapshotKind.Kind,
+		Name:               snap.GetName(),
+		UID:                snap.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+}
+
+// GetOperationOwnerRef is the controller reference of the jobs of an HdfsOperation
+func GetOperationOwnerRef(op *v1alpha1.HdfsOperation) metav1.OwnerReference {
+	controller := true
+	blockOwnerDeletion := true
+	return metav1.OwnerReference{
+		APIVersion:         operationKind.GroupVersion().String(),
+		Kind:               operationKind.Kind,
+		Name:               op.GetName(),
+		UID:                op.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+}
+
+// GetReplicationOwnerRef is the controller reference of the jobs of an HdfsReplication
+func GetReplicationOwnerRef(rep *v1alpha1.HdfsReplication) metav1.OwnerReference {
+	controller := true
+	blockOwnerDeletion := true
+	return metav1.OwnerReference{
+		APIVersion:         replicationKind.GroupVersion().String(),
+		Kind:               replicationKind.Kind,
+		Name:               rep.GetName(),
+		UID:                rep.GetUID(),
+		Controller:         &controller,
+		BlockOwnerDeletion: &blockOwnerDeletion,
+	}
+}
+
 func NameNodeServiceName(clusterName string) string {
 	return fmt.Sprintf("%snn", clusterName)
 }
@@ -35,6 +229,263 @@ func NameNodeDeployment(clusterName string) string {
 	return fmt.Sprintf("%s-namenode", clusterName)
 }
 
+// NameNodeHost is the in-cluster dns name of the name node service
+func NameNodeHost(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("%s.%s.svc", NameNodeServiceName(hc.Name), hc.Namespace)
+}
+
+// DefaultFS is the fs.defaultFS of hc
+func DefaultFS(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("hdfs://%s:%d", NameNodeServiceName(hc.Name), NameNodeRPCPort)
+}
+
+func NameNodeRPCAddress(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("%s:%d", NameNodeHost(hc), NameNodeRPCPort)
+}
+
+func NameNodeHTTPAddress(hc *v1alpha1.HdfsCluster) string {
+	if TLSEnabled(hc) {
+		return fmt.Sprintf("%s:%d", NameNodeHost(hc), NameNodeWebTLSPort)
+	}
+	return fmt.Sprintf("%s:%d", NameNodeHost(hc), NameNodeWebPort)
+}
+
+// DataNodeExcludeConfigMapName holds the dfs.hosts.exclude file of the name
+// nodes, it lists the data nodes being decommissioned or scaled in
+func DataNodeExcludeConfigMapName(clusterName string) string {
+	return fmt.Sprintf("%s-datanode-exclude", clusterName)
+}
+
+// RefreshNodesJobName makes the name nodes reread the data node exclude file
+func RefreshNodesJobName(clusterName string) string {
+	return fmt.Sprintf("%s-refresh-nodes", clusterName)
+}
+
+// DirectorySettingsJobName sets the quotas and the storage policy of an
+// HdfsDirectory, webhdfs of hadoop 2.7 can not do that
+func DirectorySettingsJobName(dirName string) string {
+	return fmt.Sprintf("%s-settings", dirName)
+}
+
+// AllowSnapshotJobName makes the directory of an HdfsSnapshot snapshottable,
+// webhdfs of hadoop 2.7 can not do that
+func AllowSnapshotJobName(snapName string) string {
+	return fmt.Sprintf("%s-allow-snapshot", snapName)
+}
+
+// NameNodeWebURL is the web endpoint of the name node service, it is https
+// only once tls is on
+func NameNodeWebURL(hc *v1alpha1.HdfsCluster) string {
+	if TLSEnabled(hc) {
+		return fmt.Sprintf("https://%s", NameNodeHTTPAddress(hc))
+	}
+	return fmt.Sprintf("http://%s", NameNodeHTTPAddress(hc))
+}
+
+func TLSEnabled(hc *v1alpha1.HdfsCluster) bool {
+	return hc.Spec.Security != nil && hc.Spec.Security.TLS != nil
+}
+
+func KerberosEnabled(hc *v1alpha1.HdfsCluster) bool {
+	return hc.Spec.Security != nil && hc.Spec.Security.Kerberos != nil
+}
+
+// WebHDFSUnsupportedMessage explains why a resource managed through WebHDFS
+// fails on a kerberos cluster, the operator does not speak SPNEGO
+func WebHDFSUnsupportedMessage(hc *v1alpha1.HdfsCluster, kind string) string {
+	return fmt.Sprintf("hdfs cluster %s uses kerberos, %s is not supported since WebHDFS needs SPNEGO", hc.Name, kind)
+}
+
+// KMSName names the deployment, service and pvc of the kms of a cluster
+func KMSName(clusterName string) string {
+	return fmt.Sprintf("%s-kms", clusterName)
+}
+
+func KMSLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "kms", "hdfs-cluster": clusterName}
+}
+
+// RouterName names the deployment, service and config of the routers of a cluster
+func RouterName(clusterName string) string {
+	return fmt.Sprintf("%s-router", clusterName)
+}
+
+func RouterLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "router", "hdfs-cluster": clusterName}
+}
+
+func RouterRPCAddress(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("%s.%s.svc:%d", RouterName(hc.Name), hc.Namespace, RouterRPCPort)
+}
+
+func RouterAdminAddress(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("%s.%s.svc:%d", RouterName(hc.Name), hc.Namespace, RouterAdminPort)
+}
+
+// RouterMountsJobName applies the mount table to the state store of the routers
+func RouterMountsJobName(clusterName string) string {
+	return fmt.Sprintf("%s-router-mounts", clusterName)
+}
+
+// HttpFSName names the deployment and service of the httpfs gateways of a cluster
+func HttpFSName(clusterName string) string {
+	return fmt.Sprintf("%s-httpfs", clusterName)
+}
+
+func HttpFSLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "httpfs", "hdfs-cluster": clusterName}
+}
+
+// NFSGatewayName names the deployment and service of the nfs gateways of a cluster
+func NFSGatewayName(clusterName string) string {
+	return fmt.Sprintf("%s-nfs", clusterName)
+}
+
+func NFSGatewayLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "nfs-gateway", "hdfs-cluster": clusterName}
+}
+
+// ResourceManagerName names the deployment and service of the yarn resource manager of a cluster
+func ResourceManagerName(clusterName string) string {
+	return fmt.Sprintf("%s-resourcemanager", clusterName)
+}
+
+func ResourceManagerLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "resourcemanager", "hdfs-cluster": clusterName}
+}
+
+func ResourceManagerHost(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("%s.%s.svc", ResourceManagerName(hc.Name), hc.Namespace)
+}
+
+// NodeManagerName names the statefulset of the yarn node managers of a
+// cluster and the headless service resolving their host names
+func NodeManagerName(clusterName string) string {
+	return fmt.Sprintf("%s-nodemanager", clusterName)
+}
+
+func NodeManagerLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "nodemanager", "hdfs-cluster": clusterName}
+}
+
+// KMSProviderURI is the key provider the name node and the clients use
+func KMSProviderURI(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf("kms://http@%s.%s.svc:%d/kms", KMSName(hc.Name), hc.Namespace, KMSPort)
+}
+
+// EncryptionZoneJobName creates the encryption zone of an HdfsDirectory
+func EncryptionZoneJobName(dirName string) string {
+	return fmt.Sprintf("%s-encryption-zone", dirName)
+}
+
+// TLSSecretName holds the certificates the operator issues for the pods of hc
+func TLSSecretName(clusterName string) string {
+	return fmt.Sprintf("%s-tls", clusterName)
+}
+
+func SaveNamespaceJobName(clusterName string) string {
+	return fmt.Sprintf("%s-save-namespace", clusterName)
+}
+
+func MetadataBackupJobName(clusterName string) string {
+	return fmt.Sprintf("%s-metadata-backup", clusterName)
+}
+
+// ScheduledBackupJobName is the job of the backup scheduled at t
+func ScheduledBackupJobName(clusterName string, t time.Time) string {
+	return fmt.Sprintf("%s-backup-%d", clusterName, t.Unix())
+}
+
+// OperationJobName is the job of a non exclusive HdfsOperation
+func OperationJobName(opName string) string {
+	return fmt.Sprintf("%s-operation", opName)
+}
+
+// ReplicationJobName is the distcp job of an HdfsReplication, one run at a time
+func ReplicationJobName(repName string) string {
+	return fmt.Sprintf("%s-distcp", repName)
+}
+
+// UpgradeJobName prepares or finalizes the rolling upgrade of a cluster
+func UpgradeJobName(clusterName string) string {
+	return fmt.Sprintf("%s-upgrade", clusterName)
+}
+
+// ExclusiveOperationJobName is shared by the exclusive operations of a
+// cluster, creating it fails while another one is running so it works as a lock
+func ExclusiveOperationJobName(clusterName string) string {
+	return fmt.Sprintf("%s-exclusive-operation", clusterName)
+}
+
+// TeardownBackupPVCName is the pvc the fsimage is fetched into on deletion,
+// it has no owner reference so it outlives the cluster
+func TeardownBackupPVCName(clusterName string) string {
+	return fmt.Sprintf("%s-namenode-backup", clusterName)
+}
+
+// TopologyConfigMapName holds the rack topology script and table of the name node
+func TopologyConfigMapName(clusterName string) string {
+	return fmt.Sprintf("%s-topology", clusterName)
+}
+
+// Krb5ConfigMapName holds the krb5.conf of a kerberos secured cluster
+func Krb5ConfigMapName(clusterName string) string {
+	return fmt.Sprintf("%s-krb5", clusterName)
+}
+
+// NameServiceName is the name of the service, deployment and pvc of the
+// name node of a name service added by federation
+func NameServiceName(clusterName string, nameService string) string {
+	return fmt.Sprintf("%s-namenode-%s", clusterName, nameService)
+}
+
+// NameServiceLabel selects the name node of a federated name service, the
+// app differs from NameNodeLabel which selects the name node of the cluster
+func NameServiceLabel(clusterName string, nameService string) map[string]string {
+	return map[string]string{
+		"app":          "federated-namenode",
+		"hdfs-cluster": clusterName,
+		"name-service": nameService,
+	}
+}
+
+// NameServiceHost is the in-cluster dns name of the name node of a name
+// service, the name service named after the cluster is served by the name node
+func NameServiceHost(hc *v1alpha1.HdfsCluster, nameService string) string {
+	if nameService == hc.Name {
+		return NameNodeHost(hc)
+	}
+	return fmt.Sprintf("%s.%s.svc", NameServiceName(hc.Name, nameService), hc.Namespace)
+}
+
+func NameServiceRPCAddress(hc *v1alpha1.HdfsCluster, nameService string) string {
+	return fmt.Sprintf("%s:%d", NameServiceHost(hc, nameService), NameNodeRPCPort)
+}
+
+func NameServiceHTTPAddress(hc *v1alpha1.HdfsCluster, nameService string) string {
+	if nameService == hc.Name {
+		return NameNodeHTTPAddress(hc)
+	}
+	if TLSEnabled(hc) {
+		return fmt.Sprintf("%s:%d", NameServiceHost(hc, nameService), NameNodeWebTLSPort)
+	}
+	return fmt.Sprintf("%s:%d", NameServiceHost(hc, nameService), NameNodeWebPort)
+}
+
+// NameServiceWebURL is the web endpoint of the name node of a name service,
+// it is https only once tls is on like NameNodeWebURL
+func NameServiceWebURL(hc *v1alpha1.HdfsCluster, nameService string) string {
+	if TLSEnabled(hc) {
+		return fmt.Sprintf("https://%s", NameServiceHTTPAddress(hc, nameService))
+	}
+	return fmt.Sprintf("http://%s", NameServiceHTTPAddress(hc, nameService))
+}
+
+// ClientConfigMapName holds the hadoop config the clients of a cluster mount
+func ClientConfigMapName(clusterName string) string {
+	return fmt.Sprintf("%s-client-config", clusterName)
+}
+
 func DataNodeServiceName(clusterName string) string {
 	return fmt.Sprintf("%sdn", clusterName)
 }
@@ -43,12 +494,27 @@ func DataNodeSetName(clusterName string) string {
 	return fmt.Sprintf("%s-datanode", clusterName)
 }
 
+// DataNodeVolumeName is the name of the volumeClaimTemplate of the data node statefulset
+const DataNodeVolumeName = "hdfs-data"
+
+// DataNodePVCPrefix is the name prefix of the pvcs created from the data node
+// volumeClaimTemplate, the pvc of ordinal N is the prefix followed by N
+func DataNodePVCPrefix(clusterName string) string {
+	return fmt.Sprintf("%s-%s-", DataNodeVolumeName, DataNodeSetName(clusterName))
+}
+
 func DataNodeLabel() map[string]string {
 	labels := make(map[string]string)
 	labels["app"] = "datanode"
 	return labels
 }
 
+// DataNodeClusterLabel labels the data node pods of one cluster, the
+// statefulset selector keeps DataNodeLabel since it is immutable
+func DataNodeClusterLabel(clusterName string) map[string]string {
+	return map[string]string{"app": "datanode", "hdfs-cluster": clusterName}
+}
+
 func NameNodeLabel() map[string]string {
 	label := make(map[string]string)
 	label["app"] = "namenode"
@@ -4,6 +4,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 )
@@ -11,6 +12,8 @@ import (
 type DeploymentControlInterface interface {
 	CreateDeployment(*v1alpha1.HdfsCluster, *apps.Deployment) error
 	GetDeployment(hc *v1alpha1.HdfsCluster, deployment string) (*apps.Deployment, error)
+	UpdateDeployment(*v1alpha1.HdfsCluster, *apps.Deployment) error
+	DeleteDeployment(hc *v1alpha1.HdfsCluster, name string) error
 }
 
 type realDeploymentControl struct {
@@ -39,3 +42,21 @@ func (c *realDeploymentControl) GetDeployment(hc *v1alpha1.HdfsCluster, deployme
 	cur, err := c.deployLister.Deployments(hc.Namespace).Get(deployment)
 	return cur, err
 }
+
+func (c *realDeploymentControl) UpdateDeployment(hc *v1alpha1.HdfsCluster, deployment *apps.Deployment) error {
+	_, err := c.kubeCli.AppsV1().Deployments(hc.Namespace).Update(deployment)
+	if err != nil {
+		glog.Errorf("update deployment error, err=%+v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *realDeploymentControl) DeleteDeployment(hc *v1alpha1.HdfsCluster, name string) error {
+	err := c.kubeCli.AppsV1().Deployments(hc.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf("delete deployment %s/%s error, err=%+v", hc.Namespace, name, err)
+		return err
+	}
+	return nil
+}
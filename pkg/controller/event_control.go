@@ -0,0 +1,57 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"time"
+)
+
+const eventComponent = "hdfs-operator"
+
+type EventControlInterface interface {
+	RecordEvent(hc *v1alpha1.HdfsCluster, eventType, reason, message string)
+}
+
+type realEventControl struct {
+	kubeCli kubernetes.Interface
+}
+
+// NewRealEventControl creates a new EventControlInterface
+func NewRealEventControl(kubeCli kubernetes.Interface) EventControlInterface {
+	return &realEventControl{
+		kubeCli,
+	}
+}
+
+// RecordEvent creates an event about hc, a failure is only logged
+func (c *realEventControl) RecordEvent(hc *v1alpha1.HdfsCluster, eventType, reason, message string) {
+	now := metav1.Now()
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%v.%x", hc.Name, time.Now().UnixNano()),
+			Namespace: hc.Namespace,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      controllerKind.GroupVersion().String(),
+			Kind:            controllerKind.Kind,
+			Namespace:       hc.Namespace,
+			Name:            hc.Name,
+			UID:             hc.UID,
+			ResourceVersion: hc.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: eventComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if _, err := c.kubeCli.CoreV1().Events(hc.Namespace).Create(event); err != nil {
+		glog.Errorf("record event %s for %s/%s error, err=%+v", reason, hc.Namespace, hc.Name, err)
+	}
+}
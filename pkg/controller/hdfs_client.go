@@ -0,0 +1,41 @@
+package controller
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+)
+
+// NewHdfsClient returns a client for the name node web endpoint of hc
+func NewHdfsClient(hc *v1alpha1.HdfsCluster) hdfs.Interface {
+	if config := NameNodeTLSConfig(hc); config != nil {
+		return hdfs.NewTLSClient(NameNodeWebURL(hc), hdfs.DefaultUser, config)
+	}
+	return hdfs.NewClient(NameNodeWebURL(hc), hdfs.DefaultUser)
+}
+
+// NewNameServiceHdfsClient returns a client for the name node of a name service
+func NewNameServiceHdfsClient(hc *v1alpha1.HdfsCluster, nameService string) hdfs.Interface {
+	if nameService == hc.Name {
+		return NewHdfsClient(hc)
+	}
+	if config := NameNodeTLSConfig(hc); config != nil {
+		return hdfs.NewTLSClient(NameServiceWebURL(hc, nameService), hdfs.DefaultUser, config)
+	}
+	return hdfs.NewClient(NameServiceWebURL(hc, nameService), hdfs.DefaultUser)
+}
+
+// NameNodeTLSConfig trusts the CA recorded in the status of hc, it is nil
+// while tls is off
+func NameNodeTLSConfig(hc *v1alpha1.HdfsCluster) *tls.Config {
+	if !TLSEnabled(hc) {
+		return nil
+	}
+	config := &tls.Config{}
+	if hc.Status.TLS != nil && hc.Status.TLS.CACertificate != "" {
+		config.RootCAs = x509.NewCertPool()
+		config.RootCAs.AppendCertsFromPEM([]byte(hc.Status.TLS.CACertificate))
+	}
+	return config
+}
@@ -0,0 +1,65 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"k8s.io/client-go/util/retry"
+)
+
+type HdfsClusterControlInterface interface {
+	UpdateHdfsCluster(*v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error)
+	UpdateHdfsClusterStatus(*v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error)
+}
+
+type realHdfsClusterControl struct {
+	cli      versioned.Interface
+	hcLister listers.HdfsClusterLister
+}
+
+// NewRealHdfsClusterControl creates a new HdfsClusterControlInterface
+func NewRealHdfsClusterControl(cli versioned.Interface, hcLister listers.HdfsClusterLister) HdfsClusterControlInterface {
+	return &realHdfsClusterControl{
+		cli,
+		hcLister,
+	}
+}
+
+// UpdateHdfsCluster writes the metadata and spec of hc, the status is ignored by the api server
+func (c *realHdfsClusterControl) UpdateHdfsCluster(hc *v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error) {
+	updated, err := c.cli.StorageV1alpha1().HdfsClusters(hc.Namespace).Update(hc)
+	if err != nil {
+		glog.Errorf("update hdfs cluster %s/%s error, err=%+v", hc.Namespace, hc.Name, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// UpdateHdfsClusterStatus writes the status of hc, on conflict the status is
+// copied onto the latest version from the lister and retried
+func (c *realHdfsClusterControl) UpdateHdfsClusterStatus(hc *v1alpha1.HdfsCluster) (*v1alpha1.HdfsCluster, error) {
+	ns := hc.GetNamespace()
+	name := hc.GetName()
+	status := hc.Status.DeepCopy()
+	var updated *v1alpha1.HdfsCluster
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsClusters(ns).UpdateStatus(hc)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.hcLister.HdfsClusters(ns).Get(name); err == nil {
+			hc = latest.DeepCopy()
+			hc.Status = *status
+		} else {
+			glog.Errorf("get hdfs cluster %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs cluster %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
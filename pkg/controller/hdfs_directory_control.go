@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"k8s.io/client-go/util/retry"
+)
+
+type HdfsDirectoryControlInterface interface {
+	UpdateHdfsDirectoryStatus(*v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error)
+}
+
+type realHdfsDirectoryControl struct {
+	cli       versioned.Interface
+	dirLister listers.HdfsDirectoryLister
+}
+
+// NewRealHdfsDirectoryControl creates a new HdfsDirectoryControlInterface
+func NewRealHdfsDirectoryControl(cli versioned.Interface, dirLister listers.HdfsDirectoryLister) HdfsDirectoryControlInterface {
+	return &realHdfsDirectoryControl{
+		cli,
+		dirLister,
+	}
+}
+
+func (c *realHdfsDirectoryControl) UpdateHdfsDirectoryStatus(dir *v1alpha1.HdfsDirectory) (*v1alpha1.HdfsDirectory, error) {
+	ns := dir.GetNamespace()
+	name := dir.GetName()
+	status := dir.Status.DeepCopy()
+	var updated *v1alpha1.HdfsDirectory
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsDirectories(ns).UpdateStatus(dir)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.dirLister.HdfsDirectories(ns).Get(name); err == nil {
+			dir = latest.DeepCopy()
+			dir.Status = *status
+		} else {
+			glog.Errorf("get hdfs directory %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs directory %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"k8s.io/client-go/util/retry"
+)
+
+type HdfsOperationControlInterface interface {
+	UpdateHdfsOperationStatus(*v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error)
+}
+
+type realHdfsOperationControl struct {
+	cli      versioned.Interface
+	opLister listers.HdfsOperationLister
+}
+
+// NewRealHdfsOperationControl creates a new HdfsOperationControlInterface
+func NewRealHdfsOperationControl(cli versioned.Interface, opLister listers.HdfsOperationLister) HdfsOperationControlInterface {
+	return &realHdfsOperationControl{
+		cli,
+		opLister,
+	}
+}
+
+func (c *realHdfsOperationControl) UpdateHdfsOperationStatus(op *v1alpha1.HdfsOperation) (*v1alpha1.HdfsOperation, error) {
+	ns := op.GetNamespace()
+	name := op.GetName()
+	status := op.Status.DeepCopy()
+	var updated *v1alpha1.HdfsOperation
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsOperations(ns).UpdateStatus(op)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.opLister.HdfsOperations(ns).Get(name); err == nil {
+			op = latest.DeepCopy()
+			op.Status = *status
+		} else {
+			glog.Errorf("get hdfs operation %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs operation %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"k8s.io/client-go/util/retry"
+)
+
+type HdfsReplicationControlInterface interface {
+	UpdateHdfsReplicationStatus(*v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error)
+}
+
+type realHdfsReplicationControl struct {
+	cli       versioned.Interface
+	repLister listers.HdfsReplicationLister
+}
+
+// NewRealHdfsReplicationControl creates a new HdfsReplicationControlInterface
+func NewRealHdfsReplicationControl(cli versioned.Interface, repLister listers.HdfsReplicationLister) HdfsReplicationControlInterface {
+	return &realHdfsReplicationControl{
+		cli,
+		repLister,
+	}
+}
+
+func (c *realHdfsReplicationControl) UpdateHdfsReplicationStatus(rep *v1alpha1.HdfsReplication) (*v1alpha1.HdfsReplication, error) {
+	ns := rep.GetNamespace()
+	name := rep.GetName()
+	status := rep.Status.DeepCopy()
+	var updated *v1alpha1.HdfsReplication
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsReplications(ns).UpdateStatus(rep)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.repLister.HdfsReplications(ns).Get(name); err == nil {
+			rep = latest.DeepCopy()
+			rep.Status = *status
+		} else {
+			glog.Errorf("get hdfs replication %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs replication %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
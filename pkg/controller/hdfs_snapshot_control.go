@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/util/retry"
+)
+
+type HdfsSnapshotControlInterface interface {
+	CreateHdfsSnapshot(*v1alpha1.HdfsSnapshot) error
+	UpdateHdfsSnapshot(*v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error)
+	UpdateHdfsSnapshotStatus(*v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error)
+	DeleteHdfsSnapshot(*v1alpha1.HdfsSnapshot) error
+	ListHdfsSnapshots(namespace string, selector map[string]string) ([]*v1alpha1.HdfsSnapshot, error)
+}
+
+type realHdfsSnapshotControl struct {
+	cli        versioned.Interface
+	snapLister listers.HdfsSnapshotLister
+}
+
+// NewRealHdfsSnapshotControl creates a new HdfsSnapshotControlInterface
+func NewRealHdfsSnapshotControl(cli versioned.Interface, snapLister listers.HdfsSnapshotLister) HdfsSnapshotControlInterface {
+	return &realHdfsSnapshotControl{
+		cli,
+		snapLister,
+	}
+}
+
+func (c *realHdfsSnapshotControl) CreateHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) error {
+	_, err := c.cli.StorageV1alpha1().HdfsSnapshots(snap.Namespace).Create(snap)
+	if err != nil {
+		glog.Errorf("create hdfs snapshot %s/%s error, err=%+v", snap.Namespace, snap.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *realHdfsSnapshotControl) UpdateHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error) {
+	updated, err := c.cli.StorageV1alpha1().HdfsSnapshots(snap.Namespace).Update(snap)
+	if err != nil {
+		glog.Errorf("update hdfs snapshot %s/%s error, err=%+v", snap.Namespace, snap.Name, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+// UpdateHdfsSnapshotStatus writes the status of snap, on conflict the status is
+// copied onto the latest version from the lister and retried
+func (c *realHdfsSnapshotControl) UpdateHdfsSnapshotStatus(snap *v1alpha1.HdfsSnapshot) (*v1alpha1.HdfsSnapshot, error) {
+	ns := snap.GetNamespace()
+	name := snap.GetName()
+	status := snap.Status.DeepCopy()
+	var updated *v1alpha1.HdfsSnapshot
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsSnapshots(ns).UpdateStatus(snap)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.snapLister.HdfsSnapshots(ns).Get(name); err == nil {
+			snap = latest.DeepCopy()
+			snap.Status = *status
+		} else {
+			glog.Errorf("get hdfs snapshot %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs snapshot %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
+
+func (c *realHdfsSnapshotControl) DeleteHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) error {
+	err := c.cli.StorageV1alpha1().HdfsSnapshots(snap.Namespace).Delete(snap.Name, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf("delete hdfs snapshot %s/%s error, err=%+v", snap.Namespace, snap.Name, err)
+		return err
+	}
+	return nil
+}
+
+func (c *realHdfsSnapshotControl) ListHdfsSnapshots(namespace string, selector map[string]string) ([]*v1alpha1.HdfsSnapshot, error) {
+	return c.snapLister.HdfsSnapshots(namespace).List(labels.SelectorFromSet(selector))
+}
+
+type HdfsSnapshotScheduleControlInterface interface {
+	UpdateHdfsSnapshotScheduleStatus(*v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error)
+}
+
+type realHdfsSnapshotScheduleControl struct {
+	cli         versioned.Interface
+	schedLister listers.HdfsSnapshotScheduleLister
+}
+
+// NewRealHdfsSnapshotScheduleControl creates a new HdfsSnapshotScheduleControlInterface
+func NewRealHdfsSnapshotScheduleControl(cli versioned.Interface, schedLister listers.HdfsSnapshotScheduleLister) HdfsSnapshotScheduleControlInterface {
+	return &realHdfsSnapshotScheduleControl{
+		cli,
+		schedLister,
+	}
+}
+
+func (c *realHdfsSnapshotScheduleControl) UpdateHdfsSnapshotScheduleStatus(sched *v1alpha1.HdfsSnapshotSchedule) (*v1alpha1.HdfsSnapshotSchedule, error) {
+	ns := sched.GetNamespace()
+	name := sched.GetName()
+	status := sched.Status.DeepCopy()
+	var updated *v1alpha1.HdfsSnapshotSchedule
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var updateErr error
+		updated, updateErr = c.cli.StorageV1alpha1().HdfsSnapshotSchedules(ns).UpdateStatus(sched)
+		if updateErr == nil {
+			return nil
+		}
+		if latest, err := c.schedLister.HdfsSnapshotSchedules(ns).Get(name); err == nil {
+			sched = latest.DeepCopy()
+			sched.Status = *status
+		} else {
+			glog.Errorf("get hdfs snapshot schedule %s/%s from lister error, err=%+v", ns, name, err)
+		}
+		return updateErr
+	})
+	if err != nil {
+		glog.Errorf("update hdfs snapshot schedule %s/%s status error, err=%+v", ns, name, err)
+		return nil, err
+	}
+	return updated, nil
+}
@@ -3,61 +3,335 @@ package hdfscluster
 import (
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
 	"github.com/tommenx/hdfs-operator/pkg/manager"
+	corev1 "k8s.io/api/core/v1"
+	"reflect"
+	"time"
 )
 
+const (
+	nameNodeMinRequeueDelay   = 5 * time.Second
+	nameNodeMaxRequeueDelay   = 2 * time.Minute
+	deletionBlockedRetryDelay = 5 * time.Minute
+)
+
+// clusterFinalizers are added to every hdfs cluster, the teardown has to run
+// while the name node is still up so it comes first
+var clusterFinalizers = []string{
+	controller.TeardownFinalizer,
+	controller.PVCRetentionFinalizer,
+	controller.ClientConfigFinalizer,
+}
+
 type ControlInterface interface {
 	UpdateHdfsCluster(cluster *v1alpha1.HdfsCluster) error
+	// RefreshHdfsStatus only reads status.hdfs from the name node
+	RefreshHdfsStatus(cluster *v1alpha1.HdfsCluster) error
 }
 
 type hdfsClusterControl struct {
-	nameNodeManager manager.Manager
-	dataNodeManager manager.Manager
+	hcControl           controller.HdfsClusterControlInterface
+	kerberosManager     manager.Manager
+	tlsManager          manager.Manager
+	topologyManager     manager.Manager
+	kmsManager          manager.Manager
+	clientConfigManager manager.ClientConfigManager
+	upgradeManager      manager.Manager
+	nameNodeManager     manager.Manager
+	nameServiceManager  manager.Manager
+	routerManager       manager.Manager
+	httpFSManager       manager.Manager
+	nfsGatewayManager   manager.Manager
+	dataNodeManager     manager.Manager
+	yarnManager         manager.Manager
+	volumeManager       manager.Manager
+	hdfsStatusManager   manager.Manager
+	backupManager       manager.Manager
+	balancerManager     manager.Manager
+	pvcReclaimer        manager.PVCReclaimer
+	teardownManager     manager.TeardownManager
 }
 
 func NewHdfsClusterControl(
+	hcControl controller.HdfsClusterControlInterface,
+	kerberosManager manager.Manager,
+	tlsManager manager.Manager,
+	topologyManager manager.Manager,
+	kmsManager manager.Manager,
+	clientConfigManager manager.ClientConfigManager,
+	upgradeManager manager.Manager,
 	nameNodeManager manager.Manager,
+	nameServiceManager manager.Manager,
+	routerManager manager.Manager,
+	httpFSManager manager.Manager,
+	nfsGatewayManager manager.Manager,
 	dataNodeManager manager.Manager,
+	yarnManager manager.Manager,
+	volumeManager manager.Manager,
+	hdfsStatusManager manager.Manager,
+	backupManager manager.Manager,
+	balancerManager manager.Manager,
+	pvcReclaimer manager.PVCReclaimer,
+	teardownManager manager.TeardownManager,
 ) ControlInterface {
 	return &hdfsClusterControl{
-		nameNodeManager: nameNodeManager,
-		dataNodeManager: dataNodeManager,
+		hcControl:           hcControl,
+		kerberosManager:     kerberosManager,
+		tlsManager:          tlsManager,
+		topologyManager:     topologyManager,
+		kmsManager:          kmsManager,
+		clientConfigManager: clientConfigManager,
+		upgradeManager:      upgradeManager,
+		nameNodeManager:     nameNodeManager,
+		nameServiceManager:  nameServiceManager,
+		routerManager:       routerManager,
+		httpFSManager:       httpFSManager,
+		nfsGatewayManager:   nfsGatewayManager,
+		dataNodeManager:     dataNodeManager,
+		yarnManager:         yarnManager,
+		volumeManager:       volumeManager,
+		hdfsStatusManager:   hdfsStatusManager,
+		backupManager:       backupManager,
+		balancerManager:     balancerManager,
+		pvcReclaimer:        pvcReclaimer,
+		teardownManager:     teardownManager,
 	}
 }
 func (c *hdfsClusterControl) UpdateHdfsCluster(cluster *v1alpha1.HdfsCluster) error {
+	if cluster.DeletionTimestamp != nil {
+		return c.finalizeHdfsCluster(cluster)
+	}
+	if err := c.ensureFinalizers(cluster); err != nil {
+		return err
+	}
+	oldStatus := cluster.Status.DeepCopy()
 	err := c.updateHdfsCluster(cluster)
-	if err != nil {
+	if !reflect.DeepEqual(oldStatus, &cluster.Status) {
+		if _, updateErr := c.hcControl.UpdateHdfsClusterStatus(cluster); updateErr != nil {
+			glog.Errorf("update hdfs cluster status failed, err=%+v", updateErr)
+			if err == nil {
+				err = updateErr
+			}
+		}
+	}
+	if err != nil && !controller.IsRequeueError(err) {
 		glog.Errorf("update hdfs cluster failed, err=%+v", err)
+	}
+	return err
+}
+
+func (c *hdfsClusterControl) RefreshHdfsStatus(cluster *v1alpha1.HdfsCluster) error {
+	oldStatus := cluster.Status.DeepCopy()
+	if err := c.hdfsStatusManager.Sync(cluster); err != nil {
 		return err
 	}
-	return nil
+	if reflect.DeepEqual(oldStatus, &cluster.Status) {
+		return nil
+	}
+	_, err := c.hcControl.UpdateHdfsClusterStatus(cluster)
+	return err
 }
 
+//生成kerberos配置并检查keytab
+//签发并轮换tls证书
+//根据data node所在节点的标签生成机架拓扑
+//同步kms的部署配置
+//发布客户端配置，并复制到选中的namespace
+//检测镜像变化，推进hdfs滚动升级或回滚
 //同步name node的部署配置
 //检查name node的服务是否可用
+//同步federation中其他name service的name node
+//同步dfs router及其挂载表
+//同步httpfs和nfs网关
 //同步data node的部署配置
+//同步yarn的resource manager和node manager
+//扩容name node和data node的pvc
+//刷新hdfs的容量和块状态
+//按照备份计划备份name node元数据
+//按照计划或在data node扩容后运行balancer
 func (c *hdfsClusterControl) updateHdfsCluster(cluster *v1alpha1.HdfsCluster) error {
+	if err := c.kerberosManager.Sync(cluster); err != nil {
+		glog.Errorf("sync kerberos error")
+		return err
+	}
+	if err := c.tlsManager.Sync(cluster); err != nil {
+		glog.Errorf("sync tls error")
+		return err
+	}
+	if err := c.topologyManager.Sync(cluster); err != nil {
+		glog.Errorf("sync rack topology error")
+		return err
+	}
+	if err := c.kmsManager.Sync(cluster); err != nil {
+		glog.Errorf("sync kms error")
+		return err
+	}
+	if err := c.clientConfigManager.Sync(cluster); err != nil {
+		glog.Errorf("sync client config error")
+		return err
+	}
+	if err := c.upgradeManager.Sync(cluster); err != nil {
+		glog.Errorf("sync rolling upgrade error")
+		return err
+	}
 	if err := c.nameNodeManager.Sync(cluster); err != nil {
 		glog.Errorf("sync name node error")
 		return err
 	}
-	//if !c.isNameNodeAvailable() {
-	//	glog.Error("name node service is not available")
-	//	return errors.New("name node is not available")
-	//}
+	if err := c.isNameNodeAvailable(cluster); err != nil {
+		glog.Infof("name node of %s/%s is not available, %v", cluster.Namespace, cluster.Name, err)
+		cluster.SetCondition(v1alpha1.NameNodeAvailable, corev1.ConditionFalse, "NameNodeUnavailable", err.Error())
+		delay := nameNodeRequeueDelay(cluster.GetCondition(v1alpha1.NameNodeAvailable))
+		return controller.RequeueErrorf(delay, "name node is not available, requeue after %s", delay)
+	}
+	cluster.SetCondition(v1alpha1.NameNodeAvailable, corev1.ConditionTrue, "NameNodeAvailable", "name node is ready and serving")
+	//从备份恢复的name node启动后，data node会重新上报块信息
+	if cond := cluster.GetCondition(v1alpha1.Restored); cond != nil && cond.Status != corev1.ConditionTrue {
+		cluster.SetCondition(v1alpha1.Restored, corev1.ConditionTrue, "RestoreCompleted",
+			"name node started from the backup, data nodes re-register their blocks")
+	}
+	if err := c.nameServiceManager.Sync(cluster); err != nil {
+		glog.Errorf("sync name services error")
+		return err
+	}
+	if err := c.routerManager.Sync(cluster); err != nil {
+		glog.Errorf("sync router error")
+		return err
+	}
+	if err := c.httpFSManager.Sync(cluster); err != nil {
+		glog.Errorf("sync httpfs error")
+		return err
+	}
+	if err := c.nfsGatewayManager.Sync(cluster); err != nil {
+		glog.Errorf("sync nfs gateway error")
+		return err
+	}
 	if err := c.dataNodeManager.Sync(cluster); err != nil {
 		glog.Errorf("sync data node error")
 		return err
 	}
+	if err := c.yarnManager.Sync(cluster); err != nil {
+		glog.Errorf("sync yarn error")
+		return err
+	}
+	if err := c.volumeManager.Sync(cluster); err != nil {
+		glog.Errorf("sync volumes error")
+		return err
+	}
+	if err := c.pvcReclaimer.ReclaimScaled(cluster); err != nil {
+		glog.Errorf("reclaim scaled in data node pvcs error")
+		return err
+	}
+	if err := c.pvcReclaimer.ReleaseOwned(cluster); err != nil {
+		glog.Errorf("release name node pvcs error")
+		return err
+	}
+	//hdfs状态刷新失败不影响备份和balancer
+	if err := c.hdfsStatusManager.Sync(cluster); err != nil {
+		glog.Errorf("refresh hdfs status of %s/%s error, err=%+v", cluster.Namespace, cluster.Name, err)
+	}
+	if err := c.backupManager.Sync(cluster); err != nil {
+		glog.Errorf("sync backup error")
+		return err
+	}
+	if err := c.balancerManager.Sync(cluster); err != nil {
+		glog.Errorf("sync balancer error")
+		return err
+	}
+	return nil
+}
+
+//集群删除前先保存name node元数据，再按照pvc保留策略删除或保留pvc，删除其他namespace中的客户端配置，最后移除finalizer
+//开启deletion protection时保留finalizer，直到关闭保护
+func (c *hdfsClusterControl) finalizeHdfsCluster(cluster *v1alpha1.HdfsCluster) error {
+	if controller.HasFinalizer(cluster, controller.TeardownFinalizer) {
+		if cluster.Spec.DeletionProtection {
+			if !cluster.IsConditionTrue(v1alpha1.DeletionBlocked) {
+				cluster.SetCondition(v1alpha1.DeletionBlocked, corev1.ConditionTrue, "DeletionProtection",
+					"deletion protection is enabled, disable it to let the deletion finish")
+				if _, err := c.hcControl.UpdateHdfsClusterStatus(cluster); err != nil {
+					return err
+				}
+			}
+			return controller.RequeueErrorf(deletionBlockedRetryDelay, "deletion of %s/%s is blocked by deletion protection", cluster.Namespace, cluster.Name)
+		}
+		if err := c.teardownManager.Finalize(cluster); err != nil {
+			return err
+		}
+		if err := c.removeFinalizer(cluster, controller.TeardownFinalizer); err != nil {
+			return err
+		}
+	}
+	if controller.HasFinalizer(cluster, controller.PVCRetentionFinalizer) {
+		if err := c.pvcReclaimer.Finalize(cluster); err != nil {
+			glog.Errorf("apply pvc retention policy of %s/%s error, err=%+v", cluster.Namespace, cluster.Name, err)
+			return err
+		}
+		if err := c.removeFinalizer(cluster, controller.PVCRetentionFinalizer); err != nil {
+			return err
+		}
+	}
+	if controller.HasFinalizer(cluster, controller.ClientConfigFinalizer) {
+		if err := c.clientConfigManager.Finalize(cluster); err != nil {
+			glog.Errorf("delete client config copies of %s/%s error, err=%+v", cluster.Namespace, cluster.Name, err)
+			return err
+		}
+		if err := c.removeFinalizer(cluster, controller.ClientConfigFinalizer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *hdfsClusterControl) removeFinalizer(cluster *v1alpha1.HdfsCluster, finalizer string) error {
+	controller.RemoveFinalizer(cluster, finalizer)
+	updated, err := c.hcControl.UpdateHdfsCluster(cluster)
+	if err != nil {
+		return err
+	}
+	cluster.ObjectMeta = updated.ObjectMeta
+	return nil
+}
+
+func (c *hdfsClusterControl) ensureFinalizers(cluster *v1alpha1.HdfsCluster) error {
+	missing := false
+	for _, f := range clusterFinalizers {
+		if !controller.HasFinalizer(cluster, f) {
+			controller.AddFinalizer(cluster, f)
+			missing = true
+		}
+	}
+	if !missing {
+		return nil
+	}
+	updated, err := c.hcControl.UpdateHdfsCluster(cluster)
+	if err != nil {
+		return err
+	}
+	cluster.ObjectMeta = updated.ObjectMeta
 	return nil
 }
 
 //检查name node是否已经能够运行
-//通过检查name node pod 的状态，
-func (c *hdfsClusterControl) isNameNodeAvailable() bool {
-	ok := c.nameNodeManager.CheckStatus()
-	if !ok {
-		return false
+//通过检查name node pod 的状态，以及rpc和web端口是否可以访问
+func (c *hdfsClusterControl) isNameNodeAvailable(cluster *v1alpha1.HdfsCluster) error {
+	return c.nameNodeManager.CheckStatus(cluster)
+}
+
+// nameNodeRequeueDelay doubles the delay for as long as the name node has been
+// unavailable, so a name node that is still formatting is not polled in a tight loop
+func nameNodeRequeueDelay(cond *v1alpha1.HdfsClusterCondition) time.Duration {
+	delay := nameNodeMinRequeueDelay
+	if cond == nil {
+		return delay
+	}
+	unavailable := time.Since(cond.LastTransitionTime.Time)
+	for delay < unavailable && delay < nameNodeMaxRequeueDelay {
+		delay *= 2
+	}
+	if delay > nameNodeMaxRequeueDelay {
+		delay = nameNodeMaxRequeueDelay
 	}
-	return true
+	return delay
 }
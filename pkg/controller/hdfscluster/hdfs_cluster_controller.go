@@ -8,10 +8,14 @@ import (
 	informers "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions"
 	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
 	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/cron"
 	"github.com/tommenx/hdfs-operator/pkg/manager"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
 	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/wait"
 	kubeinformers "k8s.io/client-go/informers"
@@ -19,11 +23,16 @@ import (
 	applisters "k8s.io/client-go/listers/apps/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
+	"reflect"
 	"time"
 )
 
 var controllerKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsCluster")
 
+// fullSyncInterval bounds how long a cluster goes without a full reconcile
+// when neither its objects nor a backup or balancer schedule wake it up
+const fullSyncInterval = 5 * time.Minute
+
 type Controller struct {
 	kubeClient      kubernetes.Interface
 	cli             versioned.Interface
@@ -32,7 +41,21 @@ type Controller struct {
 	setLister       applisters.StatefulSetLister
 	setListerSynced cache.InformerSynced
 	queue           workqueue.RateLimitingInterface
-	control         ControlInterface
+	// statusQueue only refreshes status.hdfs, it does not run the full reconcile
+	statusQueue workqueue.DelayingInterface
+	control     ControlInterface
+	// statusRefreshInterval is how often status.hdfs is read from the name node
+	statusRefreshInterval time.Duration
+	// the controls of the managers read from these listers
+	podListerSynced    cache.InformerSynced
+	svcListerSynced    cache.InformerSynced
+	pvcListerSynced    cache.InformerSynced
+	deployListerSynced cache.InformerSynced
+	cmListerSynced     cache.InformerSynced
+	jobListerSynced    cache.InformerSynced
+	scListerSynced     cache.InformerSynced
+	nodeListerSynced   cache.InformerSynced
+	nsListerSynced     cache.InformerSynced
 }
 
 type HdfsController struct {
@@ -57,6 +80,7 @@ func NewController(
 	cli versioned.Interface,
 	informerFactory informers.SharedInformerFactory,
 	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	statusRefreshInterval time.Duration,
 ) *Controller {
 	podInformer := kubeInformerFactory.Core().V1().Pods()
 	svcInformer := kubeInformerFactory.Core().V1().Services()
@@ -64,24 +88,62 @@ func NewController(
 	hcInformer := informerFactory.Storage().V1alpha1().HdfsClusters()
 	setInformer := kubeInformerFactory.Apps().V1().StatefulSets()
 	deployInformer := kubeInformerFactory.Apps().V1().Deployments()
+	cmInformer := kubeInformerFactory.Core().V1().ConfigMaps()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	scInformer := kubeInformerFactory.Storage().V1().StorageClasses()
+	nodeInformer := kubeInformerFactory.Core().V1().Nodes()
+	nsInformer := kubeInformerFactory.Core().V1().Namespaces()
 
 	setControl := controller.NewRealStatefulSetControl(kubeCli, setInformer.Lister())
 	svcControl := controller.NewRealServiceControl(kubeCli, svcInformer.Lister())
 	pvcControl := controller.NewRealPVCControl(kubeCli, pvcInformer.Lister())
 	deployControl := controller.NewRealDeploymentControl(kubeCli, deployInformer.Lister())
 	podControl := controller.NewRealPodControl(kubeCli, podInformer.Lister())
+	cmControl := controller.NewRealConfigMapControl(kubeCli, cmInformer.Lister())
+	jobControl := controller.NewRealJobControl(kubeCli, jobInformer.Lister())
+	eventControl := controller.NewRealEventControl(kubeCli)
+	scControl := controller.NewRealStorageClassControl(kubeCli, scInformer.Lister())
+	secretControl := controller.NewRealSecretControl(kubeCli)
+	nodeControl := controller.NewRealNodeControl(nodeInformer.Lister())
+	nsControl := controller.NewRealNamespaceControl(nsInformer.Lister())
+
+	hcControl := controller.NewRealHdfsClusterControl(cli, hcInformer.Lister())
 
 	control := &Controller{
 		kubeClient: kubeCli,
+		cli:        cli,
 		control: NewHdfsClusterControl(
-			manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl),
-			manager.NewDataNodeManager(setControl, svcControl, manager.NewDataNodeScaler()),
+			hcControl,
+			manager.NewKerberosManager(cmControl, secretControl, eventControl),
+			manager.NewTLSManager(secretControl, eventControl),
+			manager.NewTopologyManager(cmControl, podControl, nodeControl),
+			manager.NewKMSManager(deployControl, svcControl, pvcControl),
+			manager.NewClientConfigManager(cmControl, nsControl, eventControl),
+			manager.NewUpgradeManager(deployControl, setControl, jobControl, podControl, eventControl, controller.NewNameServiceHdfsClient),
+			manager.NewNameNodeManager(deployControl, pvcControl, podControl, svcControl, manager.NewNameNodeRestorer(secretControl, s3.NewClient)),
+			manager.NewNameServiceManager(deployControl, svcControl, pvcControl, podControl, eventControl, controller.NewNameServiceHdfsClient),
+			manager.NewRouterManager(deployControl, svcControl, cmControl, jobControl, podControl, eventControl),
+			manager.NewHttpFSManager(deployControl, svcControl),
+			manager.NewNFSGatewayManager(deployControl, svcControl),
+			manager.NewDataNodeManager(setControl, svcControl, manager.NewDataNodeScaler(cmControl, jobControl, podControl, eventControl, controller.NewHdfsClient), eventControl),
+			manager.NewYarnManager(deployControl, setControl, svcControl, eventControl),
+			manager.NewVolumeManager(pvcControl, scControl, eventControl),
+			manager.NewHdfsStatusManager(controller.NewHdfsClient, statusRefreshInterval),
+			manager.NewBackupManager(jobControl, secretControl, eventControl, controller.NewHdfsClient, s3.NewClient),
+			manager.NewBalancerManager(jobControl, podControl, eventControl),
+			manager.NewPVCReclaimer(pvcControl, setControl, podControl, eventControl),
+			manager.NewTeardownManager(jobControl, pvcControl, eventControl, controller.NewHdfsClient),
 		),
-		queue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		queue:                 workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		statusQueue:           workqueue.NewNamedDelayingQueue("hdfscluster-status"),
+		statusRefreshInterval: statusRefreshInterval,
 	}
 	hcInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
 		AddFunc: control.enqueueHdfsCluster,
 		UpdateFunc: func(old, cur interface{}) {
+			if statusRefreshOnly(old.(*v1alpha1.HdfsCluster), cur.(*v1alpha1.HdfsCluster)) {
+				return
+			}
 			control.enqueueHdfsCluster(cur)
 		},
 		DeleteFunc: control.enqueueHdfsCluster,
@@ -93,11 +155,29 @@ func NewController(
 		},
 		DeleteFunc: control.deleteStatefulSet,
 	})
+	// the rack table of a rack aware cluster follows its pods and the labels of their nodes
+	podInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    control.addPod,
+		UpdateFunc: control.updatePod,
+	})
+	nodeInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: control.updateNode,
+	})
 	control.hcLister = hcInformer.Lister()
 	control.hcListerSynced = hcInformer.Informer().HasSynced
 
 	control.setLister = setInformer.Lister()
 	control.setListerSynced = setInformer.Informer().HasSynced
+
+	control.podListerSynced = podInformer.Informer().HasSynced
+	control.svcListerSynced = svcInformer.Informer().HasSynced
+	control.pvcListerSynced = pvcInformer.Informer().HasSynced
+	control.deployListerSynced = deployInformer.Informer().HasSynced
+	control.cmListerSynced = cmInformer.Informer().HasSynced
+	control.jobListerSynced = jobInformer.Informer().HasSynced
+	control.scListerSynced = scInformer.Informer().HasSynced
+	control.nodeListerSynced = nodeInformer.Informer().HasSynced
+	control.nsListerSynced = nsInformer.Informer().HasSynced
 	return control
 }
 
@@ -171,6 +251,71 @@ func (c *Controller) deleteStatefulSet(obj interface{}) {
 	c.enqueueHdfsCluster(tc)
 }
 
+func (c *Controller) addPod(obj interface{}) {
+	pod := obj.(*corev1.Pod)
+	hc := c.resolveRackAwareCluster(pod)
+	if hc == nil {
+		return
+	}
+	glog.V(4).Infof("Pod %s/%s of rack aware HdfsCluster %s changed", pod.Namespace, pod.Name, hc.Name)
+	c.enqueueHdfsCluster(hc)
+}
+
+// updatePod only cares about what the rack table is made of
+func (c *Controller) updatePod(old, cur interface{}) {
+	oldPod := old.(*corev1.Pod)
+	curPod := cur.(*corev1.Pod)
+	if oldPod.Spec.NodeName == curPod.Spec.NodeName && oldPod.Status.PodIP == curPod.Status.PodIP &&
+		(oldPod.DeletionTimestamp == nil) == (curPod.DeletionTimestamp == nil) {
+		return
+	}
+	c.addPod(curPod)
+}
+
+// resolveRackAwareCluster returns the rack aware cluster of a data node or
+// node manager pod
+func (c *Controller) resolveRackAwareCluster(pod *corev1.Pod) *v1alpha1.HdfsCluster {
+	ref := metav1.GetControllerOf(pod)
+	if ref == nil || ref.Kind != "StatefulSet" {
+		return nil
+	}
+	set, err := c.setLister.StatefulSets(pod.Namespace).Get(ref.Name)
+	if err != nil {
+		return nil
+	}
+	hc := c.resolveHdfsClusterFromSet(pod.Namespace, set)
+	if hc == nil || hc.Spec.RackAwareness == nil {
+		return nil
+	}
+	if set.Name != controller.DataNodeSetName(hc.Name) && set.Name != controller.NodeManagerName(hc.Name) {
+		return nil
+	}
+	return hc
+}
+
+// updateNode enqueues the rack aware clusters whose rack labels changed on the node
+func (c *Controller) updateNode(old, cur interface{}) {
+	oldNode := old.(*corev1.Node)
+	curNode := cur.(*corev1.Node)
+	if reflect.DeepEqual(oldNode.Labels, curNode.Labels) {
+		return
+	}
+	hcs, err := c.hcLister.List(labels.Everything())
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("list HdfsClusters error: %v", err))
+		return
+	}
+	for _, hc := range hcs {
+		for _, label := range manager.TopologyLabels(hc) {
+			if oldNode.Labels[label] != curNode.Labels[label] {
+				glog.V(4).Infof("rack label %s of node %s changed, HdfsCluster: %s/%s", label, curNode.Name, hc.Namespace, hc.Name)
+				c.enqueueHdfsCluster(hc)
+				break
+			}
+		}
+	}
+}
+
 func (c *Controller) enqueueHdfsCluster(obj interface{}) {
 	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
 	if err != nil {
@@ -206,17 +351,21 @@ func (c *Controller) resolveHdfsClusterFromSet(namespace string, set *apps.State
 func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
 	defer utilruntime.HandleCrash()
 	defer c.queue.ShutDown()
+	defer c.statusQueue.ShutDown()
 
 	glog.Info("Starting hdfscluster controller")
 	defer glog.Info("Shutting down hdfscluster controller")
 
-	if !cache.WaitForCacheSync(stopCh, c.hcListerSynced, c.setListerSynced) {
+	if !cache.WaitForCacheSync(stopCh, c.hcListerSynced, c.setListerSynced, c.podListerSynced, c.svcListerSynced,
+		c.pvcListerSynced, c.deployListerSynced, c.cmListerSynced, c.jobListerSynced, c.scListerSynced,
+		c.nodeListerSynced, c.nsListerSynced) {
 		return
 	}
 
 	for i := 0; i < workers; i++ {
 		go wait.Until(c.worker, time.Second, stopCh)
 	}
+	go wait.Until(c.statusWorker, time.Second, stopCh)
 
 	<-stopCh
 }
@@ -234,7 +383,12 @@ func (c *Controller) processNextWorkItem() bool {
 	}
 	defer c.queue.Done(key)
 	if err := c.sync(key.(string)); err != nil {
-		c.queue.AddRateLimited(key)
+		if controller.IsRequeueError(err) {
+			glog.Infof("HdfsCluster %v still need sync: %v", key, err)
+			c.queue.AddAfter(key, controller.RequeueAfter(err))
+		} else {
+			c.queue.AddRateLimited(key)
+		}
 	} else {
 		c.queue.Forget(key)
 	}
@@ -255,9 +409,133 @@ func (tcc *Controller) sync(key string) error {
 		return err
 	}
 
-	return tcc.syncHdfsCluster(tc.DeepCopy())
+	if err := tcc.syncHdfsCluster(tc.DeepCopy()); err != nil {
+		return err
+	}
+	if tc.DeletionTimestamp != nil {
+		return nil
+	}
+	tcc.statusQueue.AddAfter(key, tcc.statusRefreshInterval)
+	tcc.queue.AddAfter(key, nextSyncDelay(tc, time.Now().UTC()))
+	return nil
 }
 
 func (tcc *Controller) syncHdfsCluster(tc *v1alpha1.HdfsCluster) error {
 	return tcc.control.UpdateHdfsCluster(tc)
 }
+
+func (c *Controller) statusWorker() {
+	for c.processNextStatusItem() {
+		// revive:disable:empty-block
+	}
+}
+
+func (c *Controller) processNextStatusItem() bool {
+	key, quit := c.statusQueue.Get()
+	if quit {
+		return false
+	}
+	defer c.statusQueue.Done(key)
+	c.refreshStatus(key.(string))
+	return true
+}
+
+// refreshStatus reads status.hdfs of one cluster and schedules the next
+// refresh, the full reconcile only runs when the new status needs it
+func (c *Controller) refreshStatus(key string) {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return
+	}
+	hc, err := c.hcLister.HdfsClusters(ns).Get(name)
+	if errors.IsNotFound(err) {
+		return
+	}
+	if err == nil && hc.DeletionTimestamp != nil {
+		return
+	}
+	if err == nil {
+		cur := hc.DeepCopy()
+		if err = c.control.RefreshHdfsStatus(cur); err != nil {
+			glog.Errorf("refresh hdfs status of %s error, err=%+v", key, err)
+		} else if statusNeedsSync(hc, cur) {
+			c.queue.Add(key)
+		}
+	}
+	c.statusQueue.AddAfter(key, c.statusRefreshInterval)
+}
+
+// statusRefreshOnly is true for the informer resync and for updates that
+// only changed what the status refresh writes
+func statusRefreshOnly(old, cur *v1alpha1.HdfsCluster) bool {
+	if old.ResourceVersion == cur.ResourceVersion {
+		return true
+	}
+	old, cur = old.DeepCopy(), cur.DeepCopy()
+	for _, hc := range []*v1alpha1.HdfsCluster{old, cur} {
+		hc.ResourceVersion = ""
+		hc.Status.Hdfs = nil
+		conds := hc.Status.Conditions[:0]
+		for _, cond := range hc.Status.Conditions {
+			if cond.Type != v1alpha1.Degraded {
+				conds = append(conds, cond)
+			}
+		}
+		hc.Status.Conditions = conds
+	}
+	return reflect.DeepEqual(old, cur)
+}
+
+// statusNeedsSync is true when the refreshed status changes a decision of
+// the reconcile: the degraded condition, the balancer run after a scale out
+// or the used capacity the autoscaler works from
+func statusNeedsSync(old, cur *v1alpha1.HdfsCluster) bool {
+	if conditionStatus(old, v1alpha1.Degraded) != conditionStatus(cur, v1alpha1.Degraded) {
+		return true
+	}
+	if old.Status.Hdfs == nil || cur.Status.Hdfs == nil {
+		return old.Status.Hdfs != cur.Status.Hdfs
+	}
+	if old.Status.Hdfs.LiveDataNodes != cur.Status.Hdfs.LiveDataNodes {
+		return true
+	}
+	return cur.Spec.DataNode.Autoscaling != nil && usedPercent(old.Status.Hdfs) != usedPercent(cur.Status.Hdfs)
+}
+
+func conditionStatus(hc *v1alpha1.HdfsCluster, condType v1alpha1.HdfsClusterConditionType) corev1.ConditionStatus {
+	if cond := hc.GetCondition(condType); cond != nil {
+		return cond.Status
+	}
+	return corev1.ConditionUnknown
+}
+
+func usedPercent(st *v1alpha1.HdfsStatus) int64 {
+	if st.CapacityTotal <= 0 {
+		return 0
+	}
+	return st.CapacityUsed * 100 / st.CapacityTotal
+}
+
+// nextSyncDelay is the time until the next backup or balancer schedule,
+// at most fullSyncInterval
+func nextSyncDelay(hc *v1alpha1.HdfsCluster, now time.Time) time.Duration {
+	schedules := []string{}
+	if hc.Spec.Backup != nil {
+		schedules = append(schedules, hc.Spec.Backup.Schedule)
+	}
+	if b := hc.Spec.Balancer; b != nil && b.Enabled && b.Schedule != "" {
+		schedules = append(schedules, b.Schedule)
+	}
+	delay := fullSyncInterval
+	for _, spec := range schedules {
+		schedule, err := cron.Parse(spec)
+		if err != nil {
+			continue
+		}
+		next := schedule.Next(now)
+		if !next.IsZero() && next.Sub(now) < delay {
+			delay = next.Sub(now)
+		}
+	}
+	return delay
+}
@@ -0,0 +1,418 @@
+package hdfsdirectory
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"github.com/tommenx/hdfs-operator/pkg/manager"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	clusterNotReadyRetryDelay = 30 * time.Second
+	encryptionZoneCheckDelay  = 10 * time.Second
+	settingsCheckDelay        = 10 * time.Second
+)
+
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+var storagePolicies = []string{
+	hdfs.StoragePolicyHot,
+	hdfs.StoragePolicyWarm,
+	hdfs.StoragePolicyCold,
+	hdfs.StoragePolicyAllSSD,
+	hdfs.StoragePolicyOneSSD,
+	hdfs.StoragePolicyLazyPersist,
+}
+
+type ControlInterface interface {
+	UpdateHdfsDirectory(dir *v1alpha1.HdfsDirectory) error
+}
+
+type hdfsDirectoryControl struct {
+	dirControl controller.HdfsDirectoryControlInterface
+	jobControl controller.JobControlInterface
+	podControl controller.PodControlInterface
+	hcLister   listers.HdfsClusterLister
+	getClient  hdfs.ClientGetter
+}
+
+func NewHdfsDirectoryControl(
+	dirControl controller.HdfsDirectoryControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	hcLister listers.HdfsClusterLister,
+	getClient hdfs.ClientGetter,
+) ControlInterface {
+	return &hdfsDirectoryControl{
+		dirControl: dirControl,
+		jobControl: jobControl,
+		podControl: podControl,
+		hcLister:   hcLister,
+		getClient:  getClient,
+	}
+}
+
+func (c *hdfsDirectoryControl) UpdateHdfsDirectory(dir *v1alpha1.HdfsDirectory) error {
+	if dir.DeletionTimestamp != nil {
+		return nil
+	}
+	oldStatus := dir.Status.DeepCopy()
+	err := c.syncDirectory(dir)
+	if !reflect.DeepEqual(oldStatus, &dir.Status) {
+		if _, updateErr := c.dirControl.UpdateHdfsDirectoryStatus(dir); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return err
+}
+
+// syncDirectory converges the path to the spec and reads back its usage
+func (c *hdfsDirectoryControl) syncDirectory(dir *v1alpha1.HdfsDirectory) error {
+	if msg := validateSpec(&dir.Spec); msg != "" {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+		dir.Status.Message = msg
+		return nil
+	}
+	if dir.Status.Phase == "" {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryPending
+	}
+	hc, err := c.hcLister.HdfsClusters(dir.Namespace).Get(dir.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		dir.Status.Message = fmt.Sprintf("hdfs cluster %s does not exist", dir.Spec.Cluster)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", dir.Status.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if controller.KerberosEnabled(hc) {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+		dir.Status.Message = controller.WebHDFSUnsupportedMessage(hc, "HdfsDirectory")
+		return nil
+	}
+	if !hc.IsConditionTrue(v1alpha1.NameNodeAvailable) {
+		dir.Status.Message = fmt.Sprintf("name node of hdfs cluster %s is not available", hc.Name)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", dir.Status.Message)
+	}
+
+	cli := c.getClient(hc)
+	p := path.Clean("/" + dir.Spec.Path)
+	if err := c.convergeDirectory(cli, p, &dir.Spec); err != nil {
+		glog.Errorf("sync directory %s of %s/%s error, err=%+v", p, hc.Namespace, hc.Name, err)
+		if _, ok := err.(*notDirectoryError); ok {
+			dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+			dir.Status.Message = err.Error()
+			return nil
+		}
+		dir.Status.Message = err.Error()
+		return err
+	}
+	// the zone is created right after the directory while it is still empty
+	if done, err := c.convergeEncryptionZone(hc, dir, p); !done {
+		return err
+	}
+	if done, err := c.convergeSettings(hc, cli, dir, p); !done {
+		if err != nil && !controller.IsRequeueError(err) {
+			glog.Errorf("set quota and storage policy of %s of %s/%s error, err=%+v", p, hc.Namespace, hc.Name, err)
+			dir.Status.Message = err.Error()
+		}
+		return err
+	}
+	summary, err := cli.GetContentSummary(p)
+	if err != nil {
+		dir.Status.Message = err.Error()
+		return err
+	}
+	dir.Status.Phase = v1alpha1.HdfsDirectoryReady
+	dir.Status.ObservedGeneration = dir.Generation
+	dir.Status.Usage = &v1alpha1.DirectoryUsage{
+		FileCount:      summary.FileCount,
+		DirectoryCount: summary.DirectoryCount,
+		Length:         summary.Length,
+		SpaceConsumed:  summary.SpaceConsumed,
+		NamespaceQuota: summary.Quota,
+		SpaceQuota:     summary.SpaceQuota,
+	}
+	dir.Status.Message = ""
+	return nil
+}
+
+type notDirectoryError struct {
+	path string
+}
+
+func (e *notDirectoryError) Error() string {
+	return fmt.Sprintf("path %s is not a directory", e.path)
+}
+
+// convergeDirectory creates p and sets its owner and permission
+func (c *hdfsDirectoryControl) convergeDirectory(cli hdfs.Interface, p string, spec *v1alpha1.HdfsDirectorySpec) error {
+	fs, err := cli.GetFileStatus(p)
+	if hdfs.IsNotFound(err) {
+		if err := cli.Mkdirs(p, spec.Permission); err != nil {
+			return err
+		}
+		glog.Infof("directory %s is created", p)
+		fs, err = cli.GetFileStatus(p)
+	}
+	if err != nil {
+		return err
+	}
+	if fs.Type != hdfs.FileTypeDirectory {
+		return &notDirectoryError{path: p}
+	}
+	if (spec.Owner != "" && spec.Owner != fs.Owner) || (spec.Group != "" && spec.Group != fs.Group) {
+		if err := cli.SetOwner(p, spec.Owner, spec.Group); err != nil {
+			return err
+		}
+		glog.Infof("owner of %s is set to %s:%s", p, spec.Owner, spec.Group)
+	}
+	if spec.Permission != "" && !samePermission(spec.Permission, fs.Permission) {
+		if err := cli.SetPermission(p, spec.Permission); err != nil {
+			return err
+		}
+		glog.Infof("permission of %s is set to %s", p, spec.Permission)
+	}
+	return nil
+}
+
+// convergeSettings runs a job setting the quotas and the storage policy
+// given in spec, which webhdfs of hadoop 2.7 can not do. It returns false
+// until they are set or the job failed
+func (c *hdfsDirectoryControl) convergeSettings(hc *v1alpha1.HdfsCluster, cli hdfs.Interface, dir *v1alpha1.HdfsDirectory, p string) (bool, error) {
+	name := controller.DirectorySettingsJobName(dir.Name)
+	job, err := c.jobControl.GetJob(hc, name)
+	if err == nil {
+		finished, cond := controller.IsJobFinished(job)
+		if !finished {
+			return false, controller.RequeueErrorf(settingsCheckDelay, "settings job %s is running", name)
+		}
+		if cond != batchv1.JobComplete {
+			dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+			dir.Status.ObservedGeneration = dir.Generation
+			dir.Status.Message = c.jobFailure(hc, job)
+		}
+		if err := c.jobControl.DeleteJob(hc, job); err != nil && !errors.IsNotFound(err) {
+			return false, err
+		}
+		if cond != batchv1.JobComplete {
+			return false, nil
+		}
+		commands, err := settingsCommands(hc, cli, p, &dir.Spec)
+		if err != nil {
+			return false, err
+		}
+		if len(commands) != 0 {
+			// running the same commands again would not help either
+			dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+			dir.Status.ObservedGeneration = dir.Generation
+			dir.Status.Message = fmt.Sprintf("settings of %s are not applied after job %s: %s", p, name, strings.Join(commands, "; "))
+			return false, nil
+		}
+		return true, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+	if dir.Status.Phase == v1alpha1.HdfsDirectoryFailed && dir.Status.ObservedGeneration == dir.Generation {
+		// the settings job of this generation failed already
+		return false, nil
+	}
+	commands, err := settingsCommands(hc, cli, p, &dir.Spec)
+	if err != nil || len(commands) == 0 {
+		return err == nil, err
+	}
+	script := "set -e\n" + strings.Join(commands, "\n")
+	job = manager.NewHadoopJob(hc, name, manager.SummarizedScript(script, false))
+	job.OwnerReferences = []metav1.OwnerReference{controller.GetDirectoryOwnerRef(dir)}
+	if err := c.jobControl.CreateJob(hc, job); err != nil && !errors.IsAlreadyExists(err) {
+		return false, err
+	}
+	glog.Infof("setting quota and storage policy of %s of %s/%s", p, hc.Namespace, hc.Name)
+	dir.Status.Message = "setting quota and storage policy"
+	return false, controller.RequeueErrorf(settingsCheckDelay, "%s", dir.Status.Message)
+}
+
+// settingsCommands returns the dfsadmin and storagepolicies commands that
+// set the quotas and the storage policy of p to spec, the quotas missing
+// in spec are kept as they are
+func settingsCommands(hc *v1alpha1.HdfsCluster, cli hdfs.Interface, p string, spec *v1alpha1.HdfsDirectorySpec) ([]string, error) {
+	fs := "hdfs://" + controller.NameNodeRPCAddress(hc)
+	var commands []string
+	if spec.NamespaceQuota != nil || spec.SpaceQuota != nil {
+		summary, err := cli.GetContentSummary(p)
+		if err != nil {
+			return nil, err
+		}
+		if spec.NamespaceQuota != nil {
+			if q := normalizeQuota(*spec.NamespaceQuota); q < 0 && summary.Quota >= 0 {
+				commands = append(commands, fmt.Sprintf("hdfs dfsadmin -fs %s -clrQuota '%s'", fs, p))
+			} else if q >= 0 && q != summary.Quota {
+				commands = append(commands, fmt.Sprintf("hdfs dfsadmin -fs %s -setQuota %d '%s'", fs, q, p))
+			}
+		}
+		if spec.SpaceQuota != nil {
+			if q := normalizeQuota(spec.SpaceQuota.Value()); q < 0 && summary.SpaceQuota >= 0 {
+				commands = append(commands, fmt.Sprintf("hdfs dfsadmin -fs %s -clrSpaceQuota '%s'", fs, p))
+			} else if q >= 0 && q != summary.SpaceQuota {
+				commands = append(commands, fmt.Sprintf("hdfs dfsadmin -fs %s -setSpaceQuota %d '%s'", fs, q, p))
+			}
+		}
+	}
+	if spec.StoragePolicy != "" {
+		status, err := cli.GetFileStatus(p)
+		if err != nil {
+			return nil, err
+		}
+		want := strings.ToUpper(spec.StoragePolicy)
+		if status.StoragePolicy != hdfs.StoragePolicyIDs[want] {
+			commands = append(commands, fmt.Sprintf("hdfs storagepolicies -fs %s -setStoragePolicy -path '%s' -policy %s", fs, p, want))
+		}
+	}
+	return commands, nil
+}
+
+// jobFailure explains why job failed with the tail of its output
+func (c *hdfsDirectoryControl) jobFailure(hc *v1alpha1.HdfsCluster, job *batchv1.Job) string {
+	msg := manager.JobFailureMessage(job)
+	if summary := manager.JobSummary(c.podControl, hc, job); summary != "" {
+		msg = fmt.Sprintf("%s, %s", msg, summary)
+	}
+	return msg
+}
+
+// convergeEncryptionZone runs a job creating the key and the zone, which
+// webhdfs can not do. It returns false until the zone exists or failed
+func (c *hdfsDirectoryControl) convergeEncryptionZone(hc *v1alpha1.HdfsCluster, dir *v1alpha1.HdfsDirectory, p string) (bool, error) {
+	key := dir.Spec.EncryptionKey
+	if key == "" || dir.Status.EncryptionZone == key {
+		return true, nil
+	}
+	if dir.Status.Phase == v1alpha1.HdfsDirectoryFailed && dir.Status.ObservedGeneration == dir.Generation {
+		// the zone job of this generation failed already
+		return false, nil
+	}
+	if dir.Status.EncryptionZone != "" {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+		dir.Status.Message = fmt.Sprintf("path %s is already an encryption zone of key %s", p, dir.Status.EncryptionZone)
+		return false, nil
+	}
+	if hc.Spec.KMS == nil {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+		dir.Status.Message = fmt.Sprintf("hdfs cluster %s has no kms", hc.Name)
+		return false, nil
+	}
+	name := controller.EncryptionZoneJobName(dir.Name)
+	job, err := c.jobControl.GetJob(hc, name)
+	if errors.IsNotFound(err) {
+		job = manager.NewHadoopJob(hc, name, manager.SummarizedScript(encryptionZoneCommand(p, key), false))
+		job.OwnerReferences = []metav1.OwnerReference{controller.GetDirectoryOwnerRef(dir)}
+		if err := c.jobControl.CreateJob(hc, job); err != nil {
+			return false, err
+		}
+		glog.Infof("creating encryption zone %s with key %s of %s/%s", p, key, hc.Namespace, hc.Name)
+		dir.Status.Message = fmt.Sprintf("creating encryption zone with key %s", key)
+		return false, controller.RequeueErrorf(encryptionZoneCheckDelay, "%s", dir.Status.Message)
+	}
+	if err != nil {
+		return false, err
+	}
+	finished, cond := controller.IsJobFinished(job)
+	if !finished {
+		return false, controller.RequeueErrorf(encryptionZoneCheckDelay, "encryption zone job %s is running", name)
+	}
+	if cond == batchv1.JobComplete {
+		dir.Status.EncryptionZone = key
+	} else {
+		dir.Status.Phase = v1alpha1.HdfsDirectoryFailed
+		dir.Status.ObservedGeneration = dir.Generation
+		dir.Status.Message = c.jobFailure(hc, job)
+	}
+	// the result is kept in the status, a failed zone is retried on the next spec change
+	if err := c.jobControl.DeleteJob(hc, job); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return cond == batchv1.JobComplete, nil
+}
+
+// encryptionZoneCommand creates the key when it is missing and makes p a
+// zone of it, a zone of the same key already on p is left as is
+func encryptionZoneCommand(p string, key string) string {
+	return fmt.Sprintf(`set -e
+if ! hadoop key list | grep -qx '%[2]s'; then
+  hadoop key create '%[2]s'
+fi
+zone=$(hdfs crypto -listZones | awk -v p='%[1]s' '$1 == p {print $2}')
+if [ -z "$zone" ]; then
+  hdfs crypto -createZone -keyName '%[2]s' -path '%[1]s'
+elif [ "$zone" != '%[2]s' ]; then
+  echo "%[1]s is an encryption zone of key $zone"
+  exit 1
+fi`, p, key)
+}
+
+// normalizeQuota maps every negative quota to -1, which clears it
+func normalizeQuota(q int64) int64 {
+	if q < 0 {
+		return -1
+	}
+	return q
+}
+
+func samePermission(a, b string) bool {
+	x, err1 := strconv.ParseUint(a, 8, 32)
+	y, err2 := strconv.ParseUint(b, 8, 32)
+	return err1 == nil && err2 == nil && x == y
+}
+
+// validateSpec returns why the spec can never converge, empty when it is valid
+func validateSpec(spec *v1alpha1.HdfsDirectorySpec) string {
+	if spec.Cluster == "" {
+		return "cluster is required"
+	}
+	if spec.Path == "" {
+		return "path is required"
+	}
+	if strings.Contains(spec.Path, "/.snapshot") {
+		return fmt.Sprintf("path %s is inside a snapshot", spec.Path)
+	}
+	if spec.Permission != "" {
+		if perm, err := strconv.ParseUint(spec.Permission, 8, 32); err != nil || perm > 01777 {
+			return fmt.Sprintf("invalid permission %s, it must be octal like 750", spec.Permission)
+		}
+	}
+	if spec.EncryptionKey != "" {
+		if !keyNamePattern.MatchString(spec.EncryptionKey) {
+			return fmt.Sprintf("invalid encryption key name %s", spec.EncryptionKey)
+		}
+		if strings.ContainsAny(spec.Path, "' ") {
+			return fmt.Sprintf("path %s of an encryption zone can not contain quotes or spaces", spec.Path)
+		}
+	}
+	if (spec.NamespaceQuota != nil || spec.SpaceQuota != nil || spec.StoragePolicy != "") && strings.Contains(spec.Path, "'") {
+		return fmt.Sprintf("path %s with a quota or a storage policy can not contain quotes", spec.Path)
+	}
+	if spec.StoragePolicy != "" {
+		valid := false
+		for _, policy := range storagePolicies {
+			if strings.EqualFold(policy, spec.StoragePolicy) {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("invalid storage policy %s, it must be one of %s", spec.StoragePolicy, strings.Join(storagePolicies, ", "))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,173 @@
+package hdfsdirectory
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	informers "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+var directoryKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsDirectory")
+
+// Controller converges the hdfs directories of HdfsDirectory resources and
+// refreshes their usage
+type Controller struct {
+	cli             versioned.Interface
+	dirLister       listers.HdfsDirectoryLister
+	dirListerSynced cache.InformerSynced
+	hcListerSynced  cache.InformerSynced
+	jobListerSynced cache.InformerSynced
+	podListerSynced cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	control         ControlInterface
+	refreshInterval time.Duration
+}
+
+func NewController(
+	kubeCli kubernetes.Interface,
+	cli versioned.Interface,
+	informerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+	refreshInterval time.Duration,
+) *Controller {
+	dirInformer := informerFactory.Storage().V1alpha1().HdfsDirectories()
+	hcInformer := informerFactory.Storage().V1alpha1().HdfsClusters()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+
+	c := &Controller{
+		cli: cli,
+		control: NewHdfsDirectoryControl(
+			controller.NewRealHdfsDirectoryControl(cli, dirInformer.Lister()),
+			controller.NewRealJobControl(kubeCli, jobInformer.Lister()),
+			controller.NewRealPodControl(kubeCli, podInformer.Lister()),
+			hcInformer.Lister(),
+			controller.NewHdfsClient,
+		),
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hdfsdirectory"),
+		refreshInterval: refreshInterval,
+	}
+	dirInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueHdfsDirectory,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueHdfsDirectory(cur)
+		},
+		DeleteFunc: c.enqueueHdfsDirectory,
+	})
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueJobOwner,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueJobOwner(cur)
+		},
+		DeleteFunc: c.enqueueJobOwner,
+	})
+	c.dirLister = dirInformer.Lister()
+	c.dirListerSynced = dirInformer.Informer().HasSynced
+	c.hcListerSynced = hcInformer.Informer().HasSynced
+	c.jobListerSynced = jobInformer.Informer().HasSynced
+	c.podListerSynced = podInformer.Informer().HasSynced
+	return c
+}
+
+func (c *Controller) enqueueHdfsDirectory(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueJobOwner enqueues the HdfsDirectory whose encryption zone or settings job changed
+func (c *Controller) enqueueJobOwner(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if job, ok = tombstone.Obj.(*batchv1.Job); !ok {
+			return
+		}
+	}
+	if ref := metav1.GetControllerOf(job); ref != nil && ref.Kind == directoryKind.Kind {
+		c.queue.Add(job.Namespace + "/" + ref.Name)
+	}
+}
+
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Info("Starting hdfsdirectory controller")
+	defer glog.Info("Shutting down hdfsdirectory controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.dirListerSynced, c.hcListerSynced, c.jobListerSynced, c.podListerSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+		// revive:disable:empty-block
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.sync(key.(string)); err != nil {
+		if controller.IsRequeueError(err) {
+			glog.Infof("HdfsDirectory %v still need sync: %v", key, err)
+			c.queue.AddAfter(key, controller.RequeueAfter(err))
+		} else {
+			c.queue.AddRateLimited(key)
+		}
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+func (c *Controller) sync(key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	dir, err := c.dirLister.HdfsDirectories(ns).Get(name)
+	if errors.IsNotFound(err) {
+		glog.Infof("HdfsDirectory has been deleted %v", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := c.control.UpdateHdfsDirectory(dir.DeepCopy()); err != nil {
+		return err
+	}
+	// the usage changes without any event of the HdfsDirectory
+	c.queue.AddAfter(key, c.refreshInterval)
+	return nil
+}
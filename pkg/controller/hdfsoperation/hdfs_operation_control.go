@@ -0,0 +1,289 @@
+package hdfsoperation
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/manager"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	clusterNotReadyRetryDelay = 30 * time.Second
+	exclusiveRetryDelay       = 30 * time.Second
+	jobSyncRetryDelay         = 10 * time.Second
+	// jobMissingTimeout is how long a job may be missing from the lister
+	// after it was created before the operation fails
+	jobMissingTimeout = time.Minute
+)
+
+type ControlInterface interface {
+	UpdateHdfsOperation(op *v1alpha1.HdfsOperation) error
+}
+
+type hdfsOperationControl struct {
+	opControl  controller.HdfsOperationControlInterface
+	jobControl controller.JobControlInterface
+	podControl controller.PodControlInterface
+	hcLister   listers.HdfsClusterLister
+}
+
+func NewHdfsOperationControl(
+	opControl controller.HdfsOperationControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	hcLister listers.HdfsClusterLister,
+) ControlInterface {
+	return &hdfsOperationControl{
+		opControl:  opControl,
+		jobControl: jobControl,
+		podControl: podControl,
+		hcLister:   hcLister,
+	}
+}
+
+func (c *hdfsOperationControl) UpdateHdfsOperation(op *v1alpha1.HdfsOperation) error {
+	if op.DeletionTimestamp != nil {
+		// the job is owned by the operation and deleted by the garbage collector
+		return nil
+	}
+	oldStatus := op.Status.DeepCopy()
+	var err error
+	switch op.Status.Phase {
+	case v1alpha1.HdfsOperationSucceeded, v1alpha1.HdfsOperationFailed:
+		err = c.releaseLock(op)
+	case v1alpha1.HdfsOperationRunning:
+		err = c.checkJob(op)
+	default:
+		err = c.startJob(op)
+	}
+	if !reflect.DeepEqual(oldStatus, &op.Status) {
+		if _, updateErr := c.opControl.UpdateHdfsOperationStatus(op); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return err
+}
+
+// startJob creates the job of op, an exclusive operation waits in Pending
+// while the exclusive job of another operation exists
+func (c *hdfsOperationControl) startJob(op *v1alpha1.HdfsOperation) error {
+	if msg := validateSpec(&op.Spec); msg != "" {
+		c.finished(op, v1alpha1.HdfsOperationFailed, msg)
+		return nil
+	}
+	op.Status.Phase = v1alpha1.HdfsOperationPending
+	hc, err := c.hcLister.HdfsClusters(op.Namespace).Get(op.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		op.Status.Message = fmt.Sprintf("hdfs cluster %s does not exist", op.Spec.Cluster)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", op.Status.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if !hc.IsConditionTrue(v1alpha1.NameNodeAvailable) {
+		op.Status.Message = fmt.Sprintf("name node of hdfs cluster %s is not available", hc.Name)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", op.Status.Message)
+	}
+
+	exclusive := IsExclusive(op.Spec.Type)
+	job := newOperationJob(hc, op, exclusive)
+	err = c.jobControl.CreateJob(hc, job)
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := c.jobControl.GetJob(hc, job.Name)
+		if getErr != nil {
+			return controller.RequeueErrorf(jobSyncRetryDelay, "job %s exists but is not synced yet", job.Name)
+		}
+		// the job is ours when the status update after creating it failed
+		if !metav1.IsControlledBy(existing, op) {
+			if exclusive {
+				op.Status.Message = fmt.Sprintf("waiting for the exclusive operation %s to finish", ownerName(existing))
+				return controller.RequeueErrorf(exclusiveRetryDelay, "%s", op.Status.Message)
+			}
+			c.finished(op, v1alpha1.HdfsOperationFailed, fmt.Sprintf("job %s already exists", job.Name))
+			return nil
+		}
+	} else if err != nil {
+		op.Status.Message = fmt.Sprintf("create job %s error: %v", job.Name, err)
+		return err
+	}
+	now := metav1.Now()
+	op.Status.Phase = v1alpha1.HdfsOperationRunning
+	op.Status.JobName = job.Name
+	op.Status.Exclusive = exclusive
+	op.Status.StartTime = &now
+	op.Status.Message = ""
+	glog.Infof("operation %s/%s of %s is started by job %s", op.Namespace, op.Name, hc.Name, job.Name)
+	return nil
+}
+
+// checkJob records the result and the output summary of a finished job
+func (c *hdfsOperationControl) checkJob(op *v1alpha1.HdfsOperation) error {
+	hc, err := c.hcLister.HdfsClusters(op.Namespace).Get(op.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		c.finished(op, v1alpha1.HdfsOperationFailed, fmt.Sprintf("hdfs cluster %s is deleted", op.Spec.Cluster))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job, err := c.jobControl.GetJob(hc, op.Status.JobName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if errors.IsNotFound(err) || !metav1.IsControlledBy(job, op) {
+		if op.Status.StartTime != nil && time.Since(op.Status.StartTime.Time) < jobMissingTimeout {
+			return controller.RequeueErrorf(jobSyncRetryDelay, "job %s is not synced yet", op.Status.JobName)
+		}
+		c.finished(op, v1alpha1.HdfsOperationFailed, fmt.Sprintf("job %s is deleted", op.Status.JobName))
+		return nil
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return nil
+	}
+	op.Status.Summary = manager.JobSummary(c.podControl, hc, job)
+	if result == batchv1.JobFailed {
+		c.finished(op, v1alpha1.HdfsOperationFailed, manager.JobFailureMessage(job))
+	} else {
+		c.finished(op, v1alpha1.HdfsOperationSucceeded, "")
+	}
+	glog.Infof("operation %s/%s of %s is %s", op.Namespace, op.Name, hc.Name, op.Status.Phase)
+	return nil
+}
+
+// releaseLock deletes the exclusive job once the result is recorded, so
+// the next exclusive operation of the cluster can start
+func (c *hdfsOperationControl) releaseLock(op *v1alpha1.HdfsOperation) error {
+	if !op.Status.Exclusive || op.Status.JobName == "" {
+		return nil
+	}
+	hc, err := c.hcLister.HdfsClusters(op.Namespace).Get(op.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	job, err := c.jobControl.GetJob(hc, op.Status.JobName)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(job, op) || job.DeletionTimestamp != nil {
+		return nil
+	}
+	return c.jobControl.DeleteJob(hc, job)
+}
+
+func (c *hdfsOperationControl) finished(op *v1alpha1.HdfsOperation, phase v1alpha1.HdfsOperationPhase, msg string) {
+	now := metav1.Now()
+	op.Status.Phase = phase
+	op.Status.CompletionTime = &now
+	op.Status.Message = msg
+}
+
+func ownerName(job *batchv1.Job) string {
+	if ref := metav1.GetControllerOf(job); ref != nil {
+		return fmt.Sprintf("%s %s", ref.Kind, ref.Name)
+	}
+	return "job " + job.Name
+}
+
+// IsExclusive returns whether t changes the cluster wide state, at most one
+// exclusive operation runs per cluster
+func IsExclusive(t v1alpha1.HdfsOperationType) bool {
+	switch t {
+	case v1alpha1.OperationBalancer, v1alpha1.OperationSaveNamespace, v1alpha1.OperationSafeMode:
+		return true
+	}
+	return false
+}
+
+func newOperationJob(hc *v1alpha1.HdfsCluster, op *v1alpha1.HdfsOperation, exclusive bool) *batchv1.Job {
+	name := controller.OperationJobName(op.Name)
+	if exclusive {
+		name = controller.ExclusiveOperationJobName(hc.Name)
+	}
+	// the cluster summary is at the top of the report
+	script := manager.SummarizedScript(operationCommand(&op.Spec), op.Spec.Type == v1alpha1.OperationReport)
+	job := manager.NewHadoopJob(hc, name, script)
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+	job.OwnerReferences = []metav1.OwnerReference{controller.GetOperationOwnerRef(op)}
+	job.Labels["hdfs-operation"] = op.Name
+	job.Spec.Template.Labels["hdfs-operation"] = op.Name
+	return job
+}
+
+func operationCommand(spec *v1alpha1.HdfsOperationSpec) string {
+	switch spec.Type {
+	case v1alpha1.OperationFsck:
+		return fmt.Sprintf("hdfs fsck '%s'", fsckPath(spec))
+	case v1alpha1.OperationBalancer:
+		return fmt.Sprintf("hdfs balancer -threshold %d", balancerThreshold(spec))
+	case v1alpha1.OperationRefreshNodes:
+		return "hdfs dfsadmin -refreshNodes"
+	case v1alpha1.OperationSaveNamespace:
+		// saveNamespace needs safe mode, which is left again unless it was on before
+		return `state=$(hdfs dfsadmin -safemode get)
+hdfs dfsadmin -safemode enter && hdfs dfsadmin -saveNamespace
+rc=$?
+case "$state" in *OFF*) hdfs dfsadmin -safemode leave ;; esac
+exit $rc`
+	case v1alpha1.OperationSafeMode:
+		return fmt.Sprintf("hdfs dfsadmin -safemode %s", spec.SafeMode.Action)
+	case v1alpha1.OperationReport:
+		return "hdfs dfsadmin -report"
+	}
+	return ""
+}
+
+func fsckPath(spec *v1alpha1.HdfsOperationSpec) string {
+	if spec.Fsck == nil || spec.Fsck.Path == "" {
+		return "/"
+	}
+	return path.Clean("/" + spec.Fsck.Path)
+}
+
+func balancerThreshold(spec *v1alpha1.HdfsOperationSpec) int32 {
+	if spec.Balancer == nil || spec.Balancer.Threshold == 0 {
+		return manager.DefaultBalancerThreshold
+	}
+	return spec.Balancer.Threshold
+}
+
+// validateSpec returns why the operation can never run, empty when it is valid
+func validateSpec(spec *v1alpha1.HdfsOperationSpec) string {
+	if spec.Cluster == "" {
+		return "cluster is required"
+	}
+	switch spec.Type {
+	case v1alpha1.OperationFsck:
+		if spec.Fsck != nil && strings.Contains(spec.Fsck.Path, "'") {
+			return fmt.Sprintf("invalid fsck path %s", spec.Fsck.Path)
+		}
+	case v1alpha1.OperationBalancer:
+		if t := balancerThreshold(spec); t < 1 || t > 100 {
+			return fmt.Sprintf("invalid balancer threshold %d, it must be between 1 and 100", t)
+		}
+	case v1alpha1.OperationSafeMode:
+		if spec.SafeMode == nil || (spec.SafeMode.Action != v1alpha1.SafeModeEnter && spec.SafeMode.Action != v1alpha1.SafeModeLeave) {
+			return "safe_mode.action must be enter or leave"
+		}
+	case v1alpha1.OperationRefreshNodes, v1alpha1.OperationSaveNamespace, v1alpha1.OperationReport:
+	default:
+		return fmt.Sprintf("unknown operation type %q", spec.Type)
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package hdfsoperation
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	informers "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+var operationKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsOperation")
+
+// Controller runs the job of each HdfsOperation and records its result
+type Controller struct {
+	cli             versioned.Interface
+	opLister        listers.HdfsOperationLister
+	opListerSynced  cache.InformerSynced
+	hcListerSynced  cache.InformerSynced
+	jobListerSynced cache.InformerSynced
+	podListerSynced cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	control         ControlInterface
+}
+
+func NewController(
+	kubeCli kubernetes.Interface,
+	cli versioned.Interface,
+	informerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+) *Controller {
+	opInformer := informerFactory.Storage().V1alpha1().HdfsOperations()
+	hcInformer := informerFactory.Storage().V1alpha1().HdfsClusters()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+
+	c := &Controller{
+		cli: cli,
+		control: NewHdfsOperationControl(
+			controller.NewRealHdfsOperationControl(cli, opInformer.Lister()),
+			controller.NewRealJobControl(kubeCli, jobInformer.Lister()),
+			controller.NewRealPodControl(kubeCli, podInformer.Lister()),
+			hcInformer.Lister(),
+		),
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hdfsoperation"),
+	}
+	opInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueHdfsOperation,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueHdfsOperation(cur)
+		},
+		DeleteFunc: c.enqueueHdfsOperation,
+	})
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueJobOwner,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueJobOwner(cur)
+		},
+		DeleteFunc: c.enqueueJobOwner,
+	})
+	c.opLister = opInformer.Lister()
+	c.opListerSynced = opInformer.Informer().HasSynced
+	c.hcListerSynced = hcInformer.Informer().HasSynced
+	c.jobListerSynced = jobInformer.Informer().HasSynced
+	c.podListerSynced = podInformer.Informer().HasSynced
+	return c
+}
+
+func (c *Controller) enqueueHdfsOperation(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueJobOwner enqueues the HdfsOperation controlling the job, pending
+// exclusive operations retry on their own until the lock job is gone
+func (c *Controller) enqueueJobOwner(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if job, ok = tombstone.Obj.(*batchv1.Job); !ok {
+			return
+		}
+	}
+	if ref := metav1.GetControllerOf(job); ref != nil && ref.Kind == operationKind.Kind {
+		c.queue.Add(job.Namespace + "/" + ref.Name)
+	}
+}
+
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Info("Starting hdfsoperation controller")
+	defer glog.Info("Shutting down hdfsoperation controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.opListerSynced, c.hcListerSynced, c.jobListerSynced, c.podListerSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+		// revive:disable:empty-block
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.sync(key.(string)); err != nil {
+		if controller.IsRequeueError(err) {
+			glog.Infof("HdfsOperation %v still need sync: %v", key, err)
+			c.queue.AddAfter(key, controller.RequeueAfter(err))
+		} else {
+			c.queue.AddRateLimited(key)
+		}
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+func (c *Controller) sync(key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	op, err := c.opLister.HdfsOperations(ns).Get(name)
+	if errors.IsNotFound(err) {
+		glog.Infof("HdfsOperation has been deleted %v", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return c.control.UpdateHdfsOperation(op.DeepCopy())
+}
@@ -0,0 +1,379 @@
+package hdfsreplication
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/cron"
+	"github.com/tommenx/hdfs-operator/pkg/manager"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	clusterNotReadyRetryDelay = 30 * time.Second
+	jobSyncRetryDelay         = 10 * time.Second
+	// jobMissingTimeout is how long a job may be missing from the lister
+	// after it was created before the run is given up
+	jobMissingTimeout  = time.Minute
+	defaultMaps        = 20
+	snapshotNameFormat = "20060102-150405"
+	// snapshotAnnotation is the snapshot a SnapshotDiff job leaves on the
+	// source and the target
+	snapshotAnnotation = "storage.io/replication-snapshot"
+)
+
+var bytesCopiedRegexp = regexp.MustCompile(`bytes_copied=(\d+)`)
+
+type ControlInterface interface {
+	// UpdateHdfsReplication returns how long until the next run is due,
+	// zero when nothing is scheduled
+	UpdateHdfsReplication(rep *v1alpha1.HdfsReplication) (time.Duration, error)
+}
+
+type hdfsReplicationControl struct {
+	repControl controller.HdfsReplicationControlInterface
+	jobControl controller.JobControlInterface
+	podControl controller.PodControlInterface
+	hcLister   listers.HdfsClusterLister
+}
+
+func NewHdfsReplicationControl(
+	repControl controller.HdfsReplicationControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	hcLister listers.HdfsClusterLister,
+) ControlInterface {
+	return &hdfsReplicationControl{
+		repControl: repControl,
+		jobControl: jobControl,
+		podControl: podControl,
+		hcLister:   hcLister,
+	}
+}
+
+func (c *hdfsReplicationControl) UpdateHdfsReplication(rep *v1alpha1.HdfsReplication) (time.Duration, error) {
+	if rep.DeletionTimestamp != nil {
+		// the job is owned by the replication and deleted by the garbage collector
+		return 0, nil
+	}
+	oldStatus := rep.Status.DeepCopy()
+	delay, err := c.updateHdfsReplication(rep)
+	if !reflect.DeepEqual(oldStatus, &rep.Status) {
+		if _, updateErr := c.repControl.UpdateHdfsReplicationStatus(rep); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return delay, err
+}
+
+func (c *hdfsReplicationControl) updateHdfsReplication(rep *v1alpha1.HdfsReplication) (time.Duration, error) {
+	if msg := validateSpec(rep); msg != "" {
+		rep.Status.Message = msg
+		return 0, nil
+	}
+	schedule, err := cron.Parse(rep.Spec.Schedule)
+	if err != nil {
+		rep.Status.Message = fmt.Sprintf("invalid schedule: %v", err)
+		return 0, nil
+	}
+	target, err := c.hcLister.HdfsClusters(rep.Namespace).Get(rep.Spec.Target.Cluster)
+	if errors.IsNotFound(err) {
+		rep.Status.Message = fmt.Sprintf("target hdfs cluster %s does not exist", rep.Spec.Target.Cluster)
+		return 0, controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", rep.Status.Message)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if rep.Status.JobName != "" {
+		if err := c.checkJob(rep, target); err != nil {
+			return 0, err
+		}
+	}
+
+	now := time.Now().UTC()
+	if !rep.Spec.Suspend {
+		last := rep.CreationTimestamp.Time
+		if rep.Status.LastScheduleTime != nil {
+			last = rep.Status.LastScheduleTime.Time
+		}
+		if scheduled, ok := schedule.Missed(last, now); ok {
+			if rep.Status.JobName != "" {
+				// runs never overlap, the next one copies what this one missed
+				rep.Status.Message = fmt.Sprintf("skipped the run scheduled at %s, job %s is still running",
+					scheduled.Format(time.RFC3339), rep.Status.JobName)
+				t := metav1.NewTime(scheduled)
+				rep.Status.LastScheduleTime = &t
+			} else if err := c.startJob(rep, target, scheduled); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	if rep.Spec.Suspend {
+		return 0, nil
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return 0, nil
+	}
+	return next.Sub(now), nil
+}
+
+// startJob creates the distcp job of the run scheduled at scheduled, the
+// run is retried while a cluster is not available
+func (c *hdfsReplicationControl) startJob(rep *v1alpha1.HdfsReplication, target *v1alpha1.HdfsCluster, scheduled time.Time) error {
+	source, err := c.hcLister.HdfsClusters(sourceNamespace(rep)).Get(rep.Spec.Source.Cluster)
+	if errors.IsNotFound(err) {
+		rep.Status.Message = fmt.Sprintf("source hdfs cluster %s/%s does not exist", sourceNamespace(rep), rep.Spec.Source.Cluster)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", rep.Status.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if !replicationAllowed(source, rep) {
+		rep.Status.Message = fmt.Sprintf("source hdfs cluster %s/%s does not allow replications from namespace %s, see its replication_namespaces",
+			source.Namespace, source.Name, rep.Namespace)
+		return nil
+	}
+	for _, hc := range []*v1alpha1.HdfsCluster{source, target} {
+		if !hc.IsConditionTrue(v1alpha1.NameNodeAvailable) {
+			rep.Status.Message = fmt.Sprintf("name node of hdfs cluster %s/%s is not available", hc.Namespace, hc.Name)
+			return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", rep.Status.Message)
+		}
+	}
+	if realm(source) != realm(target) {
+		rep.Status.Message = fmt.Sprintf("source kerberos realm %q differs from target kerberos realm %q", realm(source), realm(target))
+		return nil
+	}
+
+	job := newReplicationJob(rep, source, target, scheduled)
+	err = c.jobControl.CreateJob(target, job)
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := c.jobControl.GetJob(target, job.Name)
+		if getErr != nil {
+			return controller.RequeueErrorf(jobSyncRetryDelay, "job %s exists but is not synced yet", job.Name)
+		}
+		// the job is ours when the status update after creating it failed
+		if !metav1.IsControlledBy(existing, rep) {
+			rep.Status.Message = fmt.Sprintf("job %s already exists", job.Name)
+			return nil
+		}
+	} else if err != nil {
+		rep.Status.Message = fmt.Sprintf("create job %s error: %v", job.Name, err)
+		return err
+	}
+	now := metav1.Now()
+	t := metav1.NewTime(scheduled)
+	rep.Status.JobName = job.Name
+	rep.Status.StartTime = &now
+	rep.Status.LastScheduleTime = &t
+	rep.Status.Message = ""
+	glog.Infof("replication %s/%s from %s/%s to %s is started by job %s", rep.Namespace, rep.Name, source.Namespace, source.Name, target.Name, job.Name)
+	return nil
+}
+
+// checkJob records the result of a finished job and deletes it, so the
+// next run can create its job
+func (c *hdfsReplicationControl) checkJob(rep *v1alpha1.HdfsReplication, target *v1alpha1.HdfsCluster) error {
+	job, err := c.jobControl.GetJob(target, rep.Status.JobName)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if errors.IsNotFound(err) || !metav1.IsControlledBy(job, rep) {
+		if rep.Status.StartTime != nil && time.Since(rep.Status.StartTime.Time) < jobMissingTimeout {
+			return controller.RequeueErrorf(jobSyncRetryDelay, "job %s is not synced yet", rep.Status.JobName)
+		}
+		rep.Status.Message = fmt.Sprintf("job %s is deleted", rep.Status.JobName)
+		rep.Status.JobName = ""
+		return nil
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return nil
+	}
+	rep.Status.Summary = manager.JobSummary(c.podControl, target, job)
+	if result == batchv1.JobComplete {
+		rep.Status.LastSuccessfulSyncTime = rep.Status.StartTime
+		rep.Status.BytesCopied = bytesCopied(rep.Status.Summary)
+		if snapshot := job.Annotations[snapshotAnnotation]; snapshot != "" {
+			rep.Status.LastSnapshot = snapshot
+		}
+		rep.Status.Message = ""
+	} else {
+		rep.Status.Message = manager.JobFailureMessage(job)
+	}
+	glog.Infof("replication %s/%s job %s is %s", rep.Namespace, rep.Name, job.Name, result)
+	if job.DeletionTimestamp == nil {
+		if err := c.jobControl.DeleteJob(target, job); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	rep.Status.JobName = ""
+	return nil
+}
+
+func newReplicationJob(rep *v1alpha1.HdfsReplication, source *v1alpha1.HdfsCluster, target *v1alpha1.HdfsCluster, scheduled time.Time) *batchv1.Job {
+	snapshot := ""
+	if rep.Spec.Mode == v1alpha1.ReplicationSnapshotDiff {
+		snapshot = "distcp-" + scheduled.Format(snapshotNameFormat)
+	}
+	job := manager.NewHadoopJob(target, controller.ReplicationJobName(rep.Name), manager.SummarizedScript(replicationScript(rep, source, target, snapshot), false))
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+	job.OwnerReferences = []metav1.OwnerReference{controller.GetReplicationOwnerRef(rep)}
+	job.Labels["hdfs-replication"] = rep.Name
+	job.Spec.Template.Labels["hdfs-replication"] = rep.Name
+	if snapshot != "" {
+		job.Annotations = map[string]string{snapshotAnnotation: snapshot}
+	}
+	if realm(target) != "" {
+		// the name node principal of the source differs from the one of the target
+		c := &job.Spec.Template.Spec.Containers[0]
+		c.Env = append(c.Env, corev1.EnvVar{Name: "HDFS_CONF_dfs_namenode_kerberos_principal_pattern", Value: "*"})
+	}
+	return job
+}
+
+// replicationScript copies the source into the target and prints the
+// bytes copied last. A SnapshotDiff run snapshots the source, copies the
+// changes since the last snapshot and snapshots the target the same,
+// distcp falls back to comparing every file when the diff can not be applied
+func replicationScript(rep *v1alpha1.HdfsReplication, source *v1alpha1.HdfsCluster, target *v1alpha1.HdfsCluster, snapshot string) string {
+	spec := &rep.Spec
+	srcFS := "hdfs://" + controller.NameNodeRPCAddress(source)
+	tgtFS := "hdfs://" + controller.NameNodeRPCAddress(target)
+	var b strings.Builder
+	fmt.Fprintf(&b, "src='%s%s'\ntgt='%s%s'\n", srcFS, spec.Source.Path, tgtFS, spec.Target.Path)
+	fmt.Fprintf(&b, `distcp() {
+  hadoop distcp -Dmapreduce.framework.name=local -m %d%s "$@" > /tmp/distcp.log 2>&1
+  rc=$?
+  tail -n 20 /tmp/distcp.log
+  return $rc
+}
+hdfs dfs -mkdir -p "$tgt" || exit 1
+`, maps(spec), bandwidthOption(spec))
+	if snapshot == "" {
+		option := ""
+		if spec.DeleteMissing {
+			option = " -delete"
+		}
+		fmt.Fprintf(&b, "distcp -update%s \"$src\" \"$tgt\" || exit 1\n", option)
+	} else {
+		old := rep.Status.LastSnapshot
+		fmt.Fprintf(&b, "hdfs dfsadmin -fs %s -allowSnapshot '%s' || exit 1\n", srcFS, spec.Source.Path)
+		fmt.Fprintf(&b, "hdfs dfsadmin -fs %s -allowSnapshot '%s' || exit 1\n", tgtFS, spec.Target.Path)
+		fmt.Fprintf(&b, "hdfs dfs -createSnapshot \"$src\" %s || exit 1\n", snapshot)
+		cmd := fmt.Sprintf("distcp -update \"$src/.snapshot/%s\" \"$tgt\"", snapshot)
+		if old != "" {
+			cmd = fmt.Sprintf("distcp -update -diff %s %s \"$src\" \"$tgt\"", old, snapshot)
+		}
+		fmt.Fprintf(&b, "%s && hdfs dfs -createSnapshot \"$tgt\" %s || {\n  hdfs dfs -deleteSnapshot \"$src\" %s\n  exit 1\n}\n", cmd, snapshot, snapshot)
+		if old != "" {
+			fmt.Fprintf(&b, "hdfs dfs -deleteSnapshot \"$src\" %s\nhdfs dfs -deleteSnapshot \"$tgt\" %s\n", old, old)
+		}
+	}
+	b.WriteString(`echo "bytes_copied=$(grep -o 'Bytes Copied=[0-9]*' /tmp/distcp.log | tail -n 1 | cut -d= -f2)"`)
+	return b.String()
+}
+
+func bytesCopied(summary string) int64 {
+	m := bytesCopiedRegexp.FindAllStringSubmatch(summary, -1)
+	if len(m) == 0 {
+		return 0
+	}
+	n, _ := strconv.ParseInt(m[len(m)-1][1], 10, 64)
+	return n
+}
+
+func maps(spec *v1alpha1.HdfsReplicationSpec) int32 {
+	if spec.Maps > 0 {
+		return spec.Maps
+	}
+	return defaultMaps
+}
+
+// bandwidthOption is the -bandwidth of distcp, in MB per second per map
+func bandwidthOption(spec *v1alpha1.HdfsReplicationSpec) string {
+	if spec.Bandwidth == nil {
+		return ""
+	}
+	mb := (spec.Bandwidth.Value() + 1<<20 - 1) >> 20
+	return fmt.Sprintf(" -bandwidth %d", mb)
+}
+
+func sourceNamespace(rep *v1alpha1.HdfsReplication) string {
+	if rep.Spec.Source.Namespace != "" {
+		return rep.Spec.Source.Namespace
+	}
+	return rep.Namespace
+}
+
+// replicationAllowed is true when the source cluster lets rep read it, a
+// replication may only copy clusters of other namespaces that opted in
+func replicationAllowed(source *v1alpha1.HdfsCluster, rep *v1alpha1.HdfsReplication) bool {
+	if source.Namespace == rep.Namespace {
+		return true
+	}
+	for _, ns := range source.Spec.ReplicationNamespaces {
+		if ns == rep.Namespace {
+			return true
+		}
+	}
+	return false
+}
+
+func realm(hc *v1alpha1.HdfsCluster) string {
+	if hc.Spec.Security == nil || hc.Spec.Security.Kerberos == nil {
+		return ""
+	}
+	return hc.Spec.Security.Kerberos.Realm
+}
+
+// validateSpec returns why the replication can never run, empty when it is valid
+func validateSpec(rep *v1alpha1.HdfsReplication) string {
+	spec := &rep.Spec
+	for _, e := range []struct {
+		name     string
+		endpoint v1alpha1.ReplicationEndpoint
+	}{{"source", spec.Source}, {"target", spec.Target}} {
+		if e.endpoint.Cluster == "" {
+			return fmt.Sprintf("%s cluster is required", e.name)
+		}
+		p := e.endpoint.Path
+		if !strings.HasPrefix(p, "/") || path.Clean(p) != p || strings.ContainsAny(p, "'\"$`\\") {
+			return fmt.Sprintf("invalid %s path %q, it must be a clean absolute path", e.name, p)
+		}
+	}
+	if sourceNamespace(rep) == rep.Namespace && spec.Source.Cluster == spec.Target.Cluster &&
+		(isWithin(spec.Source.Path, spec.Target.Path) || isWithin(spec.Target.Path, spec.Source.Path)) {
+		return "source and target path overlap in the same cluster"
+	}
+	switch spec.Mode {
+	case "", v1alpha1.ReplicationFull, v1alpha1.ReplicationSnapshotDiff:
+	default:
+		return fmt.Sprintf("unknown replication mode %q", spec.Mode)
+	}
+	if spec.Maps < 0 {
+		return fmt.Sprintf("invalid maps %d", spec.Maps)
+	}
+	if spec.Bandwidth != nil && spec.Bandwidth.Sign() <= 0 {
+		return fmt.Sprintf("invalid bandwidth %s", spec.Bandwidth.String())
+	}
+	return ""
+}
+
+// isWithin returns whether p is dir or below it
+func isWithin(p string, dir string) bool {
+	return p == dir || strings.HasPrefix(p, strings.TrimSuffix(dir, "/")+"/")
+}
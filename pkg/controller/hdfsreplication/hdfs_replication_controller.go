@@ -0,0 +1,171 @@
+package hdfsreplication
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	informers "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+var replicationKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsReplication")
+
+// Controller runs the distcp jobs of HdfsReplication resources on their schedule
+type Controller struct {
+	cli             versioned.Interface
+	repLister       listers.HdfsReplicationLister
+	repListerSynced cache.InformerSynced
+	hcListerSynced  cache.InformerSynced
+	jobListerSynced cache.InformerSynced
+	podListerSynced cache.InformerSynced
+	queue           workqueue.RateLimitingInterface
+	control         ControlInterface
+}
+
+func NewController(
+	kubeCli kubernetes.Interface,
+	cli versioned.Interface,
+	informerFactory informers.SharedInformerFactory,
+	kubeInformerFactory kubeinformers.SharedInformerFactory,
+) *Controller {
+	repInformer := informerFactory.Storage().V1alpha1().HdfsReplications()
+	hcInformer := informerFactory.Storage().V1alpha1().HdfsClusters()
+	jobInformer := kubeInformerFactory.Batch().V1().Jobs()
+	podInformer := kubeInformerFactory.Core().V1().Pods()
+
+	c := &Controller{
+		cli: cli,
+		control: NewHdfsReplicationControl(
+			controller.NewRealHdfsReplicationControl(cli, repInformer.Lister()),
+			controller.NewRealJobControl(kubeCli, jobInformer.Lister()),
+			controller.NewRealPodControl(kubeCli, podInformer.Lister()),
+			hcInformer.Lister(),
+		),
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hdfsreplication"),
+	}
+	repInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueHdfsReplication,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueHdfsReplication(cur)
+		},
+		DeleteFunc: c.enqueueHdfsReplication,
+	})
+	jobInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueJobOwner(cur)
+		},
+		DeleteFunc: c.enqueueJobOwner,
+	})
+	c.repLister = repInformer.Lister()
+	c.repListerSynced = repInformer.Informer().HasSynced
+	c.hcListerSynced = hcInformer.Informer().HasSynced
+	c.jobListerSynced = jobInformer.Informer().HasSynced
+	c.podListerSynced = podInformer.Informer().HasSynced
+	return c
+}
+
+func (c *Controller) enqueueHdfsReplication(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+// enqueueJobOwner enqueues the HdfsReplication controlling the job, so a
+// finished run is recorded right away
+func (c *Controller) enqueueJobOwner(obj interface{}) {
+	job, ok := obj.(*batchv1.Job)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		if job, ok = tombstone.Obj.(*batchv1.Job); !ok {
+			return
+		}
+	}
+	if ref := metav1.GetControllerOf(job); ref != nil && ref.Kind == replicationKind.Kind {
+		c.queue.Add(job.Namespace + "/" + ref.Name)
+	}
+}
+
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Info("Starting hdfsreplication controller")
+	defer glog.Info("Shutting down hdfsreplication controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.repListerSynced, c.hcListerSynced, c.jobListerSynced, c.podListerSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+		// revive:disable:empty-block
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.sync(key.(string)); err != nil {
+		if controller.IsRequeueError(err) {
+			glog.Infof("HdfsReplication %v still need sync: %v", key, err)
+			c.queue.AddAfter(key, controller.RequeueAfter(err))
+		} else {
+			c.queue.AddRateLimited(key)
+		}
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+func (c *Controller) sync(key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	rep, err := c.repLister.HdfsReplications(ns).Get(name)
+	if errors.IsNotFound(err) {
+		glog.Infof("HdfsReplication has been deleted %v", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delay, err := c.control.UpdateHdfsReplication(rep.DeepCopy())
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		// wake up for the next run instead of waiting for the informer resync
+		c.queue.AddAfter(key, delay)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package hdfssnapshot
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"github.com/tommenx/hdfs-operator/pkg/manager"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	clusterNotReadyRetryDelay = 30 * time.Second
+	allowSnapshotCheckDelay   = 10 * time.Second
+)
+
+type ControlInterface interface {
+	UpdateHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) error
+}
+
+type hdfsSnapshotControl struct {
+	snapControl controller.HdfsSnapshotControlInterface
+	jobControl  controller.JobControlInterface
+	podControl  controller.PodControlInterface
+	hcLister    listers.HdfsClusterLister
+	getClient   hdfs.ClientGetter
+}
+
+func NewHdfsSnapshotControl(
+	snapControl controller.HdfsSnapshotControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	hcLister listers.HdfsClusterLister,
+	getClient hdfs.ClientGetter,
+) ControlInterface {
+	return &hdfsSnapshotControl{
+		snapControl: snapControl,
+		jobControl:  jobControl,
+		podControl:  podControl,
+		hcLister:    hcLister,
+		getClient:   getClient,
+	}
+}
+
+func (c *hdfsSnapshotControl) UpdateHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) error {
+	if snap.DeletionTimestamp != nil {
+		return c.finalizeHdfsSnapshot(snap)
+	}
+	if !controller.HasFinalizer(snap, controller.SnapshotFinalizer) {
+		controller.AddFinalizer(snap, controller.SnapshotFinalizer)
+		updated, err := c.snapControl.UpdateHdfsSnapshot(snap)
+		if err != nil {
+			return err
+		}
+		snap.ObjectMeta = updated.ObjectMeta
+	}
+	oldStatus := snap.Status.DeepCopy()
+	err := c.createSnapshot(snap)
+	if !reflect.DeepEqual(oldStatus, &snap.Status) {
+		if _, updateErr := c.snapControl.UpdateHdfsSnapshotStatus(snap); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return err
+}
+
+// createSnapshot makes the directory snapshottable and creates the snapshot,
+// a created snapshot is never touched again
+func (c *hdfsSnapshotControl) createSnapshot(snap *v1alpha1.HdfsSnapshot) error {
+	if snap.Status.Phase == v1alpha1.HdfsSnapshotCreated || snap.Status.Phase == v1alpha1.HdfsSnapshotFailed {
+		return nil
+	}
+	snap.Status.Phase = v1alpha1.HdfsSnapshotPending
+	hc, err := c.hcLister.HdfsClusters(snap.Namespace).Get(snap.Spec.Cluster)
+	if errors.IsNotFound(err) {
+		snap.Status.Message = fmt.Sprintf("hdfs cluster %s does not exist", snap.Spec.Cluster)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", snap.Status.Message)
+	}
+	if err != nil {
+		return err
+	}
+	if controller.KerberosEnabled(hc) {
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = controller.WebHDFSUnsupportedMessage(hc, "HdfsSnapshot")
+		return nil
+	}
+	if !hc.IsConditionTrue(v1alpha1.NameNodeAvailable) {
+		snap.Status.Message = fmt.Sprintf("name node of hdfs cluster %s is not available", hc.Name)
+		return controller.RequeueErrorf(clusterNotReadyRetryDelay, "%s", snap.Status.Message)
+	}
+
+	cli := c.getClient(hc)
+	dir := path.Clean("/" + snap.Spec.Path)
+	if strings.Contains(dir, "'") {
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = fmt.Sprintf("path %s can not contain quotes", dir)
+		return nil
+	}
+	name := snapshotName(snap)
+	snapshotPath := path.Join(dir, ".snapshot", name)
+	// the snapshot may exist when the status update after creating it failed
+	if _, err := cli.GetFileStatus(snapshotPath); err == nil {
+		c.created(snap, snapshotPath)
+		return nil
+	}
+	fs, err := cli.GetFileStatus(dir)
+	if hdfs.IsNotFound(err) {
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = fmt.Sprintf("path %s does not exist", dir)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fs.Type != hdfs.FileTypeDirectory {
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = fmt.Sprintf("path %s is not a directory", dir)
+		return nil
+	}
+	job, err := c.jobControl.GetJob(hc, controller.AllowSnapshotJobName(snap.Name))
+	if err == nil {
+		if done, err := c.checkAllowSnapshotJob(hc, snap, job); !done {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	p, err := cli.CreateSnapshot(dir, name)
+	if isNotSnapshottable(err) {
+		return c.createAllowSnapshotJob(hc, snap, dir)
+	}
+	if err != nil {
+		glog.Errorf("create snapshot %s of %s/%s error, err=%+v", snapshotPath, hc.Namespace, hc.Name, err)
+		snap.Status.Message = err.Error()
+		return err
+	}
+	c.created(snap, p)
+	glog.Infof("snapshot %s of %s/%s is created", p, hc.Namespace, hc.Name)
+	return nil
+}
+
+// createAllowSnapshotJob runs dfsadmin -allowSnapshot on dir, the snapshot
+// is created once the job completed
+func (c *hdfsSnapshotControl) createAllowSnapshotJob(hc *v1alpha1.HdfsCluster, snap *v1alpha1.HdfsSnapshot, dir string) error {
+	command := fmt.Sprintf("hdfs dfsadmin -fs hdfs://%s -allowSnapshot '%s'", controller.NameNodeRPCAddress(hc), dir)
+	job := manager.NewHadoopJob(hc, controller.AllowSnapshotJobName(snap.Name), manager.SummarizedScript(command, false))
+	job.OwnerReferences = []metav1.OwnerReference{controller.GetSnapshotOwnerRef(snap)}
+	if err := c.jobControl.CreateJob(hc, job); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	glog.Infof("allowing snapshots on %s of %s/%s", dir, hc.Namespace, hc.Name)
+	snap.Status.Message = fmt.Sprintf("making %s snapshottable", dir)
+	return controller.RequeueErrorf(allowSnapshotCheckDelay, "%s", snap.Status.Message)
+}
+
+// checkAllowSnapshotJob returns true once the allow snapshot job completed,
+// the snapshot fails with the job
+func (c *hdfsSnapshotControl) checkAllowSnapshotJob(hc *v1alpha1.HdfsCluster, snap *v1alpha1.HdfsSnapshot, job *batchv1.Job) (bool, error) {
+	finished, cond := controller.IsJobFinished(job)
+	if !finished {
+		return false, controller.RequeueErrorf(allowSnapshotCheckDelay, "allow snapshot job %s is running", job.Name)
+	}
+	if cond != batchv1.JobComplete {
+		msg := manager.JobFailureMessage(job)
+		if summary := manager.JobSummary(c.podControl, hc, job); summary != "" {
+			msg = fmt.Sprintf("%s, %s", msg, summary)
+		}
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = msg
+	}
+	if err := c.jobControl.DeleteJob(hc, job); err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return cond == batchv1.JobComplete, nil
+}
+
+// isNotSnapshottable returns true if err is the SnapshotException of a
+// directory that does not allow snapshots
+func isNotSnapshottable(err error) bool {
+	re, ok := err.(*hdfs.RemoteException)
+	return ok && re.Exception == "SnapshotException" && strings.Contains(strings.ToLower(re.Message), "not a snapshottable directory")
+}
+
+func (c *hdfsSnapshotControl) created(snap *v1alpha1.HdfsSnapshot, snapshotPath string) {
+	now := metav1.Now()
+	snap.Status.Phase = v1alpha1.HdfsSnapshotCreated
+	snap.Status.SnapshotPath = snapshotPath
+	snap.Status.CreationTime = &now
+	snap.Status.Message = ""
+}
+
+// finalizeHdfsSnapshot deletes the hdfs snapshot, nothing is left to delete
+// when the cluster is gone or the snapshot was never created
+func (c *hdfsSnapshotControl) finalizeHdfsSnapshot(snap *v1alpha1.HdfsSnapshot) error {
+	if !controller.HasFinalizer(snap, controller.SnapshotFinalizer) {
+		return nil
+	}
+	if snap.Status.Phase == v1alpha1.HdfsSnapshotCreated {
+		hc, err := c.hcLister.HdfsClusters(snap.Namespace).Get(snap.Spec.Cluster)
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err == nil && controller.KerberosEnabled(hc) {
+			// WebHDFS needs SPNEGO, the snapshot is left to the admin
+			glog.Infof("snapshot %s of %s/%s is kept, %s", snap.Status.SnapshotPath, hc.Namespace, hc.Name,
+				controller.WebHDFSUnsupportedMessage(hc, "deleting it"))
+		} else if err == nil && hc.DeletionTimestamp == nil {
+			if err := c.deleteSnapshot(hc, snap); err != nil {
+				glog.Errorf("delete snapshot %s of %s/%s error, err=%+v", snap.Status.SnapshotPath, hc.Namespace, hc.Name, err)
+				return err
+			}
+		}
+	}
+	controller.RemoveFinalizer(snap, controller.SnapshotFinalizer)
+	_, err := c.snapControl.UpdateHdfsSnapshot(snap)
+	return err
+}
+
+func (c *hdfsSnapshotControl) deleteSnapshot(hc *v1alpha1.HdfsCluster, snap *v1alpha1.HdfsSnapshot) error {
+	cli := c.getClient(hc)
+	dir := path.Clean("/" + snap.Spec.Path)
+	if strings.Contains(dir, "'") {
+		snap.Status.Phase = v1alpha1.HdfsSnapshotFailed
+		snap.Status.Message = fmt.Sprintf("path %s can not contain quotes", dir)
+		return nil
+	}
+	name := snapshotName(snap)
+	// deleting a missing snapshot is a SnapshotException, not a not found error
+	if _, err := cli.GetFileStatus(path.Join(dir, ".snapshot", name)); hdfs.IsNotFound(err) {
+		return nil
+	}
+	if err := cli.DeleteSnapshot(dir, name); err != nil {
+		return err
+	}
+	glog.Infof("snapshot %s of %s/%s is deleted", snap.Status.SnapshotPath, hc.Namespace, hc.Name)
+	return nil
+}
+
+func snapshotName(snap *v1alpha1.HdfsSnapshot) string {
+	if snap.Spec.SnapshotName != "" {
+		return snap.Spec.SnapshotName
+	}
+	return snap.Name
+}
@@ -0,0 +1,163 @@
+package hdfssnapshotschedule
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/cron"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sort"
+	"time"
+)
+
+const (
+	defaultSnapshotRetention = 7
+	snapshotNameFormat       = "20060102-150405"
+)
+
+var scheduleKind = v1alpha1.SchemeGroupVersion.WithKind("HdfsSnapshotSchedule")
+
+type ControlInterface interface {
+	// UpdateHdfsSnapshotSchedule returns how long until the next snapshot is due,
+	// zero when nothing is scheduled
+	UpdateHdfsSnapshotSchedule(sched *v1alpha1.HdfsSnapshotSchedule) (time.Duration, error)
+}
+
+type hdfsSnapshotScheduleControl struct {
+	schedControl controller.HdfsSnapshotScheduleControlInterface
+	snapControl  controller.HdfsSnapshotControlInterface
+}
+
+func NewHdfsSnapshotScheduleControl(
+	schedControl controller.HdfsSnapshotScheduleControlInterface,
+	snapControl controller.HdfsSnapshotControlInterface,
+) ControlInterface {
+	return &hdfsSnapshotScheduleControl{
+		schedControl: schedControl,
+		snapControl:  snapControl,
+	}
+}
+
+func (c *hdfsSnapshotScheduleControl) UpdateHdfsSnapshotSchedule(sched *v1alpha1.HdfsSnapshotSchedule) (time.Duration, error) {
+	if sched.DeletionTimestamp != nil {
+		// the snapshots are owned by the schedule and deleted by the garbage collector
+		return 0, nil
+	}
+	oldStatus := sched.Status.DeepCopy()
+	delay, err := c.updateHdfsSnapshotSchedule(sched)
+	if !reflect.DeepEqual(oldStatus, &sched.Status) {
+		if _, updateErr := c.schedControl.UpdateHdfsSnapshotScheduleStatus(sched); updateErr != nil && err == nil {
+			err = updateErr
+		}
+	}
+	return delay, err
+}
+
+func (c *hdfsSnapshotScheduleControl) updateHdfsSnapshotSchedule(sched *v1alpha1.HdfsSnapshotSchedule) (time.Duration, error) {
+	schedule, err := cron.Parse(sched.Spec.Schedule)
+	if err != nil {
+		sched.Status.Message = fmt.Sprintf("invalid schedule: %v", err)
+		return 0, nil
+	}
+	snaps, err := c.ownedSnapshots(sched)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now().UTC()
+	if !sched.Spec.Suspend {
+		last := sched.CreationTimestamp.Time
+		if sched.Status.LastScheduleTime != nil {
+			last = sched.Status.LastScheduleTime.Time
+		}
+		if scheduled, ok := schedule.Missed(last, now); ok {
+			snap := newSnapshot(sched, scheduled)
+			if err := c.snapControl.CreateHdfsSnapshot(snap); err != nil && !errors.IsAlreadyExists(err) {
+				sched.Status.Message = fmt.Sprintf("create snapshot %s error: %v", snap.Name, err)
+				return 0, err
+			}
+			glog.Infof("snapshot schedule %s/%s created HdfsSnapshot %s", sched.Namespace, sched.Name, snap.Name)
+			t := metav1.NewTime(scheduled)
+			sched.Status.LastScheduleTime = &t
+			if !containsSnapshot(snaps, snap.Name) {
+				snaps = append(snaps, snap)
+			}
+		}
+	}
+
+	// the names end with the schedule time, so they sort by age
+	sort.Slice(snaps, func(i, j int) bool { return snaps[i].Name > snaps[j].Name })
+	retention := defaultSnapshotRetention
+	if sched.Spec.Retention > 0 {
+		retention = int(sched.Spec.Retention)
+	}
+	names := []string{}
+	for i, snap := range snaps {
+		if i < retention {
+			names = append(names, snap.Name)
+			continue
+		}
+		if snap.DeletionTimestamp != nil {
+			continue
+		}
+		if err := c.snapControl.DeleteHdfsSnapshot(snap); err != nil && !errors.IsNotFound(err) {
+			sched.Status.Message = fmt.Sprintf("delete expired snapshot %s error: %v", snap.Name, err)
+			return 0, err
+		}
+		glog.Infof("snapshot schedule %s/%s deleted expired HdfsSnapshot %s", sched.Namespace, sched.Name, snap.Name)
+	}
+	sched.Status.Snapshots = names
+	sched.Status.Message = ""
+
+	if sched.Spec.Suspend {
+		return 0, nil
+	}
+	next := schedule.Next(now)
+	if next.IsZero() {
+		return 0, nil
+	}
+	return next.Sub(now), nil
+}
+
+func (c *hdfsSnapshotScheduleControl) ownedSnapshots(sched *v1alpha1.HdfsSnapshotSchedule) ([]*v1alpha1.HdfsSnapshot, error) {
+	snaps, err := c.snapControl.ListHdfsSnapshots(sched.Namespace, map[string]string{controller.SnapshotScheduleLabelKey: sched.Name})
+	if err != nil {
+		return nil, err
+	}
+	owned := []*v1alpha1.HdfsSnapshot{}
+	for _, snap := range snaps {
+		if ref := metav1.GetControllerOf(snap); ref != nil && ref.UID == sched.UID {
+			owned = append(owned, snap)
+		}
+	}
+	return owned, nil
+}
+
+func newSnapshot(sched *v1alpha1.HdfsSnapshotSchedule, scheduled time.Time) *v1alpha1.HdfsSnapshot {
+	name := fmt.Sprintf("%s-%s", sched.Name, scheduled.Format(snapshotNameFormat))
+	return &v1alpha1.HdfsSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       sched.Namespace,
+			Labels:          map[string]string{controller.SnapshotScheduleLabelKey: sched.Name},
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(sched, scheduleKind)},
+		},
+		Spec: v1alpha1.HdfsSnapshotSpec{
+			Cluster:      sched.Spec.Cluster,
+			Path:         sched.Spec.Path,
+			SnapshotName: name,
+		},
+	}
+}
+
+func containsSnapshot(snaps []*v1alpha1.HdfsSnapshot, name string) bool {
+	for _, snap := range snaps {
+		if snap.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package hdfssnapshotschedule
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	informers "github.com/tommenx/hdfs-operator/pkg/client/informers/externalversions"
+	listers "github.com/tommenx/hdfs-operator/pkg/client/listers/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"time"
+)
+
+// Controller creates HdfsSnapshots on the schedule of HdfsSnapshotSchedule resources
+type Controller struct {
+	cli               versioned.Interface
+	schedLister       listers.HdfsSnapshotScheduleLister
+	schedListerSynced cache.InformerSynced
+	snapListerSynced  cache.InformerSynced
+	queue             workqueue.RateLimitingInterface
+	control           ControlInterface
+}
+
+func NewController(
+	cli versioned.Interface,
+	informerFactory informers.SharedInformerFactory,
+) *Controller {
+	schedInformer := informerFactory.Storage().V1alpha1().HdfsSnapshotSchedules()
+	snapInformer := informerFactory.Storage().V1alpha1().HdfsSnapshots()
+
+	c := &Controller{
+		cli: cli,
+		control: NewHdfsSnapshotScheduleControl(
+			controller.NewRealHdfsSnapshotScheduleControl(cli, schedInformer.Lister()),
+			controller.NewRealHdfsSnapshotControl(cli, snapInformer.Lister()),
+		),
+		queue: workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "hdfssnapshotschedule"),
+	}
+	schedInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: c.enqueueHdfsSnapshotSchedule,
+		UpdateFunc: func(old, cur interface{}) {
+			c.enqueueHdfsSnapshotSchedule(cur)
+		},
+		DeleteFunc: c.enqueueHdfsSnapshotSchedule,
+	})
+	snapInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		DeleteFunc: c.deleteHdfsSnapshot,
+	})
+	c.schedLister = schedInformer.Lister()
+	c.schedListerSynced = schedInformer.Informer().HasSynced
+	c.snapListerSynced = snapInformer.Informer().HasSynced
+	return c
+}
+
+// deleteHdfsSnapshot refreshes the snapshot list in the status of the owning schedule
+func (c *Controller) deleteHdfsSnapshot(obj interface{}) {
+	snap, ok := obj.(*v1alpha1.HdfsSnapshot)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %+v", obj))
+			return
+		}
+		snap, ok = tombstone.Obj.(*v1alpha1.HdfsSnapshot)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not an HdfsSnapshot %+v", obj))
+			return
+		}
+	}
+	ref := metav1.GetControllerOf(snap)
+	if ref == nil || ref.Kind != scheduleKind.Kind {
+		return
+	}
+	sched, err := c.schedLister.HdfsSnapshotSchedules(snap.Namespace).Get(ref.Name)
+	if err != nil || sched.UID != ref.UID {
+		return
+	}
+	c.enqueueHdfsSnapshotSchedule(sched)
+}
+
+func (c *Controller) enqueueHdfsSnapshotSchedule(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("Cound't get key for object %+v: %v", obj, err))
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer c.queue.ShutDown()
+
+	glog.Info("Starting hdfssnapshotschedule controller")
+	defer glog.Info("Shutting down hdfssnapshotschedule controller")
+
+	if !cache.WaitForCacheSync(stopCh, c.schedListerSynced, c.snapListerSynced) {
+		return
+	}
+
+	for i := 0; i < workers; i++ {
+		go wait.Until(c.worker, time.Second, stopCh)
+	}
+
+	<-stopCh
+}
+
+func (c *Controller) worker() {
+	for c.processNextWorkItem() {
+		// revive:disable:empty-block
+	}
+}
+
+func (c *Controller) processNextWorkItem() bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+	if err := c.sync(key.(string)); err != nil {
+		c.queue.AddRateLimited(key)
+	} else {
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+func (c *Controller) sync(key string) error {
+	ns, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+	sched, err := c.schedLister.HdfsSnapshotSchedules(ns).Get(name)
+	if errors.IsNotFound(err) {
+		glog.Infof("HdfsSnapshotSchedule has been deleted %v", key)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	delay, err := c.control.UpdateHdfsSnapshotSchedule(sched.DeepCopy())
+	if err != nil {
+		return err
+	}
+	if delay > 0 {
+		// wake up for the next snapshot instead of waiting for the informer resync
+		c.queue.AddAfter(key, delay)
+	}
+	return nil
+}
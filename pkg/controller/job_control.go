@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	batchlisters "k8s.io/client-go/listers/batch/v1"
+)
+
+type JobControlInterface interface {
+	CreateJob(*v1alpha1.HdfsCluster, *batchv1.Job) error
+	GetJob(hc *v1alpha1.HdfsCluster, name string) (*batchv1.Job, error)
+	DeleteJob(*v1alpha1.HdfsCluster, *batchv1.Job) error
+}
+
+type realJobControl struct {
+	kubeCli   kubernetes.Interface
+	jobLister batchlisters.JobLister
+}
+
+// NewRealJobControl creates a new JobControlInterface
+func NewRealJobControl(kubeCli kubernetes.Interface, jobLister batchlisters.JobLister) JobControlInterface {
+	return &realJobControl{
+		kubeCli,
+		jobLister,
+	}
+}
+
+func (c *realJobControl) CreateJob(hc *v1alpha1.HdfsCluster, job *batchv1.Job) error {
+	_, err := c.kubeCli.BatchV1().Jobs(hc.Namespace).Create(job)
+	if err != nil {
+		glog.Errorf("create job error, err=%+v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *realJobControl) GetJob(hc *v1alpha1.HdfsCluster, name string) (*batchv1.Job, error) {
+	return c.jobLister.Jobs(hc.Namespace).Get(name)
+}
+
+// DeleteJob deletes the job together with its pods
+func (c *realJobControl) DeleteJob(hc *v1alpha1.HdfsCluster, job *batchv1.Job) error {
+	propagation := metav1.DeletePropagationBackground
+	err := c.kubeCli.BatchV1().Jobs(hc.Namespace).Delete(job.Name, &metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		glog.Errorf("delete job error, err=%+v", err)
+		return err
+	}
+	return nil
+}
+
+// IsJobFinished returns whether the job completed or failed
+func IsJobFinished(job *batchv1.Job) (bool, batchv1.JobConditionType) {
+	for _, c := range job.Status.Conditions {
+		if (c.Type == batchv1.JobComplete || c.Type == batchv1.JobFailed) && c.Status == corev1.ConditionTrue {
+			return true, c.Type
+		}
+	}
+	return false, ""
+}
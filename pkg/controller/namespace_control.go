@@ -0,0 +1,26 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+type NamespaceControlInterface interface {
+	ListNamespaces(selector labels.Selector) ([]*corev1.Namespace, error)
+}
+
+type realNamespaceControl struct {
+	nsLister corelisters.NamespaceLister
+}
+
+// NewRealNamespaceControl creates a new NamespaceControlInterface
+func NewRealNamespaceControl(nsLister corelisters.NamespaceLister) NamespaceControlInterface {
+	return &realNamespaceControl{
+		nsLister,
+	}
+}
+
+func (c *realNamespaceControl) ListNamespaces(selector labels.Selector) ([]*corev1.Namespace, error) {
+	return c.nsLister.List(selector)
+}
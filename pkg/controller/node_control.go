@@ -0,0 +1,25 @@
+package controller
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+type NodeControlInterface interface {
+	GetNode(name string) (*corev1.Node, error)
+}
+
+type realNodeControl struct {
+	nodeLister corelisters.NodeLister
+}
+
+// NewRealNodeControl creates a new NodeControlInterface
+func NewRealNodeControl(nodeLister corelisters.NodeLister) NodeControlInterface {
+	return &realNodeControl{
+		nodeLister,
+	}
+}
+
+func (c *realNodeControl) GetNode(name string) (*corev1.Node, error) {
+	return c.nodeLister.Get(name)
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
@@ -9,7 +10,8 @@ import (
 )
 
 type PodControlInterface interface {
-	CheckPodsStatus(apps map[string]string) (bool, map[string]string, error)
+	CheckPodsStatus(hc *v1alpha1.HdfsCluster, apps map[string]string) (bool, map[string]string, error)
+	ListPods(hc *v1alpha1.HdfsCluster, selector map[string]string) ([]*corev1.Pod, error)
 }
 
 type realPodControl struct {
@@ -24,17 +26,24 @@ func NewRealPodControl(kubeCli kubernetes.Interface, podLister corelisters.PodLi
 	}
 }
 
-func (c *realPodControl) CheckPodsStatus(apps map[string]string) (bool, map[string]string, error) {
+// CheckPodsStatus returns the pods of hc matching apps which are not running
+// and ready, a selector without any pod is reported as not running
+func (c *realPodControl) CheckPodsStatus(hc *v1alpha1.HdfsCluster, apps map[string]string) (bool, map[string]string, error) {
 	sel := labels.SelectorFromSet(apps)
-	pods, err := c.podLister.List(sel)
+	pods, err := c.podLister.Pods(hc.Namespace).List(sel)
 	if err != nil {
 		glog.Errorf("List pods error, err=%+v", err)
 		return false, nil, err
 	}
+	if len(pods) == 0 {
+		return false, nil, nil
+	}
 	status := make(map[string]string)
 	for _, pod := range pods {
 		if pod.Status.Phase != corev1.PodRunning {
 			status[pod.Name] = string(pod.Status.Phase)
+		} else if !IsPodReady(pod) {
+			status[pod.Name] = "NotReady"
 		}
 	}
 	if len(status) != 0 {
@@ -43,3 +52,17 @@ func (c *realPodControl) CheckPodsStatus(apps map[string]string) (bool, map[stri
 	}
 	return true, nil, nil
 }
+
+func (c *realPodControl) ListPods(hc *v1alpha1.HdfsCluster, selector map[string]string) ([]*corev1.Pod, error) {
+	return c.podLister.Pods(hc.Namespace).List(labels.SelectorFromSet(selector))
+}
+
+// IsPodReady returns true if the pod has the Ready condition set to true
+func IsPodReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
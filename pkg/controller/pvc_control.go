@@ -4,6 +4,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 )
@@ -11,6 +12,9 @@ import (
 type PVCControlInterface interface {
 	CreatePVC(*v1alpha1.HdfsCluster, *corev1.PersistentVolumeClaim) error
 	GetPVC(hc *v1alpha1.HdfsCluster, name string) (*corev1.PersistentVolumeClaim, error)
+	ListPVCs(hc *v1alpha1.HdfsCluster, apps map[string]string) ([]*corev1.PersistentVolumeClaim, error)
+	UpdatePVC(*v1alpha1.HdfsCluster, *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error)
+	DeletePVC(*v1alpha1.HdfsCluster, *corev1.PersistentVolumeClaim) error
 }
 
 type realPVCControl struct {
@@ -39,3 +43,25 @@ func (c *realPVCControl) GetPVC(hc *v1alpha1.HdfsCluster, name string) (*corev1.
 	return pvc, err
 
 }
+
+func (c *realPVCControl) ListPVCs(hc *v1alpha1.HdfsCluster, apps map[string]string) ([]*corev1.PersistentVolumeClaim, error) {
+	return c.pvcLister.PersistentVolumeClaims(hc.Namespace).List(labels.SelectorFromSet(apps))
+}
+
+func (c *realPVCControl) UpdatePVC(hc *v1alpha1.HdfsCluster, pvc *corev1.PersistentVolumeClaim) (*corev1.PersistentVolumeClaim, error) {
+	cur, err := c.kubeCli.CoreV1().PersistentVolumeClaims(hc.Namespace).Update(pvc)
+	if err != nil {
+		glog.Errorf("update pvc error, err=%+v", err)
+		return nil, err
+	}
+	return cur, nil
+}
+
+func (c *realPVCControl) DeletePVC(hc *v1alpha1.HdfsCluster, pvc *corev1.PersistentVolumeClaim) error {
+	err := c.kubeCli.CoreV1().PersistentVolumeClaims(hc.Namespace).Delete(pvc.Name, nil)
+	if err != nil {
+		glog.Errorf("delete pvc error, err=%+v", err)
+		return err
+	}
+	return nil
+}
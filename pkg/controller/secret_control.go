@@ -0,0 +1,47 @@
+package controller
+
+import (
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+type SecretControlInterface interface {
+	GetSecret(hc *v1alpha1.HdfsCluster, name string) (*corev1.Secret, error)
+	CreateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error
+	UpdateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error
+}
+
+type realSecretControl struct {
+	kubeCli kubernetes.Interface
+}
+
+// NewRealSecretControl creates a new SecretControlInterface, secrets are read
+// from the api server so the operator does not cache every secret
+func NewRealSecretControl(kubeCli kubernetes.Interface) SecretControlInterface {
+	return &realSecretControl{
+		kubeCli,
+	}
+}
+
+func (c *realSecretControl) GetSecret(hc *v1alpha1.HdfsCluster, name string) (*corev1.Secret, error) {
+	return c.kubeCli.CoreV1().Secrets(hc.Namespace).Get(name, metav1.GetOptions{})
+}
+
+func (c *realSecretControl) CreateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error {
+	_, err := c.kubeCli.CoreV1().Secrets(secret.Namespace).Create(secret)
+	if err != nil {
+		glog.Errorf("create secret %s/%s error, err=%+v", secret.Namespace, secret.Name, err)
+	}
+	return err
+}
+
+func (c *realSecretControl) UpdateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error {
+	_, err := c.kubeCli.CoreV1().Secrets(secret.Namespace).Update(secret)
+	if err != nil {
+		glog.Errorf("update secret %s/%s error, err=%+v", secret.Namespace, secret.Name, err)
+	}
+	return err
+}
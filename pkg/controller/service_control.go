@@ -4,6 +4,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	corelisters "k8s.io/client-go/listers/core/v1"
 )
@@ -11,6 +12,8 @@ import (
 type ServiceControlInterface interface {
 	CreateService(*v1alpha1.HdfsCluster, *corev1.Service) error
 	GetService(hc *v1alpha1.HdfsCluster, name string) (*corev1.Service, error)
+	UpdateService(*v1alpha1.HdfsCluster, *corev1.Service) error
+	DeleteService(hc *v1alpha1.HdfsCluster, name string) error
 }
 
 type realServiceControl struct {
@@ -38,3 +41,21 @@ func (c *realServiceControl) CreateService(hc *v1alpha1.HdfsCluster, svc *corev1
 func (c *realServiceControl) GetService(hc *v1alpha1.HdfsCluster, name string) (*corev1.Service, error) {
 	return c.svcLister.Services(hc.Namespace).Get(name)
 }
+
+func (c *realServiceControl) UpdateService(hc *v1alpha1.HdfsCluster, svc *corev1.Service) error {
+	_, err := c.kubeCli.CoreV1().Services(hc.Namespace).Update(svc)
+	if err != nil {
+		glog.Errorf("update service error, err=%+v", err)
+		return err
+	}
+	return nil
+}
+
+func (c *realServiceControl) DeleteService(hc *v1alpha1.HdfsCluster, name string) error {
+	err := c.kubeCli.CoreV1().Services(hc.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf("delete service %s/%s error, err=%+v", hc.Namespace, name, err)
+		return err
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	appslisters "k8s.io/client-go/listers/apps/v1"
 )
@@ -12,6 +13,7 @@ type StatefulSetControlInterface interface {
 	CreateStatefulSet(*v1alpha1.HdfsCluster, *apps.StatefulSet) error
 	GetStatefulSet(hc *v1alpha1.HdfsCluster, name string) (*apps.StatefulSet, error)
 	UpdateStatefulSet(*v1alpha1.HdfsCluster, *apps.StatefulSet) (*apps.StatefulSet, error)
+	DeleteStatefulSet(hc *v1alpha1.HdfsCluster, name string) error
 }
 
 type realStatefulSetControl struct {
@@ -49,3 +51,12 @@ func (c *realStatefulSetControl) UpdateStatefulSet(hc *v1alpha1.HdfsCluster, ss
 	}
 	return cur, nil
 }
+
+func (c *realStatefulSetControl) DeleteStatefulSet(hc *v1alpha1.HdfsCluster, name string) error {
+	err := c.kubeCli.AppsV1().StatefulSets(hc.Namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil {
+		glog.Errorf("delete StatefulSet %s/%s error, err=%+v", hc.Namespace, name, err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package controller
+
+import (
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/client-go/kubernetes"
+	storagelisters "k8s.io/client-go/listers/storage/v1"
+)
+
+type StorageClassControlInterface interface {
+	GetStorageClass(name string) (*storagev1.StorageClass, error)
+}
+
+type realStorageClassControl struct {
+	kubeCli  kubernetes.Interface
+	scLister storagelisters.StorageClassLister
+}
+
+// NewRealStorageClassControl creates a new StorageClassControlInterface
+func NewRealStorageClassControl(kubeCli kubernetes.Interface, scLister storagelisters.StorageClassLister) StorageClassControlInterface {
+	return &realStorageClassControl{
+		kubeCli,
+		scLister,
+	}
+}
+
+func (c *realStorageClassControl) GetStorageClass(name string) (*storagev1.StorageClass, error) {
+	return c.scLister.Get(name)
+}
@@ -0,0 +1,66 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	for _, spec := range []string{"0 2 * * *", "*/15 * * * *", "0 0 1,15 * mon-fri", "@daily", "0 12 * jan,jul sun", "0 0 * * 7"} {
+		if _, err := Parse(spec); err != nil {
+			t.Errorf("parse %q error, err=%v", spec, err)
+		}
+	}
+	for _, spec := range []string{"", "0 2 * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 * foo *"} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected an error for %q", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02 15:04:05", s)
+		return t
+	}
+	for _, tc := range []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"0 2 * * *", "2019-10-10 01:59:30", "2019-10-10 02:00:00"},
+		{"0 2 * * *", "2019-10-10 02:00:00", "2019-10-11 02:00:00"},
+		{"*/15 * * * *", "2019-10-10 23:50:00", "2019-10-11 00:00:00"},
+		{"@monthly", "2019-12-31 12:00:00", "2020-01-01 00:00:00"},
+		// 2019-10-12 is a saturday
+		{"0 0 * * 0", "2019-10-12 10:00:00", "2019-10-13 00:00:00"},
+		{"0 0 * * 7", "2019-10-12 10:00:00", "2019-10-13 00:00:00"},
+		// both day fields restricted, either of them matches
+		{"0 0 20 * mon", "2019-10-12 10:00:00", "2019-10-14 00:00:00"},
+		{"0 0 29 2 *", "2019-03-01 00:00:00", "2020-02-29 00:00:00"},
+	} {
+		s, err := Parse(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q error, err=%v", tc.spec, err)
+		}
+		if got := s.Next(at(tc.from)); !got.Equal(at(tc.want)) {
+			t.Errorf("%q after %s: got %s, want %s", tc.spec, tc.from, got, tc.want)
+		}
+	}
+	s, _ := Parse("0 0 30 2 *")
+	if got := s.Next(at("2019-01-01 00:00:00")); !got.IsZero() {
+		t.Errorf("got %s for a schedule that never matches, want the zero time", got)
+	}
+}
+
+func TestMissed(t *testing.T) {
+	s, _ := Parse("0 * * * *")
+	last := time.Date(2019, 10, 10, 1, 0, 0, 0, time.UTC)
+	if _, ok := s.Missed(last, last.Add(59*time.Minute)); ok {
+		t.Errorf("nothing is missed within the hour")
+	}
+	got, ok := s.Missed(last, last.Add(3*time.Hour+30*time.Minute))
+	if want := last.Add(3 * time.Hour); !ok || !got.Equal(want) {
+		t.Errorf("got %s %v, want the latest missed run %s", got, ok, want)
+	}
+}
@@ -0,0 +1,139 @@
+package hdfs
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultUser is the hdfs superuser, admin operations like SETOWNER need it
+	DefaultUser = "root"
+
+	defaultTimeout = 10 * time.Second
+)
+
+// Interface talks to a name node through its web port, reading metrics from
+// /jmx and doing file system operations through WebHDFS. It only has the
+// operations of WebHDFS in hadoop 2.7, quotas, storage policies and allowing
+// snapshots are dfsadmin jobs
+type Interface interface {
+	GetFSNamesystem() (*FSNamesystem, error)
+	GetFSNamesystemState() (*FSNamesystemState, error)
+	GetNameNodeInfo() (*NameNodeInfo, error)
+	IsInSafeMode() (bool, error)
+
+	ListStatus(path string) ([]FileStatus, error)
+	GetFileStatus(path string) (*FileStatus, error)
+	Mkdirs(path string, permission string) error
+	SetOwner(path string, owner string, group string) error
+	SetPermission(path string, permission string) error
+	GetContentSummary(path string) (*ContentSummary, error)
+	CreateSnapshot(path string, name string) (string, error)
+	DeleteSnapshot(path string, name string) error
+	RenameSnapshot(path string, oldName string, newName string) error
+}
+
+type client struct {
+	baseURL    string
+	user       string
+	httpClient *http.Client
+}
+
+// NewClient creates a client for the name node web endpoint at baseURL,
+// e.g. http://demonn.default.svc:80, acting as user
+func NewClient(baseURL string, user string) Interface {
+	return &client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// NewTLSClient creates a client for an https only name node web endpoint
+func NewTLSClient(baseURL string, user string, config *tls.Config) Interface {
+	transport := &http.Transport{TLSClientConfig: config}
+	return &client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		user:       user,
+		httpClient: &http.Client{Timeout: defaultTimeout, Transport: transport},
+	}
+}
+
+// RemoteException is the error body returned by WebHDFS
+type RemoteException struct {
+	Exception     string `json:"exception"`
+	JavaClassName string `json:"javaClassName"`
+	Message       string `json:"message"`
+	StatusCode    int    `json:"-"`
+}
+
+func (e *RemoteException) Error() string {
+	return fmt.Sprintf("%s: %s", e.Exception, e.Message)
+}
+
+// IsNotFound returns true if err reports a missing path
+func IsNotFound(err error) bool {
+	re, ok := err.(*RemoteException)
+	return ok && (re.Exception == "FileNotFoundException" || re.StatusCode == http.StatusNotFound)
+}
+
+func (c *client) do(method string, u string, out interface{}) error {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return decodeRemoteException(resp)
+	}
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode response of %s %s error, err=%v", method, u, err)
+	}
+	return nil
+}
+
+func decodeRemoteException(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
+	var wrapped struct {
+		RemoteException *RemoteException `json:"RemoteException"`
+	}
+	if err := json.Unmarshal(body, &wrapped); err == nil && wrapped.RemoteException != nil {
+		wrapped.RemoteException.StatusCode = resp.StatusCode
+		return wrapped.RemoteException
+	}
+	return &RemoteException{
+		Exception:  http.StatusText(resp.StatusCode),
+		Message:    strings.TrimSpace(string(body)),
+		StatusCode: resp.StatusCode,
+	}
+}
+
+func (c *client) webhdfsURL(path string, op string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("op", op)
+	if c.user != "" {
+		params.Set("user.name", c.user)
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	u := url.URL{Path: "/webhdfs/v1" + path, RawQuery: params.Encode()}
+	return c.baseURL + u.String()
+}
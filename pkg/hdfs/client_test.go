@@ -0,0 +1,273 @@
+package hdfs_test
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs/fake"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGetNameNodeInfo(t *testing.T) {
+	nn := fake.NewNameNode()
+	defer nn.Close()
+	want := hdfs.NameNodeInfo{
+		Version:     "2.7.2, rb165c4fe8a74265c792ce23f546c64604acf0e41",
+		ClusterID:   "CID-1",
+		BlockPoolID: "BP-1",
+		Safemode:    "Safe mode is ON.",
+		Total:       300,
+		Used:        100,
+		Free:        200,
+		LiveNodes: map[string]hdfs.LiveNode{
+			"demo-datanode-0:50010": {XferAddr: "10.0.0.1:50010", Version: "2.7.2", NumBlocks: 7},
+		},
+		DeadNodes: map[string]hdfs.DeadNode{
+			"demo-datanode-1:50010": {XferAddr: "10.0.0.2:50010", Decommissioned: true},
+		},
+		DecomNodes: map[string]hdfs.DecomNode{},
+	}
+	nn.SetNameNodeInfo(want)
+	nn.SetCheckpointTxID(42)
+	want.MostRecentCheckpointTxID = 42
+
+	info, err := nn.Client().GetNameNodeInfo()
+	if err != nil {
+		t.Fatalf("get name node info error, err=%v", err)
+	}
+	if !reflect.DeepEqual(*info, want) {
+		t.Errorf("got name node info %+v, want %+v", *info, want)
+	}
+}
+
+func TestGetFSNamesystem(t *testing.T) {
+	nn := fake.NewNameNode()
+	defer nn.Close()
+	nn.SetFSNamesystem(hdfs.FSNamesystem{HAState: "active", CapacityTotal: 1024, MissingBlocks: 3, UnderReplicatedBlocks: 5})
+	nn.SetFSNamesystemState(hdfs.FSNamesystemState{FSState: hdfs.FSStateSafeMode, NumLiveDataNodes: 2})
+	cli := nn.Client()
+
+	fs, err := cli.GetFSNamesystem()
+	if err != nil {
+		t.Fatalf("get fs namesystem error, err=%v", err)
+	}
+	if fs.HAState != "active" || fs.CapacityTotal != 1024 || fs.MissingBlocks != 3 || fs.UnderReplicatedBlocks != 5 {
+		t.Errorf("unexpected fs namesystem %+v", fs)
+	}
+	state, err := cli.GetFSNamesystemState()
+	if err != nil {
+		t.Fatalf("get fs namesystem state error, err=%v", err)
+	}
+	if state.NumLiveDataNodes != 2 {
+		t.Errorf("got %d live data nodes, want 2", state.NumLiveDataNodes)
+	}
+	safe, err := cli.IsInSafeMode()
+	if err != nil || !safe {
+		t.Errorf("got safe mode %v, err=%v, want true", safe, err)
+	}
+}
+
+func TestMissingBean(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"beans":[]}`))
+	}))
+	defer srv.Close()
+	if _, err := hdfs.NewClient(srv.URL, "").GetFSNamesystem(); err == nil {
+		t.Errorf("expected an error for a missing bean")
+	}
+}
+
+func TestRemoteException(t *testing.T) {
+	nn := fake.NewNameNode()
+	defer nn.Close()
+	cli := nn.Client()
+
+	_, err := cli.GetFileStatus("/missing")
+	if !hdfs.IsNotFound(err) {
+		t.Fatalf("got %v, want a not found error", err)
+	}
+	re, ok := err.(*hdfs.RemoteException)
+	if !ok {
+		t.Fatalf("got %T, want *hdfs.RemoteException", err)
+	}
+	if re.Exception != "FileNotFoundException" || re.StatusCode != http.StatusNotFound || re.Message == "" {
+		t.Errorf("unexpected remote exception %+v", re)
+	}
+
+	nn.CreateFile("/file", 1)
+	_, err = cli.CreateSnapshot("/", "s1")
+	re, ok = err.(*hdfs.RemoteException)
+	if !ok || re.Exception != "SnapshotException" || re.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want a SnapshotException", err)
+	}
+	if hdfs.IsNotFound(err) {
+		t.Errorf("a SnapshotException is not a not found error")
+	}
+}
+
+func TestPlainTextError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	_, err := hdfs.NewClient(srv.URL, "").GetFileStatus("/")
+	re, ok := err.(*hdfs.RemoteException)
+	if !ok {
+		t.Fatalf("got %T, want *hdfs.RemoteException", err)
+	}
+	want := hdfs.RemoteException{Exception: "Unauthorized", Message: "Authentication required", StatusCode: http.StatusUnauthorized}
+	if *re != want {
+		t.Errorf("got %+v, want %+v", *re, want)
+	}
+}
+
+func TestWebHDFSRequests(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		query  url.Values
+	}
+	var got request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = request{method: r.Method, path: r.URL.Path, query: r.URL.Query()}
+		ioutil.ReadAll(r.Body)
+		switch r.URL.Query().Get("op") {
+		case "MKDIRS":
+			w.Write([]byte(`{"boolean":true}`))
+		case "CREATESNAPSHOT":
+			w.Write([]byte(`{"Path":"/data/.snapshot/s1"}`))
+		}
+	}))
+	defer srv.Close()
+	cli := hdfs.NewClient(srv.URL+"/", "hdfs")
+
+	for _, tc := range []struct {
+		name string
+		call func() error
+		want request
+	}{
+		{
+			name: "mkdirs",
+			call: func() error { return cli.Mkdirs("data/a b", "750") },
+			want: request{"PUT", "/webhdfs/v1/data/a b", url.Values{"op": {"MKDIRS"}, "permission": {"750"}, "user.name": {"hdfs"}}},
+		},
+		{
+			name: "set owner keeps the group",
+			call: func() error { return cli.SetOwner("/data", "alice", "") },
+			want: request{"PUT", "/webhdfs/v1/data", url.Values{"op": {"SETOWNER"}, "owner": {"alice"}, "user.name": {"hdfs"}}},
+		},
+		{
+			name: "set permission",
+			call: func() error { return cli.SetPermission("/data", "700") },
+			want: request{"PUT", "/webhdfs/v1/data", url.Values{"op": {"SETPERMISSION"}, "permission": {"700"}, "user.name": {"hdfs"}}},
+		},
+		{
+			name: "create snapshot",
+			call: func() error {
+				p, err := cli.CreateSnapshot("/data", "s1")
+				if err == nil && p != "/data/.snapshot/s1" {
+					t.Errorf("got snapshot path %s", p)
+				}
+				return err
+			},
+			want: request{"PUT", "/webhdfs/v1/data", url.Values{"op": {"CREATESNAPSHOT"}, "snapshotname": {"s1"}, "user.name": {"hdfs"}}},
+		},
+		{
+			name: "rename snapshot",
+			call: func() error { return cli.RenameSnapshot("/data", "s1", "s2") },
+			want: request{"PUT", "/webhdfs/v1/data", url.Values{"op": {"RENAMESNAPSHOT"}, "oldsnapshotname": {"s1"}, "snapshotname": {"s2"}, "user.name": {"hdfs"}}},
+		},
+		{
+			name: "delete snapshot",
+			call: func() error { return cli.DeleteSnapshot("/data", "s2") },
+			want: request{"DELETE", "/webhdfs/v1/data", url.Values{"op": {"DELETESNAPSHOT"}, "snapshotname": {"s2"}, "user.name": {"hdfs"}}},
+		},
+	} {
+		got = request{}
+		if err := tc.call(); err != nil {
+			t.Errorf("%s: error, err=%v", tc.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got request %+v, want %+v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestDirectories(t *testing.T) {
+	nn := fake.NewNameNode()
+	defer nn.Close()
+	cli := nn.Client()
+
+	if err := cli.Mkdirs("/data/logs", "750"); err != nil {
+		t.Fatalf("mkdirs error, err=%v", err)
+	}
+	if err := cli.SetOwner("/data/logs", "alice", "analysts"); err != nil {
+		t.Fatalf("set owner error, err=%v", err)
+	}
+	fs, err := cli.GetFileStatus("/data/logs")
+	if err != nil {
+		t.Fatalf("get file status error, err=%v", err)
+	}
+	if fs.Type != hdfs.FileTypeDirectory || fs.Permission != "750" || fs.Owner != "alice" || fs.Group != "analysts" || fs.StoragePolicy != 0 {
+		t.Errorf("unexpected file status %+v", fs)
+	}
+	nn.SetStoragePolicy("/data/logs", hdfs.StoragePolicyCold)
+	if fs, _ := cli.GetFileStatus("/data/logs"); fs.StoragePolicy != hdfs.StoragePolicyIDs[hdfs.StoragePolicyCold] {
+		t.Errorf("got storage policy %d, want the id of COLD", fs.StoragePolicy)
+	}
+
+	nn.CreateFile("/data/logs/a", 10)
+	nn.SetQuota("/data", 100, -1)
+	summary, err := cli.GetContentSummary("/data")
+	if err != nil {
+		t.Fatalf("get content summary error, err=%v", err)
+	}
+	want := hdfs.ContentSummary{DirectoryCount: 2, FileCount: 1, Length: 10, Quota: 100, SpaceConsumed: 30, SpaceQuota: -1}
+	if *summary != want {
+		t.Errorf("got content summary %+v, want %+v", *summary, want)
+	}
+	statuses, err := cli.ListStatus("/data")
+	if err != nil || len(statuses) != 1 || statuses[0].PathSuffix != "logs" || statuses[0].ChildrenNum != 1 {
+		t.Errorf("unexpected list status %+v, err=%v", statuses, err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	nn := fake.NewNameNode()
+	defer nn.Close()
+	cli := nn.Client()
+	cli.Mkdirs("/data", "")
+	nn.AllowSnapshot("/data")
+
+	p, err := cli.CreateSnapshot("/data", "s1")
+	if err != nil || p != "/data/.snapshot/s1" {
+		t.Fatalf("got snapshot %s, err=%v", p, err)
+	}
+	if _, err := cli.GetFileStatus(p); err != nil {
+		t.Errorf("snapshot %s is not readable, err=%v", p, err)
+	}
+	if err := cli.RenameSnapshot("/data", "s1", "s2"); err != nil {
+		t.Fatalf("rename snapshot error, err=%v", err)
+	}
+	if err := cli.DeleteSnapshot("/data", "s2"); err != nil {
+		t.Fatalf("delete snapshot error, err=%v", err)
+	}
+	if names := nn.Snapshots("/data"); len(names) != 0 {
+		t.Errorf("got snapshots %v after deleting them", names)
+	}
+}
+
+func TestParseFsImageName(t *testing.T) {
+	txid, err := hdfs.ParseFsImageName(hdfs.FsImageName(42))
+	if err != nil || txid != 42 {
+		t.Errorf("got txid %d, err=%v, want 42", txid, err)
+	}
+	if _, err := hdfs.ParseFsImageName("edits_0000000000000000042"); err == nil {
+		t.Errorf("expected an error for an edits file")
+	}
+}
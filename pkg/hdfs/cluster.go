@@ -0,0 +1,8 @@
+package hdfs
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+)
+
+// ClientGetter returns a client for the name node of an hdfs cluster
+type ClientGetter func(hc *v1alpha1.HdfsCluster) Interface
@@ -0,0 +1,461 @@
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NameNode is an in-memory name node served by an httptest.Server, it answers
+// the /jmx beans and the WebHDFS operations of hadoop 2.7 that hdfs.Interface
+// uses. The operations dfsadmin jobs do are methods of the fake instead
+type NameNode struct {
+	server *httptest.Server
+
+	mu                sync.Mutex
+	fsNamesystem      hdfs.FSNamesystem
+	fsNamesystemState hdfs.FSNamesystemState
+	nameNodeInfo      hdfs.NameNodeInfo
+	inodes            map[string]*inode
+	nextFileID        int64
+}
+
+type inode struct {
+	status         hdfs.FileStatus
+	snapshottable  bool
+	snapshots      map[string]time.Time
+	namespaceQuota int64
+	spaceQuota     int64
+	storagePolicy  int32
+}
+
+// NewNameNode starts a fake name node with an empty root directory
+func NewNameNode() *NameNode {
+	f := &NameNode{
+		fsNamesystemState: hdfs.FSNamesystemState{FSState: "Operational"},
+		inodes:            map[string]*inode{},
+		nextFileID:        16385,
+	}
+	f.inodes["/"] = f.newDirectory("/", "755")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jmx", f.serveJMX)
+	mux.HandleFunc("/webhdfs/v1/", f.serveWebHDFS)
+	f.server = httptest.NewServer(mux)
+	return f
+}
+
+// URL is the base url of the fake, pass it to hdfs.NewClient
+func (f *NameNode) URL() string {
+	return f.server.URL
+}
+
+func (f *NameNode) Client() hdfs.Interface {
+	return hdfs.NewClient(f.server.URL, hdfs.DefaultUser)
+}
+
+func (f *NameNode) Close() {
+	f.server.Close()
+}
+
+func (f *NameNode) SetFSNamesystem(fs hdfs.FSNamesystem) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fsNamesystem = fs
+}
+
+func (f *NameNode) SetFSNamesystemState(state hdfs.FSNamesystemState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fsNamesystemState = state
+}
+
+func (f *NameNode) SetNameNodeInfo(info hdfs.NameNodeInfo) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nameNodeInfo = info
+}
+
+// SetCheckpointTxID sets the most recent checkpoint in the NameNodeInfo bean
+func (f *NameNode) SetCheckpointTxID(txid int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nameNodeInfo.MostRecentCheckpointTxID = txid
+}
+
+// SetQuota sets the namespace and space quota of p like dfsadmin -setQuota
+// and -setSpaceQuota, -1 clears them
+func (f *NameNode) SetQuota(p string, namespaceQuota int64, spaceQuota int64) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	if !ok {
+		return false
+	}
+	n.namespaceQuota = namespaceQuota
+	n.spaceQuota = spaceQuota
+	return true
+}
+
+// SetStoragePolicy sets the storage policy of p like hdfs storagepolicies
+func (f *NameNode) SetStoragePolicy(p string, policy string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	id, known := hdfs.StoragePolicyIDs[policy]
+	if !ok || !known {
+		return false
+	}
+	n.storagePolicy = id
+	return true
+}
+
+// AllowSnapshot makes p snapshottable like dfsadmin -allowSnapshot
+func (f *NameNode) AllowSnapshot(p string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	if !ok {
+		return false
+	}
+	n.snapshottable = true
+	return true
+}
+
+// Quota returns the namespace and space quota of p, -1 means unset
+func (f *NameNode) Quota(p string) (int64, int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	if !ok {
+		return 0, 0, false
+	}
+	return n.namespaceQuota, n.spaceQuota, true
+}
+
+// Status returns the file status of p
+func (f *NameNode) Status(p string) (hdfs.FileStatus, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	if !ok {
+		return hdfs.FileStatus{}, false
+	}
+	return n.status, true
+}
+
+// CreateFile adds a file of length bytes with replication 3, the parent must exist
+func (f *NameNode) CreateFile(p string, length int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p = path.Clean(p)
+	n := f.newDirectory(p, "644")
+	n.status.Type = hdfs.FileTypeFile
+	n.status.Length = length
+	n.status.Replication = 3
+	n.status.BlockSize = 128 << 20
+	f.inodes[p] = n
+}
+
+// Snapshots returns the sorted snapshot names of p
+func (f *NameNode) Snapshots(p string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n, ok := f.inodes[path.Clean(p)]
+	if !ok {
+		return nil
+	}
+	names := []string{}
+	for name := range n.snapshots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f *NameNode) newDirectory(p string, permission string) *inode {
+	f.nextFileID++
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	return &inode{
+		status: hdfs.FileStatus{
+			PathSuffix:       path.Base(p),
+			Type:             hdfs.FileTypeDirectory,
+			Owner:            hdfs.DefaultUser,
+			Group:            "supergroup",
+			Permission:       permission,
+			AccessTime:       0,
+			ModificationTime: now,
+			FileID:           f.nextFileID,
+		},
+		snapshots:      map[string]time.Time{},
+		namespaceQuota: -1,
+		spaceQuota:     -1,
+	}
+}
+
+func (f *NameNode) serveJMX(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	beans := map[string]interface{}{}
+	for name, bean := range map[string]interface{}{
+		hdfs.FSNamesystemBean:      f.fsNamesystem,
+		hdfs.FSNamesystemStateBean: f.fsNamesystemState,
+	} {
+		m, err := toMap(bean)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		m["name"] = name
+		beans[name] = m
+	}
+	info, err := hdfs.EncodeNameNodeInfo(&f.nameNodeInfo)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	beans[hdfs.NameNodeInfoBean] = info
+
+	out := []interface{}{}
+	qry := r.URL.Query().Get("qry")
+	if qry == "" {
+		for _, b := range beans {
+			out = append(out, b)
+		}
+	} else if b, ok := beans[qry]; ok {
+		out = append(out, b)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"beans": out})
+}
+
+func toMap(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]interface{}{}
+	return m, json.Unmarshal(b, &m)
+}
+
+func (f *NameNode) serveWebHDFS(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	p := path.Clean("/" + strings.TrimPrefix(r.URL.Path, "/webhdfs/v1"))
+	q := r.URL.Query()
+	op := strings.ToUpper(q.Get("op"))
+	n, exists := f.inodes[p]
+
+	// a snapshot is read through <dir>/.snapshot/<name>
+	if dir, name, ok := splitSnapshotPath(p); ok && r.Method == "GET" && op == "GETFILESTATUS" {
+		parent, exists := f.inodes[dir]
+		if !exists || !parent.snapshottable {
+			writeNotFound(w, p)
+			return
+		}
+		created, ok := parent.snapshots[name]
+		if !ok {
+			writeNotFound(w, p)
+			return
+		}
+		status := f.statusOf(dir, parent, true)
+		status.ModificationTime = created.UnixNano() / int64(time.Millisecond)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"FileStatus": status})
+		return
+	}
+
+	switch {
+	case r.Method == "GET" && op == "GETFILESTATUS":
+		if !exists {
+			writeNotFound(w, p)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"FileStatus": f.statusOf(p, n, true)})
+	case r.Method == "GET" && op == "LISTSTATUS":
+		if !exists {
+			writeNotFound(w, p)
+			return
+		}
+		statuses := []hdfs.FileStatus{}
+		for _, child := range f.children(p) {
+			statuses = append(statuses, f.statusOf(child, f.inodes[child], false))
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"FileStatuses": map[string]interface{}{"FileStatus": statuses},
+		})
+	case r.Method == "PUT" && op == "MKDIRS":
+		permission := strings.TrimLeft(q.Get("permission"), "0")
+		if permission == "" {
+			permission = "755"
+		}
+		for dir := p; ; dir = path.Dir(dir) {
+			if parent, ok := f.inodes[dir]; ok {
+				if parent.status.Type != hdfs.FileTypeDirectory {
+					writeException(w, http.StatusForbidden, "ParentNotDirectoryException", fmt.Sprintf("%s is not a directory", dir))
+					return
+				}
+				break
+			}
+			f.inodes[dir] = f.newDirectory(dir, permission)
+		}
+		writeJSON(w, http.StatusOK, map[string]bool{"boolean": true})
+	case r.Method == "PUT" && op == "SETOWNER":
+		if !exists {
+			writeNotFound(w, p)
+			return
+		}
+		if owner := q.Get("owner"); owner != "" {
+			n.status.Owner = owner
+		}
+		if group := q.Get("group"); group != "" {
+			n.status.Group = group
+		}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PUT" && op == "SETPERMISSION":
+		if !exists {
+			writeNotFound(w, p)
+			return
+		}
+		if _, err := strconv.ParseUint(q.Get("permission"), 8, 16); err != nil {
+			writeException(w, http.StatusBadRequest, "IllegalArgumentException", fmt.Sprintf("invalid permission %s", q.Get("permission")))
+			return
+		}
+		n.status.Permission = strings.TrimLeft(q.Get("permission"), "0")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "GET" && op == "GETCONTENTSUMMARY":
+		if !exists {
+			writeNotFound(w, p)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ContentSummary": f.contentSummaryOf(p, n)})
+	case r.Method == "PUT" && op == "CREATESNAPSHOT":
+		if !f.checkSnapshottable(w, p, n, exists) {
+			return
+		}
+		name := q.Get("snapshotname")
+		if name == "" {
+			name = time.Now().UTC().Format("s20060102-150405.000")
+		}
+		if _, ok := n.snapshots[name]; ok {
+			writeException(w, http.StatusForbidden, "SnapshotException", fmt.Sprintf("snapshot %s already exists", name))
+			return
+		}
+		n.snapshots[name] = time.Now()
+		writeJSON(w, http.StatusOK, map[string]string{"Path": path.Join(p, ".snapshot", name)})
+	case r.Method == "DELETE" && op == "DELETESNAPSHOT":
+		if !f.checkSnapshottable(w, p, n, exists) {
+			return
+		}
+		name := q.Get("snapshotname")
+		if _, ok := n.snapshots[name]; !ok {
+			writeException(w, http.StatusForbidden, "SnapshotException", fmt.Sprintf("cannot delete snapshot %s from path %s: the snapshot does not exist", name, p))
+			return
+		}
+		delete(n.snapshots, name)
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PUT" && op == "RENAMESNAPSHOT":
+		if !f.checkSnapshottable(w, p, n, exists) {
+			return
+		}
+		oldName, newName := q.Get("oldsnapshotname"), q.Get("snapshotname")
+		created, ok := n.snapshots[oldName]
+		if !ok {
+			writeException(w, http.StatusForbidden, "SnapshotException", fmt.Sprintf("the snapshot %s does not exist", oldName))
+			return
+		}
+		delete(n.snapshots, oldName)
+		n.snapshots[newName] = created
+		w.WriteHeader(http.StatusOK)
+	default:
+		writeException(w, http.StatusBadRequest, "IllegalArgumentException", fmt.Sprintf("invalid op %s for %s", op, r.Method))
+	}
+}
+
+func splitSnapshotPath(p string) (string, string, bool) {
+	i := strings.Index(p, "/.snapshot/")
+	if i < 0 {
+		return "", "", false
+	}
+	dir := p[:i]
+	if dir == "" {
+		dir = "/"
+	}
+	return dir, strings.TrimPrefix(p[i:], "/.snapshot/"), true
+}
+
+func (f *NameNode) checkSnapshottable(w http.ResponseWriter, p string, n *inode, exists bool) bool {
+	if !exists {
+		writeNotFound(w, p)
+		return false
+	}
+	if !n.snapshottable {
+		writeException(w, http.StatusForbidden, "SnapshotException", fmt.Sprintf("directory is not a snapshottable directory: %s", p))
+		return false
+	}
+	return true
+}
+
+func (f *NameNode) children(p string) []string {
+	children := []string{}
+	for child := range f.inodes {
+		if child != "/" && path.Dir(child) == p {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// contentSummaryOf counts p and everything below it
+func (f *NameNode) contentSummaryOf(p string, n *inode) hdfs.ContentSummary {
+	summary := hdfs.ContentSummary{Quota: n.namespaceQuota, SpaceQuota: n.spaceQuota}
+	for child, c := range f.inodes {
+		if child != p && !strings.HasPrefix(child, strings.TrimRight(p, "/")+"/") {
+			continue
+		}
+		if c.status.Type == hdfs.FileTypeDirectory {
+			summary.DirectoryCount++
+			continue
+		}
+		summary.FileCount++
+		summary.Length += c.status.Length
+		summary.SpaceConsumed += c.status.Length * int64(c.status.Replication)
+	}
+	return summary
+}
+
+func (f *NameNode) statusOf(p string, n *inode, self bool) hdfs.FileStatus {
+	status := n.status
+	status.ChildrenNum = int32(len(f.children(p)))
+	status.StoragePolicy = n.storagePolicy
+	if self {
+		status.PathSuffix = ""
+	}
+	return status
+}
+
+func writeNotFound(w http.ResponseWriter, p string) {
+	writeException(w, http.StatusNotFound, "FileNotFoundException", fmt.Sprintf("File does not exist: %s", p))
+}
+
+func writeException(w http.ResponseWriter, code int, exception string, message string) {
+	writeJSON(w, code, map[string]interface{}{
+		"RemoteException": hdfs.RemoteException{
+			Exception:     exception,
+			JavaClassName: "org.apache.hadoop." + exception,
+			Message:       message,
+		},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
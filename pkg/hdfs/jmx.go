@@ -0,0 +1,234 @@
+package hdfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	FSNamesystemBean      = "Hadoop:service=NameNode,name=FSNamesystem"
+	FSNamesystemStateBean = "Hadoop:service=NameNode,name=FSNamesystemState"
+	NameNodeInfoBean      = "Hadoop:service=NameNode,name=NameNodeInfo"
+
+	// FSStateSafeMode is the FSState reported while the name node is in safe mode
+	FSStateSafeMode = "safeMode"
+
+	fsImagePrefix = "fsimage_"
+)
+
+type FSNamesystem struct {
+	HAState                         string `json:"tag.HAState"`
+	CapacityTotal                   int64  `json:"CapacityTotal"`
+	CapacityUsed                    int64  `json:"CapacityUsed"`
+	CapacityRemaining               int64  `json:"CapacityRemaining"`
+	CapacityUsedNonDFS              int64  `json:"CapacityUsedNonDFS"`
+	FilesTotal                      int64  `json:"FilesTotal"`
+	BlocksTotal                     int64  `json:"BlocksTotal"`
+	MissingBlocks                   int64  `json:"MissingBlocks"`
+	CorruptBlocks                   int64  `json:"CorruptBlocks"`
+	UnderReplicatedBlocks           int64  `json:"UnderReplicatedBlocks"`
+	PendingReplicationBlocks        int64  `json:"PendingReplicationBlocks"`
+	StaleDataNodes                  int32  `json:"StaleDataNodes"`
+	LastCheckpointTime              int64  `json:"LastCheckpointTime"`
+	TransactionsSinceLastCheckpoint int64  `json:"TransactionsSinceLastCheckpoint"`
+}
+
+type FSNamesystemState struct {
+	FSState                     string `json:"FSState"`
+	NumLiveDataNodes            int32  `json:"NumLiveDataNodes"`
+	NumDeadDataNodes            int32  `json:"NumDeadDataNodes"`
+	NumStaleDataNodes           int32  `json:"NumStaleDataNodes"`
+	NumDecomLiveDataNodes       int32  `json:"NumDecomLiveDataNodes"`
+	NumDecomDeadDataNodes       int32  `json:"NumDecomDeadDataNodes"`
+	NumDecommissioningDataNodes int32  `json:"NumDecommissioningDataNodes"`
+}
+
+type LiveNode struct {
+	InfoAddr        string `json:"infoAddr"`
+	XferAddr        string `json:"xferaddr"`
+	LastContact     int64  `json:"lastContact"`
+	AdminState      string `json:"adminState"`
+	Version         string `json:"version"`
+	Capacity        int64  `json:"capacity"`
+	Used            int64  `json:"used"`
+	Remaining       int64  `json:"remaining"`
+	NonDfsUsedSpace int64  `json:"nonDfsUsedSpace"`
+	NumBlocks       int64  `json:"numBlocks"`
+	VolFails        int32  `json:"volfails"`
+}
+
+type DeadNode struct {
+	XferAddr       string `json:"xferaddr"`
+	LastContact    int64  `json:"lastContact"`
+	Decommissioned bool   `json:"decommissioned"`
+}
+
+type DecomNode struct {
+	XferAddr                  string `json:"xferaddr"`
+	UnderReplicatedBlocks     int64  `json:"underReplicatedBlocks"`
+	DecommissionOnlyReplicas  int64  `json:"decommissionOnlyReplicas"`
+	UnderReplicateInOpenFiles int64  `json:"underReplicateInOpenFiles"`
+}
+
+type NameNodeInfo struct {
+	Version     string
+	ClusterID   string
+	BlockPoolID string
+	// Safemode is empty when the name node is out of safe mode,
+	// otherwise it holds the safe mode tip
+	Safemode   string
+	Total      int64
+	Used       int64
+	Free       int64
+	LiveNodes  map[string]LiveNode
+	DeadNodes  map[string]DeadNode
+	DecomNodes map[string]DecomNode
+	// MostRecentCheckpointTxID is the transaction id of the newest fsimage
+	MostRecentCheckpointTxID int64
+}
+
+// nameNodeInfoBean is NameNodeInfo on the wire, the node maps are json encoded strings
+type nameNodeInfoBean struct {
+	Version     string `json:"Version"`
+	ClusterID   string `json:"ClusterId"`
+	BlockPoolID string `json:"BlockPoolId"`
+	Safemode    string `json:"Safemode"`
+	Total       int64  `json:"Total"`
+	Used        int64  `json:"Used"`
+	Free        int64  `json:"Free"`
+	LiveNodes   string `json:"LiveNodes"`
+	DeadNodes   string `json:"DeadNodes"`
+	DecomNodes  string `json:"DecomNodes"`
+	// JournalTransactionInfo holds string encoded transaction ids
+	JournalTransactionInfo map[string]string `json:"JournalTransactionInfo"`
+}
+
+func (c *client) getBean(name string, out interface{}) error {
+	u := fmt.Sprintf("%s/jmx?qry=%s", c.baseURL, url.QueryEscape(name))
+	var resp struct {
+		Beans []json.RawMessage `json:"beans"`
+	}
+	if err := c.do("GET", u, &resp); err != nil {
+		return err
+	}
+	if len(resp.Beans) == 0 {
+		return fmt.Errorf("jmx bean %s not found", name)
+	}
+	return json.Unmarshal(resp.Beans[0], out)
+}
+
+func (c *client) GetFSNamesystem() (*FSNamesystem, error) {
+	fs := &FSNamesystem{}
+	if err := c.getBean(FSNamesystemBean, fs); err != nil {
+		return nil, err
+	}
+	return fs, nil
+}
+
+func (c *client) GetFSNamesystemState() (*FSNamesystemState, error) {
+	state := &FSNamesystemState{}
+	if err := c.getBean(FSNamesystemStateBean, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+func (c *client) GetNameNodeInfo() (*NameNodeInfo, error) {
+	bean := &nameNodeInfoBean{}
+	if err := c.getBean(NameNodeInfoBean, bean); err != nil {
+		return nil, err
+	}
+	info := &NameNodeInfo{
+		Version:     bean.Version,
+		ClusterID:   bean.ClusterID,
+		BlockPoolID: bean.BlockPoolID,
+		Safemode:    bean.Safemode,
+		Total:       bean.Total,
+		Used:        bean.Used,
+		Free:        bean.Free,
+	}
+	if txid, ok := bean.JournalTransactionInfo["MostRecentCheckpointTxId"]; ok {
+		info.MostRecentCheckpointTxID, _ = strconv.ParseInt(txid, 10, 64)
+	}
+	if err := unmarshalNodes(bean.LiveNodes, &info.LiveNodes); err != nil {
+		return nil, fmt.Errorf("decode live nodes error, err=%v", err)
+	}
+	if err := unmarshalNodes(bean.DeadNodes, &info.DeadNodes); err != nil {
+		return nil, fmt.Errorf("decode dead nodes error, err=%v", err)
+	}
+	if err := unmarshalNodes(bean.DecomNodes, &info.DecomNodes); err != nil {
+		return nil, fmt.Errorf("decode decommissioning nodes error, err=%v", err)
+	}
+	return info, nil
+}
+
+func unmarshalNodes(s string, out interface{}) error {
+	if s == "" {
+		return nil
+	}
+	return json.Unmarshal([]byte(s), out)
+}
+
+// FsImageName is the file name of the fsimage written at txid
+func FsImageName(txid int64) string {
+	return fmt.Sprintf("%s%019d", fsImagePrefix, txid)
+}
+
+// ParseFsImageName returns the transaction id of an fsimage file name
+func ParseFsImageName(name string) (int64, error) {
+	if !strings.HasPrefix(name, fsImagePrefix) {
+		return 0, fmt.Errorf("%q is not an fsimage", name)
+	}
+	return strconv.ParseInt(strings.TrimPrefix(name, fsImagePrefix), 10, 64)
+}
+
+func (c *client) IsInSafeMode() (bool, error) {
+	state, err := c.GetFSNamesystemState()
+	if err != nil {
+		return false, err
+	}
+	return state.FSState == FSStateSafeMode, nil
+}
+
+// EncodeNameNodeInfo returns the NameNodeInfo bean as the name node serves it
+func EncodeNameNodeInfo(info *NameNodeInfo) (map[string]interface{}, error) {
+	bean := nameNodeInfoBean{
+		Version:     info.Version,
+		ClusterID:   info.ClusterID,
+		BlockPoolID: info.BlockPoolID,
+		Safemode:    info.Safemode,
+		Total:       info.Total,
+		Used:        info.Used,
+		Free:        info.Free,
+		JournalTransactionInfo: map[string]string{
+			"MostRecentCheckpointTxId": strconv.FormatInt(info.MostRecentCheckpointTxID, 10),
+		},
+	}
+	for _, n := range []struct {
+		src interface{}
+		dst *string
+	}{
+		{info.LiveNodes, &bean.LiveNodes},
+		{info.DeadNodes, &bean.DeadNodes},
+		{info.DecomNodes, &bean.DecomNodes},
+	} {
+		b, err := json.Marshal(n.src)
+		if err != nil {
+			return nil, err
+		}
+		*n.dst = string(b)
+	}
+	b, err := json.Marshal(bean)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]interface{}{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+	out["name"] = NameNodeInfoBean
+	return out, nil
+}
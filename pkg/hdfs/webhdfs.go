@@ -0,0 +1,162 @@
+package hdfs
+
+import (
+	"fmt"
+	"net/url"
+)
+
+const (
+	FileTypeFile      = "FILE"
+	FileTypeDirectory = "DIRECTORY"
+	FileTypeSymlink   = "SYMLINK"
+)
+
+// FileStatus is the WebHDFS FileStatus json object
+type FileStatus struct {
+	PathSuffix       string `json:"pathSuffix"`
+	Type             string `json:"type"`
+	Length           int64  `json:"length"`
+	Owner            string `json:"owner"`
+	Group            string `json:"group"`
+	Permission       string `json:"permission"`
+	AccessTime       int64  `json:"accessTime"`
+	ModificationTime int64  `json:"modificationTime"`
+	BlockSize        int64  `json:"blockSize"`
+	Replication      int32  `json:"replication"`
+	ChildrenNum      int32  `json:"childrenNum"`
+	FileID           int64  `json:"fileId"`
+	StoragePolicy    int32  `json:"storagePolicy"`
+}
+
+func (c *client) ListStatus(path string) ([]FileStatus, error) {
+	var resp struct {
+		FileStatuses struct {
+			FileStatus []FileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}
+	if err := c.do("GET", c.webhdfsURL(path, "LISTSTATUS", nil), &resp); err != nil {
+		return nil, err
+	}
+	return resp.FileStatuses.FileStatus, nil
+}
+
+func (c *client) GetFileStatus(path string) (*FileStatus, error) {
+	var resp struct {
+		FileStatus *FileStatus `json:"FileStatus"`
+	}
+	if err := c.do("GET", c.webhdfsURL(path, "GETFILESTATUS", nil), &resp); err != nil {
+		return nil, err
+	}
+	if resp.FileStatus == nil {
+		return nil, fmt.Errorf("empty file status of %s", path)
+	}
+	return resp.FileStatus, nil
+}
+
+// Mkdirs creates path and its missing parents, permission is octal and
+// falls back to the name node default when empty
+func (c *client) Mkdirs(path string, permission string) error {
+	params := url.Values{}
+	if permission != "" {
+		params.Set("permission", permission)
+	}
+	var resp struct {
+		Boolean bool `json:"boolean"`
+	}
+	if err := c.do("PUT", c.webhdfsURL(path, "MKDIRS", params), &resp); err != nil {
+		return err
+	}
+	if !resp.Boolean {
+		return fmt.Errorf("mkdirs %s returned false", path)
+	}
+	return nil
+}
+
+// CreateSnapshot creates a snapshot of path and returns the snapshot path
+func (c *client) CreateSnapshot(path string, name string) (string, error) {
+	params := url.Values{}
+	params.Set("snapshotname", name)
+	var resp struct {
+		Path string `json:"Path"`
+	}
+	if err := c.do("PUT", c.webhdfsURL(path, "CREATESNAPSHOT", params), &resp); err != nil {
+		return "", err
+	}
+	return resp.Path, nil
+}
+
+func (c *client) DeleteSnapshot(path string, name string) error {
+	params := url.Values{}
+	params.Set("snapshotname", name)
+	return c.do("DELETE", c.webhdfsURL(path, "DELETESNAPSHOT", params), nil)
+}
+
+func (c *client) RenameSnapshot(path string, oldName string, newName string) error {
+	params := url.Values{}
+	params.Set("oldsnapshotname", oldName)
+	params.Set("snapshotname", newName)
+	return c.do("PUT", c.webhdfsURL(path, "RENAMESNAPSHOT", params), nil)
+}
+
+// SetOwner changes the owner and group of path, an empty value is left unchanged
+func (c *client) SetOwner(path string, owner string, group string) error {
+	params := url.Values{}
+	if owner != "" {
+		params.Set("owner", owner)
+	}
+	if group != "" {
+		params.Set("group", group)
+	}
+	return c.do("PUT", c.webhdfsURL(path, "SETOWNER", params), nil)
+}
+
+// SetPermission sets the octal permission of path, e.g. 750
+func (c *client) SetPermission(path string, permission string) error {
+	params := url.Values{}
+	params.Set("permission", permission)
+	return c.do("PUT", c.webhdfsURL(path, "SETPERMISSION", params), nil)
+}
+
+// ContentSummary is the WebHDFS ContentSummary json object, quotas are -1 when unset
+type ContentSummary struct {
+	DirectoryCount int64 `json:"directoryCount"`
+	FileCount      int64 `json:"fileCount"`
+	Length         int64 `json:"length"`
+	Quota          int64 `json:"quota"`
+	SpaceConsumed  int64 `json:"spaceConsumed"`
+	SpaceQuota     int64 `json:"spaceQuota"`
+}
+
+func (c *client) GetContentSummary(path string) (*ContentSummary, error) {
+	var resp struct {
+		ContentSummary *ContentSummary `json:"ContentSummary"`
+	}
+	if err := c.do("GET", c.webhdfsURL(path, "GETCONTENTSUMMARY", nil), &resp); err != nil {
+		return nil, err
+	}
+	if resp.ContentSummary == nil {
+		return nil, fmt.Errorf("empty content summary of %s", path)
+	}
+	return resp.ContentSummary, nil
+}
+
+// storage policies built into hdfs
+const (
+	StoragePolicyHot         = "HOT"
+	StoragePolicyWarm        = "WARM"
+	StoragePolicyCold        = "COLD"
+	StoragePolicyAllSSD      = "ALL_SSD"
+	StoragePolicyOneSSD      = "ONE_SSD"
+	StoragePolicyLazyPersist = "LAZY_PERSIST"
+)
+
+// StoragePolicyIDs map the built in storage policies to the storagePolicy
+// of a FileStatus, which is 0 when the policy is inherited
+var StoragePolicyIDs = map[string]int32{
+	StoragePolicyHot:         7,
+	StoragePolicyWarm:        5,
+	StoragePolicyCold:        2,
+	StoragePolicyAllSSD:      12,
+	StoragePolicyOneSSD:      10,
+	StoragePolicyLazyPersist: 15,
+}
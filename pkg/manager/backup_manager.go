@@ -0,0 +1,342 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/cron"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultBackupRetention = 7
+	backupNameFormat       = "20060102-150405"
+	pvcBackupDir           = "/backup"
+	backupVersionFile      = "VERSION"
+
+	// backupNameGlob matches the directories named by backupNameFormat only,
+	// lost+found and anything else on the pvc is neither counted nor pruned
+	backupNameGlob = "[0-9][0-9][0-9][0-9][0-9][0-9][0-9][0-9]-[0-9][0-9][0-9][0-9][0-9][0-9]"
+
+	// backupJobDeadline fails a hanging backup job so the next schedule runs
+	backupJobDeadline = 2 * time.Hour
+
+	s3AccessKey = "access_key"
+	s3SecretKey = "secret_key"
+)
+
+// backupManager copies the latest name node checkpoint on the backup schedule,
+// a job runs `hdfs dfsadmin -fetchImage` onto the pvc target or uploads the
+// fsimage to the s3 target. The operator only lists and prunes the s3 backups
+type backupManager struct {
+	jobControl    controller.JobControlInterface
+	secretControl controller.SecretControlInterface
+	eventControl  controller.EventControlInterface
+	getClient     hdfs.ClientGetter
+	newS3Client   func(s3.Config) s3.Interface
+}
+
+func NewBackupManager(
+	jobControl controller.JobControlInterface,
+	secretControl controller.SecretControlInterface,
+	eventControl controller.EventControlInterface,
+	getClient hdfs.ClientGetter,
+	newS3Client func(s3.Config) s3.Interface,
+) Manager {
+	return &backupManager{
+		jobControl:    jobControl,
+		secretControl: secretControl,
+		eventControl:  eventControl,
+		getClient:     getClient,
+		newS3Client:   newS3Client,
+	}
+}
+
+func (bm *backupManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	spec := hc.Spec.Backup
+	if spec == nil {
+		return nil
+	}
+	if hc.Status.Backup == nil {
+		hc.Status.Backup = &v1alpha1.BackupStatus{}
+	}
+	status := hc.Status.Backup
+	schedule, err := cron.Parse(spec.Schedule)
+	if err != nil {
+		status.LastError = fmt.Sprintf("invalid schedule: %v", err)
+		return nil
+	}
+	if status.LastScheduleTime != nil {
+		done, err := bm.checkBackupJob(hc, status.LastScheduleTime.Time)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	last := hc.CreationTimestamp.Time
+	if status.LastScheduleTime != nil {
+		last = status.LastScheduleTime.Time
+	}
+	scheduled, ok := schedule.Missed(last, time.Now().UTC())
+	if !ok {
+		return nil
+	}
+	t := metav1.NewTime(scheduled)
+	status.LastScheduleTime = &t
+	status.LastError = ""
+	var job *batchv1.Job
+	if job, err = bm.newBackupJob(hc, scheduled); err == nil {
+		err = bm.jobControl.CreateJob(hc, job)
+	}
+	if err == nil {
+		glog.Infof("backup job of %s/%s is created", hc.Namespace, hc.Name)
+	}
+	if err != nil {
+		// a failed backup is not retried, it waits for the next schedule
+		bm.backupFailed(hc, err.Error())
+	}
+	return nil
+}
+
+func (bm *backupManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	if st := hc.Status.Backup; st != nil && st.LastError != "" {
+		return fmt.Errorf("last backup failed: %s", st.LastError)
+	}
+	return nil
+}
+
+// syncS3Backups lists the backups under the prefix of the target, records them
+// in the status and deletes the ones beyond retention. The backup name was
+// just uploaded by a job, it gets the ids of the name node
+func (bm *backupManager) syncS3Backups(hc *v1alpha1.HdfsCluster, name string, info *hdfs.NameNodeInfo) (string, error) {
+	target := hc.Spec.Backup.Target.S3
+	store, err := newS3Store(bm.secretControl, bm.newS3Client, hc, target)
+	if err != nil {
+		return "", err
+	}
+	prefix := s3BackupPrefix(hc.Namespace, hc.Name, target)
+	objects, err := store.ListObjects(prefix + "/")
+	if err != nil {
+		return "", fmt.Errorf("list backups error, err=%v", err)
+	}
+	// the ids are only known for the backups this operator listed before
+	known := map[string]v1alpha1.BackupRecord{}
+	for _, r := range hc.Status.Backup.Backups {
+		known[r.Name] = r
+	}
+	records := []v1alpha1.BackupRecord{}
+	location := ""
+	for _, o := range objects {
+		dir, file := path.Split(strings.TrimPrefix(o.Key, prefix+"/"))
+		txid, err := hdfs.ParseFsImageName(file)
+		if err != nil {
+			continue
+		}
+		record := v1alpha1.BackupRecord{
+			Name:           strings.TrimSuffix(dir, "/"),
+			Location:       fmt.Sprintf("s3://%s/%s", target.Bucket, o.Key),
+			TxID:           txid,
+			Size:           o.Size,
+			CompletionTime: metav1.NewTime(o.LastModified),
+			ClusterID:      known[strings.TrimSuffix(dir, "/")].ClusterID,
+			BlockPoolID:    known[strings.TrimSuffix(dir, "/")].BlockPoolID,
+		}
+		if record.Name == name {
+			location = record.Location
+			if info != nil {
+				record.ClusterID, record.BlockPoolID = info.ClusterID, info.BlockPoolID
+			}
+		}
+		records = append(records, record)
+	}
+	if location == "" {
+		return "", fmt.Errorf("backup %s is not found under s3://%s/%s", name, target.Bucket, prefix)
+	}
+	kept, expired := retainBackups(records, backupRetention(hc))
+	for _, r := range expired {
+		dir := path.Join(prefix, r.Name) + "/"
+		for _, o := range objects {
+			if !strings.HasPrefix(o.Key, dir) {
+				continue
+			}
+			if err := store.DeleteObject(o.Key); err != nil {
+				return "", fmt.Errorf("delete expired backup %s error, err=%v", o.Key, err)
+			}
+		}
+		glog.Infof("backup of %s/%s: expired backup %s is deleted", hc.Namespace, hc.Name, r.Location)
+	}
+	hc.Status.Backup.Backups = kept
+	return location, nil
+}
+
+func (bm *backupManager) newBackupJob(hc *v1alpha1.HdfsCluster, scheduled time.Time) (*batchv1.Job, error) {
+	info, err := bm.getClient(hc).GetNameNodeInfo()
+	if err != nil {
+		return nil, fmt.Errorf("get name node info error, err=%v", err)
+	}
+	target := hc.Spec.Backup.Target
+	name := scheduled.Format(backupNameFormat)
+	version := backupVersion(info.ClusterID, info.BlockPoolID)
+	var script string
+	switch {
+	case target.S3 != nil:
+		// the fsimage is fetched into an empty dir and streamed from there
+		dir := path.Join(s3BackupPrefix(hc.Namespace, hc.Name, target.S3), name)
+		script = s3Functions + fmt.Sprintf("set -e\ncd %s\nhdfs dfsadmin -fetchImage .\n"+
+			"image=$(ls fsimage_* | grep -v '\\.md5$' | head -n 1)\nprintf '%%s' '%s' > %s\n"+
+			"s3_put $image %s/$image\ns3_put %s %s\necho \"$image is uploaded to s3://%s/%s\"",
+			pvcBackupDir, version, backupVersionFile,
+			s3ObjectPath(target.S3.Bucket, dir), backupVersionFile, s3ObjectPath(target.S3.Bucket, path.Join(dir, backupVersionFile)),
+			target.S3.Bucket, dir)
+	case target.PVC != nil:
+		dir := path.Join(pvcBackupDir, name)
+		// the names sort by time, so everything after the first retention
+		// directories in reverse order is expired
+		script = fmt.Sprintf("mkdir -p %s && hdfs dfsadmin -fetchImage %s && printf '%%s' '%s' > %s && "+
+			"ls -1d %s/%s/ | sort -r | tail -n +%d | xargs -r rm -rf",
+			dir, dir, version, path.Join(dir, backupVersionFile), pvcBackupDir, backupNameGlob, backupRetention(hc)+1)
+	default:
+		return nil, fmt.Errorf("backup target has neither s3 nor pvc")
+	}
+	job := NewHadoopJob(hc, controller.ScheduledBackupJobName(hc.Name, scheduled), SummarizedScript(script, false))
+	deadline := int64(backupJobDeadline / time.Second)
+	job.Spec.ActiveDeadlineSeconds = &deadline
+	if target.S3 != nil {
+		spec := &job.Spec.Template.Spec
+		spec.Volumes = append(spec.Volumes, corev1.Volume{
+			Name:         "backup",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
+		spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+			Name:      "backup",
+			MountPath: pvcBackupDir,
+		})
+		spec.Containers[0].Env = append(spec.Containers[0].Env, s3Env(target.S3)...)
+	} else {
+		mountPVC(job, target.PVC.ClaimName, pvcBackupDir)
+	}
+	return job, nil
+}
+
+// checkBackupJob records the backup of a finished job and deletes the job,
+// done is false while the job is running
+func (bm *backupManager) checkBackupJob(hc *v1alpha1.HdfsCluster, scheduled time.Time) (bool, error) {
+	job, err := bm.jobControl.GetJob(hc, controller.ScheduledBackupJobName(hc.Name, scheduled))
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return false, nil
+	}
+	if result == batchv1.JobFailed {
+		bm.backupFailed(hc, JobFailureMessage(job))
+		return true, bm.jobControl.DeleteJob(hc, job)
+	}
+	name := scheduled.Format(backupNameFormat)
+	info, err := bm.getClient(hc).GetNameNodeInfo()
+	if err != nil {
+		glog.Errorf("get name node info of %s/%s error, err=%v", hc.Namespace, hc.Name, err)
+		info = nil
+	}
+	var location string
+	if hc.Spec.Backup.Target.S3 != nil {
+		if location, err = bm.syncS3Backups(hc, name, info); err != nil {
+			bm.backupFailed(hc, err.Error())
+			return true, bm.jobControl.DeleteJob(hc, job)
+		}
+	} else {
+		location = fmt.Sprintf("pvc %s at %s", hc.Spec.Backup.Target.PVC.ClaimName, path.Join(pvcBackupDir, name))
+		record := v1alpha1.BackupRecord{
+			Name:           name,
+			Location:       location,
+			CompletionTime: metav1.Now(),
+		}
+		if job.Status.CompletionTime != nil {
+			record.CompletionTime = *job.Status.CompletionTime
+		}
+		if info != nil {
+			record.TxID = info.MostRecentCheckpointTxID
+			record.ClusterID, record.BlockPoolID = info.ClusterID, info.BlockPoolID
+		}
+		records := append([]v1alpha1.BackupRecord{record}, hc.Status.Backup.Backups...)
+		hc.Status.Backup.Backups, _ = retainBackups(records, backupRetention(hc))
+	}
+	msg := fmt.Sprintf("fsimage is backed up to %s", location)
+	glog.Infof("backup of %s/%s: %s", hc.Namespace, hc.Name, msg)
+	bm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "BackupCompleted", msg)
+	return true, bm.jobControl.DeleteJob(hc, job)
+}
+
+func (bm *backupManager) backupFailed(hc *v1alpha1.HdfsCluster, msg string) {
+	hc.Status.Backup.LastError = msg
+	glog.Errorf("backup of %s/%s error, %s", hc.Namespace, hc.Name, msg)
+	bm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "BackupFailed", msg)
+}
+
+// retainBackups sorts records newest first and splits off the ones beyond retention
+func retainBackups(records []v1alpha1.BackupRecord, retention int) ([]v1alpha1.BackupRecord, []v1alpha1.BackupRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name > records[j].Name
+		}
+		return records[i].TxID > records[j].TxID
+	})
+	if len(records) <= retention {
+		return records, nil
+	}
+	return records[:retention], records[retention:]
+}
+
+func backupRetention(hc *v1alpha1.HdfsCluster) int {
+	if r := hc.Spec.Backup.Retention; r > 0 {
+		return int(r)
+	}
+	return defaultBackupRetention
+}
+
+// s3BackupPrefix is the key prefix of the backups of cluster name
+func s3BackupPrefix(namespace, name string, target *v1alpha1.S3BackupTarget) string {
+	if p := strings.Trim(target.Prefix, "/"); p != "" {
+		return p
+	}
+	return path.Join(namespace, name)
+}
+
+func newS3Store(
+	secretControl controller.SecretControlInterface,
+	newS3Client func(s3.Config) s3.Interface,
+	hc *v1alpha1.HdfsCluster,
+	target *v1alpha1.S3BackupTarget,
+) (s3.Interface, error) {
+	secret, err := secretControl.GetSecret(hc, target.SecretName)
+	if err != nil {
+		return nil, fmt.Errorf("get secret %s error, err=%v", target.SecretName, err)
+	}
+	return newS3Client(s3.Config{
+		Endpoint:  target.Endpoint,
+		Region:    target.Region,
+		Bucket:    target.Bucket,
+		AccessKey: string(secret.Data[s3AccessKey]),
+		SecretKey: string(secret.Data[s3SecretKey]),
+	}), nil
+}
+
+// backupVersion is stored next to the fsimage, it holds the ids the restore
+// has to write into the VERSION file of the new name node
+func backupVersion(clusterID, blockPoolID string) string {
+	return fmt.Sprintf("clusterID=%s\nblockpoolID=%s\n", clusterID, blockPoolID)
+}
@@ -0,0 +1,325 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	hdfsfake "github.com/tommenx/hdfs-operator/pkg/hdfs/fake"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	s3fake "github.com/tommenx/hdfs-operator/pkg/s3/fake"
+	"io/ioutil"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeJobControl struct {
+	jobs map[string]*batchv1.Job
+}
+
+func (c *fakeJobControl) CreateJob(hc *v1alpha1.HdfsCluster, job *batchv1.Job) error {
+	c.jobs[job.Name] = job
+	return nil
+}
+
+func (c *fakeJobControl) GetJob(hc *v1alpha1.HdfsCluster, name string) (*batchv1.Job, error) {
+	job, ok := c.jobs[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "batch", Resource: "jobs"}, name)
+	}
+	return job, nil
+}
+
+func (c *fakeJobControl) DeleteJob(hc *v1alpha1.HdfsCluster, job *batchv1.Job) error {
+	delete(c.jobs, job.Name)
+	return nil
+}
+
+type fakeSecretControl struct {
+	secrets map[string]*corev1.Secret
+}
+
+func (c *fakeSecretControl) GetSecret(hc *v1alpha1.HdfsCluster, name string) (*corev1.Secret, error) {
+	secret, ok := c.secrets[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Resource: "secrets"}, name)
+	}
+	return secret, nil
+}
+
+func (c *fakeSecretControl) CreateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error {
+	c.secrets[secret.Name] = secret
+	return nil
+}
+
+func (c *fakeSecretControl) UpdateSecret(hc *v1alpha1.HdfsCluster, secret *corev1.Secret) error {
+	c.secrets[secret.Name] = secret
+	return nil
+}
+
+type fakeEventControl struct {
+	reasons []string
+}
+
+func (c *fakeEventControl) RecordEvent(hc *v1alpha1.HdfsCluster, eventType, reason, message string) {
+	c.reasons = append(c.reasons, reason)
+}
+
+type backupTest struct {
+	hc      *v1alpha1.HdfsCluster
+	nn      *hdfsfake.NameNode
+	s3      *s3fake.Server
+	jobs    *fakeJobControl
+	events  *fakeEventControl
+	manager Manager
+}
+
+func newBackupTest(target v1alpha1.BackupTarget) *backupTest {
+	bt := &backupTest{
+		nn:     hdfsfake.NewNameNode(),
+		s3:     s3fake.NewServer("backup"),
+		jobs:   &fakeJobControl{jobs: map[string]*batchv1.Job{}},
+		events: &fakeEventControl{},
+	}
+	bt.nn.SetNameNodeInfo(hdfs.NameNodeInfo{ClusterID: "CID-1", BlockPoolID: "BP-1"})
+	bt.nn.SetCheckpointTxID(42)
+	if target.S3 != nil {
+		target.S3.Endpoint = bt.s3.URL()
+	}
+	bt.hc = &v1alpha1.HdfsCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "demo",
+			Namespace:         "ns",
+			CreationTimestamp: metav1.NewTime(time.Now().AddDate(-2, 0, 0)),
+		},
+		Spec: v1alpha1.HdfsClusterSpec{
+			Backup: &v1alpha1.BackupSpec{Schedule: "0 0 1 1 *", Retention: 2, Target: target},
+		},
+	}
+	secrets := &fakeSecretControl{secrets: map[string]*corev1.Secret{
+		"s3": {Data: map[string][]byte{s3AccessKey: []byte(s3fake.AccessKey), s3SecretKey: []byte(s3fake.SecretKey)}},
+	}}
+	bt.manager = NewBackupManager(bt.jobs, secrets, bt.events,
+		func(*v1alpha1.HdfsCluster) hdfs.Interface { return bt.nn.Client() }, s3.NewClient)
+	return bt
+}
+
+func (bt *backupTest) close() {
+	bt.nn.Close()
+	bt.s3.Close()
+}
+
+// scheduledJob syncs once and returns the backup job it created
+func (bt *backupTest) scheduledJob(t *testing.T) *batchv1.Job {
+	if err := bt.manager.Sync(bt.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	status := bt.hc.Status.Backup
+	if status == nil || status.LastScheduleTime == nil || status.LastError != "" {
+		t.Fatalf("unexpected backup status %+v", status)
+	}
+	job, ok := bt.jobs.jobs[controller.ScheduledBackupJobName(bt.hc.Name, status.LastScheduleTime.Time)]
+	if !ok {
+		t.Fatalf("no backup job is created")
+	}
+	if job.Spec.ActiveDeadlineSeconds == nil {
+		t.Errorf("the backup job has no deadline")
+	}
+	return job
+}
+
+func finishJob(job *batchv1.Job, result batchv1.JobConditionType) {
+	job.Status.Conditions = append(job.Status.Conditions, batchv1.JobCondition{Type: result, Status: corev1.ConditionTrue})
+}
+
+func TestBackupToS3(t *testing.T) {
+	bt := newBackupTest(v1alpha1.BackupTarget{S3: &v1alpha1.S3BackupTarget{Bucket: "backup", SecretName: "s3"}})
+	defer bt.close()
+
+	job := bt.scheduledJob(t)
+	container := job.Spec.Template.Spec.Containers[0]
+	env := map[string]corev1.EnvVar{}
+	for _, e := range container.Env {
+		env[e.Name] = e
+	}
+	if env["S3_ENDPOINT"].Value != bt.s3.URL() || env["S3_REGION"].Value != s3.DefaultRegion {
+		t.Errorf("unexpected s3 env %+v", container.Env)
+	}
+	for _, name := range []string{"S3_ACCESS_KEY", "S3_SECRET_KEY"} {
+		if e := env[name]; e.Value != "" || e.ValueFrom == nil || e.ValueFrom.SecretKeyRef == nil || e.ValueFrom.SecretKeyRef.Name != "s3" {
+			t.Errorf("%s is not read from the secret, %+v", name, e)
+		}
+	}
+	name := bt.hc.Status.Backup.LastScheduleTime.Time.Format(backupNameFormat)
+	if script := container.Args[2]; !strings.Contains(script, "s3_put $image /backup/ns/demo/"+name+"/$image") {
+		t.Errorf("the job does not upload the fsimage, script:\n%s", script)
+	}
+
+	// the job is running
+	if err := bt.manager.Sync(bt.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	if len(bt.jobs.jobs) != 1 || len(bt.hc.Status.Backup.Backups) != 0 {
+		t.Fatalf("the running job is not waited for")
+	}
+
+	// what the job uploaded, next to two older backups
+	store := bt.s3.Client("backup")
+	for _, key := range []string{
+		"ns/demo/20170101-000000/fsimage_0000000000000000010", "ns/demo/20170101-000000/VERSION",
+		"ns/demo/20180101-000000/fsimage_0000000000000000020", "ns/demo/20180101-000000/VERSION",
+		"ns/demo/" + name + "/fsimage_0000000000000000042", "ns/demo/" + name + "/VERSION",
+	} {
+		store.PutObject(key, strings.NewReader("x"), 1)
+	}
+	finishJob(job, batchv1.JobComplete)
+	if err := bt.manager.Sync(bt.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	if len(bt.jobs.jobs) != 0 {
+		t.Errorf("the finished job is not deleted")
+	}
+	backups := bt.hc.Status.Backup.Backups
+	if len(backups) != 2 || backups[0].Name != name || backups[1].Name != "20180101-000000" {
+		t.Fatalf("unexpected backups %+v", backups)
+	}
+	if b := backups[0]; b.TxID != 42 || b.ClusterID != "CID-1" || b.BlockPoolID != "BP-1" ||
+		b.Location != "s3://backup/ns/demo/"+name+"/fsimage_0000000000000000042" {
+		t.Errorf("unexpected backup %+v", b)
+	}
+	if keys := bt.s3.Keys("backup"); len(keys) != 4 || strings.HasPrefix(keys[0], "ns/demo/2017") {
+		t.Errorf("the expired backup is not deleted, keys %v", keys)
+	}
+	if len(bt.events.reasons) != 1 || bt.events.reasons[0] != "BackupCompleted" {
+		t.Errorf("got events %v", bt.events.reasons)
+	}
+	if err := bt.manager.CheckStatus(bt.hc); err != nil {
+		t.Errorf("check status error, err=%v", err)
+	}
+}
+
+func TestBackupToPVC(t *testing.T) {
+	bt := newBackupTest(v1alpha1.BackupTarget{PVC: &v1alpha1.PVCBackupTarget{ClaimName: "backup"}})
+	defer bt.close()
+
+	job := bt.scheduledJob(t)
+	spec := job.Spec.Template.Spec
+	if len(spec.Volumes) == 0 || spec.Volumes[len(spec.Volumes)-1].PersistentVolumeClaim == nil ||
+		spec.Volumes[len(spec.Volumes)-1].PersistentVolumeClaim.ClaimName != "backup" {
+		t.Errorf("the backup pvc is not mounted, volumes %+v", spec.Volumes)
+	}
+	finishJob(job, batchv1.JobComplete)
+	if err := bt.manager.Sync(bt.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	backups := bt.hc.Status.Backup.Backups
+	if len(backups) != 1 || backups[0].TxID != 42 || backups[0].ClusterID != "CID-1" {
+		t.Errorf("unexpected backups %+v", backups)
+	}
+}
+
+// TestPrunePVCBackups runs the prune command of a pvc backup job, only the
+// directories named like backups count towards the retention
+func TestPrunePVCBackups(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash is not installed")
+	}
+	bt := newBackupTest(v1alpha1.BackupTarget{PVC: &v1alpha1.PVCBackupTarget{ClaimName: "backup"}})
+	defer bt.close()
+	dir, err := ioutil.TempDir("", "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{"lost+found", "20200101-000000", "20200102-000000", "20200103-000000", "old"} {
+		os.Mkdir(filepath.Join(dir, name), 0755)
+	}
+
+	script := bt.scheduledJob(t).Spec.Template.Spec.Containers[0].Args[2]
+	i := strings.Index(script, "ls -1d ")
+	j := strings.Index(script, " ) 2>&1")
+	if i < 0 || j < i {
+		t.Fatalf("no prune command in the script:\n%s", script)
+	}
+	prune := strings.Replace(script[i:j], pvcBackupDir+"/", dir+"/", 1)
+	if out, err := exec.Command("bash", "-c", prune).CombinedOutput(); err != nil {
+		t.Fatalf("run %q error, err=%v, output:\n%s", prune, err, out)
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var left []string
+	for _, info := range infos {
+		left = append(left, info.Name())
+	}
+	if want := "20200102-000000 20200103-000000 lost+found old"; strings.Join(left, " ") != want {
+		t.Errorf("got %v left, want %s", left, want)
+	}
+}
+
+func TestBackupJobFailed(t *testing.T) {
+	bt := newBackupTest(v1alpha1.BackupTarget{S3: &v1alpha1.S3BackupTarget{Bucket: "backup", SecretName: "s3"}})
+	defer bt.close()
+
+	finishJob(bt.scheduledJob(t), batchv1.JobFailed)
+	if err := bt.manager.Sync(bt.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	if len(bt.jobs.jobs) != 0 {
+		t.Errorf("the failed job is not deleted")
+	}
+	if bt.hc.Status.Backup.LastError == "" || bt.manager.CheckStatus(bt.hc) == nil {
+		t.Errorf("the failed backup is not reported, status %+v", bt.hc.Status.Backup)
+	}
+}
+
+// TestS3Functions runs the shell functions of the backup and restore jobs
+// against the fake store
+func TestS3Functions(t *testing.T) {
+	for _, cmd := range []string{"bash", "curl", "openssl"} {
+		if _, err := exec.LookPath(cmd); err != nil {
+			t.Skipf("%s is not installed", cmd)
+		}
+	}
+	srv := s3fake.NewServer("backup")
+	defer srv.Close()
+	dir, err := ioutil.TempDir("", "s3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ioutil.WriteFile(filepath.Join(dir, "fsimage"), []byte("image"), 0644)
+
+	p := s3ObjectPath("backup", "ns/a b/fsimage_1")
+	script := s3Functions + "set -e\ns3_put fsimage " + p + "\ns3_get " + p + " copy\n"
+	cmd := exec.Command("bash", "-c", script)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "S3_ENDPOINT="+srv.URL(), "S3_REGION="+s3.DefaultRegion,
+		"S3_ACCESS_KEY="+s3fake.AccessKey, "S3_SECRET_KEY="+s3fake.SecretKey)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("run s3 functions error, err=%v, output:\n%s", err, out)
+	}
+	if data, ok := srv.Object("backup", "ns/a b/fsimage_1"); !ok || string(data) != "image" {
+		t.Errorf("got uploaded object %q, want image", data)
+	}
+	if data, _ := ioutil.ReadFile(filepath.Join(dir, "copy")); string(data) != "image" {
+		t.Errorf("got downloaded file %q, want image", data)
+	}
+
+	cmd = exec.Command("bash", "-c", s3Functions+"s3_get "+p+" copy")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "S3_ENDPOINT="+srv.URL(), "S3_REGION="+s3.DefaultRegion,
+		"S3_ACCESS_KEY="+s3fake.AccessKey, "S3_SECRET_KEY=wrong")
+	if err := cmd.Run(); err == nil {
+		t.Errorf("expected an error for a wrong secret key")
+	}
+}
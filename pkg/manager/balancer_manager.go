@@ -0,0 +1,199 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/cron"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	// DefaultBalancerThreshold is the threshold of `hdfs balancer` in percent
+	DefaultBalancerThreshold = 10
+
+	balancerTriggerSchedule = "Schedule"
+	balancerTriggerScaleOut = "ScaleOut"
+	// balancerJobMissingTimeout is how long a created job may be missing from the lister
+	balancerJobMissingTimeout = time.Minute
+)
+
+// balancerManager runs the balancer job on the schedule and once the data
+// nodes added by a scale out are live. The job shares its name with the
+// exclusive HdfsOperations, so at most one of them runs per cluster
+type balancerManager struct {
+	jobControl   controller.JobControlInterface
+	podControl   controller.PodControlInterface
+	eventControl controller.EventControlInterface
+}
+
+func NewBalancerManager(
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &balancerManager{
+		jobControl:   jobControl,
+		podControl:   podControl,
+		eventControl: eventControl,
+	}
+}
+
+func (bm *balancerManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	spec := hc.Spec.Balancer
+	enabled := spec != nil && spec.Enabled
+	if st := hc.Status.Balancer; st != nil && st.JobName != "" {
+		done, err := bm.checkBalancerJob(hc, !enabled)
+		if err != nil || !done {
+			return err
+		}
+	}
+	if !enabled {
+		return nil
+	}
+	if hc.Status.Balancer == nil {
+		hc.Status.Balancer = &v1alpha1.BalancerStatus{}
+	}
+	status := hc.Status.Balancer
+	now := time.Now().UTC()
+
+	var trigger string
+	var scheduled time.Time
+	if spec.Schedule != "" {
+		schedule, err := cron.Parse(spec.Schedule)
+		if err != nil {
+			status.Message = fmt.Sprintf("invalid schedule: %v", err)
+			return nil
+		}
+		last := hc.CreationTimestamp.Time
+		if status.LastScheduleTime != nil {
+			last = status.LastScheduleTime.Time
+		}
+		if t, ok := schedule.Missed(last, now); ok {
+			trigger, scheduled = balancerTriggerSchedule, t
+		}
+	}
+	var live int32
+	if hc.Status.Hdfs != nil {
+		live = hc.Status.Hdfs.LiveDataNodes
+	}
+	if live > status.ObservedDataNodes && status.ObservedDataNodes > 0 && spec.RunAfterScaleOut {
+		// wait until every added data node has registered
+		if trigger == "" && live >= hc.Spec.DataNode.Replicas {
+			trigger = balancerTriggerScaleOut
+		}
+	} else if live > 0 {
+		status.ObservedDataNodes = live
+	}
+	if trigger == "" {
+		return nil
+	}
+
+	job := bm.newBalancerJob(hc, spec)
+	err := bm.jobControl.CreateJob(hc, job)
+	if errors.IsAlreadyExists(err) {
+		existing, getErr := bm.jobControl.GetJob(hc, job.Name)
+		if getErr != nil || !metav1.IsControlledBy(existing, hc) {
+			// the run stays due and is retried on the next sync
+			status.Message = fmt.Sprintf("waiting for the exclusive operation job %s to finish", job.Name)
+			return nil
+		}
+	} else if err != nil {
+		return err
+	}
+	if !scheduled.IsZero() {
+		t := metav1.NewTime(scheduled)
+		status.LastScheduleTime = &t
+	}
+	started := metav1.Now()
+	status.ObservedDataNodes = live
+	status.JobName = job.Name
+	status.Trigger = trigger
+	status.LastStartTime = &started
+	status.Message = ""
+	msg := fmt.Sprintf("balancer job %s is started by %s", job.Name, trigger)
+	glog.Infof("balancer of %s/%s: %s", hc.Namespace, hc.Name, msg)
+	bm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "BalancerStarted", msg)
+	return nil
+}
+
+func (bm *balancerManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	if st := hc.Status.Balancer; st != nil && st.LastResult != nil && !st.LastResult.Succeeded {
+		return fmt.Errorf("last balancer run failed: %s", st.LastResult.Summary)
+	}
+	return nil
+}
+
+// checkBalancerJob records the result of a finished job and deletes it so the
+// next exclusive operation can start, a running job is deleted when stop is set.
+// done is false while the job is running
+func (bm *balancerManager) checkBalancerJob(hc *v1alpha1.HdfsCluster, stop bool) (bool, error) {
+	status := hc.Status.Balancer
+	job, err := bm.jobControl.GetJob(hc, status.JobName)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	if errors.IsNotFound(err) || !metav1.IsControlledBy(job, hc) {
+		if status.LastStartTime != nil && time.Since(status.LastStartTime.Time) < balancerJobMissingTimeout {
+			return false, nil
+		}
+		bm.recordResult(hc, false, fmt.Sprintf("balancer job %s is deleted", status.JobName))
+		return true, nil
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		if !stop {
+			return false, nil
+		}
+		if err := bm.jobControl.DeleteJob(hc, job); err != nil {
+			return false, err
+		}
+		bm.recordResult(hc, false, "balancer is disabled, the running balancer is stopped")
+		return true, nil
+	}
+	summary := JobSummary(bm.podControl, hc, job)
+	if result == batchv1.JobFailed && summary == "" {
+		summary = JobFailureMessage(job)
+	}
+	bm.recordResult(hc, result == batchv1.JobComplete, summary)
+	return true, bm.jobControl.DeleteJob(hc, job)
+}
+
+func (bm *balancerManager) recordResult(hc *v1alpha1.HdfsCluster, succeeded bool, summary string) {
+	status := hc.Status.Balancer
+	status.LastResult = &v1alpha1.BalancerRun{
+		Trigger:        status.Trigger,
+		Succeeded:      succeeded,
+		CompletionTime: metav1.Now(),
+		Summary:        summary,
+	}
+	status.JobName = ""
+	status.Trigger = ""
+	if succeeded {
+		glog.Infof("balancer of %s/%s succeeded", hc.Namespace, hc.Name)
+		bm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "BalancerCompleted", "balancer run succeeded")
+		return
+	}
+	glog.Errorf("balancer of %s/%s failed, %s", hc.Namespace, hc.Name, summary)
+	bm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "BalancerFailed", summary)
+}
+
+func (bm *balancerManager) newBalancerJob(hc *v1alpha1.HdfsCluster, spec *v1alpha1.BalancerSpec) *batchv1.Job {
+	threshold := spec.Threshold
+	if threshold <= 0 {
+		threshold = DefaultBalancerThreshold
+	}
+	command := fmt.Sprintf("hdfs balancer -threshold %d", threshold)
+	if spec.Bandwidth != nil && spec.Bandwidth.Value() > 0 {
+		command = fmt.Sprintf("hdfs dfsadmin -setBalancerBandwidth %d && %s", spec.Bandwidth.Value(), command)
+	}
+	job := NewHadoopJob(hc, controller.ExclusiveOperationJobName(hc.Name), SummarizedScript(command, false))
+	backoffLimit := int32(0)
+	job.Spec.BackoffLimit = &backoffLimit
+	return job
+}
@@ -0,0 +1,294 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"reflect"
+	"sort"
+)
+
+const (
+	coreSiteKey   = "core-site.xml"
+	hdfsSiteKey   = "hdfs-site.xml"
+	yarnSiteKey   = "yarn-site.xml"
+	mapredSiteKey = "mapred-site.xml"
+)
+
+// serverOnlyConf are the properties of securityConf that only mean something
+// inside the pods of the cluster
+var serverOnlyConf = []string{
+	blockAccessToken,
+	"dfs.namenode.keytab.file",
+	"dfs.namenode.kerberos.internal.spnego.principal",
+	"dfs.datanode.keytab.file",
+	"dfs.web.authentication.kerberos.principal",
+	"dfs.web.authentication.kerberos.keytab",
+}
+
+// ClientConfigManager publishes the hadoop config the workloads of a cluster mount
+type ClientConfigManager interface {
+	Manager
+	// Finalize deletes the copies of the configmap in the other namespaces
+	Finalize(cluster *v1alpha1.HdfsCluster) error
+}
+
+type clientConfigManager struct {
+	cmControl    controller.ConfigMapControlInterface
+	nsControl    controller.NamespaceControlInterface
+	eventControl controller.EventControlInterface
+}
+
+// NewClientConfigManager renders the <cluster>-client-config configmap and
+// copies it into the namespaces selected by the cluster
+func NewClientConfigManager(
+	cmControl controller.ConfigMapControlInterface,
+	nsControl controller.NamespaceControlInterface,
+	eventControl controller.EventControlInterface,
+) ClientConfigManager {
+	return &clientConfigManager{
+		cmControl:    cmControl,
+		nsControl:    nsControl,
+		eventControl: eventControl,
+	}
+}
+
+func (cm *clientConfigManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	name := controller.ClientConfigMapName(hc.Name)
+	data := clientConfigData(hc)
+	old, err := cm.cmControl.GetConfigMap(hc, name)
+	if errors.IsNotFound(err) {
+		old, err = nil, nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := cm.updateConfigMap(hc, old, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       hc.Namespace,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Data: data,
+	}); err != nil {
+		glog.Errorf("sync client configmap of %s/%s error, err=%+v", hc.Namespace, hc.Name, err)
+		return err
+	}
+	mirrored, err := cm.syncMirrors(hc, data)
+	if hc.Status.ClientConfig == nil {
+		hc.Status.ClientConfig = &v1alpha1.ClientConfigStatus{}
+	}
+	hc.Status.ClientConfig.ConfigMap = name
+	hc.Status.ClientConfig.MirroredNamespaces = mirrored
+	return err
+}
+
+func (cm *clientConfigManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+func (cm *clientConfigManager) Finalize(hc *v1alpha1.HdfsCluster) error {
+	mirrors, err := cm.cmControl.ListConfigMaps(mirrorSelector(hc))
+	if err != nil {
+		return err
+	}
+	for _, mirror := range mirrors {
+		if err := cm.cmControl.DeleteConfigMap(hc, mirror); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncMirrors copies data into the selected namespaces and deletes the copies
+// in the others, it returns the namespaces holding an up to date copy
+func (cm *clientConfigManager) syncMirrors(hc *v1alpha1.HdfsCluster, data map[string]string) ([]string, error) {
+	selected := map[string]bool{}
+	if cc := hc.Spec.ClientConfig; cc != nil && cc.MirrorNamespaceSelector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(cc.MirrorNamespaceSelector)
+		if err != nil {
+			cm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidClientConfig", err.Error())
+			return nil, err
+		}
+		namespaces, err := cm.nsControl.ListNamespaces(selector)
+		if err != nil {
+			return nil, err
+		}
+		for _, ns := range namespaces {
+			if ns.Name != hc.Namespace && ns.DeletionTimestamp == nil {
+				selected[ns.Name] = true
+			}
+		}
+	}
+	mirrors, err := cm.cmControl.ListConfigMaps(mirrorSelector(hc))
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]*corev1.ConfigMap{}
+	for _, mirror := range mirrors {
+		existing[mirror.Namespace] = mirror
+		if !selected[mirror.Namespace] {
+			glog.Infof("namespace %s is no longer selected, delete client configmap of %s/%s", mirror.Namespace, hc.Namespace, hc.Name)
+			if err := cm.cmControl.DeleteConfigMap(hc, mirror); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+		}
+	}
+	var mirrored []string
+	for ns := range selected {
+		desired := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      controller.ClientConfigMapName(hc.Name),
+				Namespace: ns,
+				Labels:    mirrorLabels(hc),
+			},
+			Data: data,
+		}
+		err := cm.updateConfigMap(hc, existing[ns], desired)
+		if errors.IsAlreadyExists(err) {
+			// the configmap of the same name is not a copy of this cluster
+			cm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "ClientConfigConflict",
+				fmt.Sprintf("namespace %s already has a configmap %s, it is not replaced", ns, desired.Name))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		mirrored = append(mirrored, ns)
+	}
+	sort.Strings(mirrored)
+	return mirrored, nil
+}
+
+// updateConfigMap creates desired when old is nil or updates the data of old
+func (cm *clientConfigManager) updateConfigMap(hc *v1alpha1.HdfsCluster, old *corev1.ConfigMap, desired *corev1.ConfigMap) error {
+	if old == nil {
+		return cm.cmControl.CreateConfigMap(hc, desired)
+	}
+	if reflect.DeepEqual(old.Data, desired.Data) {
+		return nil
+	}
+	updated := old.DeepCopy()
+	updated.Data = desired.Data
+	return cm.cmControl.UpdateConfigMap(hc, updated)
+}
+
+func mirrorLabels(hc *v1alpha1.HdfsCluster) map[string]string {
+	return map[string]string{
+		controller.ClientConfigClusterLabelKey:   hc.Name,
+		controller.ClientConfigNamespaceLabelKey: hc.Namespace,
+	}
+}
+
+func mirrorSelector(hc *v1alpha1.HdfsCluster) labels.Selector {
+	return labels.SelectorFromSet(mirrorLabels(hc))
+}
+
+// clientConf is the config a client outside the pods of hc needs, the name
+// node is addressed by its fully qualified service name so the config also
+// works in the namespaces it is copied into. With a mount table the default
+// file system is the viewfs of the cluster, or the routers when they serve it
+func clientConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	defaultFS := "hdfs://" + controller.NameNodeRPCAddress(hc)
+	mounts := viewFSConf(hc)
+	if hc.Spec.Router != nil {
+		defaultFS = "hdfs://" + controller.RouterRPCAddress(hc)
+		mounts = nil
+	} else if len(mounts) != 0 {
+		defaultFS = "viewfs://" + hc.Name + "/"
+	}
+	confs := []hadoopConf{
+		{coreConf, "fs.defaultFS", defaultFS},
+	}
+	confs = append(confs, mounts...)
+	confs = append(confs, federationConf(hc)...)
+	for _, c := range securityConf(hc) {
+		if !containsString(serverOnlyConf, c.key) {
+			confs = append(confs, c)
+		}
+	}
+	if hc.Spec.KMS != nil {
+		confs = append(confs, kmsProviderConf(hc)...)
+	}
+	if hc.Spec.Yarn != nil {
+		confs = append(confs, yarnClientConf(hc)...)
+	}
+	return confs
+}
+
+func clientConfigData(hc *v1alpha1.HdfsCluster) map[string]string {
+	var core, hdfs, yarn, mapred []hadoopConf
+	for _, c := range clientConf(hc) {
+		switch c.prefix {
+		case coreConf:
+			core = append(core, c)
+		case hdfsConf:
+			hdfs = append(hdfs, c)
+		case yarnConf:
+			yarn = append(yarn, c)
+		case mapredConf:
+			mapred = append(mapred, c)
+		}
+	}
+	var coreSite, hdfsSite map[string]string
+	if cc := hc.Spec.ClientConfig; cc != nil {
+		coreSite, hdfsSite = cc.CoreSite, cc.HdfsSite
+	}
+	data := map[string]string{
+		coreSiteKey: siteXML(core, coreSite),
+		hdfsSiteKey: siteXML(hdfs, hdfsSite),
+	}
+	if len(yarn) != 0 {
+		data[yarnSiteKey] = siteXML(yarn, nil)
+		data[mapredSiteKey] = siteXML(mapred, nil)
+	}
+	if krb := kerberosSpec(hc); krb != nil {
+		data[krb5ConfKey] = krb5Conf(krb)
+	}
+	if hc.Status.TLS != nil && hc.Status.TLS.CACertificate != "" {
+		data[caCertKey] = hc.Status.TLS.CACertificate
+	}
+	return data
+}
+
+// siteXML renders confs as a hadoop config file, overrides replace the
+// properties of the same name and the others are added in key order
+func siteXML(confs []hadoopConf, overrides map[string]string) string {
+	var keys []string
+	values := map[string]string{}
+	for _, c := range confs {
+		if _, ok := values[c.key]; !ok {
+			keys = append(keys, c.key)
+		}
+		values[c.key] = c.value
+	}
+	var extra []string
+	for k := range overrides {
+		if _, ok := values[k]; !ok {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	keys = append(keys, extra...)
+	for k, v := range overrides {
+		values[k] = v
+	}
+	var buf bytes.Buffer
+	buf.WriteString("<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n<configuration>\n")
+	for _, k := range keys {
+		buf.WriteString("  <property>\n    <name>")
+		xml.EscapeText(&buf, []byte(k))
+		buf.WriteString("</name>\n    <value>")
+		xml.EscapeText(&buf, []byte(values[k]))
+		buf.WriteString("</value>\n  </property>\n")
+	}
+	buf.WriteString("</configuration>\n")
+	return buf.String()
+}
@@ -0,0 +1,102 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	defaultTargetUsedPercent = 70
+	defaultCooldownSeconds   = 300
+)
+
+// dataNodeReplicas is the desired data node replicas, with autoscaling it
+// is the last autoscaling decision instead of spec.data_node.replicas
+func dataNodeReplicas(hc *v1alpha1.HdfsCluster) int32 {
+	as := hc.Spec.DataNode.Autoscaling
+	if as == nil {
+		return hc.Spec.DataNode.Replicas
+	}
+	if hc.Status.Autoscaling != nil {
+		return clampReplicas(as, hc.Status.Autoscaling.DesiredReplicas)
+	}
+	return clampReplicas(as, hc.Spec.DataNode.Replicas)
+}
+
+func clampReplicas(as *v1alpha1.DataNodeAutoscalingSpec, replicas int32) int32 {
+	min := as.MinReplicas
+	if min < 1 {
+		min = 1
+	}
+	max := as.MaxReplicas
+	if max < min {
+		max = min
+	}
+	if replicas < min {
+		return min
+	}
+	if replicas > max {
+		return max
+	}
+	return replicas
+}
+
+// autoscale compares the used capacity reported in status.hdfs with the
+// target and records a new desired replicas in status.autoscaling
+func (dnm *dataNodeManager) autoscale(hc *v1alpha1.HdfsCluster, current int32) {
+	as := hc.Spec.DataNode.Autoscaling
+	if as == nil {
+		hc.Status.Autoscaling = nil
+		return
+	}
+	st := hc.Status.Hdfs
+	if st == nil || st.CapacityTotal <= 0 {
+		glog.V(4).Infof("capacity of %s/%s is not reported yet, skip autoscaling", hc.Namespace, hc.Name)
+		return
+	}
+	if hc.Status.Autoscaling == nil {
+		hc.Status.Autoscaling = &v1alpha1.AutoscalingStatus{DesiredReplicas: clampReplicas(as, current)}
+	}
+	status := hc.Status.Autoscaling
+	target := as.TargetUsedPercent
+	if target <= 0 || target > 100 {
+		target = defaultTargetUsedPercent
+	}
+	cooldown := as.CooldownSeconds
+	if cooldown <= 0 {
+		cooldown = defaultCooldownSeconds
+	}
+
+	usedPercent := int32(st.CapacityUsed * 100 / st.CapacityTotal)
+	status.UsedPercent = usedPercent
+	// new data nodes are assumed to bring the same capacity as the current ones
+	desired := clampReplicas(as, (current*usedPercent+target-1)/target)
+	if desired < current && !as.ScaleInEnabled {
+		desired = current
+	}
+	if desired == status.DesiredReplicas {
+		return
+	}
+	if status.LastScaleTime != nil && time.Since(status.LastScaleTime.Time) < time.Duration(cooldown)*time.Second {
+		glog.Infof("data node of %s/%s wants %d replicas, but it is still cooling down since %s",
+			hc.Namespace, hc.Name, desired, status.LastScaleTime)
+		return
+	}
+
+	reason := "AutoscaledOut"
+	if desired < status.DesiredReplicas {
+		reason = "AutoscaledIn"
+	}
+	decision := fmt.Sprintf("used capacity %d%% with target %d%%, desired data node replicas %d -> %d",
+		usedPercent, target, status.DesiredReplicas, desired)
+	now := metav1.Now()
+	status.DesiredReplicas = desired
+	status.LastScaleTime = &now
+	status.LastDecision = decision
+	glog.Infof("autoscale data node of %s/%s, %s", hc.Namespace, hc.Name, decision)
+	dnm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, reason, decision)
+}
@@ -16,17 +16,20 @@ type dataNodeManager struct {
 	setControl     controller.StatefulSetControlInterface
 	svcControl     controller.ServiceControlInterface
 	namenodeScaler Scaler
+	eventControl   controller.EventControlInterface
 }
 
 func NewDataNodeManager(
 	setControl controller.StatefulSetControlInterface,
 	svcControl controller.ServiceControlInterface,
 	namenodeScaler Scaler,
+	eventControl controller.EventControlInterface,
 ) Manager {
 	return &dataNodeManager{
 		setControl,
 		svcControl,
 		namenodeScaler,
+		eventControl,
 	}
 }
 
@@ -78,19 +81,41 @@ func (dnm *dataNodeManager) SyncDatanodeStatefulSet(hc *v1alpha1.HdfsCluster) er
 	if oldSet == nil {
 		return nil
 	}
+	dnm.autoscale(hc, *oldSet.Spec.Replicas)
 	newSet := dnm.getDatanodeStatefulset(hc)
-	if *oldSet.Spec.Replicas < hc.Spec.DataNode.Replicas {
+	var scaleErr error
+	if *oldSet.Spec.Replicas < *newSet.Spec.Replicas {
 		err := dnm.namenodeScaler.ScaleOut(hc, oldSet, newSet)
 		if err != nil {
 			glog.Errorf("scale out data node error, err=%+v", err)
 			return err
 		}
+	} else if *oldSet.Spec.Replicas > *newSet.Spec.Replicas {
+		scaleErr = dnm.namenodeScaler.ScaleIn(hc, oldSet, newSet)
+		if scaleErr != nil && !controller.IsRequeueError(scaleErr) {
+			glog.Errorf("scale in data node error, err=%+v", scaleErr)
+			return scaleErr
+		}
+	} else {
+		scaleErr = dnm.namenodeScaler.Recommission(hc, *newSet.Spec.Replicas)
+		if scaleErr != nil && !controller.IsRequeueError(scaleErr) {
+			glog.Errorf("recommission data node error, err=%+v", scaleErr)
+			return scaleErr
+		}
 	}
+	// volumeClaimTemplates are immutable, storage changes are applied to
+	// the existing pvcs by the volume manager instead
+	newSet.Spec.VolumeClaimTemplates = oldSet.Spec.VolumeClaimTemplates
+	// the data nodes keep the upgrade script of an earlier upgrade
+	setUpgrade(&newSet.Spec.Template.Spec, dataNodeImage(hc), upgradeFrom(hc).DataNode, dataNodeUpgradeScript, oldSet.Spec.Template.Spec.Containers[0].Args, dataNodeRollback(hc))
 	_, err = dnm.setControl.UpdateStatefulSet(hc, newSet)
 	if err != nil {
 		glog.Errorf("update statefulset failed, err=%+v", err)
 		return err
 	}
+	if scaleErr != nil {
+		return scaleErr
+	}
 	glog.Infof("sync data node statefulset success")
 	return nil
 }
@@ -123,14 +148,14 @@ func (dnm *dataNodeManager) getDatanodeStatefulset(hc *v1alpha1.HdfsCluster) *ap
 	name := hc.Name
 	ns := hc.Namespace
 	setName := controller.DataNodeSetName(name)
-	replicas := hc.Spec.DataNode.Replicas
+	replicas := dataNodeReplicas(hc)
 	scName := hc.Spec.DataNode.StorageClass
 	svcName := controller.DataNodeServiceName(name)
 	namenodeSvc := controller.NameNodeServiceName(name)
 	sz := hc.Spec.DataNode.Storage
 	var q resource.Quantity
 	q, _ = resource.ParseQuantity(sz)
-	return &appsv1.StatefulSet{
+	set := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:            setName,
 			Namespace:       ns,
@@ -145,13 +170,13 @@ func (dnm *dataNodeManager) getDatanodeStatefulset(hc *v1alpha1.HdfsCluster) *ap
 			ServiceName: svcName,
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
-					Labels: controller.DataNodeLabel(),
+					Labels: controller.DataNodeClusterLabel(name),
 				},
 				Spec: corev1.PodSpec{
 					Containers: []corev1.Container{
 						{
 							Name:            "datanode",
-							Image:           "uhopper/hadoop-datanode:2.7.2",
+							Image:           controller.DataNodeImage,
 							ImagePullPolicy: corev1.PullIfNotPresent,
 							Env: []corev1.EnvVar{
 								{
@@ -189,8 +214,14 @@ func (dnm *dataNodeManager) getDatanodeStatefulset(hc *v1alpha1.HdfsCluster) *ap
 			},
 		},
 	}
+	setTopologyWait(hc, &set.Spec.Template.Spec)
+	setSecurity(hc, &set.Spec.Template, securityDataNode)
+	setFederation(hc, &set.Spec.Template.Spec, "")
+	setUpgrade(&set.Spec.Template.Spec, dataNodeImage(hc), upgradeFrom(hc).DataNode, dataNodeUpgradeScript, nil, dataNodeRollback(hc))
+	set.Spec.UpdateStrategy = dataNodeUpdateStrategy(hc)
+	return set
 }
 
-func (m *dataNodeManager) CheckStatus() bool {
-	return false
+func (m *dataNodeManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return fmt.Errorf("data node status check is not supported")
 }
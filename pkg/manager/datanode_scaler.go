@@ -1,18 +1,58 @@
 package manager
 
 import (
+	"fmt"
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
 	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
-//TODO
-//manage pvc
+const (
+	scaleInRetryDelay      = 30 * time.Second
+	decommissionCheckDelay = 30 * time.Second
+
+	hostsExcludeKey = "dfs.exclude"
+	hostsExcludeDir = "/etc/hadoop/exclude"
+
+	adminStateInService      = "In Service"
+	adminStateDecommissioned = "Decommissioned"
+)
+
+// dataNodeScaler decommissions a data node before the statefulset drops it.
+// The data nodes to keep out of service are listed in the dfs.hosts.exclude
+// file of the name nodes, a configmap the refresh nodes job makes them reread
 type dataNodeScaler struct {
+	cmControl    controller.ConfigMapControlInterface
+	jobControl   controller.JobControlInterface
+	podControl   controller.PodControlInterface
+	eventControl controller.EventControlInterface
+	getClient    hdfs.ClientGetter
 }
 
-func NewDataNodeScaler() Scaler {
-	return &dataNodeScaler{}
+func NewDataNodeScaler(
+	cmControl controller.ConfigMapControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+	getClient hdfs.ClientGetter,
+) Scaler {
+	return &dataNodeScaler{
+		cmControl:    cmControl,
+		jobControl:   jobControl,
+		podControl:   podControl,
+		eventControl: eventControl,
+		getClient:    getClient,
+	}
 }
 
 func (d *dataNodeScaler) ScaleOut(hc *v1alpha1.HdfsCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
@@ -23,5 +63,293 @@ func (d *dataNodeScaler) ScaleOut(hc *v1alpha1.HdfsCluster, oldSet *apps.Statefu
 	//should add some HDFS state check
 	//like check live datanodes, datanodes status to decide if it can scale out
 	increaseReplicas(newSet, oldSet)
+	// a data node registering while it is still excluded is decommissioned
+	// right away, and put back in service once the name nodes reread the file
+	if err := d.Recommission(hc, *newSet.Spec.Replicas); err != nil && !controller.IsRequeueError(err) {
+		return err
+	}
+	return nil
+}
+
+// ScaleIn removes one data node at a time, the highest ordinal is excluded
+// from the name nodes first and the replicas only shrink once it is
+// decommissioned, so its replicas are copied to the remaining data nodes
+func (d *dataNodeScaler) ScaleIn(hc *v1alpha1.HdfsCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error {
+	ns := hc.GetNamespace()
+	tcName := hc.GetName()
+	*newSet.Spec.Replicas = *oldSet.Spec.Replicas
+	ordinal := *oldSet.Spec.Replicas - 1
+	podName := fmt.Sprintf("%s-%d", controller.DataNodeSetName(tcName), ordinal)
+	excluded, err := d.excludedHosts(hc)
+	if err != nil {
+		return err
+	}
+	if !containsString(excluded, dataNodeHost(hc, ordinal)) {
+		if st := hc.Status.Hdfs; st != nil && (st.MissingBlocks > 0 || st.UnderReplicatedBlocks > 0) {
+			return controller.RequeueErrorf(scaleInRetryDelay, "%s/%s has %d missing and %d under replicated blocks, can not scale in",
+				ns, tcName, st.MissingBlocks, st.UnderReplicatedBlocks)
+		}
+		glog.Infof("start scale in %s/%s, decommission data node %s", ns, tcName, podName)
+		d.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "DecommissionStarted",
+			fmt.Sprintf("data node %s is decommissioned before it is scaled in", podName))
+	}
+	refreshed, err := d.syncExcludes(hc, excludeHosts(hc, excluded, ordinal, true))
+	if err != nil {
+		return err
+	}
+	if !refreshed {
+		return controller.RequeueErrorf(decommissionCheckDelay, "name nodes of %s/%s are rereading the exclude file", ns, tcName)
+	}
+	states, err := d.adminStates(hc)
+	if err != nil {
+		return err
+	}
+	state, ok := states[podName]
+	if !ok {
+		return controller.RequeueErrorf(decommissionCheckDelay, "data node %s of %s/%s is not registered with the name node, can not decommission it",
+			podName, ns, tcName)
+	}
+	if state != adminStateDecommissioned {
+		return controller.RequeueErrorf(decommissionCheckDelay, "data node %s of %s/%s is %s", podName, ns, tcName, state)
+	}
+	msg := fmt.Sprintf("data node %s is decommissioned and scaled in", podName)
+	glog.Infof("%s/%s: %s", ns, tcName, msg)
+	d.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "Decommissioned", msg)
+	decreaseReplicas(newSet, oldSet)
+	return nil
+}
+
+// Recommission puts the data nodes below replicas back in service, it
+// undoes a scale in that was reverted before the data node was removed
+func (d *dataNodeScaler) Recommission(hc *v1alpha1.HdfsCluster, replicas int32) error {
+	excluded, err := d.excludedHosts(hc)
+	if err != nil {
+		return err
+	}
+	refreshed, err := d.syncExcludes(hc, excludeHosts(hc, excluded, replicas, false))
+	if err != nil {
+		return err
+	}
+	if !refreshed {
+		return controller.RequeueErrorf(decommissionCheckDelay, "name nodes of %s/%s are rereading the exclude file", hc.Namespace, hc.Name)
+	}
 	return nil
 }
+
+// excludeHosts keeps the hosts of the data nodes from ordinal on, they are
+// being decommissioned or were scaled in already, and adds ordinal itself
+// when decommission is set
+func excludeHosts(hc *v1alpha1.HdfsCluster, excluded []string, ordinal int32, decommission bool) []string {
+	hosts := []string{}
+	for _, host := range excluded {
+		if n, ok := dataNodeOrdinal(hc, host); ok && n >= ordinal && !(decommission && n == ordinal) {
+			hosts = append(hosts, host)
+		}
+	}
+	if decommission {
+		hosts = append(hosts, dataNodeHost(hc, ordinal))
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// dataNodeHost is the host name the name nodes resolve the data node of ordinal with
+func dataNodeHost(hc *v1alpha1.HdfsCluster, ordinal int32) string {
+	return fmt.Sprintf("%s-%d.%s.%s.svc.cluster.local", controller.DataNodeSetName(hc.Name), ordinal,
+		controller.DataNodeServiceName(hc.Name), hc.Namespace)
+}
+
+func dataNodeOrdinal(hc *v1alpha1.HdfsCluster, host string) (int32, bool) {
+	pod := strings.SplitN(host, ".", 2)[0]
+	prefix := controller.DataNodeSetName(hc.Name) + "-"
+	if !strings.HasPrefix(pod, prefix) {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(strings.TrimPrefix(pod, prefix), 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	return int32(n), true
+}
+
+func (d *dataNodeScaler) excludedHosts(hc *v1alpha1.HdfsCluster) ([]string, error) {
+	cm, err := d.cmControl.GetConfigMap(hc, controller.DataNodeExcludeConfigMapName(hc.Name))
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(cm.Data[hostsExcludeKey]), nil
+}
+
+// syncExcludes writes hosts into the exclude configmap and runs the refresh
+// nodes job until the name nodes report the data nodes in the expected admin
+// state, it returns whether the name nodes have read hosts
+func (d *dataNodeScaler) syncExcludes(hc *v1alpha1.HdfsCluster, hosts []string) (bool, error) {
+	name := controller.DataNodeExcludeConfigMapName(hc.Name)
+	content := strings.Join(hosts, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	cm, err := d.cmControl.GetConfigMap(hc, name)
+	if errors.IsNotFound(err) {
+		if content == "" {
+			return true, nil
+		}
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       hc.Namespace,
+				Annotations:     map[string]string{controller.NodesRefreshedAnnotation: "false"},
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			},
+			Data: map[string]string{hostsExcludeKey: content},
+		}
+		return false, d.cmControl.CreateConfigMap(hc, cm)
+	}
+	if err != nil {
+		return false, err
+	}
+	if cm.Data[hostsExcludeKey] != content {
+		cm = cm.DeepCopy()
+		cm.Data = map[string]string{hostsExcludeKey: content}
+		if cm.Annotations == nil {
+			cm.Annotations = map[string]string{}
+		}
+		cm.Annotations[controller.NodesRefreshedAnnotation] = "false"
+		if err := d.cmControl.UpdateConfigMap(hc, cm); err != nil {
+			return false, err
+		}
+		glog.Infof("data node exclude file of %s/%s is updated to %v", hc.Namespace, hc.Name, hosts)
+		return false, nil
+	}
+	if cm.Annotations[controller.NodesRefreshedAnnotation] != "false" {
+		return true, nil
+	}
+
+	job, err := d.jobControl.GetJob(hc, controller.RefreshNodesJobName(hc.Name))
+	if errors.IsNotFound(err) {
+		job = NewHadoopJob(hc, controller.RefreshNodesJobName(hc.Name), SummarizedScript(refreshNodesScript(hc), false))
+		return false, d.jobControl.CreateJob(hc, job)
+	}
+	if err != nil {
+		return false, err
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return false, nil
+	}
+	refreshed := false
+	if result != batchv1.JobComplete {
+		summary := JobSummary(d.podControl, hc, job)
+		if summary == "" {
+			summary = JobFailureMessage(job)
+		}
+		d.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "RefreshNodesFailed", summary)
+	} else if refreshed, err = d.namenodeReadExcludes(hc, hosts); err != nil {
+		return false, err
+	}
+	// a failed job or a name node that read the file before the configmap
+	// reached its pod runs the job again
+	if err := d.jobControl.DeleteJob(hc, job); err != nil {
+		return false, err
+	}
+	if !refreshed {
+		return false, nil
+	}
+	cm = cm.DeepCopy()
+	cm.Annotations[controller.NodesRefreshedAnnotation] = "true"
+	if err := d.cmControl.UpdateConfigMap(hc, cm); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// namenodeReadExcludes compares the admin states the name node reports with
+// hosts: an excluded data node is no longer in service, the others are
+func (d *dataNodeScaler) namenodeReadExcludes(hc *v1alpha1.HdfsCluster, hosts []string) (bool, error) {
+	states, err := d.adminStates(hc)
+	if err != nil {
+		return false, err
+	}
+	for pod, state := range states {
+		host := fmt.Sprintf("%s.%s.%s.svc.cluster.local", pod, controller.DataNodeServiceName(hc.Name), hc.Namespace)
+		if containsString(hosts, host) == (state == adminStateInService) {
+			glog.Infof("data node %s of %s/%s is %s, the name node has not read the exclude file yet", pod, hc.Namespace, hc.Name, state)
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// adminStates maps the data node pods of hc known to the name node to their
+// admin state, a dead data node is only reported once it is decommissioned
+func (d *dataNodeScaler) adminStates(hc *v1alpha1.HdfsCluster) (map[string]string, error) {
+	// the data node label is shared by every cluster in the namespace
+	pods, err := d.podControl.ListPods(hc, controller.DataNodeLabel())
+	if err != nil {
+		return nil, err
+	}
+	info, err := d.getClient(hc).GetNameNodeInfo()
+	if err != nil {
+		return nil, err
+	}
+	setName := controller.DataNodeSetName(hc.Name)
+	states := map[string]string{}
+	for _, pod := range pods {
+		if ref := metav1.GetControllerOf(pod); ref == nil || ref.Name != setName || pod.Status.PodIP == "" {
+			continue
+		}
+		addr := pod.Status.PodIP + ":"
+		for _, node := range info.LiveNodes {
+			if strings.HasPrefix(node.XferAddr, addr) {
+				states[pod.Name] = node.AdminState
+			}
+		}
+		if _, ok := states[pod.Name]; ok {
+			continue
+		}
+		for _, node := range info.DeadNodes {
+			if strings.HasPrefix(node.XferAddr, addr) && node.Decommissioned {
+				states[pod.Name] = adminStateDecommissioned
+			}
+		}
+	}
+	return states, nil
+}
+
+func refreshNodesScript(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf(`set -e
+for fs in %s; do
+  hdfs dfsadmin -fs $fs -refreshNodes
+done`, nameServiceFileSystems(hc))
+}
+
+// setHostsExclude mounts the data node exclude configmap into the name node
+// spec and points dfs.hosts.exclude at it. The volume is optional, the name
+// node logs a missing exclude file at startup and starts with no exclusions
+func setHostsExclude(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	const volumeName = "hosts-exclude"
+	envName := confEnvName(hdfsConf, "dfs.hosts.exclude")
+	removeVolumes(spec, volumeName)
+	removeEnv(spec, envName)
+	optional := true
+	// the api server defaults the mode, the spec would never compare equal
+	mode := int32(0644)
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: controller.DataNodeExcludeConfigMapName(hc.Name)},
+				DefaultMode:          &mode,
+				Optional:             &optional,
+			},
+		},
+	})
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.Env = append(c.Env, corev1.EnvVar{Name: envName, Value: hostsExcludeDir + "/" + hostsExcludeKey})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: volumeName, MountPath: hostsExcludeDir, ReadOnly: true})
+	}
+}
@@ -0,0 +1,145 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	nameServicesKey   = "dfs.nameservices"
+	nameServiceIDKey  = "dfs.nameservice.id"
+	rpcAddressKey     = "dfs.namenode.rpc-address"
+	rpcBindHostKey    = "dfs.namenode.rpc-bind-host"
+	viewFSMountPrefix = "fs.viewfs.mounttable."
+)
+
+var nameServiceRegexp = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// nameServices are the name services of hc, the one named after the cluster first
+func nameServices(hc *v1alpha1.HdfsCluster) []string {
+	names := []string{hc.Name}
+	for _, ns := range hc.Spec.NameServices {
+		names = append(names, ns.Name)
+	}
+	return names
+}
+
+// nameServiceFileSystems are the rpc addresses of all name services,
+// dfsadmin only talks to the default file system otherwise
+func nameServiceFileSystems(hc *v1alpha1.HdfsCluster) string {
+	var fss []string
+	for _, ns := range nameServices(hc) {
+		fss = append(fss, "hdfs://"+controller.NameServiceRPCAddress(hc, ns))
+	}
+	return strings.Join(fss, " ")
+}
+
+// validateFederation checks the name services and the mount table of hc
+func validateFederation(hc *v1alpha1.HdfsCluster) error {
+	if len(hc.Spec.NameServices) != 0 && controller.TLSEnabled(hc) {
+		return fmt.Errorf("name services can not be combined with tls yet")
+	}
+	seen := map[string]bool{hc.Name: true}
+	for _, ns := range hc.Spec.NameServices {
+		if !nameServiceRegexp.MatchString(ns.Name) {
+			return fmt.Errorf("name service %q is not a dns label", ns.Name)
+		}
+		if seen[ns.Name] {
+			return fmt.Errorf("name service %s is defined twice or is the cluster name", ns.Name)
+		}
+		seen[ns.Name] = true
+		if _, err := resource.ParseQuantity(ns.NameNode.Storage); err != nil {
+			return fmt.Errorf("storage %q of name service %s is invalid: %v", ns.NameNode.Storage, ns.Name, err)
+		}
+	}
+	paths := map[string]bool{}
+	for _, m := range hc.Spec.Mounts {
+		if !path.IsAbs(m.Path) || path.Clean(m.Path) != m.Path {
+			return fmt.Errorf("mount path %q is not a clean absolute path", m.Path)
+		}
+		if m.Path == "/" {
+			return fmt.Errorf("viewfs can not mount at /")
+		}
+		if paths[m.Path] {
+			return fmt.Errorf("mount path %s is mounted twice", m.Path)
+		}
+		paths[m.Path] = true
+		if !seen[m.NameService] {
+			return fmt.Errorf("name service %q of mount %s does not exist", m.NameService, m.Path)
+		}
+		if m.TargetPath != "" && (!path.IsAbs(m.TargetPath) || path.Clean(m.TargetPath) != m.TargetPath) {
+			return fmt.Errorf("target path %q of mount %s is not a clean absolute path", m.TargetPath, m.Path)
+		}
+	}
+	// viewfs links can not be nested
+	for _, a := range hc.Spec.Mounts {
+		for _, b := range hc.Spec.Mounts {
+			if strings.HasPrefix(b.Path, a.Path+"/") {
+				return fmt.Errorf("mount %s is nested in mount %s", b.Path, a.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// federationConf tells every name node and data node about all name
+// services, it is empty while hc is not federated or its spec is invalid
+func federationConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	if len(hc.Spec.NameServices) == 0 || validateFederation(hc) != nil {
+		return nil
+	}
+	names := nameServices(hc)
+	confs := []hadoopConf{{hdfsConf, nameServicesKey, strings.Join(names, ",")}}
+	for _, ns := range names {
+		confs = append(confs, hadoopConf{hdfsConf, rpcAddressKey + "." + ns, controller.NameServiceRPCAddress(hc, ns)})
+	}
+	return confs
+}
+
+// viewFSConf is the client mount table of hc, the links point at the rpc
+// address of the name nodes as the name services are not logical uris
+// without ha. Paths outside the mounts are not reachable through viewfs
+func viewFSConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	if len(hc.Spec.Mounts) == 0 || validateFederation(hc) != nil {
+		return nil
+	}
+	var confs []hadoopConf
+	for _, m := range hc.Spec.Mounts {
+		target := m.TargetPath
+		if target == "" {
+			target = m.Path
+		}
+		confs = append(confs, hadoopConf{coreConf, viewFSMountPrefix + hc.Name + ".link." + m.Path,
+			"hdfs://" + controller.NameServiceRPCAddress(hc, m.NameService) + target})
+	}
+	return confs
+}
+
+// setFederation renders federationConf into every container of spec, a name
+// node also gets the name service it serves and binds its rpc port to all
+// interfaces because the rpc address is the one of its service
+func setFederation(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec, nameService string) {
+	removeEnv(spec, confEnvName(hdfsConf, nameServicesKey), confEnvName(hdfsConf, nameServiceIDKey), confEnvName(hdfsConf, rpcBindHostKey))
+	removeEnvPrefix(spec, confEnvName(hdfsConf, rpcAddressKey)+"_")
+	confs := federationConf(hc)
+	if len(confs) == 0 {
+		return
+	}
+	if nameService != "" {
+		confs = append(confs,
+			hadoopConf{hdfsConf, nameServiceIDKey, nameService},
+			hadoopConf{hdfsConf, rpcBindHostKey, "0.0.0.0"})
+	}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		for _, conf := range confs {
+			c.Env = append(c.Env, conf.env())
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// proxyUserPrefix is the env var prefix of the hadoop.proxyuser.* properties
+var proxyUserPrefix = confEnvName(coreConf, "hadoop.proxyuser.")
+
+// gatewayUser is the user the httpfs and nfs gateways reach the name node
+// as, the admin principal with kerberos and root, the user of the images, without
+func gatewayUser(hc *v1alpha1.HdfsCluster) string {
+	krb := kerberosSpec(hc)
+	if krb == nil {
+		return "root"
+	}
+	return principalUser(krb.Admin.Principal)
+}
+
+// proxyUserConf lets the gateways impersonate the users of their clients
+// when they connect from their proxy hosts
+func proxyUserConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	hosts := proxyHosts(hc)
+	if len(hosts) == 0 {
+		return nil
+	}
+	user := gatewayUser(hc)
+	return []hadoopConf{
+		{coreConf, "hadoop.proxyuser." + user + ".hosts", strings.Join(hosts, ",")},
+		{coreConf, "hadoop.proxyuser." + user + ".groups", "*"},
+	}
+}
+
+// proxyHosts are the valid proxy hosts of both gateways, they share the user
+func proxyHosts(hc *v1alpha1.HdfsCluster) []string {
+	var hosts []string
+	if hc.Spec.HttpFS != nil {
+		hosts = append(hosts, hc.Spec.HttpFS.ProxyHosts...)
+	}
+	if hc.Spec.NFSGateway != nil {
+		hosts = append(hosts, hc.Spec.NFSGateway.ProxyHosts...)
+	}
+	var valid []string
+	for _, host := range hosts {
+		if validateProxyHosts([]string{host}) == nil && !containsString(valid, host) {
+			valid = append(valid, host)
+		}
+	}
+	sort.Strings(valid)
+	return valid
+}
+
+// validateProxyHosts requires at least one host and only ips and CIDRs
+func validateProxyHosts(hosts []string) error {
+	if len(hosts) == 0 {
+		return fmt.Errorf("proxy_hosts is required, e.g. the pod CIDR of the kubernetes cluster")
+	}
+	for _, host := range hosts {
+		if net.ParseIP(host) != nil {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(host); err != nil {
+			return fmt.Errorf("proxy host %q is neither an ip nor a CIDR", host)
+		}
+	}
+	return nil
+}
+
+// setProxyUsers renders proxyUserConf into every container of the name
+// node spec, or removes it once both gateways are off
+func setProxyUsers(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	removeEnvPrefix(spec, proxyUserPrefix)
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		for _, conf := range proxyUserConf(hc) {
+			c.Env = append(c.Env, conf.env())
+		}
+	}
+}
+
+// setGatewayConf gives a gateway the config of the admin jobs, the
+// gateways are clients of the cluster acting as the admin principal. The
+// yarn daemons share it to run their jobs as clients of the cluster
+func setGatewayConf(hc *v1alpha1.HdfsCluster, template *corev1.PodTemplateSpec) {
+	spec := &template.Spec
+	removeEnv(spec, confEnvName(coreConf, "fs.defaultFS"))
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.Env = append(c.Env, corev1.EnvVar{Name: confEnvName(coreConf, "fs.defaultFS"), Value: controller.DefaultFS(hc)})
+	}
+	setSecurity(hc, template, securityAdmin)
+	setKMS(hc, spec)
+	setFederation(hc, spec, "")
+}
+
+// syncGatewayDeployment creates desired or replaces the replicas and the
+// pod template of the existing deployment with those of desired
+func syncGatewayDeployment(deployControl controller.DeploymentControlInterface, hc *v1alpha1.HdfsCluster, desired *apps.Deployment) error {
+	old, err := deployControl.GetDeployment(hc, desired.Name)
+	if errors.IsNotFound(err) {
+		return deployControl.CreateDeployment(hc, desired)
+	}
+	if err != nil {
+		return err
+	}
+	deployment := old.DeepCopy()
+	deployment.Spec.Replicas = desired.Spec.Replicas
+	deployment.Spec.Template = desired.Spec.Template
+	if reflect.DeepEqual(deployment.Spec, old.Spec) {
+		return nil
+	}
+	return deployControl.UpdateDeployment(hc, deployment)
+}
+
+// removeGateway deletes the deployment and the service of a gateway that
+// is turned off
+func removeGateway(deployControl controller.DeploymentControlInterface, svcControl controller.ServiceControlInterface, hc *v1alpha1.HdfsCluster, name string) error {
+	if _, err := deployControl.GetDeployment(hc, name); err == nil {
+		if err := deployControl.DeleteDeployment(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	if _, err := svcControl.GetService(hc, name); err == nil {
+		if err := svcControl.DeleteService(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func gatewayReplicas(replicas int32) *int32 {
+	if replicas <= 0 {
+		replicas = 1
+	}
+	return &replicas
+}
@@ -0,0 +1,40 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"testing"
+)
+
+func TestProxyUserConf(t *testing.T) {
+	hc := newSecureCluster(true)
+	if conf := proxyUserConf(hc); conf != nil {
+		t.Fatalf("expected no proxy user without gateways, got %v", conf)
+	}
+
+	hc.Spec.HttpFS = &v1alpha1.HttpFSSpec{Replicas: 1, ProxyHosts: []string{"10.244.0.0/16", "*"}}
+	hc.Spec.NFSGateway = &v1alpha1.NFSGatewaySpec{Replicas: 1, ProxyHosts: []string{"10.244.0.0/16", "192.168.1.10"}}
+	conf := proxyUserConf(hc)
+	if len(conf) != 2 {
+		t.Fatalf("expected hosts and groups, got %v", conf)
+	}
+	if conf[0].key != "hadoop.proxyuser.nn.hosts" || conf[0].value != "10.244.0.0/16,192.168.1.10" {
+		t.Errorf("unexpected proxy hosts %s=%s", conf[0].key, conf[0].value)
+	}
+}
+
+func TestValidateProxyHosts(t *testing.T) {
+	for _, c := range []struct {
+		hosts []string
+		valid bool
+	}{
+		{nil, false},
+		{[]string{"*"}, false},
+		{[]string{"httpfs.ns.svc"}, false},
+		{[]string{"10.244.0.0/16"}, true},
+		{[]string{"10.244.0.0/16", "192.168.1.10"}, true},
+	} {
+		if err := validateProxyHosts(c.hosts); (err == nil) != c.valid {
+			t.Errorf("validateProxyHosts(%v) = %v, expected valid=%v", c.hosts, err, c.valid)
+		}
+	}
+}
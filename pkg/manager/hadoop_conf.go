@@ -0,0 +1,99 @@
+package manager
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"strings"
+)
+
+// the hadoop images write every <PREFIX>_<key> env var into the config file of the prefix
+const (
+	coreConf   = "CORE_CONF"
+	hdfsConf   = "HDFS_CONF"
+	kmsConf    = "KMS_CONF"
+	httpfsConf = "HTTPFS_CONF"
+	yarnConf   = "YARN_CONF"
+	mapredConf = "MAPRED_CONF"
+)
+
+// hadoopConf is one property of a hadoop config file
+type hadoopConf struct {
+	prefix string
+	key    string
+	value  string
+}
+
+// confEnvName encodes key the way the images decode it, "." is "_",
+// "_" is "__" and "-" is "___"
+func confEnvName(prefix string, key string) string {
+	key = strings.NewReplacer("_", "__", "-", "___", ".", "_").Replace(key)
+	return prefix + "_" + key
+}
+
+func (c hadoopConf) env() corev1.EnvVar {
+	return corev1.EnvVar{Name: confEnvName(c.prefix, c.key), Value: c.value}
+}
+
+// removeEnv removes the env vars named names from every container of spec
+func removeEnv(spec *corev1.PodSpec, names ...string) {
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		var env []corev1.EnvVar
+		for _, e := range c.Env {
+			if !containsString(names, e.Name) {
+				env = append(env, e)
+			}
+		}
+		c.Env = env
+	}
+}
+
+// removeVolumes removes the volumes named names and their mounts from spec
+func removeVolumes(spec *corev1.PodSpec, names ...string) {
+	var volumes []corev1.Volume
+	for _, v := range spec.Volumes {
+		if !containsString(names, v.Name) {
+			volumes = append(volumes, v)
+		}
+	}
+	spec.Volumes = volumes
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		var mounts []corev1.VolumeMount
+		for _, m := range c.VolumeMounts {
+			if !containsString(names, m.Name) {
+				mounts = append(mounts, m)
+			}
+		}
+		c.VolumeMounts = mounts
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
+// removeEnvPrefix removes the env vars starting with one of prefixes from
+// every container of spec
+func removeEnvPrefix(spec *corev1.PodSpec, prefixes ...string) {
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		var env []corev1.EnvVar
+		for _, e := range c.Env {
+			keep := true
+			for _, p := range prefixes {
+				if strings.HasPrefix(e.Name, p) {
+					keep = false
+				}
+			}
+			if keep {
+				env = append(env, e)
+			}
+		}
+		c.Env = env
+	}
+}
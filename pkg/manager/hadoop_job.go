@@ -0,0 +1,108 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"time"
+)
+
+// maxJobSummaryBytes keeps the termination message below its 4096 bytes limit
+const maxJobSummaryBytes = 4000
+
+// NewHadoopJob returns a job owned by hc that runs script with the hdfs
+// command line pointed at the name node of hc, logged in as the kerberos
+// admin when the cluster is secured
+func NewHadoopJob(hc *v1alpha1.HdfsCluster, name string, script string) *batchv1.Job {
+	backoffLimit := int32(2)
+	labels := map[string]string{"app": "hdfs-admin", "hdfs-cluster": hc.Name}
+	script = kinitScript(hc) + script
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:            "hadoop",
+							Image:           controller.HadoopImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", script},
+							Env: []corev1.EnvVar{
+								{
+									Name:  "CORE_CONF_fs_defaultFS",
+									Value: controller.DefaultFS(hc),
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	setSecurity(hc, &job.Spec.Template, securityAdmin)
+	setKMS(hc, &job.Spec.Template.Spec)
+	setFederation(hc, &job.Spec.Template.Spec, "")
+	return job
+}
+
+// SummarizedScript runs command and keeps the tail of its output, or the head
+// when head is set, as the termination message of the container
+func SummarizedScript(command string, head bool) string {
+	summarize := "tail"
+	if head {
+		summarize = "head"
+	}
+	return fmt.Sprintf(`set -o pipefail
+( %s ) 2>&1 | tee /tmp/output
+rc=$?
+%s -c %d /tmp/output > /dev/termination-log
+exit $rc`, command, summarize, maxJobSummaryBytes)
+}
+
+// JobSummary returns the termination message of the last finished pod of job
+func JobSummary(podControl controller.PodControlInterface, hc *v1alpha1.HdfsCluster, job *batchv1.Job) string {
+	pods, err := podControl.ListPods(hc, map[string]string{"job-name": job.Name})
+	if err != nil {
+		glog.Errorf("list pods of job %s/%s error, err=%+v", job.Namespace, job.Name, err)
+		return ""
+	}
+	var summary string
+	var last time.Time
+	for _, pod := range pods {
+		for _, cs := range pod.Status.ContainerStatuses {
+			t := cs.State.Terminated
+			if t == nil || t.FinishedAt.Time.Before(last) {
+				continue
+			}
+			last = t.FinishedAt.Time
+			summary = strings.TrimSpace(t.Message)
+		}
+	}
+	return summary
+}
+
+// JobFailureMessage explains why a failed job failed
+func JobFailureMessage(job *batchv1.Job) string {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue && cond.Message != "" {
+			return fmt.Sprintf("job %s failed: %s", job.Name, cond.Message)
+		}
+	}
+	return fmt.Sprintf("job %s failed", job.Name)
+}
@@ -0,0 +1,91 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"time"
+)
+
+// hdfsStatusManager reads the name node jmx into status.hdfs, it only
+// queries the name node once the last refresh is older than refreshInterval
+type hdfsStatusManager struct {
+	getClient       hdfs.ClientGetter
+	refreshInterval time.Duration
+}
+
+func NewHdfsStatusManager(getClient hdfs.ClientGetter, refreshInterval time.Duration) Manager {
+	return &hdfsStatusManager{
+		getClient:       getClient,
+		refreshInterval: refreshInterval,
+	}
+}
+
+func (hsm *hdfsStatusManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if st := hc.Status.Hdfs; st != nil && time.Since(st.LastUpdateTime.Time)+time.Second < hsm.refreshInterval {
+		return nil
+	}
+	cli := hsm.getClient(hc)
+	fs, err := cli.GetFSNamesystem()
+	if err != nil {
+		glog.Errorf("get FSNamesystem of %s/%s error, err=%+v", hc.Namespace, hc.Name, err)
+		return err
+	}
+	state, err := cli.GetFSNamesystemState()
+	if err != nil {
+		glog.Errorf("get FSNamesystemState of %s/%s error, err=%+v", hc.Namespace, hc.Name, err)
+		return err
+	}
+	status := &v1alpha1.HdfsStatus{
+		CapacityTotal:            fs.CapacityTotal,
+		CapacityUsed:             fs.CapacityUsed,
+		CapacityRemaining:        fs.CapacityRemaining,
+		LiveDataNodes:            state.NumLiveDataNodes,
+		DeadDataNodes:            state.NumDeadDataNodes - state.NumDecomDeadDataNodes,
+		StaleDataNodes:           state.NumStaleDataNodes,
+		DecommissioningDataNodes: state.NumDecommissioningDataNodes,
+		MissingBlocks:            fs.MissingBlocks,
+		CorruptBlocks:            fs.CorruptBlocks,
+		UnderReplicatedBlocks:    fs.UnderReplicatedBlocks,
+		SafeMode:                 state.FSState == hdfs.FSStateSafeMode,
+		LastUpdateTime:           metav1.Now(),
+	}
+	if fs.LastCheckpointTime > 0 {
+		t := metav1.NewTime(time.Unix(0, fs.LastCheckpointTime*int64(time.Millisecond)))
+		status.LastCheckpointTime = &t
+	}
+	hc.Status.Hdfs = status
+
+	reasons := []string{}
+	if status.MissingBlocks > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d missing blocks", status.MissingBlocks))
+	}
+	if status.DeadDataNodes > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d dead data nodes", status.DeadDataNodes))
+	}
+	if len(reasons) != 0 {
+		reason := "MissingBlocks"
+		if status.MissingBlocks == 0 {
+			reason = "DeadDataNodes"
+		}
+		hc.SetCondition(v1alpha1.Degraded, corev1.ConditionTrue, reason, strings.Join(reasons, ", "))
+	} else {
+		hc.SetCondition(v1alpha1.Degraded, corev1.ConditionFalse, "Healthy", "no missing blocks and no dead data nodes")
+	}
+	glog.V(4).Infof("refresh hdfs status of %s/%s success", hc.Namespace, hc.Name)
+	return nil
+}
+
+func (hsm *hdfsStatusManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	if hc.Status.Hdfs == nil {
+		return fmt.Errorf("hdfs status has not been reported yet")
+	}
+	if cond := hc.GetCondition(v1alpha1.Degraded); cond != nil && cond.Status == corev1.ConditionTrue {
+		return fmt.Errorf("hdfs is degraded: %s", cond.Message)
+	}
+	return nil
+}
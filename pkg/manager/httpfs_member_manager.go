@@ -0,0 +1,155 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"strconv"
+)
+
+type httpFSManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	svcControl        controller.ServiceControlInterface
+}
+
+// NewHttpFSManager runs the httpfs gateways of a cluster
+func NewHttpFSManager(
+	deployControl controller.DeploymentControlInterface,
+	svcControl controller.ServiceControlInterface,
+) Manager {
+	return &httpFSManager{
+		deploymentControl: deployControl,
+		svcControl:        svcControl,
+	}
+}
+
+func (hm *httpFSManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.HttpFS == nil {
+		return removeGateway(hm.deploymentControl, hm.svcControl, hc, controller.HttpFSName(hc.Name))
+	}
+	if err := validateProxyHosts(hc.Spec.HttpFS.ProxyHosts); err != nil {
+		return fmt.Errorf("httpfs of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+	}
+	if err := hm.syncService(hc); err != nil {
+		glog.Errorf("sync httpfs service error, err=%+v", err)
+		return err
+	}
+	if err := syncGatewayDeployment(hm.deploymentControl, hc, hm.getDeployment(hc)); err != nil {
+		glog.Errorf("sync httpfs deployment error, err=%+v", err)
+		return err
+	}
+	glog.Infof("sync httpfs success")
+	return nil
+}
+
+func (hm *httpFSManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+func (hm *httpFSManager) syncService(hc *v1alpha1.HdfsCluster) error {
+	_, err := hm.svcControl.GetService(hc, controller.HttpFSName(hc.Name))
+	if errors.IsNotFound(err) {
+		return hm.svcControl.CreateService(hc, hm.getService(hc))
+	}
+	return err
+}
+
+func (hm *httpFSManager) getService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.HttpFSName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.HttpFSLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "httpfs",
+					Port:       controller.HttpFSPort,
+					TargetPort: intstr.FromInt(controller.HttpFSPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: controller.HttpFSLabel(hc.Name),
+		},
+	}
+}
+
+func (hm *httpFSManager) getDeployment(hc *v1alpha1.HdfsCluster) *apps.Deployment {
+	labels := controller.HttpFSLabel(hc.Name)
+	env := []corev1.EnvVar{{Name: "HTTPFS_HTTP_PORT", Value: strconv.Itoa(controller.HttpFSPort)}}
+	for _, conf := range httpFSConf(hc) {
+		env = append(env, conf.env())
+	}
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.HttpFSName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: gatewayReplicas(hc.Spec.HttpFS.Replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "httpfs",
+							Image:           controller.HadoopImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", "exec $HADOOP_PREFIX/sbin/httpfs.sh run"},
+							Env:             env,
+							Resources:       hc.Spec.HttpFS.Resources,
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: controller.HttpFSPort,
+									Name:          "httpfs",
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(controller.HttpFSPort)},
+								},
+								PeriodSeconds: 10,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	setGatewayConf(hc, &deployment.Spec.Template)
+	return deployment
+}
+
+// httpFSConf logs httpfs in as the admin principal and authenticates its
+// users by SPNEGO with any HTTP principal of the keytab
+func httpFSConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	krb := kerberosSpec(hc)
+	if krb == nil {
+		return nil
+	}
+	keytab := keytabDir + "/" + keytabFile
+	return []hadoopConf{
+		{httpfsConf, "httpfs.authentication.type", "kerberos"},
+		{httpfsConf, "httpfs.authentication.kerberos.principal", "*"},
+		{httpfsConf, "httpfs.authentication.kerberos.keytab", keytab},
+		{httpfsConf, "httpfs.hadoop.authentication.type", "kerberos"},
+		{httpfsConf, "httpfs.hadoop.authentication.kerberos.principal", krb.Admin.Principal},
+		{httpfsConf, "httpfs.hadoop.authentication.kerberos.keytab", keytab},
+	}
+}
@@ -0,0 +1,256 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net"
+	"reflect"
+	"strings"
+)
+
+const (
+	keytabDir        = "/etc/security/keytabs"
+	keytabFile       = "hdfs.keytab"
+	krb5ConfPath     = "/etc/krb5.conf"
+	krb5ConfKey      = "krb5.conf"
+	defaultKeytabKey = "keytab"
+
+	keytabVolume   = "keytab"
+	krb5ConfVolume = "krb5-conf"
+)
+
+// kerberosManager renders the krb5.conf of the realm and checks the keytab
+// secrets before any pod that needs them is created
+type kerberosManager struct {
+	cmControl     controller.ConfigMapControlInterface
+	secretControl controller.SecretControlInterface
+	eventControl  controller.EventControlInterface
+}
+
+func NewKerberosManager(
+	cmControl controller.ConfigMapControlInterface,
+	secretControl controller.SecretControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &kerberosManager{
+		cmControl:     cmControl,
+		secretControl: secretControl,
+		eventControl:  eventControl,
+	}
+}
+
+func (km *kerberosManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	krb := kerberosSpec(hc)
+	if krb == nil {
+		return nil
+	}
+	if err := km.validate(hc, krb); err != nil {
+		glog.Errorf("kerberos of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+		km.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidKerberos", err.Error())
+		return err
+	}
+	data := map[string]string{krb5ConfKey: krb5Conf(krb)}
+	name := controller.Krb5ConfigMapName(hc.Name)
+	cm, err := km.cmControl.GetConfigMap(hc, name)
+	if errors.IsNotFound(err) {
+		return km.cmControl.CreateConfigMap(hc, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       hc.Namespace,
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			},
+			Data: data,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	return km.cmControl.UpdateConfigMap(hc, cm)
+}
+
+func (km *kerberosManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+// validate checks the spec and that every keytab secret has its key
+func (km *kerberosManager) validate(hc *v1alpha1.HdfsCluster, krb *v1alpha1.KerberosSpec) error {
+	if krb.Realm == "" || krb.KDC == "" {
+		return fmt.Errorf("kerberos realm and kdc are required")
+	}
+	// the data node refuses SASL data transfer on unprivileged ports unless
+	// its web endpoint is https only, the image can not bind privileged ports
+	if tlsSpec(hc) == nil {
+		return fmt.Errorf("kerberos requires tls, the data nodes only accept SASL data transfer with https only web endpoints")
+	}
+	if krb.Admin.Principal == "" || strings.Contains(krb.Admin.Principal, "_HOST") {
+		return fmt.Errorf("kerberos admin principal is required and can not contain _HOST")
+	}
+	switch krb.DataTransferProtection {
+	case "", "authentication", "integrity", "privacy":
+	default:
+		return fmt.Errorf("invalid data transfer protection %s", krb.DataTransferProtection)
+	}
+	for _, c := range []securityComponent{securityNameNode, securityDataNode, securityAdmin} {
+		p := kerberosPrincipal(hc, krb, c)
+		if p.KeytabSecret == "" {
+			return fmt.Errorf("keytab secret of the %s is required", c)
+		}
+		secret, err := km.secretControl.GetSecret(hc, p.KeytabSecret)
+		if err != nil {
+			return fmt.Errorf("get keytab secret %s of the %s error: %v", p.KeytabSecret, c, err)
+		}
+		if len(secret.Data[keytabKey(p)]) == 0 {
+			return fmt.Errorf("keytab secret %s of the %s has no key %s", p.KeytabSecret, c, keytabKey(p))
+		}
+	}
+	return nil
+}
+
+func kerberosSpec(hc *v1alpha1.HdfsCluster) *v1alpha1.KerberosSpec {
+	if hc.Spec.Security == nil {
+		return nil
+	}
+	return hc.Spec.Security.Kerberos
+}
+
+// kerberosPrincipal returns the principal of component, the name node runs
+// in a deployment so its default principal is bound to the service instead
+// of the random pod hostname _HOST would expand to
+func kerberosPrincipal(hc *v1alpha1.HdfsCluster, krb *v1alpha1.KerberosSpec, c securityComponent) v1alpha1.KerberosPrincipal {
+	switch c {
+	case securityNameNode:
+		p := krb.NameNode
+		if p.Principal == "" {
+			p.Principal = fmt.Sprintf("nn/%s.%s.svc.cluster.local@%s", controller.NameNodeServiceName(hc.Name), hc.Namespace, krb.Realm)
+		}
+		return p
+	case securityDataNode:
+		p := krb.DataNode
+		if p.Principal == "" {
+			p.Principal = "dn/_HOST@" + krb.Realm
+		}
+		return p
+	}
+	return krb.Admin
+}
+
+// principalUser is the short name of principal, which hadoop maps to the user
+func principalUser(principal string) string {
+	if i := strings.IndexAny(principal, "/@"); i >= 0 {
+		return principal[:i]
+	}
+	return principal
+}
+
+func keytabKey(p v1alpha1.KerberosPrincipal) string {
+	if p.KeytabKey == "" {
+		return defaultKeytabKey
+	}
+	return p.KeytabKey
+}
+
+func krb5Conf(krb *v1alpha1.KerberosSpec) string {
+	adminServer := krb.KDC
+	if host, _, err := net.SplitHostPort(krb.KDC); err == nil {
+		adminServer = host
+	}
+	return fmt.Sprintf(`[libdefaults]
+  default_realm = %[1]s
+  dns_lookup_realm = false
+  dns_lookup_kdc = false
+  rdns = false
+  udp_preference_limit = 1
+
+[realms]
+  %[1]s = {
+    kdc = %[2]s
+    admin_server = %[3]s
+  }
+`, krb.Realm, krb.KDC, adminServer)
+}
+
+// kerberosConf is the config every pod of a secured cluster needs, the
+// principals of all components are known to the clients of each
+func kerberosConf(hc *v1alpha1.HdfsCluster, krb *v1alpha1.KerberosSpec) []hadoopConf {
+	webPrincipal := krb.WebPrincipal
+	if webPrincipal == "" {
+		webPrincipal = "HTTP/_HOST@" + krb.Realm
+	}
+	protection := krb.DataTransferProtection
+	if protection == "" {
+		protection = "authentication"
+	}
+	keytab := keytabDir + "/" + keytabFile
+	return []hadoopConf{
+		{coreConf, "hadoop.security.authentication", "kerberos"},
+		{coreConf, "hadoop.security.authorization", "true"},
+		{hdfsConf, "dfs.namenode.kerberos.principal", kerberosPrincipal(hc, krb, securityNameNode).Principal},
+		{hdfsConf, "dfs.namenode.keytab.file", keytab},
+		{hdfsConf, "dfs.namenode.kerberos.internal.spnego.principal", webPrincipal},
+		{hdfsConf, "dfs.datanode.kerberos.principal", kerberosPrincipal(hc, krb, securityDataNode).Principal},
+		{hdfsConf, "dfs.datanode.keytab.file", keytab},
+		{hdfsConf, "dfs.web.authentication.kerberos.principal", webPrincipal},
+		{hdfsConf, "dfs.web.authentication.kerberos.keytab", keytab},
+		{hdfsConf, "dfs.data.transfer.protection", protection},
+	}
+}
+
+// setKerberos mounts the krb5.conf and the keytab of component into every
+// container of spec, the config is rendered by setSecurity
+func setKerberos(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec, component securityComponent) {
+	removeVolumes(spec, keytabVolume, krb5ConfVolume)
+	krb := kerberosSpec(hc)
+	if krb == nil {
+		return
+	}
+	p := kerberosPrincipal(hc, krb, component)
+	mode := int32(0400)
+	confMode := int32(0644)
+	spec.Volumes = append(spec.Volumes,
+		corev1.Volume{
+			Name: keytabVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{
+					SecretName:  p.KeytabSecret,
+					Items:       []corev1.KeyToPath{{Key: keytabKey(p), Path: keytabFile}},
+					DefaultMode: &mode,
+				},
+			},
+		},
+		corev1.Volume{
+			Name: krb5ConfVolume,
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: controller.Krb5ConfigMapName(hc.Name)},
+					DefaultMode:          &confMode,
+				},
+			},
+		},
+	)
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.VolumeMounts = append(c.VolumeMounts,
+			corev1.VolumeMount{Name: keytabVolume, MountPath: keytabDir, ReadOnly: true},
+			corev1.VolumeMount{Name: krb5ConfVolume, MountPath: krb5ConfPath, SubPath: krb5ConfKey, ReadOnly: true},
+		)
+	}
+}
+
+// kinitScript logs the admin jobs in before they run any hdfs command
+func kinitScript(hc *v1alpha1.HdfsCluster) string {
+	krb := kerberosSpec(hc)
+	if krb == nil {
+		return ""
+	}
+	return fmt.Sprintf("kinit -kt %s/%s '%s' || exit 1\n", keytabDir, keytabFile, krb.Admin.Principal)
+}
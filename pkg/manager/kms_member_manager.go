@@ -0,0 +1,314 @@
+package manager
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
+	"strings"
+)
+
+const (
+	kmsKeystoreDir    = "/kms"
+	kmsKeystoreVolume = "kms-keystore"
+	kmsPasswordKey    = "password"
+)
+
+type kmsManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	svcControl        controller.ServiceControlInterface
+	pvcControl        controller.PVCControlInterface
+}
+
+// NewKMSManager runs the hadoop kms of a cluster with its keystore on a pvc
+func NewKMSManager(
+	deployControl controller.DeploymentControlInterface,
+	svcControl controller.ServiceControlInterface,
+	pvcControl controller.PVCControlInterface,
+) Manager {
+	return &kmsManager{
+		deploymentControl: deployControl,
+		svcControl:        svcControl,
+		pvcControl:        pvcControl,
+	}
+}
+
+func (km *kmsManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.KMS == nil {
+		return nil
+	}
+	if hc.Spec.KMS.KeystorePasswordSecret == "" {
+		// the keystore would be protected by the default password "none"
+		return fmt.Errorf("kms keystore_password_secret of %s/%s is required", hc.Namespace, hc.Name)
+	}
+	if err := km.syncService(hc); err != nil {
+		glog.Errorf("sync kms service error, err=%+v", err)
+		return err
+	}
+	if err := km.syncPVC(hc); err != nil {
+		glog.Errorf("sync kms pvc error, err=%+v", err)
+		return err
+	}
+	if err := km.syncDeployment(hc); err != nil {
+		glog.Errorf("sync kms deployment error, err=%+v", err)
+		return err
+	}
+	glog.Infof("sync kms success")
+	return nil
+}
+
+func (km *kmsManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+func (km *kmsManager) syncService(hc *v1alpha1.HdfsCluster) error {
+	_, err := km.svcControl.GetService(hc, controller.KMSName(hc.Name))
+	if errors.IsNotFound(err) {
+		return km.svcControl.CreateService(hc, km.getService(hc))
+	}
+	return err
+}
+
+func (km *kmsManager) syncPVC(hc *v1alpha1.HdfsCluster) error {
+	_, err := km.pvcControl.GetPVC(hc, controller.KMSName(hc.Name))
+	if errors.IsNotFound(err) {
+		return km.pvcControl.CreatePVC(hc, km.getPVC(hc))
+	}
+	return err
+}
+
+func (km *kmsManager) syncDeployment(hc *v1alpha1.HdfsCluster) error {
+	old, err := km.deploymentControl.GetDeployment(hc, controller.KMSName(hc.Name))
+	if errors.IsNotFound(err) {
+		return km.deploymentControl.CreateDeployment(hc, km.getDeployment(hc))
+	}
+	if err != nil {
+		return err
+	}
+	// changing the keystore password secret or the acls restarts the kms
+	deployment := old.DeepCopy()
+	desired := km.getDeployment(hc).Spec.Template.Spec.Containers[0]
+	deployment.Spec.Template.Spec.Containers[0].Env = desired.Env
+	deployment.Spec.Template.Spec.Containers[0].Args = desired.Args
+	if reflect.DeepEqual(deployment.Spec.Template, old.Spec.Template) {
+		return nil
+	}
+	return km.deploymentControl.UpdateDeployment(hc, deployment)
+}
+
+func (km *kmsManager) getService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.KMSName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.KMSLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "kms",
+					Port:       controller.KMSPort,
+					TargetPort: intstr.FromInt(controller.KMSPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: controller.KMSLabel(hc.Name),
+		},
+	}
+}
+
+func (km *kmsManager) getPVC(hc *v1alpha1.HdfsCluster) *corev1.PersistentVolumeClaim {
+	q, _ := resource.ParseQuantity(hc.Spec.KMS.Storage)
+	sc := hc.Spec.KMS.StorageClass
+	// no owner reference, the pvc retention finalizer deletes or keeps it
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.KMSName(hc.Name),
+			Namespace: hc.Namespace,
+			Labels:    controller.KMSLabel(hc.Name),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			StorageClassName: &sc,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: q,
+				},
+			},
+		},
+	}
+}
+
+func (km *kmsManager) getDeployment(hc *v1alpha1.HdfsCluster) *apps.Deployment {
+	name := controller.KMSName(hc.Name)
+	replicas := int32(1)
+	env := []corev1.EnvVar{
+		{Name: "KMS_HTTP_PORT", Value: "16000"},
+		hadoopConf{kmsConf, "hadoop.kms.key.provider.uri", "jceks://file@" + kmsKeystoreDir + "/kms.keystore"}.env(),
+		{
+			Name: "HADOOP_KEYSTORE_PASSWORD",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: hc.Spec.KMS.KeystorePasswordSecret},
+					Key:                  kmsPasswordKey,
+				},
+			},
+		},
+	}
+	return &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       hc.Namespace,
+			Labels:          controller.KMSLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: controller.KMSLabel(hc.Name),
+			},
+			// the keystore pvc is ReadWriteOnce
+			Strategy: apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: controller.KMSLabel(hc.Name),
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "kms",
+							Image:           controller.HadoopImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", kmsScript(hc)},
+							Env:             env,
+							Ports: []corev1.ContainerPort{
+								{
+									ContainerPort: controller.KMSPort,
+									Name:          "kms",
+									Protocol:      corev1.ProtocolTCP,
+								},
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      kmsKeystoreVolume,
+									MountPath: kmsKeystoreDir,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: kmsKeystoreVolume,
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: name,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+type kmsACL struct {
+	name string
+	acl  string
+}
+
+// kmsACLs only let the admin manage the keys and the name node generate the
+// encrypted keys of new files. The key material is never handed out and the
+// name node user can not decrypt, so the hdfs superuser can not read the zones
+func kmsACLs(hc *v1alpha1.HdfsCluster) []kmsACL {
+	admin := gatewayUser(hc)
+	nameNode := "root"
+	if krb := kerberosSpec(hc); krb != nil {
+		nameNode = principalUser(kerberosPrincipal(hc, krb, securityNameNode).Principal)
+	}
+	keyUsers := hc.Spec.KMS.KeyUsers
+	if keyUsers == "" {
+		keyUsers = "*"
+	}
+	users := func(names ...string) string {
+		var unique []string
+		for _, name := range names {
+			if !containsString(unique, name) {
+				unique = append(unique, name)
+			}
+		}
+		return strings.Join(unique, ",")
+	}
+	// a single space is nobody
+	const nobody = " "
+	return []kmsACL{
+		{"hadoop.kms.acl.CREATE", admin},
+		{"hadoop.kms.acl.DELETE", admin},
+		{"hadoop.kms.acl.ROLLOVER", admin},
+		{"hadoop.kms.acl.GET", nobody},
+		{"hadoop.kms.acl.GET_KEYS", admin},
+		{"hadoop.kms.acl.GET_METADATA", users(admin, nameNode)},
+		{"hadoop.kms.acl.SET_KEY_MATERIAL", nobody},
+		{"hadoop.kms.acl.GENERATE_EEK", nameNode},
+		{"hadoop.kms.acl.DECRYPT_EEK", keyUsers},
+		{"hadoop.kms.blacklist.DECRYPT_EEK", nameNode},
+		{"default.key.acl.MANAGEMENT", admin},
+		{"default.key.acl.READ", users(admin, nameNode)},
+		{"default.key.acl.GENERATE_EEK", nameNode},
+		{"default.key.acl.DECRYPT_EEK", keyUsers},
+	}
+}
+
+// kmsScript writes kms-acls.xml next to the kms-site.xml the image renders
+// and runs the kms
+func kmsScript(hc *v1alpha1.HdfsCluster) string {
+	var props bytes.Buffer
+	for _, a := range kmsACLs(hc) {
+		props.WriteString("  <property><name>" + a.name + "</name><value>")
+		xml.EscapeText(&props, []byte(a.acl))
+		props.WriteString("</value></property>\n")
+	}
+	return "cat > ${HADOOP_CONF_DIR:-/etc/hadoop}/kms-acls.xml <<'EOF'\n<configuration>\n" + props.String() +
+		"</configuration>\nEOF\nexec $HADOOP_PREFIX/sbin/kms.sh run"
+}
+
+// kmsProviderConf points the clients at the kms, hadoop 2.7 reads the provider
+// from dfs.encryption.key.provider.uri and later versions from
+// hadoop.security.key.provider.path
+func kmsProviderConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	uri := controller.KMSProviderURI(hc)
+	return []hadoopConf{
+		{coreConf, "hadoop.security.key.provider.path", uri},
+		{hdfsConf, "dfs.encryption.key.provider.uri", uri},
+	}
+}
+
+// setKMS renders the key provider into every container of spec, or removes it
+func setKMS(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	var names []string
+	for _, c := range kmsProviderConf(hc) {
+		names = append(names, confEnvName(c.prefix, c.key))
+	}
+	removeEnv(spec, names...)
+	if hc.Spec.KMS == nil {
+		return
+	}
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		for _, conf := range kmsProviderConf(hc) {
+			c.Env = append(c.Env, conf.env())
+		}
+	}
+}
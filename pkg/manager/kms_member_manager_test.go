@@ -0,0 +1,95 @@
+package manager
+
+import (
+	"encoding/xml"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func newKMSCluster() *v1alpha1.HdfsCluster {
+	return &v1alpha1.HdfsCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+		Spec: v1alpha1.HdfsClusterSpec{
+			KMS: &v1alpha1.KMSSpec{Storage: "1Gi", KeystorePasswordSecret: "kms-keystore"},
+		},
+	}
+}
+
+func TestKMSKeystorePasswordRequired(t *testing.T) {
+	hc := newKMSCluster()
+	hc.Spec.KMS.KeystorePasswordSecret = ""
+	if err := (&kmsManager{}).Sync(hc); err == nil {
+		t.Errorf("expected an error without a keystore password secret")
+	}
+
+	hc = newKMSCluster()
+	container := (&kmsManager{}).getDeployment(hc).Spec.Template.Spec.Containers[0]
+	for _, e := range container.Env {
+		if e.Name != "HADOOP_KEYSTORE_PASSWORD" {
+			continue
+		}
+		if e.Value != "" || e.ValueFrom == nil || e.ValueFrom.SecretKeyRef.Name != "kms-keystore" || e.ValueFrom.SecretKeyRef.Key != kmsPasswordKey {
+			t.Errorf("unexpected keystore password env %+v", e)
+		}
+		return
+	}
+	t.Errorf("the keystore password is not set")
+}
+
+// kmsACLFile parses the kms-acls.xml written by the script of the kms
+func kmsACLFile(t *testing.T, script string) map[string]string {
+	start := strings.Index(script, "<configuration>")
+	end := strings.Index(script, "</configuration>")
+	if start < 0 || end < 0 {
+		t.Fatalf("the script does not write kms-acls.xml:\n%s", script)
+	}
+	var conf struct {
+		Properties []struct {
+			Name  string `xml:"name"`
+			Value string `xml:"value"`
+		} `xml:"property"`
+	}
+	if err := xml.Unmarshal([]byte(script[start:end+len("</configuration>")]), &conf); err != nil {
+		t.Fatalf("parse kms-acls.xml error, err=%v", err)
+	}
+	acls := map[string]string{}
+	for _, p := range conf.Properties {
+		acls[p.Name] = p.Value
+	}
+	return acls
+}
+
+func TestKMSACLs(t *testing.T) {
+	hc := newKMSCluster()
+	acls := kmsACLFile(t, kmsScript(hc))
+	for name, want := range map[string]string{
+		"hadoop.kms.acl.CREATE":            "root",
+		"hadoop.kms.acl.GET":               " ",
+		"hadoop.kms.acl.SET_KEY_MATERIAL":  " ",
+		"hadoop.kms.acl.GET_METADATA":      "root",
+		"hadoop.kms.acl.DECRYPT_EEK":       "*",
+		"hadoop.kms.blacklist.DECRYPT_EEK": "root",
+		"default.key.acl.MANAGEMENT":       "root",
+	} {
+		if got, ok := acls[name]; !ok || got != want {
+			t.Errorf("got %s=%q, want %q", name, got, want)
+		}
+	}
+
+	hc = newSecureCluster(true)
+	hc.Spec.KMS = &v1alpha1.KMSSpec{KeystorePasswordSecret: "kms-keystore", KeyUsers: "alice,bob analysts&co"}
+	acls = kmsACLFile(t, kmsScript(hc))
+	for name, want := range map[string]string{
+		"hadoop.kms.acl.CREATE":            "nn",
+		"hadoop.kms.acl.GET_METADATA":      "nn",
+		"hadoop.kms.acl.GENERATE_EEK":      "nn",
+		"hadoop.kms.blacklist.DECRYPT_EEK": "nn",
+		"default.key.acl.DECRYPT_EEK":      "alice,bob analysts&co",
+	} {
+		if got := acls[name]; got != want {
+			t.Errorf("kerberos: got %s=%q, want %q", name, got, want)
+		}
+	}
+}
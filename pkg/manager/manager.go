@@ -6,5 +6,6 @@ import (
 
 type Manager interface {
 	Sync(cluster *v1alpha1.HdfsCluster) error
-	CheckStatus() bool
+	// CheckStatus returns nil when the component is available
+	CheckStatus(cluster *v1alpha1.HdfsCluster) error
 }
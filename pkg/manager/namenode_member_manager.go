@@ -1,6 +1,7 @@
 package manager
 
 import (
+	"fmt"
 	"github.com/golang/glog"
 	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
 	"github.com/tommenx/hdfs-operator/pkg/controller"
@@ -10,13 +11,21 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"net"
+	"net/http"
+	"reflect"
+	"time"
 )
 
+const probeTimeout = 3 * time.Second
+
 type nameNodeManager struct {
 	deploymentControl controller.DeploymentControlInterface
 	pvcControl        controller.PVCControlInterface
 	svcControl        controller.ServiceControlInterface
 	podControl        controller.PodControlInterface
+	restorer          NameNodeRestorer
+	probeEndpoints    func(hc *v1alpha1.HdfsCluster) error
 }
 
 func NewNameNodeManager(
@@ -24,12 +33,15 @@ func NewNameNodeManager(
 	pvcControl controller.PVCControlInterface,
 	podControl controller.PodControlInterface,
 	svcControl controller.ServiceControlInterface,
+	restorer NameNodeRestorer,
 ) Manager {
 	return &nameNodeManager{
 		deploymentControl: deployControl,
 		pvcControl:        pvcControl,
 		podControl:        podControl,
 		svcControl:        svcControl,
+		restorer:          restorer,
+		probeEndpoints:    probeNameNodeEndpoints,
 	}
 }
 
@@ -45,24 +57,49 @@ func (nnm *nameNodeManager) Sync(hc *v1alpha1.HdfsCluster) error {
 	return nnm.SyncNameNodeDeployment(hc)
 }
 
-func (nnm *nameNodeManager) CheckStatus() bool {
-	_, status, err := nnm.podControl.CheckPodsStatus(controller.NameNodeLabel())
+// the name node is available when its pod is ready and both the rpc and web endpoints answer
+func (nnm *nameNodeManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	ok, status, err := nnm.podControl.CheckPodsStatus(hc, controller.NameNodeLabel())
 	if err != nil {
 		glog.Errorf("check pod status error, err=%+v", err)
-		return false
+		return err
 	}
-	if status != nil {
+	if !ok {
 		for name, state := range status {
 			glog.Errorf("%s status is %s", name, state)
-			return false
+			return fmt.Errorf("name node pod %s is %s", name, state)
 		}
+		return fmt.Errorf("name node pod does not exist")
+	}
+	return nnm.probeEndpoints(hc)
+}
+
+func probeNameNodeEndpoints(hc *v1alpha1.HdfsCluster) error {
+	rpcAddr := controller.NameNodeRPCAddress(hc)
+	conn, err := net.DialTimeout("tcp", rpcAddr, probeTimeout)
+	if err != nil {
+		return fmt.Errorf("name node rpc %s is unreachable: %v", rpcAddr, err)
+	}
+	conn.Close()
+	httpAddr := controller.NameNodeHTTPAddress(hc)
+	cli := &http.Client{
+		Timeout:   probeTimeout,
+		Transport: &http.Transport{TLSClientConfig: controller.NameNodeTLSConfig(hc)},
+	}
+	resp, err := cli.Get(controller.NameNodeWebURL(hc) + "/")
+	if err != nil {
+		return fmt.Errorf("name node web %s is unreachable: %v", httpAddr, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("name node web %s returned %s", httpAddr, resp.Status)
 	}
-	return true
+	return nil
 }
 
 func (nnm *nameNodeManager) SyncNameNodeService(hc *v1alpha1.HdfsCluster) error {
 	svcName := controller.NameNodeServiceName(hc.Name)
-	_, err := nnm.svcControl.GetService(hc, svcName)
+	old, err := nnm.svcControl.GetService(hc, svcName)
 	if err != nil && errors.IsNotFound(err) {
 		svc := nnm.getNameNodeService(hc)
 		err := nnm.svcControl.CreateService(hc, svc)
@@ -73,6 +110,23 @@ func (nnm *nameNodeManager) SyncNameNodeService(hc *v1alpha1.HdfsCluster) error
 	} else if err != nil {
 		glog.Errorf("get name node service failed, err=%+v", err)
 		return err
+	} else {
+		// the web port moves to 443 with tls, unchanged ports keep their node port
+		svc := old.DeepCopy()
+		svc.Spec.Ports = nnm.getNameNodeService(hc).Spec.Ports
+		for i := range svc.Spec.Ports {
+			for _, p := range old.Spec.Ports {
+				if p.Name == svc.Spec.Ports[i].Name && p.Port == svc.Spec.Ports[i].Port {
+					svc.Spec.Ports[i].NodePort = p.NodePort
+				}
+			}
+		}
+		if !reflect.DeepEqual(svc.Spec.Ports, old.Spec.Ports) {
+			if err := nnm.svcControl.UpdateService(hc, svc); err != nil {
+				glog.Errorf("update name node service error, err=%+v", err)
+				return err
+			}
+		}
 	}
 	glog.Infof("sync name node service success")
 	return nil
@@ -98,10 +152,30 @@ func (nnm *nameNodeManager) SyncNameNodePVC(hc *v1alpha1.HdfsCluster) error {
 
 func (nnm *nameNodeManager) SyncNameNodeDeployment(hc *v1alpha1.HdfsCluster) error {
 	deploymentName := controller.NameNodeDeployment(hc.Name)
-	_, err := nnm.deploymentControl.GetDeployment(hc, deploymentName)
+	old, err := nnm.deploymentControl.GetDeployment(hc, deploymentName)
 	if err != nil && errors.IsNotFound(err) {
 		deployment := nnm.getNameNodeDeployment(hc)
-		err := nnm.deploymentControl.CreateDeployment(hc, deployment)
+		// a new name node is restored from a backup instead of being formatted
+		initContainer, volumes, err := nnm.restorer.InitContainer(hc)
+		if err != nil {
+			glog.Errorf("prepare name node restore error, err=%+v", err)
+			return err
+		}
+		if initContainer != nil {
+			spec := &deployment.Spec.Template.Spec
+			spec.InitContainers = append(spec.InitContainers, *initContainer)
+			spec.Volumes = append(spec.Volumes, volumes...)
+		}
+		// applied in the same order as on updates so an unchanged
+		// deployment compares equal
+		setRackAwareness(hc, &deployment.Spec.Template.Spec)
+		setHostsExclude(hc, &deployment.Spec.Template.Spec)
+		setSecurity(hc, &deployment.Spec.Template, securityNameNode)
+		setKMS(hc, &deployment.Spec.Template.Spec)
+		setFederation(hc, &deployment.Spec.Template.Spec, hc.Name)
+		setProxyUsers(hc, &deployment.Spec.Template.Spec)
+		setUpgrade(&deployment.Spec.Template.Spec, nameNodeImage(hc), upgradeFrom(hc).NameNode, nameNodeUpgradeScript, nil, nameNodeRollback(hc))
+		err = nnm.deploymentControl.CreateDeployment(hc, deployment)
 		if err != nil {
 			glog.Errorf("create name node deployment error, err=%+v", err)
 			return err
@@ -109,6 +183,29 @@ func (nnm *nameNodeManager) SyncNameNodeDeployment(hc *v1alpha1.HdfsCluster) err
 	} else if err != nil {
 		glog.Errorf("get deployment error, err=%+v", err)
 		return err
+	} else {
+		// turning rack awareness, kerberos, tls, the kms, federation or the
+		// gateways on or off, renewing the certificates and the steps of a
+		// rolling upgrade restart the name node
+		deployment := old.DeepCopy()
+		deployment.Spec.Template.Spec.Containers[0].Ports = nameNodeContainerPorts(hc)
+		setRackAwareness(hc, &deployment.Spec.Template.Spec)
+		setHostsExclude(hc, &deployment.Spec.Template.Spec)
+		setSecurity(hc, &deployment.Spec.Template, securityNameNode)
+		setKMS(hc, &deployment.Spec.Template.Spec)
+		setFederation(hc, &deployment.Spec.Template.Spec, hc.Name)
+		setProxyUsers(hc, &deployment.Spec.Template.Spec)
+		setUpgrade(&deployment.Spec.Template.Spec, nameNodeImage(hc), upgradeFrom(hc).NameNode, nameNodeUpgradeScript, old.Spec.Template.Spec.Containers[0].Args, nameNodeRollback(hc))
+		// older deployments default to a rolling update whose new name node
+		// waits on the pvc forever, replacing the strategy also drops the
+		// defaulted rollingUpdate the api rejects next to Recreate
+		deployment.Spec.Strategy = apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType}
+		if !reflect.DeepEqual(deployment.Spec.Template, old.Spec.Template) || !reflect.DeepEqual(deployment.Spec.Strategy, old.Spec.Strategy) {
+			if err := nnm.deploymentControl.UpdateDeployment(hc, deployment); err != nil {
+				glog.Errorf("update name node deployment error, err=%+v", err)
+				return err
+			}
+		}
 	}
 	glog.Infof("sync name node deployment success")
 	return nil
@@ -134,18 +231,51 @@ func (nnm *nameNodeManager) getNameNodeService(hc *v1alpha1.HdfsCluster) *corev1
 					TargetPort: intstr.FromInt(8020),
 					Protocol:   corev1.ProtocolTCP,
 				},
-				{
-					Name:       "nn-web",
-					Port:       80,
-					TargetPort: intstr.FromInt(50070),
-					Protocol:   corev1.ProtocolTCP,
-				},
+				nameNodeWebServicePort(hc),
 			},
 			Selector: controller.NameNodeLabel(),
 		},
 	}
 }
 
+// nameNodeContainerPorts follows the http policy, the web port is https
+// only once tls is on
+func nameNodeContainerPorts(hc *v1alpha1.HdfsCluster) []corev1.ContainerPort {
+	webPort := int32(controller.NameNodeHTTPPort)
+	if controller.TLSEnabled(hc) {
+		webPort = controller.NameNodeHTTPSPort
+	}
+	return []corev1.ContainerPort{
+		{
+			ContainerPort: controller.NameNodeRPCPort,
+			Name:          "nn-rpc",
+			Protocol:      corev1.ProtocolTCP,
+		},
+		{
+			ContainerPort: webPort,
+			Name:          "nn-web",
+			Protocol:      corev1.ProtocolTCP,
+		},
+	}
+}
+
+func nameNodeWebServicePort(hc *v1alpha1.HdfsCluster) corev1.ServicePort {
+	if controller.TLSEnabled(hc) {
+		return corev1.ServicePort{
+			Name:       "nn-web",
+			Port:       controller.NameNodeWebTLSPort,
+			TargetPort: intstr.FromInt(controller.NameNodeHTTPSPort),
+			Protocol:   corev1.ProtocolTCP,
+		}
+	}
+	return corev1.ServicePort{
+		Name:       "nn-web",
+		Port:       controller.NameNodeWebPort,
+		TargetPort: intstr.FromInt(controller.NameNodeHTTPPort),
+		Protocol:   corev1.ProtocolTCP,
+	}
+}
+
 func (nnm *nameNodeManager) getNameNodePVC(hc *v1alpha1.HdfsCluster) *corev1.PersistentVolumeClaim {
 	ns := hc.Namespace
 	name := hc.Name
@@ -154,12 +284,12 @@ func (nnm *nameNodeManager) getNameNodePVC(hc *v1alpha1.HdfsCluster) *corev1.Per
 	var q resource.Quantity
 	q, _ = resource.ParseQuantity(sz)
 	sc := hc.Spec.NameNode.StorageClass
+	// no owner reference, the pvc retention finalizer deletes or keeps it
 	return &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:            pvcName,
-			Namespace:       ns,
-			Labels:          controller.NameNodeLabel(),
-			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			Name:      pvcName,
+			Namespace: ns,
+			Labels:    controller.NameNodeLabel(),
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
@@ -192,6 +322,8 @@ func (nnm *nameNodeManager) getNameNodeDeployment(hc *v1alpha1.HdfsCluster) *app
 			Selector: &metav1.LabelSelector{
 				MatchLabels: controller.NameNodeLabel(),
 			},
+			// the name pvc is ReadWriteOnce
+			Strategy: apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: controller.NameNodeLabel(),
@@ -200,20 +332,11 @@ func (nnm *nameNodeManager) getNameNodeDeployment(hc *v1alpha1.HdfsCluster) *app
 					Containers: []corev1.Container{
 						{
 							Name:  "namenode",
-							Image: "uhopper/hadoop-namenode:2.7.2",
+							Image: controller.NameNodeImage,
 							Env: []corev1.EnvVar{
 								{Name: "CLUSTER_NAME", Value: name},
 							},
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 8020,
-									Name:          "nn-rpc",
-								},
-								{
-									ContainerPort: 50070,
-									Name:          "nn-web",
-								},
-							},
+							Ports: nameNodeContainerPorts(hc),
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      "hdfs-name",
@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	corev1 "k8s.io/api/core/v1"
+	"path"
+	"strings"
+)
+
+const (
+	restoreDir       = "/restore"
+	restoreSourceDir = "/restore-source"
+	restoreVolume    = "restore-source"
+)
+
+// NameNodeRestorer builds the init container that loads the backup in
+// spec.restore_from into the empty name node pvc, the name node then starts
+// from the fsimage instead of formatting and the data nodes re-register their blocks
+type NameNodeRestorer interface {
+	// InitContainer returns nil when the cluster is not restored from a backup
+	InitContainer(hc *v1alpha1.HdfsCluster) (*corev1.Container, []corev1.Volume, error)
+}
+
+type nameNodeRestorer struct {
+	secretControl controller.SecretControlInterface
+	newS3Client   func(s3.Config) s3.Interface
+}
+
+func NewNameNodeRestorer(
+	secretControl controller.SecretControlInterface,
+	newS3Client func(s3.Config) s3.Interface,
+) NameNodeRestorer {
+	return &nameNodeRestorer{
+		secretControl: secretControl,
+		newS3Client:   newS3Client,
+	}
+}
+
+func (r *nameNodeRestorer) InitContainer(hc *v1alpha1.HdfsCluster) (*corev1.Container, []corev1.Volume, error) {
+	src := hc.Spec.RestoreFrom
+	if src == nil {
+		return nil, nil, nil
+	}
+	var fetch string
+	var volumes []corev1.Volume
+	var location string
+	switch {
+	case src.Target.S3 != nil:
+		var err error
+		fetch, location, err = r.fetchFromS3(hc, src)
+		if err != nil {
+			hc.SetCondition(v1alpha1.Restored, corev1.ConditionFalse, "BackupNotFound", err.Error())
+			return nil, nil, err
+		}
+	case src.Target.PVC != nil:
+		dir := path.Join(restoreSourceDir, src.Backup)
+		fetch = fmt.Sprintf("cp %s/fsimage_* %s/%s .", dir, dir, backupVersionFile)
+		location = fmt.Sprintf("pvc %s at %s", src.Target.PVC.ClaimName, path.Join(pvcBackupDir, src.Backup))
+		volumes = append(volumes, corev1.Volume{
+			Name: restoreVolume,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: src.Target.PVC.ClaimName,
+					ReadOnly:  true,
+				},
+			},
+		})
+	default:
+		err := fmt.Errorf("restore target has neither s3 nor pvc")
+		hc.SetCondition(v1alpha1.Restored, corev1.ConditionFalse, "InvalidRestoreSource", err.Error())
+		return nil, nil, err
+	}
+
+	container := &corev1.Container{
+		Name:            "restore",
+		Image:           controller.HadoopImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Args:            []string{"/bin/bash", "-c", restoreScript(fetch)},
+		Env: []corev1.EnvVar{
+			{Name: "CLUSTER_NAME", Value: hc.Name},
+			{Name: "HDFS_CONF_dfs_namenode_name_dir", Value: "file://" + nameNodeDataDir},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: "hdfs-name", MountPath: nameNodeDataDir},
+		},
+	}
+	if src.Target.S3 != nil {
+		container.Env = append(container.Env, s3Env(src.Target.S3)...)
+	}
+	if len(volumes) != 0 {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      restoreVolume,
+			MountPath: restoreSourceDir,
+			ReadOnly:  true,
+		})
+	}
+	msg := fmt.Sprintf("restoring the name node from backup %s in %s", src.Backup, location)
+	glog.Infof("%s/%s: %s", hc.Namespace, hc.Name, msg)
+	hc.SetCondition(v1alpha1.Restored, corev1.ConditionFalse, "Restoring", msg)
+	return container, volumes, nil
+}
+
+// fetchFromS3 returns the commands downloading the backup, the init container
+// signs the requests itself with the credentials of the target secret
+func (r *nameNodeRestorer) fetchFromS3(hc *v1alpha1.HdfsCluster, src *v1alpha1.RestoreSource) (string, string, error) {
+	target := src.Target.S3
+	store, err := newS3Store(r.secretControl, r.newS3Client, hc, target)
+	if err != nil {
+		return "", "", err
+	}
+	dir := path.Join(s3BackupPrefix(hc.Namespace, hc.Name, target), src.Backup) + "/"
+	objects, err := store.ListObjects(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("list backup %s error, err=%v", dir, err)
+	}
+	var image, version string
+	for _, o := range objects {
+		name := strings.TrimPrefix(o.Key, dir)
+		if _, err := hdfs.ParseFsImageName(name); err == nil {
+			image = o.Key
+		} else if name == backupVersionFile {
+			version = o.Key
+		}
+	}
+	if image == "" || version == "" {
+		return "", "", fmt.Errorf("backup s3://%s/%s is incomplete or does not exist", target.Bucket, dir)
+	}
+	cmds := []string{}
+	for _, key := range []string{image, version} {
+		cmds = append(cmds, fmt.Sprintf("s3_get %s %s", s3ObjectPath(target.Bucket, key), path.Base(key)))
+	}
+	return s3Functions + strings.Join(cmds, "\n"), fmt.Sprintf("s3://%s/%s", target.Bucket, image), nil
+}
+
+// restoreScript formats the name node with the ids of the backup, then replaces
+// the empty fsimage with the backup. The namespace id is read from the fsimage.
+// A name node which is already initialized is left alone, so a restarted pod
+// does not restore again
+func restoreScript(fetch string) string {
+	return strings.Join([]string{
+		"set -e",
+		"current=" + path.Join(nameNodeDataDir, "current"),
+		`if [ -f $current/VERSION ]; then echo "name node is already initialized, skip the restore"; exit 0; fi`,
+		"mkdir -p " + restoreDir,
+		"cd " + restoreDir,
+		fetch,
+		`image=$(ls fsimage_* | grep -v '\.md5$' | head -n 1)`,
+		`txid=$((10#${image#fsimage_}))`,
+		". ./" + backupVersionFile,
+		`nsid=$(hdfs oiv -p XML -i $image -o - | grep -o '<namespaceId>[0-9]*</namespaceId>' | grep -o '[0-9][0-9]*')`,
+		"hdfs namenode -format -clusterid $clusterID -force -nonInteractive",
+		`sed -i -e "s/^namespaceID=.*/namespaceID=$nsid/" -e "s/^blockpoolID=.*/blockpoolID=$blockpoolID/" $current/VERSION`,
+		"rm -f $current/fsimage_*",
+		"cp $image $current/$image",
+		`echo "$(md5sum $image | cut -d' ' -f1) *$image" > $current/$image.md5`,
+		"echo $txid > $current/seen_txid",
+		`echo "restored fsimage at txid $txid"`,
+	}, "\n")
+}
@@ -0,0 +1,54 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	s3fake "github.com/tommenx/hdfs-operator/pkg/s3/fake"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestRestoreFromS3(t *testing.T) {
+	srv := s3fake.NewServer("backup")
+	defer srv.Close()
+	for _, key := range []string{"ns/demo/20191010-020000/fsimage_0000000000000000042", "ns/demo/20191010-020000/VERSION"} {
+		srv.Client("backup").PutObject(key, strings.NewReader("x"), 1)
+	}
+	secrets := &fakeSecretControl{secrets: map[string]*corev1.Secret{
+		"s3": {Data: map[string][]byte{s3AccessKey: []byte(s3fake.AccessKey), s3SecretKey: []byte(s3fake.SecretKey)}},
+	}}
+	hc := &v1alpha1.HdfsCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+		Spec: v1alpha1.HdfsClusterSpec{
+			RestoreFrom: &v1alpha1.RestoreSource{
+				Backup: "20191010-020000",
+				Target: v1alpha1.BackupTarget{S3: &v1alpha1.S3BackupTarget{Endpoint: srv.URL(), Bucket: "backup", SecretName: "s3"}},
+			},
+		},
+	}
+
+	container, _, err := NewNameNodeRestorer(secrets, s3.NewClient).InitContainer(hc)
+	if err != nil {
+		t.Fatalf("init container error, err=%v", err)
+	}
+	script := container.Args[2]
+	if !strings.Contains(script, "s3_get /backup/ns/demo/20191010-020000/fsimage_0000000000000000042 fsimage_0000000000000000042") ||
+		!strings.Contains(script, "s3_get /backup/ns/demo/20191010-020000/VERSION VERSION") {
+		t.Errorf("the backup is not downloaded, script:\n%s", script)
+	}
+	if strings.Contains(script, "X-Amz-Signature") || strings.Contains(script, s3fake.SecretKey) {
+		t.Errorf("the script holds credentials, script:\n%s", script)
+	}
+	for _, e := range container.Env {
+		if (e.Name == "S3_ACCESS_KEY" || e.Name == "S3_SECRET_KEY") && (e.ValueFrom == nil || e.ValueFrom.SecretKeyRef.Name != "s3") {
+			t.Errorf("%s is not read from the secret", e.Name)
+		}
+	}
+
+	hc.Spec.RestoreFrom.Backup = "20191011-020000"
+	if _, _, err := NewNameNodeRestorer(secrets, s3.NewClient).InitContainer(hc); err == nil {
+		t.Errorf("expected an error for a missing backup")
+	}
+}
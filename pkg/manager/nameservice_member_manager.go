@@ -0,0 +1,299 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
+)
+
+// nameServiceScript formats a new name node into the cluster id of the
+// federation, the image would format it with a random cluster id
+const nameServiceScript = nameServiceFormat + `exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR namenode`
+
+const nameServiceFormat = `namedir=/hadoop/dfs/name
+if [ ! -f $namedir/current/VERSION ]; then
+  $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR namenode -format -clusterId "$CLUSTER_ID" -nonInteractive || exit 1
+fi
+`
+
+type nameServiceManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	svcControl        controller.ServiceControlInterface
+	pvcControl        controller.PVCControlInterface
+	podControl        controller.PodControlInterface
+	eventControl      controller.EventControlInterface
+	newClient         func(hc *v1alpha1.HdfsCluster, nameService string) hdfs.Interface
+}
+
+// NewNameServiceManager runs the name nodes of the name services federated
+// with the name node of the cluster
+func NewNameServiceManager(
+	deployControl controller.DeploymentControlInterface,
+	svcControl controller.ServiceControlInterface,
+	pvcControl controller.PVCControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+	newClient func(hc *v1alpha1.HdfsCluster, nameService string) hdfs.Interface,
+) Manager {
+	return &nameServiceManager{
+		deploymentControl: deployControl,
+		svcControl:        svcControl,
+		pvcControl:        pvcControl,
+		podControl:        podControl,
+		eventControl:      eventControl,
+		newClient:         newClient,
+	}
+}
+
+func (nsm *nameServiceManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if len(hc.Spec.NameServices) == 0 && hc.Status.Federation == nil {
+		return nil
+	}
+	if err := validateFederation(hc); err != nil {
+		glog.Errorf("federation of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+		nsm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidFederation", err.Error())
+		return err
+	}
+	if err := nsm.removeNameServices(hc); err != nil {
+		return err
+	}
+	if len(hc.Spec.NameServices) == 0 {
+		hc.Status.Federation = nil
+		return nil
+	}
+	if hc.Status.Federation == nil {
+		hc.Status.Federation = &v1alpha1.FederationStatus{}
+	}
+	if hc.Status.Federation.ClusterID == "" {
+		info, err := nsm.newClient(hc, hc.Name).GetNameNodeInfo()
+		if err != nil {
+			return fmt.Errorf("get cluster id of the name node error: %v", err)
+		}
+		hc.Status.Federation.ClusterID = info.ClusterID
+	}
+	for _, ns := range hc.Spec.NameServices {
+		if err := nsm.syncNameService(hc, ns); err != nil {
+			glog.Errorf("sync name service %s error, err=%+v", ns.Name, err)
+			return err
+		}
+	}
+	hc.Status.Federation.NameServices = nsm.nameServiceStatus(hc)
+	glog.Infof("sync name services success")
+	return nil
+}
+
+func (nsm *nameServiceManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+// removeNameServices deletes the name nodes of the name services no longer
+// in the spec, their pvcs are kept so adding them back brings their namespace back
+func (nsm *nameServiceManager) removeNameServices(hc *v1alpha1.HdfsCluster) error {
+	if hc.Status.Federation == nil {
+		return nil
+	}
+	names := nameServices(hc)
+	for _, status := range hc.Status.Federation.NameServices {
+		if containsString(names, status.Name) {
+			continue
+		}
+		name := controller.NameServiceName(hc.Name, status.Name)
+		if err := nsm.deploymentControl.DeleteDeployment(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		if err := nsm.svcControl.DeleteService(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		nsm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "NameServiceRemoved",
+			fmt.Sprintf("name node of name service %s is deleted, its pvc %s is kept", status.Name, name))
+	}
+	return nil
+}
+
+func (nsm *nameServiceManager) syncNameService(hc *v1alpha1.HdfsCluster, ns v1alpha1.NameServiceSpec) error {
+	name := controller.NameServiceName(hc.Name, ns.Name)
+	if _, err := nsm.svcControl.GetService(hc, name); errors.IsNotFound(err) {
+		if err := nsm.svcControl.CreateService(hc, nsm.getService(hc, ns)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	if _, err := nsm.pvcControl.GetPVC(hc, name); errors.IsNotFound(err) {
+		if err := nsm.pvcControl.CreatePVC(hc, nsm.getPVC(hc, ns)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+	old, err := nsm.deploymentControl.GetDeployment(hc, name)
+	if errors.IsNotFound(err) {
+		return nsm.deploymentControl.CreateDeployment(hc, nsm.getDeployment(hc, ns))
+	}
+	if err != nil {
+		return err
+	}
+	// the same settings as the name node of the cluster, adding a name
+	// service restarts all name nodes
+	deployment := old.DeepCopy()
+	spec := &deployment.Spec.Template.Spec
+	setRackAwareness(hc, spec)
+	setHostsExclude(hc, spec)
+	setSecurity(hc, &deployment.Spec.Template, securityNameNode)
+	setKMS(hc, spec)
+	setFederation(hc, spec, ns.Name)
+	setProxyUsers(hc, spec)
+	setUpgrade(spec, nameNodeImage(hc), upgradeFrom(hc).NameNode, nameServiceUpgradeScript, old.Spec.Template.Spec.Containers[0].Args, nameNodeRollback(hc))
+	if reflect.DeepEqual(deployment.Spec.Template, old.Spec.Template) {
+		return nil
+	}
+	return nsm.deploymentControl.UpdateDeployment(hc, deployment)
+}
+
+// nameServiceStatus reports the block pool of every name service whose
+// name node answers
+func (nsm *nameServiceManager) nameServiceStatus(hc *v1alpha1.HdfsCluster) []v1alpha1.NameServiceStatus {
+	var statuses []v1alpha1.NameServiceStatus
+	for _, name := range nameServices(hc) {
+		status := v1alpha1.NameServiceStatus{Name: name}
+		ready := true
+		if name != hc.Name {
+			ok, _, err := nsm.podControl.CheckPodsStatus(hc, controller.NameServiceLabel(hc.Name, name))
+			ready = err == nil && ok
+		}
+		if ready {
+			info, err := nsm.newClient(hc, name).GetNameNodeInfo()
+			if err != nil {
+				glog.Infof("name node of name service %s is not available, %v", name, err)
+			} else {
+				status.Ready = true
+				status.BlockPoolID = info.BlockPoolID
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+func (nsm *nameServiceManager) getService(hc *v1alpha1.HdfsCluster, ns v1alpha1.NameServiceSpec) *corev1.Service {
+	labels := controller.NameServiceLabel(hc.Name, ns.Name)
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.NameServiceName(hc.Name, ns.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "nn-rpc",
+					Port:       controller.NameNodeRPCPort,
+					TargetPort: intstr.FromInt(controller.NameNodeRPCPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				nameNodeWebServicePort(hc),
+			},
+			Selector: labels,
+		},
+	}
+}
+
+func (nsm *nameServiceManager) getPVC(hc *v1alpha1.HdfsCluster, ns v1alpha1.NameServiceSpec) *corev1.PersistentVolumeClaim {
+	q, _ := resource.ParseQuantity(ns.NameNode.Storage)
+	sc := ns.NameNode.StorageClass
+	// no owner reference, the pvc retention finalizer deletes or keeps it
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      controller.NameServiceName(hc.Name, ns.Name),
+			Namespace: hc.Namespace,
+			Labels:    controller.NameServiceLabel(hc.Name, ns.Name),
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes: []corev1.PersistentVolumeAccessMode{
+				corev1.ReadWriteOnce,
+			},
+			StorageClassName: &sc,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: q,
+				},
+			},
+		},
+	}
+}
+
+func (nsm *nameServiceManager) getDeployment(hc *v1alpha1.HdfsCluster, ns v1alpha1.NameServiceSpec) *apps.Deployment {
+	name := controller.NameServiceName(hc.Name, ns.Name)
+	labels := controller.NameServiceLabel(hc.Name, ns.Name)
+	replicas := int32(1)
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			// the name pvc is ReadWriteOnce
+			Strategy: apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:  "namenode",
+							Image: controller.NameNodeImage,
+							Args:  []string{"/bin/bash", "-c", nameServiceScript},
+							Env: []corev1.EnvVar{
+								{Name: "CLUSTER_NAME", Value: hc.Name},
+								{Name: "CLUSTER_ID", Value: hc.Status.Federation.ClusterID},
+							},
+							Ports: nameNodeContainerPorts(hc),
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      "hdfs-name",
+									MountPath: "/hadoop/dfs/name",
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name: "hdfs-name",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+									ClaimName: name,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	spec := &deployment.Spec.Template.Spec
+	setRackAwareness(hc, spec)
+	setHostsExclude(hc, spec)
+	setSecurity(hc, &deployment.Spec.Template, securityNameNode)
+	setKMS(hc, spec)
+	setFederation(hc, spec, ns.Name)
+	setProxyUsers(hc, spec)
+	setUpgrade(spec, nameNodeImage(hc), upgradeFrom(hc).NameNode, nameServiceUpgradeScript, nil, nameNodeRollback(hc))
+	return deployment
+}
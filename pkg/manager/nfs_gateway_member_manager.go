@@ -0,0 +1,215 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+const (
+	nfsDumpDir    = "/tmp/.hdfs-nfs"
+	nfsDumpVolume = "nfs-dump"
+)
+
+// nfs3Script waits for the portmap of the pod, the nfs gateway registers
+// its mountd and nfs programs with it on start
+const nfs3Script = `until (echo > /dev/tcp/127.0.0.1/111) 2>/dev/null; do
+  echo "waiting for portmap"
+  sleep 1
+done
+exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR nfs3`
+
+type nfsGatewayManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	svcControl        controller.ServiceControlInterface
+}
+
+// NewNFSGatewayManager runs the nfs gateways of a cluster, every pod runs
+// the portmap of its gateway
+func NewNFSGatewayManager(
+	deployControl controller.DeploymentControlInterface,
+	svcControl controller.ServiceControlInterface,
+) Manager {
+	return &nfsGatewayManager{
+		deploymentControl: deployControl,
+		svcControl:        svcControl,
+	}
+}
+
+func (nm *nfsGatewayManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.NFSGateway == nil {
+		return removeGateway(nm.deploymentControl, nm.svcControl, hc, controller.NFSGatewayName(hc.Name))
+	}
+	if err := validateProxyHosts(hc.Spec.NFSGateway.ProxyHosts); err != nil {
+		return fmt.Errorf("nfs gateway of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+	}
+	if err := nm.syncService(hc); err != nil {
+		glog.Errorf("sync nfs gateway service error, err=%+v", err)
+		return err
+	}
+	if err := syncGatewayDeployment(nm.deploymentControl, hc, nm.getDeployment(hc)); err != nil {
+		glog.Errorf("sync nfs gateway deployment error, err=%+v", err)
+		return err
+	}
+	glog.Infof("sync nfs gateway success")
+	return nil
+}
+
+func (nm *nfsGatewayManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+func (nm *nfsGatewayManager) syncService(hc *v1alpha1.HdfsCluster) error {
+	_, err := nm.svcControl.GetService(hc, controller.NFSGatewayName(hc.Name))
+	if errors.IsNotFound(err) {
+		return nm.svcControl.CreateService(hc, nm.getService(hc))
+	}
+	return err
+}
+
+func (nm *nfsGatewayManager) getService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.NFSGatewayName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.NFSGatewayLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "portmap-tcp",
+					Port:       controller.PortmapPort,
+					TargetPort: intstr.FromInt(controller.PortmapPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				{
+					Name:       "portmap-udp",
+					Port:       controller.PortmapPort,
+					TargetPort: intstr.FromInt(controller.PortmapPort),
+					Protocol:   corev1.ProtocolUDP,
+				},
+				{
+					Name:       "nfs",
+					Port:       controller.NFSPort,
+					TargetPort: intstr.FromInt(controller.NFSPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				{
+					Name:       "mountd-tcp",
+					Port:       controller.NFSMountdPort,
+					TargetPort: intstr.FromInt(controller.NFSMountdPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				{
+					Name:       "mountd-udp",
+					Port:       controller.NFSMountdPort,
+					TargetPort: intstr.FromInt(controller.NFSMountdPort),
+					Protocol:   corev1.ProtocolUDP,
+				},
+			},
+			Selector: controller.NFSGatewayLabel(hc.Name),
+			// the gateway buffers the writes of a file until they are in order
+			SessionAffinity: corev1.ServiceAffinityClientIP,
+		},
+	}
+}
+
+func (nm *nfsGatewayManager) getDeployment(hc *v1alpha1.HdfsCluster) *apps.Deployment {
+	labels := controller.NFSGatewayLabel(hc.Name)
+	var env []corev1.EnvVar
+	for _, conf := range nfsGatewayConf(hc) {
+		env = append(env, conf.env())
+	}
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.NFSGatewayName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: gatewayReplicas(hc.Spec.NFSGateway.Replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "portmap",
+							Image:           controller.HadoopImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", "exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR portmap"},
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: controller.PortmapPort, Name: "portmap-tcp", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.PortmapPort, Name: "portmap-udp", Protocol: corev1.ProtocolUDP},
+							},
+						},
+						{
+							Name:            "nfs3",
+							Image:           controller.HadoopImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", nfs3Script},
+							Env:             env,
+							Resources:       hc.Spec.NFSGateway.Resources,
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: controller.NFSPort, Name: "nfs", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.NFSMountdPort, Name: "mountd-tcp", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.NFSMountdPort, Name: "mountd-udp", Protocol: corev1.ProtocolUDP},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(controller.NFSPort)},
+								},
+								PeriodSeconds: 10,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      nfsDumpVolume,
+									MountPath: nfsDumpDir,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name:         nfsDumpVolume,
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						},
+					},
+				},
+			},
+		},
+	}
+	setGatewayConf(hc, &deployment.Spec.Template)
+	return deployment
+}
+
+// nfsGatewayConf exports the file system to the allowed hosts, with
+// kerberos the gateway logs in as the admin principal
+func nfsGatewayConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	allowed := hc.Spec.NFSGateway.AllowedHosts
+	if allowed == "" {
+		allowed = "* rw"
+	}
+	confs := []hadoopConf{
+		{hdfsConf, "nfs.exports.allowed.hosts", allowed},
+		{hdfsConf, "nfs.dump.dir", nfsDumpDir},
+	}
+	if krb := kerberosSpec(hc); krb != nil {
+		confs = append(confs,
+			hadoopConf{hdfsConf, "nfs.kerberos.principal", krb.Admin.Principal},
+			hadoopConf{hdfsConf, "nfs.keytab.file", keytabDir + "/" + keytabFile})
+	}
+	return confs
+}
@@ -0,0 +1,244 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scaledPVCMinAge keeps a pvc the statefulset just created for a scale out
+// from being taken for a scaled in one while the listers lag behind
+const scaledPVCMinAge = 5 * time.Minute
+
+// PVCReclaimer applies the pvc retention policy of an hdfs cluster
+type PVCReclaimer interface {
+	// ReclaimScaled applies whenScaled to the pvcs of removed data nodes
+	ReclaimScaled(cluster *v1alpha1.HdfsCluster) error
+	// ReleaseOwned drops the owner reference of the name node, name service
+	// and kms pvcs created before the finalizer owned their deletion, the
+	// garbage collector would delete them with the cluster otherwise
+	ReleaseOwned(cluster *v1alpha1.HdfsCluster) error
+	// Finalize applies whenDeleted to all pvcs, the cluster finalizer is
+	// only removed after it succeeds
+	Finalize(cluster *v1alpha1.HdfsCluster) error
+}
+
+type pvcReclaimer struct {
+	pvcControl   controller.PVCControlInterface
+	setControl   controller.StatefulSetControlInterface
+	podControl   controller.PodControlInterface
+	eventControl controller.EventControlInterface
+}
+
+func NewPVCReclaimer(
+	pvcControl controller.PVCControlInterface,
+	setControl controller.StatefulSetControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+) PVCReclaimer {
+	return &pvcReclaimer{
+		pvcControl:   pvcControl,
+		setControl:   setControl,
+		podControl:   podControl,
+		eventControl: eventControl,
+	}
+}
+
+func (r *pvcReclaimer) ReclaimScaled(hc *v1alpha1.HdfsCluster) error {
+	if hc.DataNodePVCRetentionPolicy().WhenScaled != v1alpha1.DeletePVCRetentionPolicy {
+		return nil
+	}
+	set, err := r.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		glog.Errorf("get data node statefulset error, err=%+v", err)
+		return err
+	}
+	if set.Status.ObservedGeneration < set.Generation {
+		glog.V(4).Infof("data node statefulset of %s/%s is not observed yet, skip reclaiming pvcs", hc.Namespace, hc.Name)
+		return nil
+	}
+	// a pvc is only released once its pod is gone as well
+	replicas := set.Status.Replicas
+	if set.Spec.Replicas != nil && *set.Spec.Replicas > replicas {
+		replicas = *set.Spec.Replicas
+	}
+	pvcs, err := r.dataNodePVCs(hc)
+	if err != nil {
+		return err
+	}
+	// the data node label is shared by every cluster in the namespace
+	pods, err := r.podControl.ListPods(hc, controller.DataNodeLabel())
+	if err != nil {
+		return err
+	}
+	running := map[string]bool{}
+	for _, pod := range pods {
+		running[pod.Name] = true
+	}
+	for ordinal, pvc := range pvcs {
+		if ordinal < int(replicas) || running[fmt.Sprintf("%s-%d", set.Name, ordinal)] {
+			continue
+		}
+		if time.Since(pvc.CreationTimestamp.Time) < scaledPVCMinAge {
+			continue
+		}
+		if err := r.deletePVC(hc, pvc, "scaled in"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *pvcReclaimer) ReleaseOwned(hc *v1alpha1.HdfsCluster) error {
+	for _, name := range clusterPVCNames(hc) {
+		pvc, err := r.pvcControl.GetPVC(hc, name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := r.dropOwnerRef(hc, pvc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clusterPVCNames are the pvcs created by the operator itself, the name
+// nodes of the name services and the keystore of the kms follow the name
+// node, the encrypted files can not be read without the keys of the kms
+func clusterPVCNames(hc *v1alpha1.HdfsCluster) []string {
+	names := []string{controller.NameNodePVCName(hc.Name), controller.KMSName(hc.Name)}
+	for _, ns := range hc.Spec.NameServices {
+		names = append(names, controller.NameServiceName(hc.Name, ns.Name))
+	}
+	if hc.Status.Federation != nil {
+		for _, ns := range hc.Status.Federation.NameServices {
+			if ns.Name != hc.Name {
+				names = append(names, controller.NameServiceName(hc.Name, ns.Name))
+			}
+		}
+	}
+	seen := map[string]bool{}
+	out := []string{}
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+func (r *pvcReclaimer) Finalize(hc *v1alpha1.HdfsCluster) error {
+	for _, name := range clusterPVCNames(hc) {
+		pvc, err := r.pvcControl.GetPVC(hc, name)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			glog.Errorf("get pvc %s error, err=%+v", name, err)
+			return err
+		}
+		if hc.NameNodePVCRetentionPolicy().WhenDeleted == v1alpha1.DeletePVCRetentionPolicy {
+			err = r.deletePVC(hc, pvc, "cluster deleted")
+		} else {
+			err = r.orphanPVC(hc, pvc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	pvcs, err := r.dataNodePVCs(hc)
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs {
+		if hc.DataNodePVCRetentionPolicy().WhenDeleted == v1alpha1.DeletePVCRetentionPolicy {
+			err = r.deletePVC(hc, pvc, "cluster deleted")
+		} else {
+			err = r.orphanPVC(hc, pvc)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataNodePVCs returns the data node pvcs of hc by ordinal
+func (r *pvcReclaimer) dataNodePVCs(hc *v1alpha1.HdfsCluster) (map[int]*corev1.PersistentVolumeClaim, error) {
+	pvcs, err := r.pvcControl.ListPVCs(hc, controller.DataNodeLabel())
+	if err != nil {
+		glog.Errorf("list data node pvcs error, err=%+v", err)
+		return nil, err
+	}
+	prefix := controller.DataNodePVCPrefix(hc.Name)
+	out := map[int]*corev1.PersistentVolumeClaim{}
+	for _, pvc := range pvcs {
+		if !strings.HasPrefix(pvc.Name, prefix) {
+			continue
+		}
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pvc.Name, prefix))
+		if err != nil {
+			continue
+		}
+		out[ordinal] = pvc
+	}
+	return out, nil
+}
+
+func (r *pvcReclaimer) deletePVC(hc *v1alpha1.HdfsCluster, pvc *corev1.PersistentVolumeClaim, why string) error {
+	if pvc.DeletionTimestamp != nil {
+		return nil
+	}
+	if err := r.pvcControl.DeletePVC(hc, pvc); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	msg := fmt.Sprintf("delete pvc %s, %s", pvc.Name, why)
+	glog.Info(msg)
+	r.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "PVCDeleted", msg)
+	return nil
+}
+
+// orphanPVC drops the owner reference to hc, so the garbage collector keeps the pvc
+func (r *pvcReclaimer) orphanPVC(hc *v1alpha1.HdfsCluster, pvc *corev1.PersistentVolumeClaim) error {
+	dropped, err := r.dropOwnerRef(hc, pvc)
+	if err != nil || !dropped {
+		return err
+	}
+	msg := fmt.Sprintf("retain pvc %s after the cluster is deleted", pvc.Name)
+	glog.Info(msg)
+	r.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "PVCRetained", msg)
+	return nil
+}
+
+func (r *pvcReclaimer) dropOwnerRef(hc *v1alpha1.HdfsCluster, pvc *corev1.PersistentVolumeClaim) (bool, error) {
+	refs := []metav1.OwnerReference{}
+	for _, ref := range pvc.OwnerReferences {
+		if ref.UID != hc.UID {
+			refs = append(refs, ref)
+		}
+	}
+	if len(refs) == len(pvc.OwnerReferences) {
+		return false, nil
+	}
+	newPVC := pvc.DeepCopy()
+	newPVC.OwnerReferences = refs
+	if _, err := r.pvcControl.UpdatePVC(hc, newPVC); err != nil {
+		return false, err
+	}
+	return true, nil
+}
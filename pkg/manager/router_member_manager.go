@@ -0,0 +1,475 @@
+package manager
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	routerConfigDir    = "/etc/hadoop-router"
+	routerConfigVolume = "router-config"
+	// routerConfigAnnotation restarts the routers when their config changes
+	routerConfigAnnotation = "storage.io/router-config"
+	// routerMountsAnnotation keeps the mount table a mounts job applies
+	routerMountsAnnotation = "storage.io/router-mounts"
+	// routerStatePath keeps the state store in the name service of the
+	// cluster when there is no zookeeper, all routers share it
+	routerStatePath = "/system/router"
+)
+
+// routerPathRegexp keeps the paths of the mount table quotable in the job script
+var routerPathRegexp = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+type routerManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	svcControl        controller.ServiceControlInterface
+	cmControl         controller.ConfigMapControlInterface
+	jobControl        controller.JobControlInterface
+	podControl        controller.PodControlInterface
+	eventControl      controller.EventControlInterface
+}
+
+// NewRouterManager runs the dfs routers of a cluster and keeps their mount
+// table in line with the mounts of the spec
+func NewRouterManager(
+	deployControl controller.DeploymentControlInterface,
+	svcControl controller.ServiceControlInterface,
+	cmControl controller.ConfigMapControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &routerManager{
+		deploymentControl: deployControl,
+		svcControl:        svcControl,
+		cmControl:         cmControl,
+		jobControl:        jobControl,
+		podControl:        podControl,
+		eventControl:      eventControl,
+	}
+}
+
+func (rm *routerManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.Router == nil {
+		if hc.Status.Router == nil {
+			return nil
+		}
+		return rm.removeRouter(hc)
+	}
+	if err := validateRouter(hc); err != nil {
+		glog.Errorf("router of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+		rm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidRouter", err.Error())
+		return err
+	}
+	if hc.Status.Router == nil {
+		hc.Status.Router = &v1alpha1.RouterStatus{}
+	}
+	data := routerConfigData(hc)
+	if err := rm.syncConfigMap(hc, data); err != nil {
+		glog.Errorf("sync router config error, err=%+v", err)
+		return err
+	}
+	if err := rm.syncService(hc); err != nil {
+		glog.Errorf("sync router service error, err=%+v", err)
+		return err
+	}
+	if err := rm.syncDeployment(hc, data); err != nil {
+		glog.Errorf("sync router deployment error, err=%+v", err)
+		return err
+	}
+	if err := rm.syncMounts(hc); err != nil {
+		glog.Errorf("sync router mount table error, err=%+v", err)
+		return err
+	}
+	glog.Infof("sync router success")
+	return nil
+}
+
+func (rm *routerManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	if st := hc.Status.Router; st != nil && st.Message != "" {
+		return fmt.Errorf("%s", st.Message)
+	}
+	return nil
+}
+
+// removeRouter deletes the routers once the router is removed from the
+// spec, the state store is left as it is
+func (rm *routerManager) removeRouter(hc *v1alpha1.HdfsCluster) error {
+	name := controller.RouterName(hc.Name)
+	if err := rm.deploymentControl.DeleteDeployment(hc, name); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err := rm.svcControl.DeleteService(hc, name); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	cm, err := rm.cmControl.GetConfigMap(hc, name)
+	if err == nil {
+		err = rm.cmControl.DeleteConfigMap(hc, cm)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	hc.Status.Router = nil
+	return nil
+}
+
+func validateRouter(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.Security != nil && (hc.Spec.Security.Kerberos != nil || hc.Spec.Security.TLS != nil) {
+		return fmt.Errorf("the router can not be combined with kerberos or tls yet")
+	}
+	if err := validateFederation(hc); err != nil {
+		return err
+	}
+	for _, m := range hc.Spec.Mounts {
+		if !routerPathRegexp.MatchString(m.Path) || (m.TargetPath != "" && !routerPathRegexp.MatchString(m.TargetPath)) {
+			return fmt.Errorf("mount %s has characters the router mount table does not take", m.Path)
+		}
+	}
+	return nil
+}
+
+func (rm *routerManager) syncConfigMap(hc *v1alpha1.HdfsCluster, data map[string]string) error {
+	name := controller.RouterName(hc.Name)
+	cm, err := rm.cmControl.GetConfigMap(hc, name)
+	if errors.IsNotFound(err) {
+		return rm.cmControl.CreateConfigMap(hc, &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       hc.Namespace,
+				Labels:          controller.RouterLabel(hc.Name),
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			},
+			Data: data,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	return rm.cmControl.UpdateConfigMap(hc, cm)
+}
+
+func (rm *routerManager) syncService(hc *v1alpha1.HdfsCluster) error {
+	_, err := rm.svcControl.GetService(hc, controller.RouterName(hc.Name))
+	if errors.IsNotFound(err) {
+		return rm.svcControl.CreateService(hc, rm.getService(hc))
+	}
+	return err
+}
+
+func (rm *routerManager) syncDeployment(hc *v1alpha1.HdfsCluster, data map[string]string) error {
+	desired := rm.getDeployment(hc, data)
+	old, err := rm.deploymentControl.GetDeployment(hc, desired.Name)
+	if errors.IsNotFound(err) {
+		return rm.deploymentControl.CreateDeployment(hc, desired)
+	}
+	if err != nil {
+		return err
+	}
+	deployment := old.DeepCopy()
+	deployment.Spec.Replicas = desired.Spec.Replicas
+	deployment.Spec.Template = desired.Spec.Template
+	if reflect.DeepEqual(deployment.Spec, old.Spec) {
+		return nil
+	}
+	return rm.deploymentControl.UpdateDeployment(hc, deployment)
+}
+
+// syncMounts runs a job applying the difference between the mount table in
+// the spec and the one last applied, once the routers are ready
+func (rm *routerManager) syncMounts(hc *v1alpha1.HdfsCluster) error {
+	status := hc.Status.Router
+	if status.JobName != "" {
+		// a failed job is retried on the next sync
+		return rm.checkMountsJob(hc)
+	}
+	desired := sortedMounts(hc.Spec.Mounts)
+	if reflect.DeepEqual(desired, sortedMounts(status.Mounts)) {
+		return nil
+	}
+	ok, _, err := rm.podControl.CheckPodsStatus(hc, controller.RouterLabel(hc.Name))
+	if err != nil || !ok {
+		glog.Infof("routers of %s/%s are not ready, the mount table is applied later", hc.Namespace, hc.Name)
+		return err
+	}
+	job := rm.newMountsJob(hc, status.Mounts, desired)
+	if err := rm.jobControl.CreateJob(hc, job); err != nil && !errors.IsAlreadyExists(err) {
+		return err
+	}
+	status.JobName = job.Name
+	return nil
+}
+
+// checkMountsJob records the mount table of a finished job and deletes it
+func (rm *routerManager) checkMountsJob(hc *v1alpha1.HdfsCluster) error {
+	status := hc.Status.Router
+	job, err := rm.jobControl.GetJob(hc, status.JobName)
+	if errors.IsNotFound(err) {
+		status.JobName = ""
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return nil
+	}
+	if result == batchv1.JobComplete {
+		var mounts []v1alpha1.MountSpec
+		if err := json.Unmarshal([]byte(job.Annotations[routerMountsAnnotation]), &mounts); err != nil {
+			return err
+		}
+		status.Mounts = mounts
+		status.Message = ""
+		rm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "RouterMountsApplied",
+			fmt.Sprintf("router mount table has %d mounts", len(mounts)))
+	} else {
+		summary := JobSummary(rm.podControl, hc, job)
+		if summary == "" {
+			summary = JobFailureMessage(job)
+		}
+		status.Message = "apply router mount table failed: " + summary
+		rm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "RouterMountsFailed", summary)
+	}
+	status.JobName = ""
+	return rm.jobControl.DeleteJob(hc, job)
+}
+
+// newMountsJob removes the mounts of applied that are gone or changed and
+// adds the new and changed mounts of desired
+func (rm *routerManager) newMountsJob(hc *v1alpha1.HdfsCluster, applied []v1alpha1.MountSpec, desired []v1alpha1.MountSpec) *batchv1.Job {
+	old := map[string]v1alpha1.MountSpec{}
+	for _, m := range applied {
+		old[m.Path] = m
+	}
+	var lines []string
+	for _, m := range desired {
+		if o, ok := old[m.Path]; ok && o == m {
+			delete(old, m.Path)
+			continue
+		}
+		if _, ok := old[m.Path]; ok {
+			lines = append(lines, fmt.Sprintf("hdfs dfsrouteradmin -rm '%s' || true", m.Path))
+			delete(old, m.Path)
+		}
+		target := m.TargetPath
+		if target == "" {
+			target = m.Path
+		}
+		lines = append(lines, fmt.Sprintf("hdfs dfsrouteradmin -add '%s' '%s' '%s'", m.Path, m.NameService, target))
+	}
+	for _, m := range sortedMounts(mapMounts(old)) {
+		lines = append(lines, fmt.Sprintf("hdfs dfsrouteradmin -rm '%s' || true", m.Path))
+	}
+	lines = append(lines, "hdfs dfsrouteradmin -ls")
+	script := "set -e\n" + strings.Join(lines, "\n")
+	job := NewHadoopJob(hc, controller.RouterMountsJobName(hc.Name), SummarizedScript(script, false))
+	encoded, _ := json.Marshal(desired)
+	job.Annotations = map[string]string{routerMountsAnnotation: string(encoded)}
+	spec := &job.Spec.Template.Spec
+	spec.Containers[0].Image = routerImage(hc)
+	setRouterConfig(hc, spec)
+	return job
+}
+
+func (rm *routerManager) getService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.RouterName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.RouterLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "router-rpc",
+					Port:       controller.RouterRPCPort,
+					TargetPort: intstr.FromInt(controller.RouterRPCPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				{
+					Name:       "router-admin",
+					Port:       controller.RouterAdminPort,
+					TargetPort: intstr.FromInt(controller.RouterAdminPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+				{
+					Name:       "router-web",
+					Port:       controller.RouterHTTPPort,
+					TargetPort: intstr.FromInt(controller.RouterHTTPPort),
+					Protocol:   corev1.ProtocolTCP,
+				},
+			},
+			Selector: controller.RouterLabel(hc.Name),
+		},
+	}
+}
+
+func (rm *routerManager) getDeployment(hc *v1alpha1.HdfsCluster, data map[string]string) *apps.Deployment {
+	labels := controller.RouterLabel(hc.Name)
+	replicas := hc.Spec.Router.Replicas
+	if replicas <= 0 {
+		replicas = 1
+	}
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.RouterName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      labels,
+					Annotations: map[string]string{routerConfigAnnotation: routerConfigRevision(data)},
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "router",
+							Image:           routerImage(hc),
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Args:            []string{"/bin/bash", "-c", "exec hdfs dfsrouter"},
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: controller.RouterRPCPort, Name: "router-rpc", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.RouterAdminPort, Name: "router-admin", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.RouterHTTPPort, Name: "router-web", Protocol: corev1.ProtocolTCP},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(controller.RouterRPCPort)},
+								},
+								PeriodSeconds: 10,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	setRouterConfig(hc, &deployment.Spec.Template.Spec)
+	return deployment
+}
+
+func routerImage(hc *v1alpha1.HdfsCluster) string {
+	if hc.Spec.Router.Image != "" {
+		return hc.Spec.Router.Image
+	}
+	return controller.RouterImage
+}
+
+// setRouterConfig mounts the router config as HADOOP_CONF_DIR, the hadoop 3
+// images do not read the *_CONF env vars of the hadoop 2.7 images
+func setRouterConfig(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	removeVolumes(spec, routerConfigVolume)
+	removeEnv(spec, "HADOOP_CONF_DIR")
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: routerConfigVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: controller.RouterName(hc.Name)},
+			},
+		},
+	})
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.Env = append(c.Env, corev1.EnvVar{Name: "HADOOP_CONF_DIR", Value: routerConfigDir})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: routerConfigVolume, MountPath: routerConfigDir, ReadOnly: true})
+	}
+}
+
+// routerConf is the config of the routers and of the router admin jobs, the
+// name nodes are monitored through their rpc and web services
+func routerConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	confs := []hadoopConf{
+		{coreConf, "fs.defaultFS", "hdfs://" + controller.NameNodeRPCAddress(hc)},
+	}
+	if fed := federationConf(hc); len(fed) != 0 {
+		confs = append(confs, fed...)
+	} else {
+		confs = append(confs,
+			hadoopConf{hdfsConf, nameServicesKey, hc.Name},
+			hadoopConf{hdfsConf, rpcAddressKey + "." + hc.Name, controller.NameNodeRPCAddress(hc)})
+	}
+	names := nameServices(hc)
+	for _, ns := range names {
+		confs = append(confs, hadoopConf{hdfsConf, "dfs.namenode.http-address." + ns, controller.NameServiceHTTPAddress(hc, ns)})
+	}
+	confs = append(confs,
+		hadoopConf{hdfsConf, "dfs.federation.router.default.nameserviceId", hc.Name},
+		hadoopConf{hdfsConf, "dfs.federation.router.monitor.namenode", strings.Join(names, ",")},
+		hadoopConf{hdfsConf, "dfs.federation.router.rpc-bind-host", "0.0.0.0"},
+		hadoopConf{hdfsConf, "dfs.federation.router.admin-address", controller.RouterAdminAddress(hc)},
+		hadoopConf{hdfsConf, "dfs.federation.router.admin-bind-host", "0.0.0.0"},
+	)
+	if zk := hc.Spec.Router.ZooKeeper; zk != "" {
+		confs = append(confs,
+			hadoopConf{hdfsConf, "dfs.federation.router.store.driver.class", "org.apache.hadoop.hdfs.server.federation.store.driver.impl.StateStoreZooKeeperImpl"},
+			hadoopConf{coreConf, "hadoop.zk.address", zk})
+	} else {
+		confs = append(confs,
+			hadoopConf{hdfsConf, "dfs.federation.router.store.driver.class", "org.apache.hadoop.hdfs.server.federation.store.driver.impl.StateStoreFileSystemImpl"},
+			hadoopConf{hdfsConf, "dfs.federation.router.store.driver.fs.path", routerStatePath})
+	}
+	return confs
+}
+
+func routerConfigData(hc *v1alpha1.HdfsCluster) map[string]string {
+	var core, hdfs []hadoopConf
+	for _, c := range routerConf(hc) {
+		if c.prefix == coreConf {
+			core = append(core, c)
+		} else {
+			hdfs = append(hdfs, c)
+		}
+	}
+	return map[string]string{
+		coreSiteKey: siteXML(core, nil),
+		hdfsSiteKey: siteXML(hdfs, nil),
+	}
+}
+
+func routerConfigRevision(data map[string]string) string {
+	sum := sha256.Sum256([]byte(data[coreSiteKey] + data[hdfsSiteKey]))
+	return hex.EncodeToString(sum[:8])
+}
+
+func sortedMounts(mounts []v1alpha1.MountSpec) []v1alpha1.MountSpec {
+	sorted := append([]v1alpha1.MountSpec{}, mounts...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted
+}
+
+func mapMounts(mounts map[string]v1alpha1.MountSpec) []v1alpha1.MountSpec {
+	var list []v1alpha1.MountSpec
+	for _, m := range mounts {
+		list = append(list, m)
+	}
+	return list
+}
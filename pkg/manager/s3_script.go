@@ -0,0 +1,70 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	corev1 "k8s.io/api/core/v1"
+	"net/url"
+	"strings"
+)
+
+// s3Functions defines s3_put and s3_get for the hadoop image, they sign the
+// requests with aws signature version 4 through openssl so the credentials
+// stay in the pod env and the objects are streamed by curl. The path must be
+// escaped already, see s3ObjectPath
+const s3Functions = `s3_hmac() { printf '%s' "$2" | openssl dgst -sha256 -mac HMAC -macopt "$1" | sed 's/^.* //'; }
+s3_request() {
+  local method=$1 path=$2 now day host scope creq sts key sig
+  shift 2
+  now=$(date -u +%Y%m%dT%H%M%SZ)
+  day=${now%%T*}
+  host=${S3_ENDPOINT#*://}
+  host=${host%%/*}
+  scope=$day/$S3_REGION/s3/aws4_request
+  creq=$(printf '%s\n%s\n\nhost:%s\nx-amz-content-sha256:UNSIGNED-PAYLOAD\nx-amz-date:%s\n\nhost;x-amz-content-sha256;x-amz-date\nUNSIGNED-PAYLOAD' "$method" "$path" "$host" "$now")
+  sts=$(printf 'AWS4-HMAC-SHA256\n%s\n%s\n%s' "$now" "$scope" "$(printf '%s' "$creq" | openssl dgst -sha256 | sed 's/^.* //')")
+  key=$(s3_hmac "key:AWS4$S3_SECRET_KEY" "$day")
+  key=$(s3_hmac "hexkey:$key" "$S3_REGION")
+  key=$(s3_hmac "hexkey:$key" s3)
+  key=$(s3_hmac "hexkey:$key" aws4_request)
+  sig=$(s3_hmac "hexkey:$key" "$sts")
+  curl -fsS --connect-timeout 30 -X "$method" \
+    -H "x-amz-date: $now" -H "x-amz-content-sha256: UNSIGNED-PAYLOAD" \
+    -H "Authorization: AWS4-HMAC-SHA256 Credential=$S3_ACCESS_KEY/$scope, SignedHeaders=host;x-amz-content-sha256;x-amz-date, Signature=$sig" \
+    "$@" "${S3_ENDPOINT%/}$path"
+}
+s3_put() { s3_request PUT "$2" -T "$1"; }
+s3_get() { s3_request GET "$1" -o "$2"; }
+`
+
+// s3Env points the s3 functions at target, the credentials come from the
+// target secret
+func s3Env(target *v1alpha1.S3BackupTarget) []corev1.EnvVar {
+	region := target.Region
+	if region == "" {
+		region = s3.DefaultRegion
+	}
+	secretEnv := func(name, key string) corev1.EnvVar {
+		return corev1.EnvVar{
+			Name: name,
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: target.SecretName},
+					Key:                  key,
+				},
+			},
+		}
+	}
+	return []corev1.EnvVar{
+		{Name: "S3_ENDPOINT", Value: strings.TrimRight(target.Endpoint, "/")},
+		{Name: "S3_REGION", Value: region},
+		secretEnv("S3_ACCESS_KEY", s3AccessKey),
+		secretEnv("S3_SECRET_KEY", s3SecretKey),
+	}
+}
+
+// s3ObjectPath is the escaped path style url path of key in bucket
+func s3ObjectPath(bucket, key string) string {
+	u := url.URL{Path: "/" + bucket + "/" + strings.TrimLeft(key, "/")}
+	return u.EscapedPath()
+}
@@ -7,8 +7,14 @@ import (
 
 type Scaler interface {
 	ScaleOut(cluster *v1alpha1.HdfsCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error
+	ScaleIn(cluster *v1alpha1.HdfsCluster, oldSet *apps.StatefulSet, newSet *apps.StatefulSet) error
+	Recommission(cluster *v1alpha1.HdfsCluster, replicas int32) error
 }
 
 func increaseReplicas(newSet *apps.StatefulSet, oldSet *apps.StatefulSet) {
 	*newSet.Spec.Replicas = *oldSet.Spec.Replicas + 1
 }
+
+func decreaseReplicas(newSet *apps.StatefulSet, oldSet *apps.StatefulSet) {
+	*newSet.Spec.Replicas = *oldSet.Spec.Replicas - 1
+}
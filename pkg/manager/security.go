@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// securityComponent picks the keytab and the certificate of a pod
+type securityComponent string
+
+const (
+	securityNameNode securityComponent = "namenode"
+	securityDataNode securityComponent = "datanode"
+	securityAdmin    securityComponent = "admin"
+)
+
+const (
+	blockAccessToken = "dfs.block.access.token.enable"
+	// ignoreSecurePorts is no longer rendered, kerberos requires tls, its
+	// env is still removed from the pods of older versions
+	ignoreSecurePorts = "ignore.secure.ports.for.testing"
+)
+
+// securityConf is the hadoop config of the kerberos and tls settings of hc
+func securityConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	krb := kerberosSpec(hc)
+	tls := tlsSpec(hc)
+	var confs []hadoopConf
+	if krb != nil || tls != nil {
+		// kerberos and encrypted data transfer both need block tokens
+		confs = append(confs, hadoopConf{hdfsConf, blockAccessToken, "true"})
+	}
+	if krb != nil {
+		confs = append(confs, kerberosConf(hc, krb)...)
+	}
+	if tls != nil {
+		confs = append(confs, tlsConf()...)
+	}
+	return confs
+}
+
+// securityConfNames are the env vars securityConf renders with everything on
+func securityConfNames(hc *v1alpha1.HdfsCluster) []string {
+	names := []string{confEnvName(hdfsConf, blockAccessToken), confEnvName(hdfsConf, ignoreSecurePorts)}
+	for _, c := range append(kerberosConf(hc, &v1alpha1.KerberosSpec{}), tlsConf()...) {
+		names = append(names, confEnvName(c.prefix, c.key))
+	}
+	return names
+}
+
+// setSecurity applies the kerberos and tls settings of hc to template, or
+// removes them once they are turned off
+func setSecurity(hc *v1alpha1.HdfsCluster, template *corev1.PodTemplateSpec, component securityComponent) {
+	spec := &template.Spec
+	removeEnv(spec, securityConfNames(hc)...)
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		for _, conf := range securityConf(hc) {
+			c.Env = append(c.Env, conf.env())
+		}
+	}
+	setKerberos(hc, spec, component)
+	setTLS(hc, template, component)
+}
@@ -0,0 +1,153 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func newSecureCluster(tls bool) *v1alpha1.HdfsCluster {
+	hc := &v1alpha1.HdfsCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+		Spec: v1alpha1.HdfsClusterSpec{
+			Security: &v1alpha1.SecuritySpec{
+				Kerberos: &v1alpha1.KerberosSpec{
+					Realm:    "EXAMPLE.COM",
+					KDC:      "kdc:88",
+					NameNode: v1alpha1.KerberosPrincipal{KeytabSecret: "nn-keytab"},
+					DataNode: v1alpha1.KerberosPrincipal{KeytabSecret: "dn-keytab", KeytabKey: "dn"},
+					Admin:    v1alpha1.KerberosPrincipal{Principal: "nn@EXAMPLE.COM", KeytabSecret: "admin-keytab"},
+				},
+			},
+		},
+	}
+	if tls {
+		hc.Spec.Security.TLS = &v1alpha1.TLSSpec{CASecret: "ca"}
+	}
+	return hc
+}
+
+func securedTemplate(hc *v1alpha1.HdfsCluster, component securityComponent) *corev1.PodTemplateSpec {
+	template := &corev1.PodTemplateSpec{
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{
+				Name: "datanode",
+				// rendered by older versions
+				Env: []corev1.EnvVar{{Name: confEnvName(hdfsConf, ignoreSecurePorts), Value: "true"}},
+			}},
+		},
+	}
+	setSecurity(hc, template, component)
+	return template
+}
+
+func TestSecurityConf(t *testing.T) {
+	hc := newSecureCluster(true)
+	env := map[string]string{}
+	for _, e := range securedTemplate(hc, securityDataNode).Spec.Containers[0].Env {
+		env[e.Name] = e.Value
+	}
+	for key, want := range map[string]string{
+		confEnvName(coreConf, "hadoop.security.authentication"):  "kerberos",
+		confEnvName(coreConf, "hadoop.security.authorization"):   "true",
+		confEnvName(hdfsConf, blockAccessToken):                  "true",
+		confEnvName(hdfsConf, "dfs.namenode.kerberos.principal"): "nn/demonn.ns.svc.cluster.local@EXAMPLE.COM",
+		confEnvName(hdfsConf, "dfs.datanode.kerberos.principal"): "dn/_HOST@EXAMPLE.COM",
+		confEnvName(hdfsConf, "dfs.datanode.keytab.file"):        keytabDir + "/" + keytabFile,
+		confEnvName(hdfsConf, "dfs.data.transfer.protection"):    "authentication",
+		confEnvName(hdfsConf, "dfs.http.policy"):                 "HTTPS_ONLY",
+	} {
+		if env[key] != want {
+			t.Errorf("got %s=%q, want %q", key, env[key], want)
+		}
+	}
+	if _, ok := env[confEnvName(hdfsConf, ignoreSecurePorts)]; ok {
+		t.Errorf("%s is rendered", ignoreSecurePorts)
+	}
+}
+
+func TestKerberosRequiresTLS(t *testing.T) {
+	secrets := &fakeSecretControl{secrets: map[string]*corev1.Secret{
+		"nn-keytab":    {Data: map[string][]byte{"keytab": []byte("nn")}},
+		"dn-keytab":    {Data: map[string][]byte{"dn": []byte("dn")}},
+		"admin-keytab": {Data: map[string][]byte{"keytab": []byte("admin")}},
+	}}
+	km := &kerberosManager{secretControl: secrets}
+	hc := newSecureCluster(false)
+	if err := km.validate(hc, kerberosSpec(hc)); err == nil || !strings.Contains(err.Error(), "requires tls") {
+		t.Errorf("got %v, want an error for kerberos without tls", err)
+	}
+	hc = newSecureCluster(true)
+	if err := km.validate(hc, kerberosSpec(hc)); err != nil {
+		t.Errorf("validate error, err=%v", err)
+	}
+	delete(secrets.secrets["dn-keytab"].Data, "dn")
+	if err := km.validate(hc, kerberosSpec(hc)); err == nil {
+		t.Errorf("expected an error for a keytab secret without its key")
+	}
+}
+
+func TestKerberosVolumes(t *testing.T) {
+	hc := newSecureCluster(true)
+	spec := securedTemplate(hc, securityDataNode).Spec
+	volumes := map[string]corev1.Volume{}
+	for _, v := range spec.Volumes {
+		volumes[v.Name] = v
+	}
+	keytab := volumes[keytabVolume].Secret
+	if keytab == nil || keytab.SecretName != "dn-keytab" || len(keytab.Items) != 1 ||
+		keytab.Items[0] != (corev1.KeyToPath{Key: "dn", Path: keytabFile}) || *keytab.DefaultMode != 0400 {
+		t.Errorf("unexpected keytab volume %+v", volumes[keytabVolume])
+	}
+	krb5 := volumes[krb5ConfVolume].ConfigMap
+	if krb5 == nil || krb5.Name != controller.Krb5ConfigMapName(hc.Name) {
+		t.Errorf("unexpected krb5.conf volume %+v", volumes[krb5ConfVolume])
+	}
+	mounts := map[string]corev1.VolumeMount{}
+	for _, m := range spec.Containers[0].VolumeMounts {
+		mounts[m.Name] = m
+	}
+	if m := mounts[keytabVolume]; m.MountPath != keytabDir || !m.ReadOnly {
+		t.Errorf("unexpected keytab mount %+v", m)
+	}
+	if m := mounts[krb5ConfVolume]; m.MountPath != krb5ConfPath || m.SubPath != krb5ConfKey {
+		t.Errorf("unexpected krb5.conf mount %+v", m)
+	}
+
+	// the name node and the admin jobs get their own keytabs
+	for component, secret := range map[securityComponent]string{securityNameNode: "nn-keytab", securityAdmin: "admin-keytab"} {
+		for _, v := range securedTemplate(hc, component).Spec.Volumes {
+			if v.Name == keytabVolume && v.Secret.SecretName != secret {
+				t.Errorf("got keytab %s for the %s, want %s", v.Secret.SecretName, component, secret)
+			}
+		}
+	}
+
+	// turning kerberos off removes the volumes
+	template := securedTemplate(hc, securityDataNode)
+	hc.Spec.Security.Kerberos = nil
+	setSecurity(hc, template, securityDataNode)
+	for _, v := range template.Spec.Volumes {
+		if v.Name == keytabVolume || v.Name == krb5ConfVolume {
+			t.Errorf("volume %s is kept without kerberos", v.Name)
+		}
+	}
+}
+
+func TestKinitScript(t *testing.T) {
+	hc := newSecureCluster(true)
+	want := "kinit -kt /etc/security/keytabs/hdfs.keytab 'nn@EXAMPLE.COM' || exit 1\n"
+	if got := kinitScript(hc); got != want {
+		t.Errorf("got kinit script %q, want %q", got, want)
+	}
+	job := NewHadoopJob(hc, "job", "hdfs dfsadmin -report")
+	if script := job.Spec.Template.Spec.Containers[0].Args[2]; !strings.HasPrefix(script, want) {
+		t.Errorf("the job does not log in first, script:\n%s", script)
+	}
+	if got := kinitScript(&v1alpha1.HdfsCluster{}); got != "" {
+		t.Errorf("got kinit script %q without kerberos", got)
+	}
+}
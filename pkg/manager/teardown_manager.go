@@ -0,0 +1,182 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	teardownRequeueDelay = 10 * time.Second
+	nameNodeDataDir      = "/hadoop/dfs/name"
+	teardownBackupDir    = "/backup"
+)
+
+// TeardownManager saves the name node metadata before the cluster is deleted
+type TeardownManager interface {
+	Finalize(cluster *v1alpha1.HdfsCluster) error
+}
+
+type teardownManager struct {
+	jobControl   controller.JobControlInterface
+	pvcControl   controller.PVCControlInterface
+	eventControl controller.EventControlInterface
+	getClient    hdfs.ClientGetter
+}
+
+func NewTeardownManager(
+	jobControl controller.JobControlInterface,
+	pvcControl controller.PVCControlInterface,
+	eventControl controller.EventControlInterface,
+	getClient hdfs.ClientGetter,
+) TeardownManager {
+	return &teardownManager{
+		jobControl:   jobControl,
+		pvcControl:   pvcControl,
+		eventControl: eventControl,
+		getClient:    getClient,
+	}
+}
+
+// Finalize enters safe mode and runs saveNamespace, then fetches the fsimage
+// into the backup pvc if asked, each step is a job and Finalize returns a
+// RequeueError until the jobs are finished
+func (tm *teardownManager) Finalize(hc *v1alpha1.HdfsCluster) error {
+	teardown := hc.Spec.Teardown
+	if teardown == nil {
+		teardown = &v1alpha1.TeardownSpec{}
+	}
+	cli := tm.getClient(hc)
+	var steps []string
+
+	if !teardown.SkipSaveNamespace {
+		script := "hdfs dfsadmin -safemode enter && hdfs dfsadmin -saveNamespace"
+		job := NewHadoopJob(hc, controller.SaveNamespaceJobName(hc.Name), script)
+		done, ok, err := tm.runJob(hc, job, cli)
+		if err != nil || !done {
+			return err
+		}
+		if ok {
+			steps = append(steps, "namespace saved")
+		}
+	}
+
+	var backupLocation string
+	if teardown.Backup {
+		if err := tm.ensureBackupPVC(hc); err != nil {
+			return err
+		}
+		pvcName := controller.TeardownBackupPVCName(hc.Name)
+		dir := path.Join(teardownBackupDir, time.Now().UTC().Format("20060102"))
+		script := fmt.Sprintf("mkdir -p %s && hdfs dfsadmin -fetchImage %s", dir, dir)
+		job := NewHadoopJob(hc, controller.MetadataBackupJobName(hc.Name), script)
+		mountPVC(job, pvcName, teardownBackupDir)
+		done, ok, err := tm.runJob(hc, job, cli)
+		if err != nil || !done {
+			return err
+		}
+		if ok {
+			backupLocation = fmt.Sprintf("pvc %s", pvcName)
+			steps = append(steps, "metadata backed up")
+		}
+	}
+
+	location := fmt.Sprintf("pvc %s at %s/current", controller.NameNodePVCName(hc.Name), nameNodeDataDir)
+	if info, err := cli.GetNameNodeInfo(); err == nil {
+		location = fmt.Sprintf("pvc %s at %s", controller.NameNodePVCName(hc.Name),
+			path.Join(nameNodeDataDir, "current", hdfs.FsImageName(info.MostRecentCheckpointTxID)))
+	}
+	msg := fmt.Sprintf("final fsimage is in %s", location)
+	if backupLocation != "" {
+		msg = fmt.Sprintf("%s, a copy is in %s", msg, backupLocation)
+	}
+	if len(steps) != 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(steps, ", "))
+	}
+	glog.Infof("teardown of %s/%s finished, %s", hc.Namespace, hc.Name, msg)
+	tm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "FinalFsImage", msg)
+	return nil
+}
+
+// runJob creates the job when it does not exist yet, done is false while the
+// job is running, ok is false when the step was skipped or failed, failures
+// are reported as events but do not block the deletion
+func (tm *teardownManager) runJob(hc *v1alpha1.HdfsCluster, job *batchv1.Job, cli hdfs.Interface) (bool, bool, error) {
+	cur, err := tm.jobControl.GetJob(hc, job.Name)
+	if errors.IsNotFound(err) {
+		if _, err := cli.GetNameNodeInfo(); err != nil {
+			msg := fmt.Sprintf("skip job %s, name node is not available: %v", job.Name, err)
+			glog.Errorf("teardown of %s/%s: %s", hc.Namespace, hc.Name, msg)
+			tm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "TeardownStepSkipped", msg)
+			return true, false, nil
+		}
+		if err := tm.jobControl.CreateJob(hc, job); err != nil {
+			return false, false, err
+		}
+		return false, false, controller.RequeueErrorf(teardownRequeueDelay, "job %s is created", job.Name)
+	}
+	if err != nil {
+		return false, false, err
+	}
+	finished, result := controller.IsJobFinished(cur)
+	if !finished {
+		return false, false, controller.RequeueErrorf(teardownRequeueDelay, "job %s is still running", job.Name)
+	}
+	if result == batchv1.JobFailed {
+		msg := fmt.Sprintf("job %s failed, continue the teardown", job.Name)
+		glog.Errorf("teardown of %s/%s: %s", hc.Namespace, hc.Name, msg)
+		tm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "TeardownStepFailed", msg)
+		return true, false, nil
+	}
+	return true, true, nil
+}
+
+func (tm *teardownManager) ensureBackupPVC(hc *v1alpha1.HdfsCluster) error {
+	name := controller.TeardownBackupPVCName(hc.Name)
+	_, err := tm.pvcControl.GetPVC(hc, name)
+	if err == nil || !errors.IsNotFound(err) {
+		return err
+	}
+	q, _ := resource.ParseQuantity(hc.Spec.NameNode.Storage)
+	sc := hc.Spec.NameNode.StorageClass
+	pvc := &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: hc.Namespace,
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: &sc,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: q,
+				},
+			},
+		},
+	}
+	return tm.pvcControl.CreatePVC(hc, pvc)
+}
+
+func mountPVC(job *batchv1.Job, pvcName string, mountPath string) {
+	spec := &job.Spec.Template.Spec
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: pvcName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: pvcName},
+		},
+	})
+	spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      pvcName,
+		MountPath: mountPath,
+	})
+}
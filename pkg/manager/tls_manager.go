@@ -0,0 +1,437 @@
+package manager
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultCertificateDuration = 90 * 24 * time.Hour
+	defaultCertificateRenewal  = 30 * 24 * time.Hour
+
+	tlsDir               = "/etc/hadoop/tls"
+	tlsSecretDir         = "/etc/hadoop/tls-secret"
+	tlsVolume            = "tls"
+	tlsSecretVolume      = "tls-secret"
+	tlsInitContainerName = "tls-keystore"
+
+	caCertKey  = "ca.crt"
+	tlsCertKey = "tls.crt"
+	tlsKeyKey  = "tls.key"
+)
+
+// tlsManager issues a certificate for every pod from the CA of the cluster,
+// or reads the certificate shared by the pods from a cert-manager secret,
+// and records which certificates the pods should run with
+type tlsManager struct {
+	secretControl controller.SecretControlInterface
+	eventControl  controller.EventControlInterface
+	now           func() time.Time
+}
+
+func NewTLSManager(
+	secretControl controller.SecretControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &tlsManager{
+		secretControl: secretControl,
+		eventControl:  eventControl,
+		now:           time.Now,
+	}
+}
+
+func (tm *tlsManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	spec := tlsSpec(hc)
+	if spec == nil {
+		hc.Status.TLS = nil
+		return nil
+	}
+	var err error
+	switch {
+	case spec.Secret != "" && spec.CASecret != "":
+		err = fmt.Errorf("only one of tls secret and ca secret can be set")
+	case spec.Secret != "":
+		err = tm.syncSharedCertificate(hc, spec)
+	case spec.CASecret != "":
+		err = tm.syncIssuedCertificates(hc, spec)
+	default:
+		err = fmt.Errorf("tls needs a secret or a ca secret")
+	}
+	if err != nil {
+		glog.Errorf("sync tls of %s/%s error, %v", hc.Namespace, hc.Name, err)
+		tm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidTLS", err.Error())
+	}
+	return err
+}
+
+func (tm *tlsManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+// syncSharedCertificate follows the secret its issuer keeps renewed
+func (tm *tlsManager) syncSharedCertificate(hc *v1alpha1.HdfsCluster, spec *v1alpha1.TLSSpec) error {
+	secret, err := tm.secretControl.GetSecret(hc, spec.Secret)
+	if err != nil {
+		return fmt.Errorf("get tls secret %s error: %v", spec.Secret, err)
+	}
+	if _, err := tls.X509KeyPair(secret.Data[tlsCertKey], secret.Data[tlsKeyKey]); err != nil {
+		return fmt.Errorf("tls secret %s has no valid %s and %s: %v", spec.Secret, tlsCertKey, tlsKeyKey, err)
+	}
+	cert, err := parseCertificate(secret.Data[tlsCertKey])
+	if err != nil {
+		return err
+	}
+	ca := secret.Data[caCertKey]
+	if len(ca) == 0 {
+		ca = secret.Data[tlsCertKey]
+	}
+	notAfter := metav1.NewTime(cert.NotAfter)
+	hc.Status.TLS = &v1alpha1.TLSStatus{
+		Revision:      certificateRevision(secret.Data[tlsCertKey]),
+		NotAfter:      &notAfter,
+		CACertificate: string(ca),
+	}
+	return nil
+}
+
+// syncIssuedCertificates issues the certificates missing from the tls secret
+// of the cluster and renews all of them together once the first one is
+// about to expire or the CA changed
+func (tm *tlsManager) syncIssuedCertificates(hc *v1alpha1.HdfsCluster, spec *v1alpha1.TLSSpec) error {
+	caSecret, err := tm.secretControl.GetSecret(hc, spec.CASecret)
+	if err != nil {
+		return fmt.Errorf("get ca secret %s error: %v", spec.CASecret, err)
+	}
+	ca, err := tls.X509KeyPair(caSecret.Data[tlsCertKey], caSecret.Data[tlsKeyKey])
+	if err != nil {
+		return fmt.Errorf("ca secret %s has no valid %s and %s: %v", spec.CASecret, tlsCertKey, tlsKeyKey, err)
+	}
+	caCert, err := x509.ParseCertificate(ca.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("parse ca certificate error: %v", err)
+	}
+	if !caCert.IsCA {
+		return fmt.Errorf("certificate of ca secret %s is not a CA", spec.CASecret)
+	}
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Certificate[0]})
+
+	name := controller.TLSSecretName(hc.Name)
+	secret, err := tm.secretControl.GetSecret(hc, name)
+	if errors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return err
+	}
+	old := map[string][]byte{}
+	if secret != nil {
+		old = secret.Data
+	}
+
+	now := tm.now()
+	pods := issuedCertificateNames(hc, old)
+	renew := !bytes.Equal(old[caCertKey], caPEM)
+	for _, pod := range pods {
+		if cert, err := parseCertificate(old[pod+".crt"]); err == nil && cert.NotAfter.Sub(now) < renewBefore(spec) {
+			renew = true
+		}
+	}
+	data := map[string][]byte{caCertKey: caPEM}
+	for _, pod := range pods {
+		crt, key := old[pod+".crt"], old[pod+".key"]
+		if renew || len(crt) == 0 || len(key) == 0 {
+			crt, key, err = issueCertificate(caCert, ca, podDNSNames(hc, pod), now, certificateDuration(spec))
+			if err != nil {
+				return fmt.Errorf("issue certificate of %s error: %v", pod, err)
+			}
+		}
+		data[pod+".crt"], data[pod+".key"] = crt, key
+	}
+
+	if secret == nil {
+		err = tm.secretControl.CreateSecret(hc, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       hc.Namespace,
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			},
+			Data: data,
+		})
+	} else if !reflect.DeepEqual(secret.Data, data) {
+		secret = secret.DeepCopy()
+		secret.Data = data
+		err = tm.secretControl.UpdateSecret(hc, secret)
+	}
+	if err != nil {
+		return err
+	}
+
+	status := &v1alpha1.TLSStatus{
+		Revision:      certificateRevision(data[string(securityNameNode)+".crt"]),
+		CACertificate: string(caPEM),
+	}
+	if hc.Status.TLS != nil {
+		status.RenewTime = hc.Status.TLS.RenewTime
+	}
+	if renew && secret != nil {
+		renewTime := metav1.NewTime(now)
+		status.RenewTime = &renewTime
+		tm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "CertificatesRenewed",
+			fmt.Sprintf("renewed the certificates of %d pods", len(pods)))
+	}
+	for _, pod := range pods {
+		cert, err := parseCertificate(data[pod+".crt"])
+		if err != nil {
+			return err
+		}
+		if status.NotAfter == nil || cert.NotAfter.Before(status.NotAfter.Time) {
+			notAfter := metav1.NewTime(cert.NotAfter)
+			status.NotAfter = &notAfter
+		}
+	}
+	hc.Status.TLS = status
+	return nil
+}
+
+// issuedCertificateNames are the name node and the data node pods up to the
+// desired replicas, data nodes that are still being decommissioned keep
+// the certificates they already have
+func issuedCertificateNames(hc *v1alpha1.HdfsCluster, old map[string][]byte) []string {
+	names := []string{string(securityNameNode)}
+	setName := controller.DataNodeSetName(hc.Name)
+	for i := int32(0); i < dataNodeReplicas(hc); i++ {
+		names = append(names, fmt.Sprintf("%s-%d", setName, i))
+	}
+	var stale []string
+	for key := range old {
+		pod := strings.TrimSuffix(key, ".crt")
+		if key != caCertKey && pod != key && !containsString(names, pod) {
+			stale = append(stale, pod)
+		}
+	}
+	sort.Strings(stale)
+	return append(names, stale...)
+}
+
+// podDNSNames are the names a pod is reached by, the name node through its
+// service and a data node through the headless service of its set
+func podDNSNames(hc *v1alpha1.HdfsCluster, pod string) []string {
+	host := controller.NameNodeServiceName(hc.Name)
+	if pod != string(securityNameNode) {
+		host = fmt.Sprintf("%s.%s", pod, controller.DataNodeServiceName(hc.Name))
+	}
+	return []string{
+		fmt.Sprintf("%s.%s.svc.cluster.local", host, hc.Namespace),
+		fmt.Sprintf("%s.%s.svc", host, hc.Namespace),
+		fmt.Sprintf("%s.%s", host, hc.Namespace),
+		host,
+	}
+}
+
+func issueCertificate(caCert *x509.Certificate, ca tls.Certificate, dnsNames []string, now time.Time, duration time.Duration) ([]byte, []byte, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: dnsNames[0]},
+		DNSNames:     dnsNames,
+		NotBefore:    now.Add(-5 * time.Minute),
+		NotAfter:     now.Add(duration),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	crt := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return crt, keyPEM, nil
+}
+
+// parseCertificate parses the first certificate of a pem chain
+func parseCertificate(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("no pem certificate found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func certificateRevision(crt []byte) string {
+	sum := sha256.Sum256(crt)
+	return hex.EncodeToString(sum[:8])
+}
+
+func certificateDuration(spec *v1alpha1.TLSSpec) time.Duration {
+	if spec.Duration != nil && spec.Duration.Duration > 0 {
+		return spec.Duration.Duration
+	}
+	return defaultCertificateDuration
+}
+
+func renewBefore(spec *v1alpha1.TLSSpec) time.Duration {
+	if spec.RenewBefore != nil && spec.RenewBefore.Duration > 0 {
+		return spec.RenewBefore.Duration
+	}
+	return defaultCertificateRenewal
+}
+
+func tlsSpec(hc *v1alpha1.HdfsCluster) *v1alpha1.TLSSpec {
+	if hc.Spec.Security == nil {
+		return nil
+	}
+	return hc.Spec.Security.TLS
+}
+
+func tlsConf() []hadoopConf {
+	return []hadoopConf{
+		{hdfsConf, "dfs.http.policy", "HTTPS_ONLY"},
+		{hdfsConf, "dfs.encrypt.data.transfer", "true"},
+		{hdfsConf, "dfs.encrypt.data.transfer.cipher.suites", "AES/CTR/NoPadding"},
+	}
+}
+
+// keystoreScript builds the java keystores out of the pem files, a server
+// waits until the certificate of its pod is issued. The keystore password
+// is generated per pod and only written to the pod local emptyDir
+const keystoreScript = `set -e
+out=` + tlsDir + `
+in=` + tlsSecretDir + `
+password=$(head -c 24 /dev/urandom | base64 | tr -d '/+=')
+ca=$in/ca.crt
+[ -f $ca ] || ca=$in/tls.crt
+rm -f $out/*.jks
+keytool -importcert -noprompt -alias ca -file $ca -keystore $out/truststore.jks -storepass $password
+cat > $out/ssl-client.xml <<EOF
+<configuration>
+<property><name>ssl.client.truststore.location</name><value>$out/truststore.jks</value></property>
+<property><name>ssl.client.truststore.password</name><value>$password</value></property>
+<property><name>ssl.client.truststore.type</name><value>jks</value></property>
+</configuration>
+EOF
+if [ "$TLS_CLIENT_ONLY" = "true" ]; then
+  exit 0
+fi
+name=${TLS_CERT:-$HOSTNAME}
+while true; do
+  if [ -f $in/$name.crt ]; then crt=$in/$name.crt; key=$in/$name.key; break; fi
+  if [ -f $in/tls.crt ]; then crt=$in/tls.crt; key=$in/tls.key; break; fi
+  echo "waiting for the certificate of $name"
+  sleep 10
+done
+openssl pkcs12 -export -in $crt -inkey $key -certfile $ca -name server -out $out/keystore.p12 -passout pass:$password
+keytool -importkeystore -noprompt -srckeystore $out/keystore.p12 -srcstoretype PKCS12 -srcstorepass $password \
+  -destkeystore $out/keystore.jks -deststorepass $password -destkeypass $password
+rm $out/keystore.p12
+cat > $out/ssl-server.xml <<EOF
+<configuration>
+<property><name>ssl.server.keystore.location</name><value>$out/keystore.jks</value></property>
+<property><name>ssl.server.keystore.password</name><value>$password</value></property>
+<property><name>ssl.server.keystore.keypassword</name><value>$password</value></property>
+<property><name>ssl.server.truststore.location</name><value>$out/truststore.jks</value></property>
+<property><name>ssl.server.truststore.password</name><value>$password</value></property>
+</configuration>
+EOF
+`
+
+// setTLS adds the init container building the keystores of component and
+// mounts them with the ssl-server.xml and ssl-client.xml into every
+// container of template, or removes all of it
+func setTLS(hc *v1alpha1.HdfsCluster, template *corev1.PodTemplateSpec, component securityComponent) {
+	spec := &template.Spec
+	removeVolumes(spec, tlsVolume, tlsSecretVolume)
+	var initContainers []corev1.Container
+	for _, c := range spec.InitContainers {
+		if c.Name != tlsInitContainerName {
+			initContainers = append(initContainers, c)
+		}
+	}
+	spec.InitContainers = initContainers
+	delete(template.Annotations, controller.TLSRevisionAnnotation)
+	if len(template.Annotations) == 0 {
+		template.Annotations = nil
+	}
+	ts := tlsSpec(hc)
+	if ts == nil {
+		return
+	}
+	secretName := ts.Secret
+	if secretName == "" {
+		secretName = controller.TLSSecretName(hc.Name)
+	}
+	mode := int32(0400)
+	spec.Volumes = append(spec.Volumes,
+		corev1.Volume{
+			Name:         tlsVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		},
+		corev1.Volume{
+			Name: tlsSecretVolume,
+			VolumeSource: corev1.VolumeSource{
+				Secret: &corev1.SecretVolumeSource{SecretName: secretName, DefaultMode: &mode},
+			},
+		},
+	)
+	var env []corev1.EnvVar
+	switch component {
+	case securityNameNode:
+		env = append(env, corev1.EnvVar{Name: "TLS_CERT", Value: string(securityNameNode)})
+	case securityAdmin:
+		env = append(env, corev1.EnvVar{Name: "TLS_CLIENT_ONLY", Value: "true"})
+	}
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:                     tlsInitContainerName,
+		Image:                    controller.HadoopImage,
+		ImagePullPolicy:          corev1.PullIfNotPresent,
+		Args:                     []string{"/bin/bash", "-c", keystoreScript},
+		Env:                      env,
+		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: tlsVolume, MountPath: tlsDir},
+			{Name: tlsSecretVolume, MountPath: tlsSecretDir, ReadOnly: true},
+		},
+	})
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.VolumeMounts = append(c.VolumeMounts,
+			corev1.VolumeMount{Name: tlsVolume, MountPath: tlsDir, ReadOnly: true},
+			corev1.VolumeMount{Name: tlsVolume, MountPath: "/etc/hadoop/ssl-client.xml", SubPath: "ssl-client.xml", ReadOnly: true},
+		)
+		if component != securityAdmin {
+			c.VolumeMounts = append(c.VolumeMounts,
+				corev1.VolumeMount{Name: tlsVolume, MountPath: "/etc/hadoop/ssl-server.xml", SubPath: "ssl-server.xml", ReadOnly: true})
+		}
+	}
+	if hc.Status.TLS != nil {
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[controller.TLSRevisionAnnotation] = hc.Status.TLS.Revision
+	}
+}
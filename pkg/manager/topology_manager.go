@@ -0,0 +1,295 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultTopologyLabel is the node label of the rack when none is configured
+	DefaultTopologyLabel = "topology.kubernetes.io/zone"
+	defaultRack          = "/default-rack"
+
+	topologyScriptKey = "topology.sh"
+	topologyTableKey  = "topology.data"
+	topologyDir       = "/etc/hadoop/topology"
+
+	topologyWaitContainerName = "wait-for-rack"
+	topologyWaitVolume        = "topology-table"
+	// topologyWaitSeconds covers the kubelet refreshing a configmap volume
+	topologyWaitSeconds = 300
+)
+
+// topologyScript prints the rack of every ip or host name the name node or
+// the resource manager passes in, the ones missing from the table are in the
+// default rack, which has the depth of the other racks
+func topologyScript(depth int) string {
+	return `#!/bin/bash
+table="$(dirname "$0")/` + topologyTableKey + `"
+racks=""
+for host in "$@"; do
+  rack=$(awk -v h="$host" '$1 == h { print $2; exit }' "$table")
+  racks="$racks ${rack:-` + defaultRackPath(depth) + `}"
+done
+echo $racks
+`
+}
+
+// topologyWaitScript exits once the ip of the pod is in the rack table, or
+// gives up after topologyWaitSeconds and lets the pod start in the default rack
+var topologyWaitScript = fmt.Sprintf(`table=%s/%s
+for i in $(seq %d); do
+  if awk -v h="$POD_IP" '$1 == h { found = 1 } END { exit !found }' "$table"; then
+    exit 0
+  fi
+  sleep 5
+done
+echo "$POD_IP is not in the rack table, starting in the default rack"
+`, topologyDir, topologyTableKey, topologyWaitSeconds/5)
+
+// topologyManager keeps the rack table of the data node pods in a configmap
+// mounted by the name node. The name node caches the rack of a data node
+// until it registers again, so a moved pod is resolved with its new ip and
+// the pods wait for their ip in the table before they register
+type topologyManager struct {
+	cmControl   controller.ConfigMapControlInterface
+	podControl  controller.PodControlInterface
+	nodeControl controller.NodeControlInterface
+}
+
+func NewTopologyManager(
+	cmControl controller.ConfigMapControlInterface,
+	podControl controller.PodControlInterface,
+	nodeControl controller.NodeControlInterface,
+) Manager {
+	return &topologyManager{
+		cmControl:   cmControl,
+		podControl:  podControl,
+		nodeControl: nodeControl,
+	}
+}
+
+func (tm *topologyManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.RackAwareness == nil {
+		return nil
+	}
+	table, err := tm.topologyTable(hc)
+	if err != nil {
+		return err
+	}
+	data := map[string]string{
+		topologyScriptKey: topologyScript(len(TopologyLabels(hc))),
+		topologyTableKey:  table,
+	}
+	name := controller.TopologyConfigMapName(hc.Name)
+	cm, err := tm.cmControl.GetConfigMap(hc, name)
+	if errors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            name,
+				Namespace:       hc.Namespace,
+				OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+			},
+			Data: data,
+		}
+		return tm.cmControl.CreateConfigMap(hc, cm)
+	}
+	if err != nil {
+		return err
+	}
+	if reflect.DeepEqual(cm.Data, data) {
+		return nil
+	}
+	cm = cm.DeepCopy()
+	cm.Data = data
+	if err := tm.cmControl.UpdateConfigMap(hc, cm); err != nil {
+		return err
+	}
+	glog.Infof("rack topology of %s/%s is updated", hc.Namespace, hc.Name)
+	return nil
+}
+
+func (tm *topologyManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+// topologyTable maps the ip and the host name of every scheduled data node
+// and node manager pod to the rack of its node, one "<host> <rack>" per line.
+// The yarn scheduler resolves the node managers with the same script
+func (tm *topologyManager) topologyTable(hc *v1alpha1.HdfsCluster) (string, error) {
+	labels := TopologyLabels(hc)
+	// the data node label is shared by every cluster in the namespace
+	dataNodes, err := tm.podControl.ListPods(hc, controller.DataNodeLabel())
+	if err != nil {
+		return "", err
+	}
+	lines, err := tm.podRacks(hc, dataNodes, controller.DataNodeSetName(hc.Name), controller.DataNodeServiceName(hc.Name), labels)
+	if err != nil {
+		return "", err
+	}
+	if hc.Spec.Yarn != nil {
+		name := controller.NodeManagerName(hc.Name)
+		nodeManagers, err := tm.podControl.ListPods(hc, controller.NodeManagerLabel(hc.Name))
+		if err != nil {
+			return "", err
+		}
+		nmLines, err := tm.podRacks(hc, nodeManagers, name, name, labels)
+		if err != nil {
+			return "", err
+		}
+		lines = append(lines, nmLines...)
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// podRacks maps the pods of the statefulset setName, whose host names
+// resolve through svcName, to the racks of their nodes
+func (tm *topologyManager) podRacks(hc *v1alpha1.HdfsCluster, pods []*corev1.Pod, setName string, svcName string, labels []string) ([]string, error) {
+	var lines []string
+	for _, pod := range pods {
+		if ref := metav1.GetControllerOf(pod); ref == nil || ref.Name != setName {
+			continue
+		}
+		if pod.Spec.NodeName == "" || pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+			continue
+		}
+		node, err := tm.nodeControl.GetNode(pod.Spec.NodeName)
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		rack := nodeRack(node, labels)
+		lines = append(lines,
+			fmt.Sprintf("%s %s", pod.Status.PodIP, rack),
+			fmt.Sprintf("%s.%s.%s.svc.cluster.local %s", pod.Name, svcName, hc.Namespace, rack),
+		)
+	}
+	return lines, nil
+}
+
+// TopologyLabels are the node labels the rack of a rack aware cluster is made of
+func TopologyLabels(hc *v1alpha1.HdfsCluster) []string {
+	if hc.Spec.RackAwareness == nil {
+		return nil
+	}
+	if labels := hc.Spec.RackAwareness.NodeLabels; len(labels) != 0 {
+		return labels
+	}
+	return []string{DefaultTopologyLabel}
+}
+
+// defaultRackPath is the default rack at depth levels, hadoop rejects racks
+// of different depths in one topology
+func defaultRackPath(depth int) string {
+	if depth < 1 {
+		depth = 1
+	}
+	return strings.Repeat(defaultRack, depth)
+}
+
+// nodeRack joins the values of labels into a rack path, a missing label is
+// "unknown" and a node without any of the labels is in the default rack
+func nodeRack(node *corev1.Node, labels []string) string {
+	var parts []string
+	found := false
+	for _, label := range labels {
+		value, ok := node.Labels[label]
+		if ok && value != "" {
+			found = true
+		} else {
+			value = "unknown"
+		}
+		parts = append(parts, strings.NewReplacer("/", "_", " ", "_").Replace(value))
+	}
+	if !found {
+		return defaultRackPath(len(labels))
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// setRackAwareness mounts the topology configmap into spec and
+// points net.topology.script.file.name at the script, or removes both, the
+// rest of spec is left untouched so an unchanged spec compares equal
+func setRackAwareness(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	const volumeName = "topology"
+	envName := confEnvName(coreConf, "net.topology.script.file.name")
+	removeVolumes(spec, volumeName)
+	removeEnv(spec, envName)
+	if hc.Spec.RackAwareness == nil {
+		return
+	}
+	mode := int32(0755)
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: volumeName,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: controller.TopologyConfigMapName(hc.Name)},
+				DefaultMode:          &mode,
+			},
+		},
+	})
+	for i := range spec.Containers {
+		c := &spec.Containers[i]
+		c.Env = append(c.Env, corev1.EnvVar{Name: envName, Value: topologyDir + "/" + topologyScriptKey})
+		c.VolumeMounts = append(c.VolumeMounts, corev1.VolumeMount{Name: volumeName, MountPath: topologyDir, ReadOnly: true})
+	}
+}
+
+// setTopologyWait adds the init container holding back a data node or node
+// manager until its ip is in the rack table, or removes it. The name node and
+// the resource manager resolve the rack of a host once, when it registers,
+// so a pod starting before the operator added its ip would stay in the
+// default rack until it restarts. Waiting for the table is chosen over
+// refreshing the racks afterwards, hadoop 2.7 has no command that drops the
+// cached rack of a registered host
+func setTopologyWait(hc *v1alpha1.HdfsCluster, spec *corev1.PodSpec) {
+	removeVolumes(spec, topologyWaitVolume)
+	var initContainers []corev1.Container
+	for _, c := range spec.InitContainers {
+		if c.Name != topologyWaitContainerName {
+			initContainers = append(initContainers, c)
+		}
+	}
+	spec.InitContainers = initContainers
+	if hc.Spec.RackAwareness == nil {
+		return
+	}
+	mode := int32(0644)
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: topologyWaitVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: controller.TopologyConfigMapName(hc.Name)},
+				DefaultMode:          &mode,
+			},
+		},
+	})
+	spec.InitContainers = append(spec.InitContainers, corev1.Container{
+		Name:            topologyWaitContainerName,
+		Image:           controller.HadoopImage,
+		ImagePullPolicy: corev1.PullIfNotPresent,
+		Args:            []string{"/bin/bash", "-c", topologyWaitScript},
+		Env: []corev1.EnvVar{
+			{
+				Name:      "POD_IP",
+				ValueFrom: &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: "status.podIP"}},
+			},
+		},
+		TerminationMessagePath:   corev1.TerminationMessagePathDefault,
+		TerminationMessagePolicy: corev1.TerminationMessageReadFile,
+		VolumeMounts: []corev1.VolumeMount{
+			{Name: topologyWaitVolume, MountPath: topologyDir, ReadOnly: true},
+		},
+	})
+}
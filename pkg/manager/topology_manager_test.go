@@ -0,0 +1,64 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strings"
+	"testing"
+)
+
+func TestNodeRack(t *testing.T) {
+	labels := []string{"zone", "rack"}
+	for _, tc := range []struct {
+		node map[string]string
+		want string
+	}{
+		{map[string]string{"zone": "z1", "rack": "r/1"}, "/z1/r_1"},
+		{map[string]string{"zone": "z1"}, "/z1/unknown"},
+		// the default rack has the depth of the others
+		{map[string]string{}, "/default-rack/default-rack"},
+	} {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Labels: tc.node}}
+		if got := nodeRack(node, labels); got != tc.want {
+			t.Errorf("labels %v: got rack %s, want %s", tc.node, got, tc.want)
+		}
+	}
+	if got := nodeRack(&corev1.Node{}, []string{"zone"}); got != "/default-rack" {
+		t.Errorf("got rack %s, want /default-rack", got)
+	}
+	if script := topologyScript(2); !strings.Contains(script, "${rack:-/default-rack/default-rack}") {
+		t.Errorf("the script does not pad the default rack:\n%s", script)
+	}
+}
+
+func TestSetTopologyWait(t *testing.T) {
+	hc := &v1alpha1.HdfsCluster{ObjectMeta: metav1.ObjectMeta{Name: "demo"}}
+	hc.Spec.RackAwareness = &v1alpha1.RackAwarenessSpec{}
+	spec := &corev1.PodSpec{
+		InitContainers: []corev1.Container{{Name: "other"}},
+		Containers:     []corev1.Container{{Name: "datanode"}},
+	}
+	setTopologyWait(hc, spec)
+	// applied again on every sync, the spec has to compare equal
+	setTopologyWait(hc, spec)
+	if len(spec.InitContainers) != 2 || spec.InitContainers[1].Name != topologyWaitContainerName {
+		t.Fatalf("got init containers %+v, want other and %s", spec.InitContainers, topologyWaitContainerName)
+	}
+	wait := spec.InitContainers[1]
+	if len(wait.Env) != 1 || wait.Env[0].ValueFrom.FieldRef.FieldPath != "status.podIP" {
+		t.Errorf("got env %+v, want POD_IP from status.podIP", wait.Env)
+	}
+	if len(spec.Volumes) != 1 || spec.Volumes[0].ConfigMap.Name != "demo-topology" || *spec.Volumes[0].ConfigMap.DefaultMode != 0644 {
+		t.Errorf("got volumes %+v, want the topology configmap", spec.Volumes)
+	}
+	if len(spec.Containers[0].VolumeMounts) != 0 {
+		t.Errorf("the data node mounts the rack table: %+v", spec.Containers[0].VolumeMounts)
+	}
+
+	hc.Spec.RackAwareness = nil
+	setTopologyWait(hc, spec)
+	if len(spec.InitContainers) != 1 || len(spec.Volumes) != 0 {
+		t.Errorf("got init containers %+v and volumes %+v after turning rack awareness off", spec.InitContainers, spec.Volumes)
+	}
+}
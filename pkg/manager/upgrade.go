@@ -0,0 +1,159 @@
+package manager
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// rollbackEnv has the name nodes and the data nodes start from the state
+// saved by the prepare of the upgrade
+const rollbackEnv = "ROLLING_UPGRADE_ROLLBACK"
+
+// rollingUpgradeOption picks the startup option of a name node, a rolling
+// upgrade is in progress while the rollback fsimage is in the name dir
+const rollingUpgradeOption = `option=""
+if ls /hadoop/dfs/name/current/fsimage_rollback_* > /dev/null 2>&1; then
+  option="-rollingUpgrade started"
+  if [ "$` + rollbackEnv + `" = "true" ]; then
+    option="-rollingUpgrade rollback"
+  fi
+fi
+`
+
+// nameNodeUpgradeScript and nameServiceUpgradeScript start a name node with
+// the startup option of the rolling upgrade, outside of an upgrade they
+// start it like the image and nameServiceScript do
+const (
+	nameNodeUpgradeScript    = rollingUpgradeOption + `exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR namenode $option`
+	nameServiceUpgradeScript = nameServiceFormat + rollingUpgradeOption + `exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR namenode $option`
+)
+
+// dataNodeUpgradeScript restores the blocks deleted since the prepare of
+// the upgrade on a rollback
+const dataNodeUpgradeScript = `option=""
+if [ "$` + rollbackEnv + `" = "true" ]; then
+  option="-rollback"
+fi
+exec $HADOOP_PREFIX/bin/hdfs --config $HADOOP_CONF_DIR datanode $option`
+
+// specImages are the images of hc with the defaults filled in
+func specImages(hc *v1alpha1.HdfsCluster) v1alpha1.ImagesSpec {
+	images := v1alpha1.ImagesSpec{
+		NameNode: controller.NameNodeImage,
+		DataNode: controller.DataNodeImage,
+	}
+	if spec := hc.Spec.Images; spec != nil {
+		if spec.NameNode != "" {
+			images.NameNode = spec.NameNode
+		}
+		if spec.DataNode != "" {
+			images.DataNode = spec.DataNode
+		}
+	}
+	return images
+}
+
+// nameNodeImage is the image the name nodes run in the current phase of
+// the upgrade, the image of the spec without one
+func nameNodeImage(hc *v1alpha1.HdfsCluster) string {
+	up := hc.Status.Upgrade
+	if up == nil {
+		return specImages(hc).NameNode
+	}
+	switch up.Phase {
+	case v1alpha1.UpgradeNameNodes, v1alpha1.UpgradeDataNodes, v1alpha1.UpgradeFinalizing:
+		return up.To.NameNode
+	}
+	return up.From.NameNode
+}
+
+// dataNodeImage is the image of the data node statefulset, the partition
+// keeps the data nodes below it on the old image while they are upgraded
+func dataNodeImage(hc *v1alpha1.HdfsCluster) string {
+	up := hc.Status.Upgrade
+	if up == nil {
+		return specImages(hc).DataNode
+	}
+	switch up.Phase {
+	case v1alpha1.UpgradeDataNodes, v1alpha1.UpgradeFinalizing, v1alpha1.UpgradeRollingBackNameNodes:
+		return up.To.DataNode
+	}
+	return up.From.DataNode
+}
+
+// nameNodeRollback tells the name nodes to start from the rollback fsimage,
+// it stays set after the rollback so they are not restarted once more
+func nameNodeRollback(hc *v1alpha1.HdfsCluster) bool {
+	up := hc.Status.Upgrade
+	if up == nil {
+		return false
+	}
+	switch up.Phase {
+	case v1alpha1.UpgradeRollingBackNameNodes, v1alpha1.UpgradeRollingBackDataNodes, v1alpha1.UpgradeRolledBack:
+		return true
+	}
+	return false
+}
+
+// dataNodeRollback waits for the name nodes to be rolled back
+func dataNodeRollback(hc *v1alpha1.HdfsCluster) bool {
+	up := hc.Status.Upgrade
+	return up != nil && (up.Phase == v1alpha1.UpgradeRollingBackDataNodes || up.Phase == v1alpha1.UpgradeRolledBack)
+}
+
+// dataNodeUpdateStrategy upgrades the data nodes from the partition of the
+// upgrade on, the default rolling update replaces them all otherwise
+func dataNodeUpdateStrategy(hc *v1alpha1.HdfsCluster) apps.StatefulSetUpdateStrategy {
+	up := hc.Status.Upgrade
+	if up == nil {
+		return apps.StatefulSetUpdateStrategy{}
+	}
+	switch up.Phase {
+	case v1alpha1.UpgradeDataNodes, v1alpha1.UpgradeFinalizing, v1alpha1.UpgradeRollingBackNameNodes:
+		partition := up.DataNodePartition
+		return apps.StatefulSetUpdateStrategy{
+			Type:          apps.RollingUpdateStatefulSetStrategyType,
+			RollingUpdate: &apps.RollingUpdateStatefulSetStrategy{Partition: &partition},
+		}
+	}
+	return apps.StatefulSetUpdateStrategy{}
+}
+
+// upgradeFrom are the images the upgrade started from, empty without one
+func upgradeFrom(hc *v1alpha1.HdfsCluster) v1alpha1.ImagesSpec {
+	if up := hc.Status.Upgrade; up != nil {
+		return up.From
+	}
+	return v1alpha1.ImagesSpec{}
+}
+
+// setUpgrade points the hdfs container of spec at image, and at script once
+// it leaves the image from or rolls back. current is the command the
+// container runs now, a container keeps the script once it has it instead
+// of being restarted again when the upgrade is over
+func setUpgrade(spec *corev1.PodSpec, image string, from string, script string, current []string, rollback bool) {
+	c := &spec.Containers[0]
+	c.Image = image
+	if (from != "" && image != from) || rollback || (len(current) == 3 && current[2] == script) {
+		c.Args = []string{"/bin/bash", "-c", script}
+	}
+	removeEnv(spec, rollbackEnv)
+	if rollback {
+		c.Env = append(c.Env, corev1.EnvVar{Name: rollbackEnv, Value: "true"})
+	}
+}
+
+// runsImage returns whether the hdfs container of spec runs image, started
+// for a rollback or not as rollback says
+func runsImage(spec *corev1.PodSpec, image string, rollback bool) bool {
+	c := spec.Containers[0]
+	found := false
+	for _, e := range c.Env {
+		if e.Name == rollbackEnv {
+			found = true
+		}
+	}
+	return c.Image == image && found == rollback
+}
@@ -0,0 +1,495 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"strconv"
+	"strings"
+)
+
+const (
+	// upgradePrepareAttempts polls the rollback fsimage for ten minutes
+	upgradePrepareAttempts = 60
+	upgradePrepareInterval = 10
+)
+
+type upgradeManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	setControl        controller.StatefulSetControlInterface
+	jobControl        controller.JobControlInterface
+	podControl        controller.PodControlInterface
+	eventControl      controller.EventControlInterface
+	newClient         func(hc *v1alpha1.HdfsCluster, nameService string) hdfs.Interface
+}
+
+// NewUpgradeManager turns a change of the images into an hdfs rolling
+// upgrade. The phase of the upgrade in the status decides the images the
+// name node and data node managers apply, this manager only moves it on
+func NewUpgradeManager(
+	deployControl controller.DeploymentControlInterface,
+	setControl controller.StatefulSetControlInterface,
+	jobControl controller.JobControlInterface,
+	podControl controller.PodControlInterface,
+	eventControl controller.EventControlInterface,
+	newClient func(hc *v1alpha1.HdfsCluster, nameService string) hdfs.Interface,
+) Manager {
+	return &upgradeManager{
+		deploymentControl: deployControl,
+		setControl:        setControl,
+		jobControl:        jobControl,
+		podControl:        podControl,
+		eventControl:      eventControl,
+		newClient:         newClient,
+	}
+}
+
+func (um *upgradeManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if up == nil {
+		return um.detectUpgrade(hc)
+	}
+	// a finalizing upgrade has no rollback fsimage to go back to
+	rollback := hc.Spec.Upgrade != nil && hc.Spec.Upgrade.Rollback
+	if rollback {
+		switch up.Phase {
+		case v1alpha1.UpgradePreparing, v1alpha1.UpgradeNameNodes, v1alpha1.UpgradeDataNodes:
+			return um.startRollback(hc)
+		}
+	}
+	switch up.Phase {
+	case v1alpha1.UpgradePending:
+		return um.syncPending(hc, rollback)
+	case v1alpha1.UpgradePreparing:
+		return um.syncPreparing(hc)
+	case v1alpha1.UpgradeNameNodes:
+		return um.syncNameNodes(hc)
+	case v1alpha1.UpgradeDataNodes:
+		return um.syncDataNodes(hc)
+	case v1alpha1.UpgradeFinalizing:
+		return um.syncFinalizing(hc)
+	case v1alpha1.UpgradeRollingBackNameNodes:
+		return um.syncRollingBackNameNodes(hc)
+	case v1alpha1.UpgradeRollingBackDataNodes:
+		return um.syncRollingBackDataNodes(hc)
+	case v1alpha1.UpgradeRolledBack:
+		return um.syncRolledBack(hc)
+	}
+	return fmt.Errorf("unknown upgrade phase %q", up.Phase)
+}
+
+func (um *upgradeManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+// detectUpgrade starts an upgrade when the images of the spec differ from
+// the running ones, a new cluster starts on the images of the spec
+func (um *upgradeManager) detectUpgrade(hc *v1alpha1.HdfsCluster) error {
+	running, err := um.runningImages(hc)
+	if err != nil || running == nil {
+		return err
+	}
+	images := specImages(hc)
+	if *running == images {
+		return nil
+	}
+	now := metav1.Now()
+	hc.Status.Upgrade = &v1alpha1.UpgradeStatus{
+		Phase:     v1alpha1.UpgradePending,
+		From:      *running,
+		To:        images,
+		StartTime: &now,
+	}
+	glog.Infof("upgrade %s/%s from %+v to %+v", hc.Namespace, hc.Name, *running, images)
+	um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradeStarted",
+		fmt.Sprintf("rolling upgrade from %s, %s to %s, %s", running.NameNode, running.DataNode, images.NameNode, images.DataNode))
+	return nil
+}
+
+// runningImages are the images of the name node deployment and the data
+// node statefulset, nil before the name node is created
+func (um *upgradeManager) runningImages(hc *v1alpha1.HdfsCluster) (*v1alpha1.ImagesSpec, error) {
+	deployment, err := um.deploymentControl.GetDeployment(hc, controller.NameNodeDeployment(hc.Name))
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	images := &v1alpha1.ImagesSpec{
+		NameNode: deployment.Spec.Template.Spec.Containers[0].Image,
+		DataNode: specImages(hc).DataNode,
+	}
+	set, err := um.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if err == nil {
+		images.DataNode = set.Spec.Template.Spec.Containers[0].Image
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+	return images, nil
+}
+
+// syncPending waits for a healthy cluster before preparing the upgrade
+func (um *upgradeManager) syncPending(hc *v1alpha1.HdfsCluster, rollback bool) error {
+	up := hc.Status.Upgrade
+	images := specImages(hc)
+	if images == up.From {
+		hc.Status.Upgrade = nil
+		um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradeCanceled", "images are set back before the upgrade started")
+		return nil
+	}
+	up.To = images
+	if rollback {
+		up.Message = "upgrade.rollback is set, unset it to start the upgrade"
+		return nil
+	}
+	if msg := um.nameNodesHealthy(hc); msg != "" {
+		up.Message = msg
+		return nil
+	}
+	set, err := um.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err == nil && set.Status.ReadyReplicas != *set.Spec.Replicas {
+		up.Message = fmt.Sprintf("waiting for the data nodes, %d of %d are ready", set.Status.ReadyReplicas, *set.Spec.Replicas)
+		return nil
+	}
+	up.Phase = v1alpha1.UpgradePreparing
+	up.Message = ""
+	return nil
+}
+
+// syncPreparing runs a job saving the rollback fsimage on every name node
+func (um *upgradeManager) syncPreparing(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if up.JobName == "" {
+		job := um.newUpgradeJob(hc, nameNodeImage(hc), prepareScript(hc))
+		if err := um.jobControl.CreateJob(hc, job); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		up.JobName = job.Name
+		up.Message = "preparing the rolling upgrade"
+		return nil
+	}
+	done, err := um.checkJob(hc, "prepare")
+	if err != nil || !done {
+		return err
+	}
+	up.Phase = v1alpha1.UpgradeNameNodes
+	up.Message = ""
+	um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradePrepared", "rollback fsimages are saved, upgrading the name nodes")
+	return nil
+}
+
+// syncNameNodes waits for every name node to run the new image before the
+// data nodes follow, from the highest ordinal down
+func (um *upgradeManager) syncNameNodes(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if msg, err := um.nameNodesRolledOut(hc, up.To.NameNode, false); err != nil || msg != "" {
+		up.Message = msg
+		return err
+	}
+	set, err := um.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if err != nil {
+		return err
+	}
+	up.Phase = v1alpha1.UpgradeDataNodes
+	up.DataNodePartition = *set.Spec.Replicas
+	up.Message = ""
+	return nil
+}
+
+// syncDataNodes lowers the partition one data node at a time, once the data
+// nodes above it run the new image and are registered again
+func (um *upgradeManager) syncDataNodes(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	set, err := um.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if err != nil {
+		return err
+	}
+	if set.Spec.Template.Spec.Containers[0].Image != up.To.DataNode || set.Status.ObservedGeneration < set.Generation {
+		up.Message = "waiting for the data node statefulset to be updated"
+		return nil
+	}
+	pods, err := um.podControl.ListPods(hc, controller.DataNodeLabel())
+	if err != nil {
+		return err
+	}
+	info, err := um.newClient(hc, hc.Name).GetNameNodeInfo()
+	if err != nil {
+		up.Message = fmt.Sprintf("waiting for the name node: %v", err)
+		return nil
+	}
+	version := strings.TrimSpace(strings.Split(info.Version, ",")[0])
+	for _, pod := range pods {
+		ref := metav1.GetControllerOf(pod)
+		if ref == nil || ref.Name != set.Name {
+			continue
+		}
+		ordinal, err := strconv.Atoi(strings.TrimPrefix(pod.Name, set.Name+"-"))
+		if err != nil || int32(ordinal) < up.DataNodePartition {
+			continue
+		}
+		if pod.Labels[apps.StatefulSetRevisionLabel] != set.Status.UpdateRevision || pod.DeletionTimestamp != nil || !controller.IsPodReady(pod) {
+			up.Message = fmt.Sprintf("waiting for data node %s to restart on %s", pod.Name, up.To.DataNode)
+			return nil
+		}
+		if !liveDataNode(info, pod.Status.PodIP, version) {
+			up.Message = fmt.Sprintf("waiting for data node %s to register with the name node", pod.Name)
+			return nil
+		}
+	}
+	if up.DataNodePartition > 0 {
+		up.DataNodePartition--
+		up.Message = fmt.Sprintf("upgrading data node %s-%d", set.Name, up.DataNodePartition)
+		return nil
+	}
+	up.Phase = v1alpha1.UpgradeFinalizing
+	up.Message = ""
+	um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradeDataNodesDone", "all data nodes run the new image, finalizing the upgrade")
+	return nil
+}
+
+// syncFinalizing finalizes the upgrade once no block is missing, the
+// rollback fsimages are dropped and the upgrade can not be rolled back anymore
+func (um *upgradeManager) syncFinalizing(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if up.JobName == "" {
+		if msg := um.nameNodesHealthy(hc); msg != "" {
+			up.Message = msg
+			return nil
+		}
+		for _, ns := range nameServices(hc) {
+			fs, err := um.newClient(hc, ns).GetFSNamesystem()
+			if err != nil {
+				up.Message = fmt.Sprintf("waiting for name service %s: %v", ns, err)
+				return nil
+			}
+			if fs.MissingBlocks != 0 {
+				up.Message = fmt.Sprintf("waiting for the %d missing blocks of name service %s", fs.MissingBlocks, ns)
+				return nil
+			}
+		}
+		job := um.newUpgradeJob(hc, nameNodeImage(hc), finalizeScript(hc))
+		if err := um.jobControl.CreateJob(hc, job); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+		up.JobName = job.Name
+		up.Message = "finalizing the rolling upgrade"
+		return nil
+	}
+	done, err := um.checkJob(hc, "finalize")
+	if err != nil || !done {
+		return err
+	}
+	hc.Status.Upgrade = nil
+	um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradeCompleted",
+		fmt.Sprintf("cluster runs %s, %s", up.To.NameNode, up.To.DataNode))
+	return nil
+}
+
+// startRollback stops the upgrade and restarts the name nodes on the old
+// image from the rollback fsimage
+func (um *upgradeManager) startRollback(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if up.JobName != "" {
+		job, err := um.jobControl.GetJob(hc, up.JobName)
+		if err == nil {
+			if err := um.jobControl.DeleteJob(hc, job); err != nil {
+				return err
+			}
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+		up.JobName = ""
+	}
+	glog.Infof("roll back the upgrade of %s/%s in phase %s", hc.Namespace, hc.Name, up.Phase)
+	um.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "UpgradeRollingBack",
+		fmt.Sprintf("rolling back to %s, %s, everything written since the upgrade started is lost", up.From.NameNode, up.From.DataNode))
+	up.Phase = v1alpha1.UpgradeRollingBackNameNodes
+	up.Message = ""
+	return nil
+}
+
+func (um *upgradeManager) syncRollingBackNameNodes(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if msg, err := um.nameNodesRolledOut(hc, up.From.NameNode, true); err != nil || msg != "" {
+		up.Message = msg
+		return err
+	}
+	up.Phase = v1alpha1.UpgradeRollingBackDataNodes
+	up.Message = ""
+	return nil
+}
+
+// syncRollingBackDataNodes waits for all data nodes to restart on the old
+// image at once, the blocks of the rolled back namespace are restored
+func (um *upgradeManager) syncRollingBackDataNodes(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	set, err := um.setControl.GetStatefulSet(hc, controller.DataNodeSetName(hc.Name))
+	if err != nil {
+		return err
+	}
+	replicas := *set.Spec.Replicas
+	if !runsImage(&set.Spec.Template.Spec, up.From.DataNode, true) || set.Status.ObservedGeneration < set.Generation ||
+		set.Status.CurrentRevision != set.Status.UpdateRevision ||
+		set.Status.UpdatedReplicas != replicas || set.Status.ReadyReplicas != replicas {
+		up.Message = fmt.Sprintf("waiting for the data nodes to restart on %s, %d of %d are ready", up.From.DataNode, set.Status.ReadyReplicas, replicas)
+		return nil
+	}
+	info, err := um.newClient(hc, hc.Name).GetNameNodeInfo()
+	if err != nil {
+		up.Message = fmt.Sprintf("waiting for the name node: %v", err)
+		return nil
+	}
+	if int32(len(info.LiveNodes)) < replicas {
+		up.Message = fmt.Sprintf("waiting for the data nodes to register, %d of %d are live", len(info.LiveNodes), replicas)
+		return nil
+	}
+	up.Phase = v1alpha1.UpgradeRolledBack
+	up.Message = ""
+	um.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "UpgradeRolledBack",
+		fmt.Sprintf("cluster runs %s, %s again", up.From.NameNode, up.From.DataNode))
+	return nil
+}
+
+// syncRolledBack keeps the old images until the spec has them again, so
+// the rolled back upgrade does not start over right away
+func (um *upgradeManager) syncRolledBack(hc *v1alpha1.HdfsCluster) error {
+	up := hc.Status.Upgrade
+	if specImages(hc) != up.From {
+		up.Message = fmt.Sprintf("rolled back, set the images back to %s, %s", up.From.NameNode, up.From.DataNode)
+		return nil
+	}
+	hc.Status.Upgrade = nil
+	return nil
+}
+
+// nameNodesHealthy returns what the name nodes of all name services are
+// waiting for, empty when they all serve out of safe mode
+func (um *upgradeManager) nameNodesHealthy(hc *v1alpha1.HdfsCluster) string {
+	for _, ns := range nameServices(hc) {
+		info, err := um.newClient(hc, ns).GetNameNodeInfo()
+		if err != nil {
+			return fmt.Sprintf("waiting for name service %s: %v", ns, err)
+		}
+		if info.Safemode != "" {
+			return fmt.Sprintf("waiting for name service %s to leave safe mode", ns)
+		}
+	}
+	return ""
+}
+
+// nameNodesRolledOut returns what the name node deployments are waiting for
+// to run image, empty when they all run it and serve
+func (um *upgradeManager) nameNodesRolledOut(hc *v1alpha1.HdfsCluster, image string, rollback bool) (string, error) {
+	for _, ns := range nameServices(hc) {
+		name := controller.NameServiceName(hc.Name, ns)
+		if ns == hc.Name {
+			name = controller.NameNodeDeployment(hc.Name)
+		}
+		deployment, err := um.deploymentControl.GetDeployment(hc, name)
+		if errors.IsNotFound(err) {
+			return fmt.Sprintf("waiting for name node %s to be created", name), nil
+		}
+		if err != nil {
+			return "", err
+		}
+		status := deployment.Status
+		replicas := *deployment.Spec.Replicas
+		if !runsImage(&deployment.Spec.Template.Spec, image, rollback) || status.ObservedGeneration < deployment.Generation ||
+			status.UpdatedReplicas != replicas || status.AvailableReplicas != replicas || status.Replicas != replicas {
+			return fmt.Sprintf("waiting for name node %s to restart on %s", name, image), nil
+		}
+	}
+	return um.nameNodesHealthy(hc), nil
+}
+
+// checkJob returns whether the prepare or finalize job completed, a failed
+// job is deleted and run again on the next sync
+func (um *upgradeManager) checkJob(hc *v1alpha1.HdfsCluster, step string) (bool, error) {
+	up := hc.Status.Upgrade
+	job, err := um.jobControl.GetJob(hc, up.JobName)
+	if errors.IsNotFound(err) {
+		up.JobName = ""
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	finished, result := controller.IsJobFinished(job)
+	if !finished {
+		return false, nil
+	}
+	if result != batchv1.JobComplete {
+		summary := JobSummary(um.podControl, hc, job)
+		if summary == "" {
+			summary = JobFailureMessage(job)
+		}
+		up.Message = fmt.Sprintf("%s the rolling upgrade failed, retrying: %s", step, summary)
+		um.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "UpgradeJobFailed", summary)
+	}
+	up.JobName = ""
+	if err := um.jobControl.DeleteJob(hc, job); err != nil {
+		return false, err
+	}
+	return result == batchv1.JobComplete, nil
+}
+
+func (um *upgradeManager) newUpgradeJob(hc *v1alpha1.HdfsCluster, image string, script string) *batchv1.Job {
+	job := NewHadoopJob(hc, controller.UpgradeJobName(hc.Name), SummarizedScript(script, false))
+	job.Spec.Template.Spec.Containers[0].Image = image
+	return job
+}
+
+// prepareScript prepares the name services not prepared yet and waits for
+// their rollback fsimages
+func prepareScript(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf(`set -e
+for fs in %[1]s; do
+  case "$(hdfs dfsadmin -fs $fs -rollingUpgrade query)" in
+    *"no rolling upgrade"*) hdfs dfsadmin -fs $fs -rollingUpgrade prepare ;;
+  esac
+done
+for fs in %[1]s; do
+  ready=""
+  for i in $(seq %[2]d); do
+    case "$(hdfs dfsadmin -fs $fs -rollingUpgrade query)" in
+      *"Proceed with rolling upgrade"*) ready=1; break ;;
+    esac
+    sleep %[3]d
+  done
+  if [ -z "$ready" ]; then
+    echo "rollback fsimage of $fs is not ready"
+    exit 1
+  fi
+done`, nameServiceFileSystems(hc), upgradePrepareAttempts, upgradePrepareInterval)
+}
+
+func finalizeScript(hc *v1alpha1.HdfsCluster) string {
+	return fmt.Sprintf(`set -e
+for fs in %s; do
+  hdfs dfsadmin -fs $fs -rollingUpgrade finalize
+done`, nameServiceFileSystems(hc))
+}
+
+// liveDataNode returns whether the data node at ip is live on version
+func liveDataNode(info *hdfs.NameNodeInfo, ip string, version string) bool {
+	if ip == "" {
+		return false
+	}
+	for _, node := range info.LiveNodes {
+		if strings.HasPrefix(node.XferAddr, ip+":") && node.Version == version {
+			return true
+		}
+	}
+	return false
+}
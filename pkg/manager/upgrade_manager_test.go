@@ -0,0 +1,392 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	"github.com/tommenx/hdfs-operator/pkg/hdfs"
+	hdfsfake "github.com/tommenx/hdfs-operator/pkg/hdfs/fake"
+	apps "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"testing"
+)
+
+type fakeDeploymentControl struct {
+	deployments map[string]*apps.Deployment
+}
+
+func (c *fakeDeploymentControl) CreateDeployment(hc *v1alpha1.HdfsCluster, deployment *apps.Deployment) error {
+	c.deployments[deployment.Name] = deployment
+	return nil
+}
+
+func (c *fakeDeploymentControl) GetDeployment(hc *v1alpha1.HdfsCluster, name string) (*apps.Deployment, error) {
+	deployment, ok := c.deployments[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, name)
+	}
+	return deployment, nil
+}
+
+func (c *fakeDeploymentControl) UpdateDeployment(hc *v1alpha1.HdfsCluster, deployment *apps.Deployment) error {
+	c.deployments[deployment.Name] = deployment
+	return nil
+}
+
+func (c *fakeDeploymentControl) DeleteDeployment(hc *v1alpha1.HdfsCluster, name string) error {
+	delete(c.deployments, name)
+	return nil
+}
+
+type fakeStatefulSetControl struct {
+	sets map[string]*apps.StatefulSet
+}
+
+func (c *fakeStatefulSetControl) CreateStatefulSet(hc *v1alpha1.HdfsCluster, set *apps.StatefulSet) error {
+	c.sets[set.Name] = set
+	return nil
+}
+
+func (c *fakeStatefulSetControl) GetStatefulSet(hc *v1alpha1.HdfsCluster, name string) (*apps.StatefulSet, error) {
+	set, ok := c.sets[name]
+	if !ok {
+		return nil, errors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "statefulsets"}, name)
+	}
+	return set, nil
+}
+
+func (c *fakeStatefulSetControl) UpdateStatefulSet(hc *v1alpha1.HdfsCluster, set *apps.StatefulSet) (*apps.StatefulSet, error) {
+	c.sets[set.Name] = set
+	return set, nil
+}
+
+func (c *fakeStatefulSetControl) DeleteStatefulSet(hc *v1alpha1.HdfsCluster, name string) error {
+	delete(c.sets, name)
+	return nil
+}
+
+type fakePodControl struct {
+	pods []*corev1.Pod
+}
+
+func (c *fakePodControl) CheckPodsStatus(hc *v1alpha1.HdfsCluster, selector map[string]string) (bool, map[string]string, error) {
+	return true, nil, nil
+}
+
+func (c *fakePodControl) ListPods(hc *v1alpha1.HdfsCluster, selector map[string]string) ([]*corev1.Pod, error) {
+	var pods []*corev1.Pod
+	for _, pod := range c.pods {
+		matches := true
+		for k, v := range selector {
+			if pod.Labels[k] != v {
+				matches = false
+			}
+		}
+		if matches {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+const (
+	oldNameNodeImage = "uhopper/hadoop-namenode:2.7.2"
+	oldDataNodeImage = "uhopper/hadoop-datanode:2.7.2"
+	newNameNodeImage = "uhopper/hadoop-namenode:2.7.7"
+	newDataNodeImage = "uhopper/hadoop-datanode:2.7.7"
+)
+
+// upgradeTest is a cluster of one name node and two data nodes running the
+// old images, the test plays the name node and data node managers and the
+// kubelet by changing the deployment, the statefulset and the pods
+type upgradeTest struct {
+	hc      *v1alpha1.HdfsCluster
+	nn      *hdfsfake.NameNode
+	deploys *fakeDeploymentControl
+	sets    *fakeStatefulSetControl
+	jobs    *fakeJobControl
+	pods    *fakePodControl
+	events  *fakeEventControl
+	manager Manager
+}
+
+func newUpgradeTest() *upgradeTest {
+	ut := &upgradeTest{
+		nn:      hdfsfake.NewNameNode(),
+		deploys: &fakeDeploymentControl{deployments: map[string]*apps.Deployment{}},
+		sets:    &fakeStatefulSetControl{sets: map[string]*apps.StatefulSet{}},
+		jobs:    &fakeJobControl{jobs: map[string]*batchv1.Job{}},
+		pods:    &fakePodControl{},
+		events:  &fakeEventControl{},
+	}
+	ut.hc = &v1alpha1.HdfsCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "demo", Namespace: "ns"},
+		Spec: v1alpha1.HdfsClusterSpec{
+			Images: &v1alpha1.ImagesSpec{NameNode: newNameNodeImage, DataNode: newDataNodeImage},
+		},
+	}
+	one, two := int32(1), int32(2)
+	ut.deploys.deployments[controller.NameNodeDeployment("demo")] = &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: controller.NameNodeDeployment("demo")},
+		Spec: apps.DeploymentSpec{
+			Replicas: &one,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: oldNameNodeImage}}}},
+		},
+		Status: apps.DeploymentStatus{Replicas: 1, UpdatedReplicas: 1, AvailableReplicas: 1},
+	}
+	set := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{Name: controller.DataNodeSetName("demo")},
+		Spec: apps.StatefulSetSpec{
+			Replicas: &two,
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: oldDataNodeImage}}}},
+		},
+		Status: apps.StatefulSetStatus{ReadyReplicas: 2, UpdatedReplicas: 2, CurrentRevision: "r1", UpdateRevision: "r1"},
+	}
+	ut.sets.sets[set.Name] = set
+	isController := true
+	live := map[string]hdfs.LiveNode{}
+	for i := 0; i < 2; i++ {
+		ip := fmt.Sprintf("10.0.0.%d", i+1)
+		ut.pods.pods = append(ut.pods.pods, &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:            fmt.Sprintf("%s-%d", set.Name, i),
+				Labels:          map[string]string{"app": "datanode", apps.StatefulSetRevisionLabel: "r1"},
+				OwnerReferences: []metav1.OwnerReference{{Kind: "StatefulSet", Name: set.Name, Controller: &isController}},
+			},
+			Status: corev1.PodStatus{
+				PodIP:      ip,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		})
+		live[ip] = hdfs.LiveNode{XferAddr: ip + ":50010", Version: "2.7.2"}
+	}
+	ut.nn.SetNameNodeInfo(hdfs.NameNodeInfo{Version: "2.7.2, r1", LiveNodes: live})
+	ut.manager = NewUpgradeManager(ut.deploys, ut.sets, ut.jobs, ut.pods, ut.events,
+		func(*v1alpha1.HdfsCluster, string) hdfs.Interface { return ut.nn.Client() })
+	return ut
+}
+
+// sync syncs once and checks the phase the upgrade is in afterwards, empty
+// when there is no upgrade
+func (ut *upgradeTest) sync(t *testing.T, phase v1alpha1.UpgradePhase) {
+	t.Helper()
+	if err := ut.manager.Sync(ut.hc); err != nil {
+		t.Fatalf("sync error, err=%v", err)
+	}
+	var got v1alpha1.UpgradePhase
+	if up := ut.hc.Status.Upgrade; up != nil {
+		got = up.Phase
+	}
+	if got != phase {
+		t.Fatalf("got phase %q, want %q, status %+v", got, phase, ut.hc.Status.Upgrade)
+	}
+}
+
+// finishUpgradeJob completes the prepare or finalize job
+func (ut *upgradeTest) finishUpgradeJob(t *testing.T) {
+	t.Helper()
+	job, ok := ut.jobs.jobs[controller.UpgradeJobName("demo")]
+	if !ok || ut.hc.Status.Upgrade.JobName != job.Name {
+		t.Fatalf("no upgrade job is running, status %+v", ut.hc.Status.Upgrade)
+	}
+	finishJob(job, batchv1.JobComplete)
+}
+
+// rollOutNameNode restarts the name node on image like the name node
+// manager and the deployment controller do
+func (ut *upgradeTest) rollOutNameNode(image string, rollback bool) {
+	deployment := ut.deploys.deployments[controller.NameNodeDeployment("demo")]
+	spec := &deployment.Spec.Template.Spec
+	setUpgrade(spec, image, "", nameNodeUpgradeScript, nil, rollback)
+}
+
+// restartDataNode runs data node ordinal on the update revision of the
+// statefulset and registers it with the name node on version
+func (ut *upgradeTest) restartDataNode(ordinal int, version string) {
+	set := ut.sets.sets[controller.DataNodeSetName("demo")]
+	pod := ut.pods.pods[ordinal]
+	pod.Labels[apps.StatefulSetRevisionLabel] = set.Status.UpdateRevision
+	info, _ := ut.nn.Client().GetNameNodeInfo()
+	info.LiveNodes[pod.Status.PodIP] = hdfs.LiveNode{XferAddr: pod.Status.PodIP + ":50010", Version: version}
+	ut.nn.SetNameNodeInfo(*info)
+}
+
+func (ut *upgradeTest) hasEvent(reason string) bool {
+	for _, r := range ut.events.reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func TestUpgrade(t *testing.T) {
+	ut := newUpgradeTest()
+	defer ut.nn.Close()
+
+	ut.sync(t, v1alpha1.UpgradePending)
+	up := ut.hc.Status.Upgrade
+	if up.From != (v1alpha1.ImagesSpec{NameNode: oldNameNodeImage, DataNode: oldDataNodeImage}) || up.To != specImages(ut.hc) {
+		t.Fatalf("unexpected images of the upgrade %+v", up)
+	}
+	if !ut.hasEvent("UpgradeStarted") {
+		t.Errorf("no UpgradeStarted event, got %v", ut.events.reasons)
+	}
+
+	// the upgrade waits for the data nodes before it is prepared
+	ut.sets.sets[controller.DataNodeSetName("demo")].Status.ReadyReplicas = 1
+	ut.sync(t, v1alpha1.UpgradePending)
+	ut.sets.sets[controller.DataNodeSetName("demo")].Status.ReadyReplicas = 2
+	ut.sync(t, v1alpha1.UpgradePreparing)
+
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	ut.finishUpgradeJob(t)
+	ut.sync(t, v1alpha1.UpgradeNameNodes)
+	if len(ut.jobs.jobs) != 0 || up.JobName != "" {
+		t.Errorf("the prepare job is not deleted")
+	}
+	if nameNodeImage(ut.hc) != newNameNodeImage || dataNodeImage(ut.hc) != oldDataNodeImage {
+		t.Errorf("got images %s, %s while the name nodes are upgraded", nameNodeImage(ut.hc), dataNodeImage(ut.hc))
+	}
+
+	// the data nodes wait for the name node to run the new image
+	ut.sync(t, v1alpha1.UpgradeNameNodes)
+	ut.rollOutNameNode(newNameNodeImage, false)
+	ut.nn.SetNameNodeInfo(hdfs.NameNodeInfo{Version: "2.7.7, r2", LiveNodes: map[string]hdfs.LiveNode{}})
+	ut.restartDataNode(0, "2.7.2")
+	ut.restartDataNode(1, "2.7.2")
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 2 {
+		t.Fatalf("got partition %d, want 2", up.DataNodePartition)
+	}
+
+	// the data nodes are upgraded from the highest ordinal down
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 2 {
+		t.Fatalf("the partition is lowered before the statefulset runs the new image")
+	}
+	set := ut.sets.sets[controller.DataNodeSetName("demo")]
+	set.Spec.Template.Spec.Containers[0].Image = newDataNodeImage
+	set.Status.UpdateRevision = "r2"
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 1 {
+		t.Fatalf("got partition %d, want 1", up.DataNodePartition)
+	}
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 1 {
+		t.Fatalf("the partition is lowered before data node 1 restarts")
+	}
+	ut.restartDataNode(1, "2.7.2")
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 1 {
+		t.Fatalf("the partition is lowered before data node 1 registers on the new version")
+	}
+	ut.restartDataNode(1, "2.7.7")
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	if up.DataNodePartition != 0 {
+		t.Fatalf("got partition %d, want 0", up.DataNodePartition)
+	}
+	ut.restartDataNode(0, "2.7.7")
+	ut.sync(t, v1alpha1.UpgradeFinalizing)
+
+	// rollback has no effect once the upgrade is finalized
+	ut.hc.Spec.Upgrade = &v1alpha1.UpgradeSpec{Rollback: true}
+	ut.nn.SetFSNamesystem(hdfs.FSNamesystem{MissingBlocks: 1})
+	ut.sync(t, v1alpha1.UpgradeFinalizing)
+	if up.JobName != "" {
+		t.Fatalf("the finalize job is created while blocks are missing")
+	}
+	ut.nn.SetFSNamesystem(hdfs.FSNamesystem{})
+	ut.sync(t, v1alpha1.UpgradeFinalizing)
+	ut.finishUpgradeJob(t)
+	ut.sync(t, "")
+	if !ut.hasEvent("UpgradeCompleted") {
+		t.Errorf("no UpgradeCompleted event, got %v", ut.events.reasons)
+	}
+}
+
+func TestUpgradeRollback(t *testing.T) {
+	ut := newUpgradeTest()
+	defer ut.nn.Close()
+
+	ut.sync(t, v1alpha1.UpgradePending)
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	ut.finishUpgradeJob(t)
+	ut.sync(t, v1alpha1.UpgradeNameNodes)
+	ut.rollOutNameNode(newNameNodeImage, false)
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+	ut.sync(t, v1alpha1.UpgradeDataNodes)
+
+	ut.hc.Spec.Upgrade = &v1alpha1.UpgradeSpec{Rollback: true}
+	ut.sync(t, v1alpha1.UpgradeRollingBackNameNodes)
+	if !ut.hasEvent("UpgradeRollingBack") {
+		t.Errorf("no UpgradeRollingBack event, got %v", ut.events.reasons)
+	}
+	if !nameNodeRollback(ut.hc) || dataNodeRollback(ut.hc) {
+		t.Errorf("the name nodes roll back before the data nodes")
+	}
+
+	// a name node started on the old image without the rollback does not count
+	ut.sync(t, v1alpha1.UpgradeRollingBackNameNodes)
+	ut.rollOutNameNode(oldNameNodeImage, false)
+	ut.sync(t, v1alpha1.UpgradeRollingBackNameNodes)
+	ut.rollOutNameNode(oldNameNodeImage, true)
+	ut.sync(t, v1alpha1.UpgradeRollingBackDataNodes)
+	if !dataNodeRollback(ut.hc) || dataNodeImage(ut.hc) != oldDataNodeImage {
+		t.Errorf("the data nodes are not rolled back")
+	}
+
+	// all data nodes restart at once on the old image
+	set := ut.sets.sets[controller.DataNodeSetName("demo")]
+	setUpgrade(&set.Spec.Template.Spec, oldDataNodeImage, "", dataNodeUpgradeScript, nil, true)
+	set.Status.UpdateRevision = "r3"
+	ut.sync(t, v1alpha1.UpgradeRollingBackDataNodes)
+	set.Status.CurrentRevision = "r3"
+	ut.sync(t, v1alpha1.UpgradeRolledBack)
+	if !ut.hasEvent("UpgradeRolledBack") {
+		t.Errorf("no UpgradeRolledBack event, got %v", ut.events.reasons)
+	}
+
+	// the rolled back upgrade does not start over until the spec has the old
+	// images again
+	ut.hc.Spec.Upgrade = nil
+	ut.sync(t, v1alpha1.UpgradeRolledBack)
+	ut.hc.Spec.Images = nil
+	ut.sync(t, "")
+	ut.sync(t, "")
+}
+
+func TestUpgradeRollbackDeletesPrepareJob(t *testing.T) {
+	ut := newUpgradeTest()
+	defer ut.nn.Close()
+
+	ut.sync(t, v1alpha1.UpgradePending)
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	ut.sync(t, v1alpha1.UpgradePreparing)
+	if len(ut.jobs.jobs) != 1 {
+		t.Fatalf("no prepare job is created")
+	}
+	ut.hc.Spec.Upgrade = &v1alpha1.UpgradeSpec{Rollback: true}
+	ut.sync(t, v1alpha1.UpgradeRollingBackNameNodes)
+	if len(ut.jobs.jobs) != 0 || ut.hc.Status.Upgrade.JobName != "" {
+		t.Errorf("the prepare job is not deleted on the rollback")
+	}
+}
+
+func TestUpgradeCanceled(t *testing.T) {
+	ut := newUpgradeTest()
+	defer ut.nn.Close()
+
+	ut.sync(t, v1alpha1.UpgradePending)
+	ut.hc.Spec.Images = nil
+	ut.sync(t, "")
+	if !ut.hasEvent("UpgradeCanceled") {
+		t.Errorf("no UpgradeCanceled event, got %v", ut.events.reasons)
+	}
+}
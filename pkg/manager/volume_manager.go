@@ -0,0 +1,181 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sort"
+	"strings"
+)
+
+const (
+	NameNodeComponent = "namenode"
+	DataNodeComponent = "datanode"
+)
+
+// volumeManager expands the name node and data node pvcs when the storage in
+// the spec grows, the data node pvcs come from the immutable
+// volumeClaimTemplates so they have to be patched one by one
+type volumeManager struct {
+	pvcControl   controller.PVCControlInterface
+	scControl    controller.StorageClassControlInterface
+	eventControl controller.EventControlInterface
+}
+
+func NewVolumeManager(
+	pvcControl controller.PVCControlInterface,
+	scControl controller.StorageClassControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &volumeManager{
+		pvcControl:   pvcControl,
+		scControl:    scControl,
+		eventControl: eventControl,
+	}
+}
+
+func (vm *volumeManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	var firstErr error
+	statuses := []v1alpha1.VolumeStatus{}
+	sync := func(component string, pvc *corev1.PersistentVolumeClaim, size string) {
+		status, err := vm.syncPVC(hc, component, pvc, size)
+		statuses = append(statuses, status)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	pvc, err := vm.pvcControl.GetPVC(hc, controller.NameNodePVCName(hc.Name))
+	if err != nil && !errors.IsNotFound(err) {
+		glog.Errorf("get name node pvc error, err=%+v", err)
+		return err
+	}
+	if err == nil {
+		sync(NameNodeComponent, pvc, hc.Spec.NameNode.Storage)
+	}
+
+	pvcs, err := vm.pvcControl.ListPVCs(hc, controller.DataNodeLabel())
+	if err != nil {
+		glog.Errorf("list data node pvcs error, err=%+v", err)
+		return err
+	}
+	sort.Slice(pvcs, func(i, j int) bool { return pvcs[i].Name < pvcs[j].Name })
+	prefix := controller.DataNodePVCPrefix(hc.Name)
+	for _, pvc := range pvcs {
+		if strings.HasPrefix(pvc.Name, prefix) {
+			sync(DataNodeComponent, pvc, hc.Spec.DataNode.Storage)
+		}
+	}
+
+	hc.Status.Volumes = statuses
+	return firstErr
+}
+
+func (vm *volumeManager) syncPVC(hc *v1alpha1.HdfsCluster, component string, pvc *corev1.PersistentVolumeClaim, size string) (v1alpha1.VolumeStatus, error) {
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	status := v1alpha1.VolumeStatus{
+		Name:          pvc.Name,
+		Component:     component,
+		RequestedSize: size,
+		CurrentSize:   requested.String(),
+	}
+	if capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]; ok {
+		status.CurrentSize = capacity.String()
+	}
+
+	want, err := resource.ParseQuantity(size)
+	if err != nil {
+		vm.reject(hc, &status, fmt.Sprintf("invalid storage %q: %v", size, err))
+		return status, nil
+	}
+	switch want.Cmp(requested) {
+	case -1:
+		vm.reject(hc, &status, fmt.Sprintf("storage can not be decreased from %s to %s", requested.String(), size))
+		return status, nil
+	case 1:
+		if msg := vm.checkExpandable(pvc); msg != "" {
+			vm.reject(hc, &status, msg)
+			return status, nil
+		}
+		newPVC := pvc.DeepCopy()
+		if newPVC.Spec.Resources.Requests == nil {
+			newPVC.Spec.Resources.Requests = corev1.ResourceList{}
+		}
+		newPVC.Spec.Resources.Requests[corev1.ResourceStorage] = want
+		updated, err := vm.pvcControl.UpdatePVC(hc, newPVC)
+		if err != nil {
+			status.Phase = VolumePhaseOf(pvc)
+			status.Message = fmt.Sprintf("expand pvc error: %v", err)
+			return status, err
+		}
+		pvc = updated
+		msg := fmt.Sprintf("expand pvc %s from %s to %s", pvc.Name, requested.String(), size)
+		glog.Info(msg)
+		vm.eventControl.RecordEvent(hc, corev1.EventTypeNormal, "VolumeResizeStarted", msg)
+	}
+	status.Phase = VolumePhaseOf(pvc)
+	return status, nil
+}
+
+func (vm *volumeManager) checkExpandable(pvc *corev1.PersistentVolumeClaim) string {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return "pvc has no storage class, it can not be expanded"
+	}
+	scName := *pvc.Spec.StorageClassName
+	sc, err := vm.scControl.GetStorageClass(scName)
+	if err != nil {
+		return fmt.Sprintf("get storage class %s error: %v", scName, err)
+	}
+	if sc.AllowVolumeExpansion == nil || !*sc.AllowVolumeExpansion {
+		return fmt.Sprintf("storage class %s does not allow volume expansion", scName)
+	}
+	return ""
+}
+
+// reject marks the volume as rejected, the warning event is only recorded
+// when the volume was not rejected in the previous status
+func (vm *volumeManager) reject(hc *v1alpha1.HdfsCluster, status *v1alpha1.VolumeStatus, msg string) {
+	status.Phase = v1alpha1.VolumeResizeRejected
+	status.Message = msg
+	for _, old := range hc.Status.Volumes {
+		if old.Name == status.Name && old.Phase == v1alpha1.VolumeResizeRejected && old.Message == msg {
+			return
+		}
+	}
+	glog.Errorf("resize pvc %s of %s/%s rejected, %s", status.Name, hc.Namespace, hc.Name, msg)
+	vm.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "VolumeResizeRejected", fmt.Sprintf("pvc %s: %s", status.Name, msg))
+}
+
+// VolumePhaseOf derives the resize phase from the pvc conditions and capacity
+func VolumePhaseOf(pvc *corev1.PersistentVolumeClaim) v1alpha1.VolumePhase {
+	for _, cond := range pvc.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case corev1.PersistentVolumeClaimFileSystemResizePending:
+			return v1alpha1.VolumeFileSystemResizePending
+		case corev1.PersistentVolumeClaimResizing:
+			return v1alpha1.VolumeResizing
+		}
+	}
+	requested := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+	capacity, ok := pvc.Status.Capacity[corev1.ResourceStorage]
+	if ok && capacity.Cmp(requested) < 0 {
+		return v1alpha1.VolumeResizing
+	}
+	return v1alpha1.VolumeResized
+}
+
+func (vm *volumeManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	for _, v := range hc.Status.Volumes {
+		if v.Phase != v1alpha1.VolumeResized {
+			return fmt.Errorf("pvc %s is %s", v.Name, v.Phase)
+		}
+	}
+	return nil
+}
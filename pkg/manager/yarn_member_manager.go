@@ -0,0 +1,412 @@
+package manager
+
+import (
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	apps "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"reflect"
+	"strconv"
+)
+
+const (
+	hostnameTopologyKey = "kubernetes.io/hostname"
+
+	// nodeManagerReservedMB is the memory of the node manager pod kept for
+	// the node manager itself
+	nodeManagerReservedMB = 1024
+	// minContainerMB is yarn.scheduler.minimum-allocation-mb
+	minContainerMB = 1024
+
+	yarnDir    = "/hadoop/yarn"
+	yarnVolume = "yarn-local"
+)
+
+type yarnManager struct {
+	deploymentControl controller.DeploymentControlInterface
+	setControl        controller.StatefulSetControlInterface
+	svcControl        controller.ServiceControlInterface
+	eventControl      controller.EventControlInterface
+}
+
+// NewYarnManager runs the yarn resource manager of a cluster and its node
+// managers on the nodes of the data nodes
+func NewYarnManager(
+	deployControl controller.DeploymentControlInterface,
+	setControl controller.StatefulSetControlInterface,
+	svcControl controller.ServiceControlInterface,
+	eventControl controller.EventControlInterface,
+) Manager {
+	return &yarnManager{
+		deploymentControl: deployControl,
+		setControl:        setControl,
+		svcControl:        svcControl,
+		eventControl:      eventControl,
+	}
+}
+
+func (ym *yarnManager) Sync(hc *v1alpha1.HdfsCluster) error {
+	if hc.Spec.Yarn == nil {
+		return ym.removeYarn(hc)
+	}
+	if err := validateYarn(hc); err != nil {
+		glog.Errorf("yarn of %s/%s is invalid, %v", hc.Namespace, hc.Name, err)
+		ym.eventControl.RecordEvent(hc, corev1.EventTypeWarning, "InvalidYarn", err.Error())
+		return err
+	}
+	if err := ym.syncService(hc, ym.getResourceManagerService(hc)); err != nil {
+		glog.Errorf("sync resource manager service error, err=%+v", err)
+		return err
+	}
+	if err := syncGatewayDeployment(ym.deploymentControl, hc, ym.getResourceManagerDeployment(hc)); err != nil {
+		glog.Errorf("sync resource manager deployment error, err=%+v", err)
+		return err
+	}
+	if err := ym.syncService(hc, ym.getNodeManagerService(hc)); err != nil {
+		glog.Errorf("sync node manager service error, err=%+v", err)
+		return err
+	}
+	if err := ym.syncNodeManagerStatefulSet(hc); err != nil {
+		glog.Errorf("sync node manager statefulset error, err=%+v", err)
+		return err
+	}
+	glog.Infof("sync yarn success")
+	return nil
+}
+
+func (ym *yarnManager) CheckStatus(hc *v1alpha1.HdfsCluster) error {
+	return nil
+}
+
+func validateYarn(hc *v1alpha1.HdfsCluster) error {
+	if kerberosSpec(hc) != nil {
+		return fmt.Errorf("yarn can not be combined with kerberos yet")
+	}
+	limits := hc.Spec.Yarn.NodeManager.Resources.Limits
+	if mem, ok := limits[corev1.ResourceMemory]; ok && mem.Value()>>20 < nodeManagerReservedMB+minContainerMB {
+		return fmt.Errorf("the memory limit of the node managers must be at least %dMi", nodeManagerReservedMB+minContainerMB)
+	}
+	return nil
+}
+
+// removeYarn deletes the resource manager and the node managers once yarn
+// is turned off, the running jobs are lost
+func (ym *yarnManager) removeYarn(hc *v1alpha1.HdfsCluster) error {
+	if err := removeGateway(ym.deploymentControl, ym.svcControl, hc, controller.ResourceManagerName(hc.Name)); err != nil {
+		return err
+	}
+	name := controller.NodeManagerName(hc.Name)
+	if _, err := ym.setControl.GetStatefulSet(hc, name); err == nil {
+		if err := ym.setControl.DeleteStatefulSet(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	if _, err := ym.svcControl.GetService(hc, name); err == nil {
+		if err := ym.svcControl.DeleteService(hc, name); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	} else if !errors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+func (ym *yarnManager) syncService(hc *v1alpha1.HdfsCluster, svc *corev1.Service) error {
+	_, err := ym.svcControl.GetService(hc, svc.Name)
+	if errors.IsNotFound(err) {
+		return ym.svcControl.CreateService(hc, svc)
+	}
+	return err
+}
+
+// syncNodeManagerStatefulSet creates the node managers or replaces the
+// replicas and the pod template of the existing statefulset
+func (ym *yarnManager) syncNodeManagerStatefulSet(hc *v1alpha1.HdfsCluster) error {
+	desired := ym.getNodeManagerStatefulSet(hc)
+	old, err := ym.setControl.GetStatefulSet(hc, desired.Name)
+	if errors.IsNotFound(err) {
+		return ym.setControl.CreateStatefulSet(hc, desired)
+	}
+	if err != nil {
+		return err
+	}
+	set := old.DeepCopy()
+	set.Spec.Replicas = desired.Spec.Replicas
+	set.Spec.Template = desired.Spec.Template
+	if reflect.DeepEqual(set.Spec, old.Spec) {
+		return nil
+	}
+	_, err = ym.setControl.UpdateStatefulSet(hc, set)
+	return err
+}
+
+func (ym *yarnManager) getResourceManagerService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	port := func(name string, port int) corev1.ServicePort {
+		return corev1.ServicePort{
+			Name:       name,
+			Port:       int32(port),
+			TargetPort: intstr.FromInt(port),
+			Protocol:   corev1.ProtocolTCP,
+		}
+	}
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.ResourceManagerName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.ResourceManagerLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				port("rpc", controller.ResourceManagerPort),
+				port("scheduler", controller.ResourceManagerSchedulerPort),
+				port("tracker", controller.ResourceManagerTrackerPort),
+				port("admin", controller.ResourceManagerAdminPort),
+				port("http", controller.ResourceManagerWebPort),
+			},
+			Selector: controller.ResourceManagerLabel(hc.Name),
+		},
+	}
+}
+
+// getNodeManagerService resolves the host names the node managers register
+// with, the application masters reach the node managers by them
+func (ym *yarnManager) getNodeManagerService(hc *v1alpha1.HdfsCluster) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.NodeManagerName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          controller.NodeManagerLabel(hc.Name),
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: []corev1.ServicePort{
+				{
+					Name:     "http",
+					Port:     controller.NodeManagerWebPort,
+					Protocol: corev1.ProtocolTCP,
+				},
+			},
+			ClusterIP:                "None",
+			Selector:                 controller.NodeManagerLabel(hc.Name),
+			PublishNotReadyAddresses: true,
+		},
+	}
+}
+
+func (ym *yarnManager) getResourceManagerDeployment(hc *v1alpha1.HdfsCluster) *apps.Deployment {
+	labels := controller.ResourceManagerLabel(hc.Name)
+	replicas := int32(1)
+	var env []corev1.EnvVar
+	for _, conf := range yarnSiteConf(hc) {
+		env = append(env, conf.env())
+	}
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.ResourceManagerName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			// a second resource manager would split the node managers
+			Strategy: apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name:            "resourcemanager",
+							Image:           controller.ResourceManagerImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Env:             env,
+							Resources:       hc.Spec.Yarn.ResourceManager.Resources,
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: controller.ResourceManagerPort, Name: "rpc", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.ResourceManagerSchedulerPort, Name: "scheduler", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.ResourceManagerTrackerPort, Name: "tracker", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.ResourceManagerAdminPort, Name: "admin", Protocol: corev1.ProtocolTCP},
+								{ContainerPort: controller.ResourceManagerWebPort, Name: "http", Protocol: corev1.ProtocolTCP},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(controller.ResourceManagerPort)},
+								},
+								PeriodSeconds: 10,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+	setGatewayConf(hc, &deployment.Spec.Template)
+	setRackAwareness(hc, &deployment.Spec.Template.Spec)
+	return deployment
+}
+
+func (ym *yarnManager) getNodeManagerStatefulSet(hc *v1alpha1.HdfsCluster) *apps.StatefulSet {
+	labels := controller.NodeManagerLabel(hc.Name)
+	replicas := hc.Spec.Yarn.NodeManager.Replicas
+	if replicas <= 0 {
+		replicas = dataNodeReplicas(hc)
+	}
+	var env []corev1.EnvVar
+	for _, conf := range yarnSiteConf(hc) {
+		env = append(env, conf.env())
+	}
+	for _, conf := range nodeManagerConf(hc) {
+		env = append(env, conf.env())
+	}
+	set := &apps.StatefulSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            controller.NodeManagerName(hc.Name),
+			Namespace:       hc.Namespace,
+			Labels:          labels,
+			OwnerReferences: []metav1.OwnerReference{controller.GetOwnerRef(hc)},
+		},
+		Spec: apps.StatefulSetSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: labels,
+			},
+			ServiceName:         controller.NodeManagerName(hc.Name),
+			PodManagementPolicy: apps.ParallelPodManagement,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: corev1.PodSpec{
+					Affinity: nodeManagerAffinity(hc),
+					Containers: []corev1.Container{
+						{
+							Name:            "nodemanager",
+							Image:           controller.NodeManagerImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Env:             env,
+							Resources:       hc.Spec.Yarn.NodeManager.Resources,
+							Ports: []corev1.ContainerPort{
+								{ContainerPort: controller.NodeManagerWebPort, Name: "http", Protocol: corev1.ProtocolTCP},
+							},
+							ReadinessProbe: &corev1.Probe{
+								Handler: corev1.Handler{
+									TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt(controller.NodeManagerWebPort)},
+								},
+								PeriodSeconds: 10,
+							},
+							VolumeMounts: []corev1.VolumeMount{
+								{
+									Name:      yarnVolume,
+									MountPath: yarnDir,
+								},
+							},
+						},
+					},
+					Volumes: []corev1.Volume{
+						{
+							Name:         yarnVolume,
+							VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+						},
+					},
+				},
+			},
+		},
+	}
+	setGatewayConf(hc, &set.Spec.Template)
+	setRackAwareness(hc, &set.Spec.Template.Spec)
+	setTopologyWait(hc, &set.Spec.Template.Spec)
+	return set
+}
+
+// nodeManagerAffinity requires a data node on the node of a node manager so
+// the jobs read their blocks without leaving the node, and spreads the node
+// managers
+func nodeManagerAffinity(hc *v1alpha1.HdfsCluster) *corev1.Affinity {
+	return &corev1.Affinity{
+		PodAffinity: &corev1.PodAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+				{
+					LabelSelector: &metav1.LabelSelector{MatchLabels: controller.DataNodeClusterLabel(hc.Name)},
+					TopologyKey:   hostnameTopologyKey,
+				},
+			},
+		},
+		PodAntiAffinity: &corev1.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: corev1.PodAffinityTerm{
+						LabelSelector: &metav1.LabelSelector{MatchLabels: controller.NodeManagerLabel(hc.Name)},
+						TopologyKey:   hostnameTopologyKey,
+					},
+				},
+			},
+		},
+	}
+}
+
+// yarnClientConf is what a client needs to submit a mapreduce job
+func yarnClientConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	return []hadoopConf{
+		{yarnConf, "yarn.resourcemanager.hostname", controller.ResourceManagerHost(hc)},
+		{mapredConf, "mapreduce.framework.name", "yarn"},
+	}
+}
+
+// yarnSiteConf is shared by the resource manager and the node managers,
+// they bind every address so the service names resolve to them
+func yarnSiteConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	confs := append(yarnClientConf(hc),
+		hadoopConf{yarnConf, "yarn.resourcemanager.bind-host", "0.0.0.0"},
+		hadoopConf{yarnConf, "yarn.nodemanager.bind-host", "0.0.0.0"},
+		hadoopConf{yarnConf, "yarn.nodemanager.aux-services", "mapreduce_shuffle"},
+		hadoopConf{yarnConf, "yarn.nodemanager.aux-services.mapreduce_shuffle.class", "org.apache.hadoop.mapred.ShuffleHandler"},
+		hadoopConf{yarnConf, "yarn.nodemanager.local-dirs", yarnDir + "/local"},
+		hadoopConf{yarnConf, "yarn.nodemanager.log-dirs", yarnDir + "/logs"},
+		// the pod limits bound the containers, virtual memory is no measure of them
+		hadoopConf{yarnConf, "yarn.nodemanager.vmem-check-enabled", "false"},
+		// the logs outlive the node manager pods in the cluster
+		hadoopConf{yarnConf, "yarn.log-aggregation-enable", "true"},
+	)
+	// a container larger than a node manager would never be scheduled
+	for _, conf := range nodeManagerConf(hc) {
+		switch conf.key {
+		case "yarn.nodemanager.resource.memory-mb":
+			confs = append(confs, hadoopConf{yarnConf, "yarn.scheduler.maximum-allocation-mb", conf.value})
+		case "yarn.nodemanager.resource.cpu-vcores":
+			confs = append(confs, hadoopConf{yarnConf, "yarn.scheduler.maximum-allocation-vcores", conf.value})
+		}
+	}
+	return confs
+}
+
+// nodeManagerConf offers the limits of the node manager pod to the jobs
+func nodeManagerConf(hc *v1alpha1.HdfsCluster) []hadoopConf {
+	limits := hc.Spec.Yarn.NodeManager.Resources.Limits
+	var confs []hadoopConf
+	if mem, ok := limits[corev1.ResourceMemory]; ok {
+		mb := mem.Value()>>20 - nodeManagerReservedMB
+		confs = append(confs, hadoopConf{yarnConf, "yarn.nodemanager.resource.memory-mb", strconv.FormatInt(mb, 10)})
+	}
+	if cpu, ok := limits[corev1.ResourceCPU]; ok {
+		vcores := cpu.MilliValue() / 1000
+		if vcores < 1 {
+			vcores = 1
+		}
+		confs = append(confs, hadoopConf{yarnConf, "yarn.nodemanager.resource.cpu-vcores", strconv.FormatInt(vcores, 10)})
+	}
+	return confs
+}
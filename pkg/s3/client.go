@@ -0,0 +1,159 @@
+package s3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultRegion = "us-east-1"
+
+	defaultTimeout = 30 * time.Second
+)
+
+// Interface is the part of the S3 api the operator needs, it works with any
+// S3 compatible store like MinIO or Ceph RGW
+type Interface interface {
+	PutObject(key string, body io.Reader, size int64) error
+	ListObjects(prefix string) ([]Object, error)
+	DeleteObject(key string) error
+}
+
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Config addresses a bucket, requests use path style urls
+// like http://minio:9000/bucket/key
+type Config struct {
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+type client struct {
+	Config
+	httpClient *http.Client
+}
+
+func NewClient(cfg Config) Interface {
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	if cfg.Region == "" {
+		cfg.Region = DefaultRegion
+	}
+	return &client{
+		Config:     cfg,
+		httpClient: &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// Error is the error document returned by S3
+type Error struct {
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	StatusCode int    `xml:"-"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (c *client) objectURL(key string) string {
+	u := url.URL{Path: "/" + c.Bucket + "/" + strings.TrimLeft(key, "/")}
+	return c.Endpoint + u.EscapedPath()
+}
+
+func (c *client) do(req *http.Request, out interface{}) error {
+	if err := sign(req, c.Region, c.AccessKey, c.SecretKey, time.Now()); err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		e := &Error{StatusCode: resp.StatusCode}
+		if xml.Unmarshal(body, e) != nil || e.Code == "" {
+			e.Code = http.StatusText(resp.StatusCode)
+			e.Message = strings.TrimSpace(string(body))
+		}
+		return e
+	}
+	if out == nil {
+		io.Copy(ioutil.Discard, resp.Body)
+		return nil
+	}
+	return xml.NewDecoder(resp.Body).Decode(out)
+}
+
+// PutObject uploads body as key, size must be the exact length of body
+func (c *client) PutObject(key string, body io.Reader, size int64) error {
+	req, err := http.NewRequest("PUT", c.objectURL(key), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	return c.do(req, nil)
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects returns all objects under prefix
+func (c *client) ListObjects(prefix string) ([]Object, error) {
+	objects := []Object{}
+	token := ""
+	for {
+		q := url.Values{}
+		q.Set("list-type", "2")
+		q.Set("prefix", prefix)
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+		req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s?%s", c.Endpoint, c.Bucket, q.Encode()), nil)
+		if err != nil {
+			return nil, err
+		}
+		result := &listBucketResult{}
+		if err := c.do(req, result); err != nil {
+			return nil, err
+		}
+		for _, o := range result.Contents {
+			objects = append(objects, Object{Key: o.Key, Size: o.Size, LastModified: o.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return objects, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (c *client) DeleteObject(key string) error {
+	req, err := http.NewRequest("DELETE", c.objectURL(key), nil)
+	if err != nil {
+		return err
+	}
+	return c.do(req, nil)
+}
@@ -0,0 +1,52 @@
+package s3_test
+
+import (
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	"github.com/tommenx/hdfs-operator/pkg/s3/fake"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestObjects(t *testing.T) {
+	srv := fake.NewServer("backup")
+	defer srv.Close()
+	cli := srv.Client("backup")
+
+	for key, data := range map[string]string{"ns/a/fsimage_1": "image", "ns/a/VERSION": "", "other/b": "b"} {
+		if err := cli.PutObject(key, strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("put %s error, err=%v", key, err)
+		}
+	}
+	if data, ok := srv.Object("backup", "ns/a/fsimage_1"); !ok || string(data) != "image" {
+		t.Errorf("got object %q, want image", data)
+	}
+	objects, err := cli.ListObjects("ns/")
+	if err != nil {
+		t.Fatalf("list objects error, err=%v", err)
+	}
+	if len(objects) != 2 || objects[0].Key != "ns/a/VERSION" || objects[1].Key != "ns/a/fsimage_1" || objects[1].Size != 5 {
+		t.Errorf("unexpected objects %+v", objects)
+	}
+	if err := cli.DeleteObject("ns/a/VERSION"); err != nil {
+		t.Fatalf("delete object error, err=%v", err)
+	}
+	if keys := srv.Keys("backup"); len(keys) != 2 {
+		t.Errorf("got keys %v after the delete", keys)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv := fake.NewServer("backup")
+	defer srv.Close()
+
+	wrong := s3.NewClient(s3.Config{Endpoint: srv.URL(), Bucket: "backup", AccessKey: fake.AccessKey, SecretKey: "wrong"})
+	err := wrong.PutObject("key", strings.NewReader("x"), 1)
+	if e, ok := err.(*s3.Error); !ok || e.Code != "SignatureDoesNotMatch" || e.StatusCode != http.StatusForbidden {
+		t.Errorf("got %v, want SignatureDoesNotMatch", err)
+	}
+	_, err = srv.Client("missing").ListObjects("")
+	if e, ok := err.(*s3.Error); !ok || e.Code != "NoSuchBucket" || e.StatusCode != http.StatusNotFound {
+		t.Errorf("got %v, want NoSuchBucket", err)
+	}
+}
@@ -0,0 +1,169 @@
+package fake
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/tommenx/hdfs-operator/pkg/s3"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	AccessKey = "minio"
+	SecretKey = "minio123"
+)
+
+// Server is an in-memory S3 compatible store served by an httptest.Server in
+// the way MinIO does, path style buckets and signature version 4 are checked
+type Server struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	buckets map[string]map[string]*object
+}
+
+type object struct {
+	data         []byte
+	lastModified time.Time
+}
+
+// NewServer starts a fake with the given empty buckets
+func NewServer(buckets ...string) *Server {
+	s := &Server{buckets: map[string]map[string]*object{}}
+	for _, b := range buckets {
+		s.buckets[b] = map[string]*object{}
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serve))
+	return s
+}
+
+func (s *Server) URL() string {
+	return s.server.URL
+}
+
+// Client returns a client for bucket signed with the fake credentials
+func (s *Server) Client(bucket string) s3.Interface {
+	return s3.NewClient(s3.Config{
+		Endpoint:  s.server.URL,
+		Bucket:    bucket,
+		AccessKey: AccessKey,
+		SecretKey: SecretKey,
+	})
+}
+
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Object returns the content of key in bucket
+func (s *Server) Object(bucket, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	o, ok := s.buckets[bucket][key]
+	if !ok {
+		return nil, false
+	}
+	return o.data, true
+}
+
+// Keys returns the sorted keys of bucket
+func (s *Server) Keys(bucket string) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := []string{}
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if err := s3.Verify(r, s3.DefaultRegion, AccessKey, SecretKey); err != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch", err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+	bucket, ok := s.buckets[parts[0]]
+	if !ok {
+		writeError(w, http.StatusNotFound, "NoSuchBucket", fmt.Sprintf("the bucket %s does not exist", parts[0]))
+		return
+	}
+	key := ""
+	if len(parts) == 2 {
+		key = parts[1]
+	}
+
+	switch {
+	case r.Method == "GET" && key == "":
+		s.list(w, r, bucket)
+	case r.Method == "PUT" && key != "":
+		data, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", err.Error())
+			return
+		}
+		if r.ContentLength >= 0 && int64(len(data)) != r.ContentLength {
+			writeError(w, http.StatusBadRequest, "IncompleteBody", "the body is shorter than the content length")
+			return
+		}
+		bucket[key] = &object{data: data, lastModified: time.Now().UTC()}
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "GET" && key != "":
+		o, ok := bucket[key]
+		if !ok {
+			writeError(w, http.StatusNotFound, "NoSuchKey", "the specified key does not exist")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(o.data)
+	case r.Method == "DELETE" && key != "":
+		delete(bucket, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "MethodNotAllowed", fmt.Sprintf("%s is not allowed", r.Method))
+	}
+}
+
+type listBucketResult struct {
+	XMLName     xml.Name       `xml:"ListBucketResult"`
+	Contents    []listedObject `xml:"Contents"`
+	IsTruncated bool           `xml:"IsTruncated"`
+}
+
+type listedObject struct {
+	Key          string    `xml:"Key"`
+	Size         int64     `xml:"Size"`
+	LastModified time.Time `xml:"LastModified"`
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request, bucket map[string]*object) {
+	prefix := r.URL.Query().Get("prefix")
+	result := listBucketResult{}
+	for key, o := range bucket {
+		if strings.HasPrefix(key, prefix) {
+			result.Contents = append(result.Contents, listedObject{Key: key, Size: int64(len(o.data)), LastModified: o.lastModified})
+		}
+	}
+	sort.Slice(result.Contents, func(i, j int) bool { return result.Contents[i].Key < result.Contents[j].Key })
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(http.StatusOK)
+	xml.NewEncoder(w).Encode(result)
+}
+
+func writeError(w http.ResponseWriter, code int, s3Code string, message string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(code)
+	xml.NewEncoder(w).Encode(struct {
+		XMLName xml.Name `xml:"Error"`
+		Code    string   `xml:"Code"`
+		Message string   `xml:"Message"`
+	}{Code: s3Code, Message: message})
+}
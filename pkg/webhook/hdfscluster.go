@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/client/clientset/versioned"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+)
+
+// HdfsClusterValidator rejects the deletion of hdfs clusters with deletion protection
+type HdfsClusterValidator struct {
+	cli versioned.Interface
+}
+
+func NewHdfsClusterValidator(cli versioned.Interface) *HdfsClusterValidator {
+	return &HdfsClusterValidator{cli: cli}
+}
+
+func (v *HdfsClusterValidator) Admit(req *AdmissionRequest) *AdmissionResponse {
+	if req.Operation != Delete || req.Resource.Resource != "hdfsclusters" {
+		return allow()
+	}
+	hc, err := v.oldHdfsCluster(req)
+	if errors.IsNotFound(err) {
+		return allow()
+	}
+	if err != nil {
+		glog.Errorf("get hdfs cluster %s/%s error, err=%+v", req.Namespace, req.Name, err)
+		return deny(http.StatusInternalServerError, err.Error())
+	}
+	if hc.Spec.DeletionProtection {
+		glog.Infof("deny deletion of hdfs cluster %s/%s, deletion protection is enabled", req.Namespace, req.Name)
+		return deny(http.StatusForbidden, fmt.Sprintf(
+			"hdfs cluster %s/%s has deletion protection enabled, set spec.deletion_protection to false before deleting it",
+			req.Namespace, req.Name))
+	}
+	return allow()
+}
+
+// oldHdfsCluster decodes the object being deleted, older api servers do not send
+// the old object on delete so it is read from the api server instead
+func (v *HdfsClusterValidator) oldHdfsCluster(req *AdmissionRequest) (*v1alpha1.HdfsCluster, error) {
+	if len(req.OldObject.Raw) != 0 {
+		hc := &v1alpha1.HdfsCluster{}
+		if err := json.Unmarshal(req.OldObject.Raw, hc); err != nil {
+			return nil, fmt.Errorf("decode hdfs cluster error, err=%v", err)
+		}
+		return hc, nil
+	}
+	return v.cli.StorageV1alpha1().HdfsClusters(req.Namespace).Get(req.Name, metav1.GetOptions{})
+}
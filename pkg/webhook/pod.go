@@ -0,0 +1,255 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"github.com/tommenx/hdfs-operator/pkg/controller"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"net/http"
+	"strings"
+)
+
+const (
+	// ClusterAnnotation selects the hdfs cluster a pod is a client of, as
+	// <namespace>/<name> or just <name> in the namespace of the pod
+	ClusterAnnotation = "hdfs.storage.io/cluster"
+	// KeytabSecretAnnotation names the secret with the keytab of the pod,
+	// it is mounted when the cluster uses kerberos
+	KeytabSecretAnnotation = "hdfs.storage.io/keytab-secret"
+	// KeytabKeyAnnotation is the key of the keytab in the secret, keytab by default
+	KeytabKeyAnnotation = "hdfs.storage.io/keytab-key"
+	// PrincipalAnnotation adds an init container that logs the principal in
+	// with the keytab, the ticket cache is shared with the containers
+	PrincipalAnnotation = "hdfs.storage.io/principal"
+)
+
+const (
+	clientConfigVolume = "hdfs-client-config"
+	clientConfigDir    = "/etc/hadoop-client"
+	keytabVolume       = "hdfs-keytab"
+	keytabDir          = "/etc/security/keytabs"
+	keytabFile         = "client.keytab"
+	ticketCacheVolume  = "hdfs-krb5-ccache"
+	ticketCacheDir     = "/var/run/hdfs-krb5"
+	krb5ConfPath       = "/etc/krb5.conf"
+	kinitContainer     = "hdfs-kinit"
+)
+
+// ClusterGetter returns the hdfs cluster namespace/name
+type ClusterGetter func(namespace string, name string) (*v1alpha1.HdfsCluster, error)
+
+// PodInjector mounts the client configmap of an hdfs cluster into the pods
+// annotated with ClusterAnnotation
+type PodInjector struct {
+	getCluster ClusterGetter
+}
+
+func NewPodInjector(getCluster ClusterGetter) *PodInjector {
+	return &PodInjector{getCluster: getCluster}
+}
+
+type patchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+func (p *PodInjector) Admit(req *AdmissionRequest) *AdmissionResponse {
+	if req.Operation != Create || req.Resource.Resource != "pods" {
+		return allow()
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(req.Object.Raw, pod); err != nil {
+		return deny(http.StatusBadRequest, fmt.Sprintf("decode pod error, err=%v", err))
+	}
+	ref, ok := pod.Annotations[ClusterAnnotation]
+	if !ok {
+		return allow()
+	}
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = pod.Namespace
+	}
+	hc, err := p.clientCluster(namespace, ref)
+	if err != nil {
+		glog.Infof("deny pod %s/%s%s, %v", namespace, pod.Name, pod.GenerateName, err)
+		return deny(http.StatusBadRequest, err.Error())
+	}
+	mutated := pod.DeepCopy()
+	if err := injectClientConfig(hc, mutated); err != nil {
+		return deny(http.StatusBadRequest, err.Error())
+	}
+	patch, err := json.Marshal(podPatch(pod, mutated))
+	if err != nil {
+		return deny(http.StatusInternalServerError, fmt.Sprintf("encode patch error, err=%v", err))
+	}
+	glog.Infof("inject client config of hdfs cluster %s/%s into pod %s/%s%s", hc.Namespace, hc.Name, namespace, pod.Name, pod.GenerateName)
+	patchType := "JSONPatch"
+	return &AdmissionResponse{Allowed: true, Patch: patch, PatchType: &patchType}
+}
+
+// clientCluster resolves ref and checks that its client configmap is
+// published in namespace, a pod can only mount configmaps of its namespace
+func (p *PodInjector) clientCluster(namespace string, ref string) (*v1alpha1.HdfsCluster, error) {
+	clusterNamespace, name := namespace, ref
+	if i := strings.Index(ref, "/"); i >= 0 {
+		clusterNamespace, name = ref[:i], ref[i+1:]
+	}
+	if clusterNamespace == "" || name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("annotation %s=%q is not <namespace>/<name>", ClusterAnnotation, ref)
+	}
+	hc, err := p.getCluster(clusterNamespace, name)
+	if errors.IsNotFound(err) {
+		return nil, fmt.Errorf("hdfs cluster %s/%s of annotation %s does not exist", clusterNamespace, name, ClusterAnnotation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get hdfs cluster %s/%s error, err=%v", clusterNamespace, name, err)
+	}
+	status := hc.Status.ClientConfig
+	if status == nil {
+		return nil, fmt.Errorf("hdfs cluster %s/%s has not published its client config yet", clusterNamespace, name)
+	}
+	if clusterNamespace != namespace && !containsString(status.MirroredNamespaces, namespace) {
+		return nil, fmt.Errorf("client config of hdfs cluster %s/%s is not mirrored into namespace %s, add the namespace to spec.client_config.mirror_namespace_selector",
+			clusterNamespace, name, namespace)
+	}
+	return hc, nil
+}
+
+// injectClientConfig adds the client config of hc to pod, the volumes and
+// env vars the pod already has are left alone so injecting twice is a no-op
+func injectClientConfig(hc *v1alpha1.HdfsCluster, pod *corev1.Pod) error {
+	spec := &pod.Spec
+	addVolume(spec, corev1.Volume{
+		Name: clientConfigVolume,
+		VolumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: hc.Status.ClientConfig.ConfigMap},
+			},
+		},
+	})
+	mounts := []corev1.VolumeMount{{Name: clientConfigVolume, MountPath: clientConfigDir, ReadOnly: true}}
+	env := []corev1.EnvVar{{Name: "HADOOP_CONF_DIR", Value: clientConfigDir}}
+	if hc.Spec.Security != nil && hc.Spec.Security.Kerberos != nil {
+		mounts = append(mounts,
+			corev1.VolumeMount{Name: clientConfigVolume, MountPath: krb5ConfPath, SubPath: "krb5.conf", ReadOnly: true},
+			corev1.VolumeMount{Name: ticketCacheVolume, MountPath: ticketCacheDir})
+		env = append(env, corev1.EnvVar{Name: "KRB5CCNAME", Value: "FILE:" + ticketCacheDir + "/krb5cc"})
+		addVolume(spec, corev1.Volume{
+			Name:         ticketCacheVolume,
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{Medium: corev1.StorageMediumMemory}},
+		})
+		if secret := pod.Annotations[KeytabSecretAnnotation]; secret != "" {
+			key := pod.Annotations[KeytabKeyAnnotation]
+			if key == "" {
+				key = "keytab"
+			}
+			mode := int32(0400)
+			addVolume(spec, corev1.Volume{
+				Name: keytabVolume,
+				VolumeSource: corev1.VolumeSource{
+					Secret: &corev1.SecretVolumeSource{
+						SecretName:  secret,
+						Items:       []corev1.KeyToPath{{Key: key, Path: keytabFile}},
+						DefaultMode: &mode,
+					},
+				},
+			})
+			mounts = append(mounts, corev1.VolumeMount{Name: keytabVolume, MountPath: keytabDir, ReadOnly: true})
+			env = append(env, corev1.EnvVar{Name: "KRB5_CLIENT_KTNAME", Value: keytabDir + "/" + keytabFile})
+		}
+		if principal := pod.Annotations[PrincipalAnnotation]; principal != "" {
+			if pod.Annotations[KeytabSecretAnnotation] == "" {
+				return fmt.Errorf("annotation %s needs the keytab of annotation %s", PrincipalAnnotation, KeytabSecretAnnotation)
+			}
+			if !hasContainer(spec.InitContainers, kinitContainer) {
+				spec.InitContainers = append([]corev1.Container{{
+					Name:    kinitContainer,
+					Image:   controller.HadoopImage,
+					Command: []string{"kinit", "-kt", keytabDir + "/" + keytabFile, principal},
+				}}, spec.InitContainers...)
+			}
+		}
+	}
+	for i := range spec.InitContainers {
+		injectContainer(&spec.InitContainers[i], mounts, env)
+	}
+	for i := range spec.Containers {
+		injectContainer(&spec.Containers[i], mounts, env)
+	}
+	return nil
+}
+
+func injectContainer(c *corev1.Container, mounts []corev1.VolumeMount, env []corev1.EnvVar) {
+	for _, m := range mounts {
+		if !hasMount(c.VolumeMounts, m.MountPath) {
+			c.VolumeMounts = append(c.VolumeMounts, m)
+		}
+	}
+	for _, e := range env {
+		if !hasEnv(c.Env, e.Name) {
+			c.Env = append(c.Env, e)
+		}
+	}
+}
+
+// podPatch replaces the parts of the pod spec injectClientConfig changed
+func podPatch(pod *corev1.Pod, mutated *corev1.Pod) []patchOperation {
+	patch := []patchOperation{}
+	if len(mutated.Spec.Volumes) != len(pod.Spec.Volumes) {
+		patch = append(patch, patchOperation{"add", "/spec/volumes", mutated.Spec.Volumes})
+	}
+	if len(mutated.Spec.InitContainers) != 0 {
+		patch = append(patch, patchOperation{"add", "/spec/initContainers", mutated.Spec.InitContainers})
+	}
+	patch = append(patch, patchOperation{"add", "/spec/containers", mutated.Spec.Containers})
+	return patch
+}
+
+func addVolume(spec *corev1.PodSpec, v corev1.Volume) {
+	for _, old := range spec.Volumes {
+		if old.Name == v.Name {
+			return
+		}
+	}
+	spec.Volumes = append(spec.Volumes, v)
+}
+
+func hasContainer(containers []corev1.Container, name string) bool {
+	for _, c := range containers {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasMount(mounts []corev1.VolumeMount, path string) bool {
+	for _, m := range mounts {
+		if m.MountPath == path {
+			return true
+		}
+	}
+	return false
+}
+
+func hasEnv(env []corev1.EnvVar, name string) bool {
+	for _, e := range env {
+		if e.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"encoding/json"
+	"flag"
+	"github.com/tommenx/hdfs-operator/pkg/apis/storage.io/v1alpha1"
+	"io/ioutil"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const fixtures = "../../cmd/podwebhook/fixtures"
+
+var update = flag.Bool("update", false, "rewrite the expected answers of the pod webhook fixtures")
+
+// TestPodFixtures answers every admission review fixture against
+// cluster.json and compares the answer with its .expected.json, the patch is
+// compared decoded. go test -update rewrites the expected answers
+func TestPodFixtures(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join(fixtures, "cluster.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hc := &v1alpha1.HdfsCluster{}
+	if err := json.Unmarshal(data, hc); err != nil {
+		t.Fatalf("decode hdfs cluster error, err=%v", err)
+	}
+	injector := NewPodInjector(func(namespace string, name string) (*v1alpha1.HdfsCluster, error) {
+		if hc.Namespace != namespace || hc.Name != name {
+			return nil, errors.NewNotFound(v1alpha1.Resource("hdfsclusters"), name)
+		}
+		return hc, nil
+	})
+	reviews, err := filepath.Glob(filepath.Join(fixtures, "pod*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range reviews {
+		if strings.HasSuffix(file, ".expected.json") {
+			continue
+		}
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			data, err := ioutil.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			review := &AdmissionReview{}
+			if err := json.Unmarshal(data, review); err != nil || review.Request == nil {
+				t.Fatalf("decode admission review error, err=%v", err)
+			}
+			resp := injector.Admit(review.Request)
+			resp.UID = review.Request.UID
+			answer := map[string]interface{}{"response": resp}
+			var patch []patchOperation
+			if len(resp.Patch) != 0 {
+				if err := json.Unmarshal(resp.Patch, &patch); err != nil {
+					t.Fatalf("decode patch error, err=%v", err)
+				}
+				answer["patch"] = patch
+				resp.Patch = nil
+			}
+			got, err := json.MarshalIndent(answer, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expectedFile := strings.TrimSuffix(file, ".json") + ".expected.json"
+			if *update {
+				if err := ioutil.WriteFile(expectedFile, append(got, '\n'), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			expected, err := ioutil.ReadFile(expectedFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !jsonEqual(t, got, expected) {
+				t.Errorf("got answer\n%s\nwant\n%s", got, expected)
+			}
+			if len(patch) != 0 {
+				checkPatchedPod(t, hc, review.Request.Object.Raw, patch)
+			}
+		})
+	}
+}
+
+// checkPatchedPod applies patch to the pod and checks that every container
+// reads the client config and that injecting again changes nothing
+func checkPatchedPod(t *testing.T, hc *v1alpha1.HdfsCluster, raw []byte, patch []patchOperation) {
+	var obj map[string]interface{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		t.Fatal(err)
+	}
+	spec := obj["spec"].(map[string]interface{})
+	for _, op := range patch {
+		if op.Op != "add" || !strings.HasPrefix(op.Path, "/spec/") || strings.Count(op.Path, "/") != 2 {
+			t.Fatalf("unexpected patch operation %s %s", op.Op, op.Path)
+		}
+		spec[strings.TrimPrefix(op.Path, "/spec/")] = op.Value
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod := &corev1.Pod{}
+	if err := json.Unmarshal(data, pod); err != nil {
+		t.Fatalf("decode patched pod error, err=%v", err)
+	}
+	for _, c := range pod.Spec.Containers {
+		if !hasMount(c.VolumeMounts, clientConfigDir) || !hasEnv(c.Env, "HADOOP_CONF_DIR") {
+			t.Errorf("container %s of the patched pod does not read the client config", c.Name)
+		}
+	}
+	again := pod.DeepCopy()
+	if err := injectClientConfig(hc, again); err != nil {
+		t.Fatalf("inject into the patched pod error, err=%v", err)
+	}
+	if !reflect.DeepEqual(again, pod) {
+		t.Errorf("injecting into the patched pod changes it again")
+	}
+}
+
+func jsonEqual(t *testing.T, a []byte, b []byte) bool {
+	var x, y interface{}
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	return reflect.DeepEqual(x, y)
+}
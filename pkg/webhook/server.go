@@ -0,0 +1,58 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/golang/glog"
+	"io/ioutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"net/http"
+)
+
+// AdmitFunc reviews a single admission request
+type AdmitFunc func(req *AdmissionRequest) *AdmissionResponse
+
+// Serve returns a handler that decodes the AdmissionReview, calls admit and
+// writes the review back with the response
+func Serve(admit AdmitFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("read body error, err=%v", err), http.StatusBadRequest)
+			return
+		}
+		review := &AdmissionReview{}
+		if err := json.Unmarshal(body, review); err != nil || review.Request == nil {
+			glog.Errorf("decode admission review error, err=%v", err)
+			http.Error(w, "invalid admission review", http.StatusBadRequest)
+			return
+		}
+		resp := admit(review.Request)
+		resp.UID = review.Request.UID
+		out, err := json.Marshal(&AdmissionReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: AdmissionAPIVersion, Kind: AdmissionReviewKind},
+			Response: resp,
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("encode admission review error, err=%v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(out)
+	}
+}
+
+func allow() *AdmissionResponse {
+	return &AdmissionResponse{Allowed: true}
+}
+
+func deny(code int32, msg string) *AdmissionResponse {
+	return &AdmissionResponse{
+		Allowed: false,
+		Result: &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Message: msg,
+			Code:    code,
+		},
+	}
+}